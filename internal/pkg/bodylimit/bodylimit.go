@@ -0,0 +1,101 @@
+package bodylimit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+// ErrTooLarge is returned by Reader when the body exceeds the limit.
+var ErrTooLarge = errors.New("request body too large")
+
+// Reader fails with ErrTooLarge as soon as more than limit bytes are read,
+// so a body is never buffered beyond the limit.
+type Reader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded atomic.Bool
+}
+
+// NewReader creates a new Reader instance.
+func NewReader(r io.Reader, limit int64) *Reader {
+	return &Reader{
+		r:     r,
+		limit: limit,
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.exceeded.Load() {
+		return 0, ErrTooLarge
+	}
+
+	// read one byte more than allowed to detect an exceeded limit
+	remaining := r.limit - r.read + 1
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		r.exceeded.Store(true)
+		return n - int(r.read-r.limit), ErrTooLarge
+	}
+	return n, err
+}
+
+// Exceeded reports whether the body exceeded the limit.
+func (r *Reader) Exceeded() bool {
+	return r.exceeded.Load()
+}
+
+// Close closes the underlying reader if it is an io.Closer.
+func (r *Reader) Close() error {
+	if closer, ok := r.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ReadBody reads the request body into memory and fails with ErrTooLarge as soon as more than
+// limit bytes are read. The transform function, if any, is applied to the original body before
+// the limit, e.g. to decompress it. The body is read before the request is passed on, because
+// hertz keeps the bytes read before an error in the body buffer: the following middlewares and
+// the upstream would receive a truncated body.
+func ReadBody(req *protocol.Request, limit int64, transform func(io.Reader) io.Reader) error {
+	var src io.Reader
+	if req.IsBodyStream() {
+		src = req.BodyStream()
+	} else {
+		src = bytes.NewReader(req.Body())
+	}
+
+	if transform != nil {
+		src = transform(src)
+	}
+
+	body, err := io.ReadAll(NewReader(src, limit))
+	if err != nil {
+		return err
+	}
+
+	req.SetBody(body)
+	req.Header.SetContentLength(len(body))
+	return nil
+}
+
+// Reject responds 413 and closes the connection, because the unread rest of the
+// body cannot be told apart from the next request on the connection.
+func Reject(c *app.RequestContext) {
+	c.Response.ResetBody()
+	c.Response.Header.Reset()
+	c.SetConnectionClose()
+	c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+}
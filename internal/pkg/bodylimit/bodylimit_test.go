@@ -0,0 +1,64 @@
+package bodylimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader("hello"), 5)
+	body, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.False(t, r.Exceeded())
+
+	r = NewReader(strings.NewReader("hello world"), 5)
+	body, err = io.ReadAll(r)
+	require.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, "hello", string(body))
+	assert.True(t, r.Exceeded())
+
+	_, err = r.Read(make([]byte, 10))
+	require.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestReadBody(t *testing.T) {
+	c := app.NewContext(0)
+	c.Request.SetBodyStream(strings.NewReader("HELLO"), -1)
+
+	err := ReadBody(&c.Request, 10, func(r io.Reader) io.Reader {
+		data, _ := io.ReadAll(r)
+		return strings.NewReader(strings.ToLower(string(data)))
+	})
+	require.NoError(t, err)
+	assert.False(t, c.Request.IsBodyStream())
+	assert.Equal(t, 5, c.Request.Header.ContentLength())
+	assert.Equal(t, "hello", string(c.Request.Body()))
+
+	// the body is not changed when it exceeds the limit
+	c = app.NewContext(0)
+	c.Request.SetBody([]byte("hello world"))
+	err = ReadBody(&c.Request, 5, nil)
+	require.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, "hello world", string(c.Request.Body()))
+}
+
+func TestReject(t *testing.T) {
+	c := app.NewContext(0)
+	c.Response.SetBodyString("upstream")
+	c.Response.Header.Set("X-Upstream", "1")
+
+	Reject(c)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, c.Response.StatusCode())
+	assert.Empty(t, c.Response.Body())
+	assert.Empty(t, c.Response.Header.Get("X-Upstream"))
+	assert.True(t, c.Response.ConnectionClose())
+	assert.True(t, c.IsAborted())
+}
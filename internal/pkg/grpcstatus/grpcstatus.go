@@ -0,0 +1,34 @@
+package grpcstatus
+
+import (
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"google.golang.org/grpc/codes"
+
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// FromResponse returns the gRPC status code of the response. The code set by the gRPC proxy is
+// preferred, otherwise the `grpc-status` trailer or header of the upstream response is used. It
+// reports false when the response has no gRPC status.
+func FromResponse(c *app.RequestContext) (codes.Code, bool) {
+	if val, found := c.Get(variable.GRPCStatusCode); found {
+		if code, ok := val.(codes.Code); ok {
+			return code, true
+		}
+	}
+
+	val := c.Response.Header.Trailer().Get("grpc-status")
+	if val == "" {
+		val = c.Response.Header.Get("grpc-status")
+	}
+	if val == "" {
+		return codes.OK, false
+	}
+	code, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		return codes.Unknown, true
+	}
+	return codes.Code(code), true
+}
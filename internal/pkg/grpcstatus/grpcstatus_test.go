@@ -0,0 +1,38 @@
+package grpcstatus
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+func TestFromResponse(t *testing.T) {
+	c := app.NewContext(0)
+	_, found := FromResponse(c)
+	assert.False(t, found)
+
+	c.Response.Header.Set("grpc-status", "5")
+	code, found := FromResponse(c)
+	assert.True(t, found)
+	assert.Equal(t, codes.NotFound, code)
+
+	// trailers take precedence over headers
+	_ = c.Response.Header.Trailer().Set("grpc-status", "7")
+	code, _ = FromResponse(c)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	// the code set by the gRPC proxy takes precedence over the response
+	c.Set(variable.GRPCStatusCode, codes.Unavailable)
+	code, _ = FromResponse(c)
+	assert.Equal(t, codes.Unavailable, code)
+
+	c = app.NewContext(0)
+	c.Response.Header.Set("grpc-status", "invalid")
+	code, found = FromResponse(c)
+	assert.True(t, found)
+	assert.Equal(t, codes.Unknown, code)
+}
@@ -0,0 +1,404 @@
+package jsonbody
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const wildcard = "*"
+
+// numbers are decoded as json.Number, so large integers are not changed by a transformation
+var jsonAPI = sonic.Config{UseNumber: true, SortMapKeys: true}.Froze()
+
+// Options defines the operations applied to a JSON body. Fields are addressed by a
+// dot path (`user.address.city`, `items.0.id`) or a JSONPath (`$.items[0].id`).
+type Options struct {
+	// Remove deletes the fields, `*` matches every field of an object or element of an array.
+	Remove []string
+	// Rename moves the value of a field to another path.
+	Rename map[string]string
+	// Add sets the fields only if they do not already exist.
+	Add map[string]any
+	// Set adds or overwrites the fields.
+	Set map[string]any
+}
+
+type rename struct {
+	from []string
+	to   []string
+}
+
+type assignment struct {
+	value any
+	path  []string
+}
+
+// Transformer applies the body operations of Options to JSON documents.
+type Transformer struct {
+	remove [][]string
+	rename []rename
+	add    []assignment
+	set    []assignment
+}
+
+// New creates a new Transformer instance.
+func New(opts Options) (*Transformer, error) {
+	t := &Transformer{}
+
+	for _, p := range opts.Remove {
+		path, err := ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		t.remove = append(t.remove, path)
+	}
+
+	// map keys are sorted, so the operations are applied in a predictable order
+	for _, from := range sortedKeys(opts.Rename) {
+		src, err := parseFixedPath(from)
+		if err != nil {
+			return nil, err
+		}
+		dst, err := parseFixedPath(opts.Rename[from])
+		if err != nil {
+			return nil, err
+		}
+		t.rename = append(t.rename, rename{from: src, to: dst})
+	}
+
+	var err error
+	t.add, err = parseAssignments(opts.Add)
+	if err != nil {
+		return nil, err
+	}
+	t.set, err = parseAssignments(opts.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// IsEmpty reports whether the transformer has no operations.
+func (t *Transformer) IsEmpty() bool {
+	return len(t.remove) == 0 && len(t.rename) == 0 && len(t.add) == 0 && len(t.set) == 0
+}
+
+// Transform applies the operations in the order remove, rename, add and set. Values of
+// add and set which are directives are resolved with the request context. It reports
+// whether the body was changed.
+func (t *Transformer) Transform(c *app.RequestContext, body []byte) ([]byte, bool, error) {
+	var root any
+	if err := jsonAPI.Unmarshal(body, &root); err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	var ok bool
+
+	for _, path := range t.remove {
+		root, ok = removePath(root, path)
+		changed = changed || ok
+	}
+
+	for _, r := range t.rename {
+		val, found := getPath(root, r.from)
+		if !found {
+			continue
+		}
+		root, _ = removePath(root, r.from)
+		root, _ = setPath(root, r.to, val, true)
+		changed = true
+	}
+
+	for _, a := range t.add {
+		root, ok = setPath(root, a.path, resolve(a.value, c), false)
+		changed = changed || ok
+	}
+
+	for _, a := range t.set {
+		root, ok = setPath(root, a.path, resolve(a.value, c), true)
+		changed = changed || ok
+	}
+
+	if !changed {
+		return body, false, nil
+	}
+	result, err := jsonAPI.Marshal(root)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// IsJSON reports whether the content type is `application/json` or a `+json` media type.
+func IsJSON(contentType []byte) bool {
+	mediaType, _, _ := bytes.Cut(contentType, []byte(";"))
+	mediaType = bytes.ToLower(bytes.TrimSpace(mediaType))
+	return bytes.Equal(mediaType, []byte("application/json")) || bytes.HasSuffix(mediaType, []byte("+json"))
+}
+
+// ParsePath parses a dot path or a JSONPath into its segments.
+func ParsePath(path string) ([]string, error) {
+	original := path
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	var segments []string
+	var current strings.Builder
+	pending := false
+
+	flush := func() {
+		if pending || current.Len() > 0 {
+			segments = append(segments, current.String())
+		}
+		current.Reset()
+		pending = false
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch ch := path[i]; ch {
+		case '\\':
+			// an escaped dot is part of the field name
+			if i+1 < len(path) {
+				i++
+				current.WriteByte(path[i])
+				pending = true
+			}
+		case '.':
+			if current.Len() == 0 && !pending {
+				return nil, fmt.Errorf("path '%s' is invalid", original)
+			}
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path '%s' is invalid", original)
+			}
+			segment := path[i+1 : i+end]
+			if len(segment) >= 2 && (segment[0] == '\'' || segment[0] == '"') && segment[len(segment)-1] == segment[0] {
+				segment = segment[1 : len(segment)-1]
+			} else if segment != wildcard {
+				if _, err := strconv.Atoi(segment); err != nil {
+					return nil, fmt.Errorf("path '%s' is invalid", original)
+				}
+			}
+			segments = append(segments, segment)
+			i += end
+			// a bracket may be followed directly by another segment, e.g. `items[0].id`
+			if i+1 < len(path) && path[i+1] == '.' {
+				i++
+				if i+1 >= len(path) {
+					return nil, fmt.Errorf("path '%s' is invalid", original)
+				}
+			}
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("path '%s' is invalid", original)
+	}
+	return segments, nil
+}
+
+// parseFixedPath parses a path which must address a single field.
+func parseFixedPath(path string) ([]string, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(segments, wildcard) {
+		return nil, fmt.Errorf("path '%s' is invalid, wildcards are only supported by remove", path)
+	}
+	return segments, nil
+}
+
+func parseAssignments(values map[string]any) ([]assignment, error) {
+	result := make([]assignment, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		path, err := parseFixedPath(key)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, assignment{path: path, value: values[key]})
+	}
+	return result, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// resolve returns the value of a directive; a missing directive becomes null, so a value
+// sent by the client is never kept by mistake.
+func resolve(value any, c *app.RequestContext) any {
+	s, ok := value.(string)
+	if !ok || !variable.IsDirective(s) {
+		return value
+	}
+	val, found := variable.Get(s, c)
+	if !found {
+		return nil
+	}
+	return val
+}
+
+func getPath(node any, path []string) (any, bool) {
+	for _, segment := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			val, found := n[segment]
+			if !found {
+				return nil, false
+			}
+			node = val
+		case []any:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil, false
+			}
+			node = n[idx]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setPath sets the value and creates the missing objects on the way. It returns the
+// updated node and whether it was changed.
+func setPath(node any, path []string, value any, overwrite bool) (any, bool) {
+	segment := path[0]
+	last := len(path) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, found := n[segment]
+		if last {
+			if found && !overwrite {
+				return n, false
+			}
+			n[segment] = value
+			return n, true
+		}
+		child, changed := setPath(child, path[1:], value, overwrite)
+		if changed {
+			n[segment] = child
+		}
+		return n, changed
+	case []any:
+		idx, err := strconv.Atoi(segment)
+		if err != nil || idx < 0 || idx >= len(n) {
+			return n, false
+		}
+		if last {
+			if !overwrite {
+				return n, false
+			}
+			n[idx] = value
+			return n, true
+		}
+		child, changed := setPath(n[idx], path[1:], value, overwrite)
+		if changed {
+			n[idx] = child
+		}
+		return n, changed
+	case nil:
+		return setPath(map[string]any{}, path, value, overwrite)
+	default:
+		// fields cannot be set on a scalar value
+		return node, false
+	}
+}
+
+// removePath deletes the value and returns the updated node and whether it was changed.
+func removePath(node any, path []string) (any, bool) {
+	segment := path[0]
+	last := len(path) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		if segment == wildcard {
+			if last {
+				if len(n) == 0 {
+					return n, false
+				}
+				return map[string]any{}, true
+			}
+			changed := false
+			for k, v := range n {
+				child, ok := removePath(v, path[1:])
+				if ok {
+					n[k] = child
+					changed = true
+				}
+			}
+			return n, changed
+		}
+
+		child, found := n[segment]
+		if !found {
+			return n, false
+		}
+		if last {
+			delete(n, segment)
+			return n, true
+		}
+		child, changed := removePath(child, path[1:])
+		if changed {
+			n[segment] = child
+		}
+		return n, changed
+	case []any:
+		if segment == wildcard {
+			if last {
+				if len(n) == 0 {
+					return n, false
+				}
+				return []any{}, true
+			}
+			changed := false
+			for i, v := range n {
+				child, ok := removePath(v, path[1:])
+				if ok {
+					n[i] = child
+					changed = true
+				}
+			}
+			return n, changed
+		}
+
+		idx, err := strconv.Atoi(segment)
+		if err != nil || idx < 0 || idx >= len(n) {
+			return n, false
+		}
+		if last {
+			return slices.Delete(n, idx, idx+1), true
+		}
+		child, changed := removePath(n[idx], path[1:])
+		if changed {
+			n[idx] = child
+		}
+		return n, changed
+	default:
+		return node, false
+	}
+}
@@ -0,0 +1,107 @@
+package jsonbody
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "name", want: []string{"name"}},
+		{path: "user.address.city", want: []string{"user", "address", "city"}},
+		{path: "items.0.id", want: []string{"items", "0", "id"}},
+		{path: "$.items[0].id", want: []string{"items", "0", "id"}},
+		{path: "$.items[*]", want: []string{"items", "*"}},
+		{path: "$['a.b'].c", want: []string{"a.b", "c"}},
+		{path: `a\.b.c`, want: []string{"a.b", "c"}},
+		{path: "matrix[0][1]", want: []string{"matrix", "0", "1"}},
+		{path: "", wantErr: true},
+		{path: "$", wantErr: true},
+		{path: "a..b", wantErr: true},
+		{path: "items[abc]", wantErr: true},
+		{path: "items[0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ParsePath(tt.path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tr, err := New(Options{
+		Remove: []string{"items.*.secret", "items.1", "missing.field"},
+		Rename: map[string]string{"old": "nested.new"},
+		Add: map[string]any{
+			"existing": "ignored",
+			"added":    true,
+		},
+		Set: map[string]any{
+			"tenant":  "$var.tenant",
+			"missing": "$var.missing",
+			"name":    "overwritten",
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, tr.IsEmpty())
+
+	c := app.NewContext(0)
+	c.Set("tenant", 42)
+
+	body := []byte(`{"existing":1,"old":"value","name":"john","items":[{"id":1,"secret":"a"},{"id":2}],"big":12345678901234567890}`)
+	result, changed, err := tr.Transform(c, body)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.JSONEq(t, `{"existing":1,"nested":{"new":"value"},"name":"overwritten","items":[{"id":1}],"big":12345678901234567890,"added":true,"tenant":42,"missing":null}`, string(result))
+}
+
+func TestTransformUnchanged(t *testing.T) {
+	tr, err := New(Options{
+		Remove: []string{"missing"},
+		Add:    map[string]any{"name": "john"},
+	})
+	require.NoError(t, err)
+
+	body := []byte(`{"name": "jane"}`)
+	result, changed, err := tr.Transform(app.NewContext(0), body)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, body, result)
+
+	_, _, err = tr.Transform(app.NewContext(0), []byte(`{"name":`))
+	require.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	tr, err := New(Options{})
+	require.NoError(t, err)
+	assert.True(t, tr.IsEmpty())
+
+	_, err = New(Options{Rename: map[string]string{"items.*": "other"}})
+	require.Error(t, err)
+
+	_, err = New(Options{Add: map[string]any{"a..b": 1}})
+	require.Error(t, err)
+}
+
+func TestIsJSON(t *testing.T) {
+	assert.True(t, IsJSON([]byte("application/json")))
+	assert.True(t, IsJSON([]byte("Application/JSON; charset=utf-8")))
+	assert.True(t, IsJSON([]byte("application/vnd.api+json")))
+	assert.False(t, IsJSON([]byte("text/plain")))
+	assert.False(t, IsJSON(nil))
+}
@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// NewChatResponseStream replays a chat response as a canonical SSE stream, e.g. to serve a
+// cached response to a streaming request. The stream is closed by the caller.
+func NewChatResponseStream(resp *ChatResponse) io.ReadCloser {
+	var buf bytes.Buffer
+
+	writeChunk := func(choices []StreamChoice, usage *Usage) {
+		chunk := StreamChunk{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: choices,
+			Usage:   usage,
+		}
+		data, err := sonic.Marshal(chunk)
+		if err != nil {
+			return
+		}
+		buf.WriteString("data: ")
+		buf.Write(data)
+		buf.WriteString("\n\n")
+	}
+
+	for _, choice := range resp.Choices {
+		writeChunk([]StreamChoice{{
+			Index: choice.Index,
+			Delta: StreamDelta{
+				Role:      choice.Message.Role,
+				Content:   choice.Message.Text(),
+				ToolCalls: choice.Message.ToolCalls,
+			},
+		}}, nil)
+
+		finishReason := choice.FinishReason
+		writeChunk([]StreamChoice{{Index: choice.Index, FinishReason: &finishReason}}, nil)
+	}
+
+	usage := resp.Usage
+	writeChunk([]StreamChoice{}, &usage)
+	buf.WriteString("data: [DONE]\n\n")
+
+	return io.NopCloser(&buf)
+}
+
+// ChatStreamRecorder is a decorator for a canonical SSE stream which assembles the chunks into a
+// chat response, e.g. to cache the response of a streaming request.
+type ChatStreamRecorder struct {
+	io.ReadCloser
+
+	resp     ChatResponse
+	contents map[int]*strings.Builder
+	buf      []byte
+	finished bool
+	failed   bool
+}
+
+// NewChatStreamRecorder creates a new recorder of the stream.
+func NewChatStreamRecorder(stream io.ReadCloser) *ChatStreamRecorder {
+	return &ChatStreamRecorder{
+		ReadCloser: stream,
+		resp:       ChatResponse{Object: "chat.completion"},
+		contents:   make(map[int]*strings.Builder),
+	}
+}
+
+// Read implements the io.Reader interface. It records the chunks passing through the stream.
+func (r *ChatStreamRecorder) Read(p []byte) (n int, err error) {
+	const eventDelimiterLen = 2
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.buf = append(r.buf, p[:n]...)
+		for {
+			idx := bytes.Index(r.buf, []byte("\n\n"))
+			if idx == -1 {
+				break
+			}
+			r.processEvent(r.buf[:idx])
+			nextIdx := idx + eventDelimiterLen
+			copy(r.buf, r.buf[nextIdx:])
+			r.buf = r.buf[:len(r.buf)-nextIdx]
+		}
+	}
+	if err != nil && err != io.EOF {
+		r.failed = true
+	}
+	return n, err
+}
+
+func (r *ChatStreamRecorder) processEvent(event []byte) {
+	for line := range bytes.SplitSeq(event, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		data, found := bytes.CutPrefix(line, []byte("data: "))
+		if !found || bytes.Equal(data, []byte("[DONE]")) {
+			continue
+		}
+
+		var chunk StreamChunk
+		if err := sonic.Unmarshal(data, &chunk); err != nil {
+			continue
+		}
+		r.record(&chunk)
+	}
+}
+
+func (r *ChatStreamRecorder) record(chunk *StreamChunk) {
+	if r.resp.ID == "" {
+		r.resp.ID = chunk.ID
+		r.resp.Created = chunk.Created
+		r.resp.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		r.resp.Usage = *chunk.Usage
+	}
+
+	for _, streamChoice := range chunk.Choices {
+		choice := r.choice(streamChoice.Index)
+		delta := streamChoice.Delta
+		if delta.Role != "" {
+			choice.Message.Role = delta.Role
+		}
+		if delta.Content != "" {
+			r.contents[streamChoice.Index].WriteString(delta.Content)
+		}
+		for _, call := range delta.ToolCalls {
+			// the first delta of a tool call carries its ID, the following deltas carry the arguments
+			calls := choice.Message.ToolCalls
+			if call.ID != "" || len(calls) == 0 {
+				choice.Message.ToolCalls = append(calls, call)
+				continue
+			}
+			last := &calls[len(calls)-1]
+			last.Function.Name += call.Function.Name
+			last.Function.Arguments += call.Function.Arguments
+		}
+		if streamChoice.FinishReason != nil && *streamChoice.FinishReason != "" {
+			choice.FinishReason = *streamChoice.FinishReason
+			r.finished = true
+		}
+	}
+}
+
+func (r *ChatStreamRecorder) choice(index int) *Choice {
+	for i := range r.resp.Choices {
+		if r.resp.Choices[i].Index == index {
+			return &r.resp.Choices[i]
+		}
+	}
+	r.resp.Choices = append(r.resp.Choices, Choice{Index: index, Message: Message{Role: "assistant"}})
+	r.contents[index] = &strings.Builder{}
+	return &r.resp.Choices[len(r.resp.Choices)-1]
+}
+
+// Response returns the recorded chat response, it returns false if the stream failed or
+// didn't complete.
+func (r *ChatStreamRecorder) Response() (*ChatResponse, bool) {
+	if r.failed || !r.finished {
+		return nil, false
+	}
+
+	resp := r.resp
+	resp.Choices = make([]Choice, len(r.resp.Choices))
+	for i, choice := range r.resp.Choices {
+		choice.Message.Content = r.contents[choice.Index].String()
+		resp.Choices[i] = choice
+	}
+	return &resp, true
+}
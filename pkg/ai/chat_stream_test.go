@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatResponseStream_RoundTrip(t *testing.T) {
+	resp := &ChatResponse{
+		ID:      "chat-123",
+		Object:  "chat.completion",
+		Created: 1670000000,
+		Model:   "gpt-4o",
+		Choices: []Choice{
+			{
+				Index: 0,
+				Message: Message{
+					Role:    "assistant",
+					Content: "hello",
+					ToolCalls: []ToolCall{
+						{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Taipei"}`}},
+					},
+				},
+				FinishReason: "tool_calls",
+			},
+		},
+		Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+
+	stream := NewChatResponseStream(resp)
+	recorder := NewChatStreamRecorder(stream)
+	data, err := io.ReadAll(recorder)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(data), "data: [DONE]\n\n"))
+
+	recorded, ok := recorder.Response()
+	require.True(t, ok)
+	assert.Equal(t, resp, recorded)
+}
+
+func TestChatStreamRecorder(t *testing.T) {
+	t.Run("assembles deltas", func(t *testing.T) {
+		sse := `data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"}}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{\"a\""}}]}}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"id":"","type":"","function":{"name":"","arguments":":1}"}}]},"finish_reason":"tool_calls"}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}
+
+data: [DONE]
+
+`
+		recorder := NewChatStreamRecorder(io.NopCloser(strings.NewReader(sse)))
+		_, err := io.ReadAll(recorder)
+		require.NoError(t, err)
+
+		resp, ok := recorder.Response()
+		require.True(t, ok)
+		require.Len(t, resp.Choices, 1)
+		assert.Equal(t, "Hello", resp.Choices[0].Message.Content)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"a":1}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+		assert.Equal(t, 5, resp.Usage.TotalTokens)
+	})
+
+	t.Run("incomplete stream", func(t *testing.T) {
+		sse := "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n"
+		recorder := NewChatStreamRecorder(io.NopCloser(strings.NewReader(sse)))
+		_, err := io.ReadAll(recorder)
+		require.NoError(t, err)
+
+		_, ok := recorder.Response()
+		assert.False(t, ok)
+	})
+
+	t.Run("failed stream", func(t *testing.T) {
+		sse := "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"},\"finish_reason\":\"stop\"}]}\n\n"
+		reader := io.MultiReader(strings.NewReader(sse), &errReader{err: errors.New("connection reset")})
+		recorder := NewChatStreamRecorder(io.NopCloser(reader))
+		_, err := io.ReadAll(recorder)
+		require.Error(t, err)
+
+		_, ok := recorder.Response()
+		assert.False(t, ok)
+	})
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float64{1, 2}, []float64{2, 4}), 0.0001)
+	assert.InDelta(t, 0, CosineSimilarity([]float64{1, 0}, []float64{0, 1}), 0.0001)
+	assert.InDelta(t, 0, CosineSimilarity([]float64{1, 0}, []float64{1}), 0.0001)
+	assert.InDelta(t, 0, CosineSimilarity([]float64{0, 0}, []float64{1, 1}), 0.0001)
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(_ []byte) (int, error) {
+	return 0, r.err
+}
@@ -3,6 +3,7 @@ package ai
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 // ClientAdapter defines the contract for translating between the client's SDK format
@@ -35,10 +36,21 @@ type ClientAdapter interface {
 	// The returned stream emits client-format SSE lines and must be closed by the caller.
 	StreamConverter(stream io.ReadCloser) io.ReadCloser
 
+	// ToClientStreamError encodes a canonical AIError as the SSE event which ends a client stream
+	// that failed after the response was started.
+	ToClientStreamError(err *AIError) ([]byte, error)
+
 	// ToClientError translates a canonical AIError into the client's expected format.
 	ToClientError(err *AIError) (any, error)
 }
 
+// PathClientAdapter is implemented by client adapters of protocols which carry request parameters,
+// such as the model, in the request path instead of the body.
+type PathClientAdapter interface {
+	// ToChatRequestWithPath translates a raw client JSON body and its request path into a canonical ChatRequest.
+	ToChatRequestWithPath(path string, body []byte) (*ChatRequest, error)
+}
+
 // ClientAdapterFactory is a function type that creates a specific ClientAdapter instance.
 type ClientAdapterFactory func() ClientAdapter
 
@@ -57,3 +69,60 @@ func GetClientAdapter(name string) (ClientAdapter, error) {
 	}
 	return factory(), nil
 }
+
+// thinking budgets of the reasoning efforts, for protocols which configure reasoning by tokens
+const (
+	reasoningBudgetLow    = 1024
+	reasoningBudgetMedium = 8192
+	reasoningBudgetHigh   = 24576
+)
+
+// reasoningEffort maps a thinking budget in tokens to the closest reasoning effort.
+func reasoningEffort(budget int) string {
+	switch {
+	case budget < (reasoningBudgetLow+reasoningBudgetMedium)/2:
+		return "low"
+	case budget < (reasoningBudgetMedium+reasoningBudgetHigh)/2:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// reasoningBudget maps a reasoning effort to a thinking budget in tokens.
+func reasoningBudget(effort string) int {
+	switch effort {
+	case "low":
+		return reasoningBudgetLow
+	case "high":
+		return reasoningBudgetHigh
+	default:
+		return reasoningBudgetMedium
+	}
+}
+
+// contentText returns the text of canonical message content, which is a string or a list of parts.
+func contentText(content any) string {
+	switch val := content.(type) {
+	case string:
+		return val
+	case []ContentPart:
+		var sb strings.Builder
+		for _, part := range val {
+			sb.WriteString(part.Text)
+		}
+		return sb.String()
+	case []any:
+		var sb strings.Builder
+		for _, part := range val {
+			if m, ok := part.(map[string]any); ok {
+				if text, ok := m["text"].(string); ok {
+					sb.WriteString(text)
+				}
+			}
+		}
+		return sb.String()
+	default:
+		return ""
+	}
+}
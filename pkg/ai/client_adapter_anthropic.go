@@ -0,0 +1,701 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+func init() {
+	RegisterClientAdapter("anthropic", func() ClientAdapter {
+		return NewAnthropicClientAdapter()
+	})
+}
+
+// AnthropicClientAdapter implements ClientAdapter for the Anthropic Messages API (`/v1/messages`).
+type AnthropicClientAdapter struct{}
+
+// NewAnthropicClientAdapter creates a new AnthropicClientAdapter instance.
+func NewAnthropicClientAdapter() *AnthropicClientAdapter {
+	return &AnthropicClientAdapter{}
+}
+
+// Name returns the client protocol name.
+func (a *AnthropicClientAdapter) Name() string {
+	return "anthropic"
+}
+
+// --- Anthropic Messages API wire types ---
+
+type anthropicMessagesRequest struct {
+	Model         string               `json:"model"`
+	Messages      []anthropicMessage   `json:"messages"`
+	System        json.RawMessage      `json:"system,omitempty"` // string OR []anthropicContentBlock
+	MaxTokens     int                  `json:"max_tokens"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking      *anthropicThinking   `json:"thinking,omitempty"`
+	Metadata      *anthropicMetadata   `json:"metadata,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // string OR []anthropicContentBlock
+}
+
+type anthropicContentBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage       `json:"content,omitempty"` // tool_result content, string OR []anthropicContentBlock
+	IsError   bool                  `json:"is_error,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Type        string          `json:"type,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"` // "auto", "any", "tool" or "none"
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"` // "enabled" or "disabled"
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+type anthropicMessagesResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"` // "message"
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []anthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        anthropicUsage          `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// --- Ingress ---
+
+// ToChatRequest translates an Anthropic Messages API request into a canonical ChatRequest.
+func (a *AnthropicClientAdapter) ToChatRequest(body []byte) (*ChatRequest, error) {
+	var req anthropicMessagesRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	if len(req.Model) == 0 {
+		return nil, newAnthropicRequestError("model: field required")
+	}
+	if len(req.Messages) == 0 {
+		return nil, newAnthropicRequestError("messages: at least one message is required")
+	}
+
+	chatReq := &ChatRequest{
+		Model:       req.Model,
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.StopSequences,
+	}
+	if req.MaxTokens > 0 {
+		chatReq.MaxTokens = &req.MaxTokens
+	}
+
+	if len(req.System) > 0 {
+		blocks, err := decodeAnthropicContent(req.System)
+		if err != nil {
+			return nil, newAnthropicRequestError("system: " + err.Error())
+		}
+		if system := anthropicText(blocks); len(system) > 0 {
+			chatReq.Messages = append(chatReq.Messages, Message{Role: "system", Content: system})
+		}
+	}
+
+	for i, msg := range req.Messages {
+		messages, err := fromAnthropicMessage(msg)
+		if err != nil {
+			return nil, newAnthropicRequestError(fmt.Sprintf("messages.%d: %s", i, err.Error()))
+		}
+		chatReq.Messages = append(chatReq.Messages, messages...)
+	}
+
+	for i, tool := range req.Tools {
+		if tool.Type != "" && tool.Type != "custom" {
+			return nil, newAnthropicRequestError(fmt.Sprintf("tools.%d: tool type '%s' is not supported", i, tool.Type))
+		}
+		chatReq.Tools = append(chatReq.Tools, Tool{
+			Type: "function",
+			Function: FunctionDesc{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if req.ToolChoice != nil {
+		switch req.ToolChoice.Type {
+		case "auto", "none":
+			chatReq.ToolChoice = req.ToolChoice.Type
+		case "any":
+			chatReq.ToolChoice = "required"
+		case "tool":
+			chatReq.ToolChoice = map[string]any{
+				"type":     "function",
+				"function": map[string]any{"name": req.ToolChoice.Name},
+			}
+		default:
+			return nil, newAnthropicRequestError(fmt.Sprintf("tool_choice: type '%s' is not supported", req.ToolChoice.Type))
+		}
+		if req.ToolChoice.DisableParallelToolUse {
+			parallel := false
+			chatReq.ParallelToolCalls = &parallel
+		}
+	}
+
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		chatReq.Reasoning = &Reasoning{Effort: reasoningEffort(req.Thinking.BudgetTokens)}
+	}
+
+	if req.Metadata != nil && len(req.Metadata.UserID) > 0 {
+		chatReq.UnknownFields = map[string]any{"user": req.Metadata.UserID}
+	}
+
+	return chatReq, nil
+}
+
+// fromAnthropicMessage translates an Anthropic message into canonical messages. The tool results of a
+// user message become messages of the "tool" role, which must directly follow the tool calls.
+func fromAnthropicMessage(msg anthropicMessage) ([]Message, error) {
+	if msg.Role != "user" && msg.Role != "assistant" {
+		return nil, fmt.Errorf("role '%s' is not supported", msg.Role)
+	}
+
+	blocks, err := decodeAnthropicContent(msg.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Role == "assistant" {
+		assistant := Message{Role: "assistant"}
+		var text strings.Builder
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "tool_use":
+				arguments := "{}"
+				if len(block.Input) > 0 {
+					arguments = string(block.Input)
+				}
+				assistant.ToolCalls = append(assistant.ToolCalls, ToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: FunctionCall{Name: block.Name, Arguments: arguments},
+				})
+			case "thinking", "redacted_thinking":
+				// thinking blocks are only meaningful to the model which produced them
+			default:
+				return nil, fmt.Errorf("content type '%s' is not supported for assistant messages", block.Type)
+			}
+		}
+		if text.Len() > 0 || len(assistant.ToolCalls) == 0 {
+			assistant.Content = text.String()
+		}
+		return []Message{assistant}, nil
+	}
+
+	var messages []Message
+	var parts []ContentPart
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, ContentPart{Type: "text", Text: block.Text})
+		case "image":
+			url, err := anthropicImageURL(block.Source)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}})
+		case "tool_result":
+			resultBlocks, err := decodeAnthropicContent(block.Content)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, Message{
+				Role:       "tool",
+				ToolCallID: block.ToolUseID,
+				Content:    anthropicText(resultBlocks),
+			})
+		default:
+			return nil, fmt.Errorf("content type '%s' is not supported for user messages", block.Type)
+		}
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0].Type == "text":
+		messages = append(messages, Message{Role: "user", Content: parts[0].Text})
+	case len(parts) > 0:
+		messages = append(messages, Message{Role: "user", Content: parts})
+	}
+	return messages, nil
+}
+
+// decodeAnthropicContent decodes content which is either a string or a list of content blocks.
+func decodeAnthropicContent(raw json.RawMessage) ([]anthropicContentBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	if raw[0] == '"' {
+		var text string
+		if err := sonic.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicContentBlock{{Type: "text", Text: text}}, nil
+	}
+
+	var blocks []anthropicContentBlock
+	if err := sonic.Unmarshal(raw, &blocks); err != nil {
+		return nil, errors.New("content must be a string or a list of content blocks")
+	}
+	return blocks, nil
+}
+
+func anthropicText(blocks []anthropicContentBlock) string {
+	var sb strings.Builder
+	for _, block := range blocks {
+		if block.Type == "text" {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
+
+func anthropicImageURL(source *anthropicImageSource) (string, error) {
+	if source == nil {
+		return "", errors.New("image source is missing")
+	}
+	switch source.Type {
+	case "base64":
+		return "data:" + source.MediaType + ";base64," + source.Data, nil
+	case "url":
+		return source.URL, nil
+	default:
+		return "", fmt.Errorf("image source type '%s' is not supported", source.Type)
+	}
+}
+
+func newAnthropicRequestError(message string) *AIError {
+	return &AIError{
+		Type:       "invalid_request_error",
+		Message:    message,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// ToResponsesRequest is not supported, since the Anthropic API has no Responses family.
+func (a *AnthropicClientAdapter) ToResponsesRequest(_ []byte) (*ResponsesRequest, error) {
+	return nil, &AIError{
+		Type:       "not_found_error",
+		Message:    "Responses API is not supported by the anthropic format",
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// --- Egress ---
+
+// ToClientChatResponse translates a canonical ChatResponse into an Anthropic message object.
+func (a *AnthropicClientAdapter) ToClientChatResponse(resp *ChatResponse) (any, error) {
+	stopReason := "end_turn"
+	msg := &anthropicMessagesResponse{
+		ID:         resp.ID,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      resp.Model,
+		Content:    []anthropicContentBlock{},
+		StopReason: &stopReason,
+		Usage:      toAnthropicUsage(resp.Usage),
+	}
+
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		stopReason = anthropicStopReason(choice.FinishReason)
+
+		if text := contentText(choice.Message.Content); len(text) > 0 {
+			msg.Content = append(msg.Content, anthropicContentBlock{Type: "text", Text: text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			input := json.RawMessage(call.Function.Arguments)
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			msg.Content = append(msg.Content, anthropicContentBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: input,
+			})
+		}
+	}
+
+	return msg, nil
+}
+
+// ToClientResponsesResponse is not supported, since the Anthropic API has no Responses family.
+func (a *AnthropicClientAdapter) ToClientResponsesResponse(_ *ResponsesResponse) (any, error) {
+	return nil, errors.New("responses API is not supported by the anthropic format")
+}
+
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// toAnthropicUsage converts the usage, Anthropic's input tokens don't include the cached tokens.
+func toAnthropicUsage(usage Usage) anthropicUsage {
+	result := anthropicUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	}
+	if usage.PromptTokensDetails != nil && usage.PromptTokensDetails.CachedTokens > 0 {
+		result.CacheReadInputTokens = usage.PromptTokensDetails.CachedTokens
+		result.InputTokens = max(usage.PromptTokens-usage.PromptTokensDetails.CachedTokens, 0)
+	}
+	return result
+}
+
+// AnthropicErrorResponse represents the error response returned by Anthropic APIs.
+type AnthropicErrorResponse struct {
+	Type  string               `json:"type"` // always "error"
+	Error AnthropicErrorDetail `json:"error"`
+}
+
+// AnthropicErrorDetail represents details of the Anthropic error.
+type AnthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ToClientError translates a canonical AIError into the Anthropic error format.
+func (a *AnthropicClientAdapter) ToClientError(err *AIError) (any, error) {
+	return &AnthropicErrorResponse{
+		Type: "error",
+		Error: AnthropicErrorDetail{
+			Type:    anthropicErrorType(err),
+			Message: err.Message,
+		},
+	}, nil
+}
+
+// ToClientStreamError encodes the error as an `error` event, which Anthropic sends instead of
+// the remaining events when a stream fails.
+func (a *AnthropicClientAdapter) ToClientStreamError(err *AIError) ([]byte, error) {
+	resp, _ := a.ToClientError(err)
+	data, marshalErr := sonic.Marshal(resp)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return fmt.Appendf(nil, "event: error\ndata: %s\n\n", data), nil
+}
+
+// anthropicErrorType keeps the error types which Anthropic SDKs know, other types are derived
+// from the status code.
+func anthropicErrorType(err *AIError) string {
+	switch err.Type {
+	case "invalid_request_error", "authentication_error", "permission_error", "not_found_error",
+		"request_too_large", "rate_limit_error", "api_error", "overloaded_error":
+		return err.Type
+	}
+
+	switch err.StatusCode {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// --- Streaming ---
+
+// StreamConverter re-encodes the canonical SSE stream as Anthropic message stream events.
+func (a *AnthropicClientAdapter) StreamConverter(stream io.ReadCloser) io.ReadCloser {
+	return &anthropicStreamConverter{
+		stream: stream,
+		reader: bufio.NewReader(stream),
+	}
+}
+
+type anthropicStreamEvent struct {
+	Type         string                     `json:"type"`
+	Message      *anthropicMessagesResponse `json:"message,omitempty"`
+	Index        *int                       `json:"index,omitempty"`
+	ContentBlock any                        `json:"content_block,omitempty"`
+	Delta        any                        `json:"delta,omitempty"`
+	Usage        *anthropicUsage            `json:"usage,omitempty"`
+}
+
+type anthropicMessageDelta struct {
+	StopReason   string  `json:"stop_reason"`
+	StopSequence *string `json:"stop_sequence"`
+}
+
+// anthropicStreamConverter reads canonical chat.completion.chunk lines and writes the events of
+// an Anthropic message stream: message_start, content blocks of text, thinking and tool_use,
+// message_delta with the stop reason and usage, and message_stop.
+type anthropicStreamConverter struct {
+	stream io.ReadCloser
+	reader *bufio.Reader
+	out    bytes.Buffer
+	err    error
+
+	started    bool
+	finished   bool
+	blockIndex int
+	blockType  string // type of the open content block, empty if no block is open
+	stopReason string
+	usage      *Usage
+}
+
+func (s *anthropicStreamConverter) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && s.err == nil {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.processLine(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.finish()
+			}
+			s.err = err
+		}
+	}
+
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	return 0, s.err
+}
+
+func (s *anthropicStreamConverter) Close() error {
+	return s.stream.Close()
+}
+
+func (s *anthropicStreamConverter) processLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	data, found := bytes.CutPrefix(line, []byte("data:"))
+	if !found {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("[DONE]")) {
+		s.finish()
+		return
+	}
+
+	var chunk StreamChunk
+	if err := sonic.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+	s.processChunk(&chunk)
+}
+
+func (s *anthropicStreamConverter) processChunk(chunk *StreamChunk) {
+	if s.finished {
+		return
+	}
+	if !s.started {
+		s.start(chunk.ID, chunk.Model)
+	}
+
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	choice := chunk.Choices[0]
+
+	if len(choice.Delta.ReasoningContent) > 0 {
+		s.openBlock("thinking", map[string]any{"type": "thinking", "thinking": ""})
+		s.writeDelta(map[string]any{"type": "thinking_delta", "thinking": choice.Delta.ReasoningContent})
+	}
+
+	if len(choice.Delta.Content) > 0 {
+		s.openBlock("text", map[string]any{"type": "text", "text": ""})
+		s.writeDelta(map[string]any{"type": "text_delta", "text": choice.Delta.Content})
+	}
+
+	for _, call := range choice.Delta.ToolCalls {
+		// the first chunk of a tool call has its ID, the following chunks only have arguments
+		if len(call.ID) > 0 {
+			s.closeBlock()
+			s.openBlock("tool_use", map[string]any{
+				"type":  "tool_use",
+				"id":    call.ID,
+				"name":  call.Function.Name,
+				"input": map[string]any{},
+			})
+		}
+		if len(call.Function.Arguments) > 0 && s.blockType == "tool_use" {
+			s.writeDelta(map[string]any{"type": "input_json_delta", "partial_json": call.Function.Arguments})
+		}
+	}
+
+	if choice.FinishReason != nil && len(*choice.FinishReason) > 0 {
+		s.stopReason = anthropicStopReason(*choice.FinishReason)
+	}
+}
+
+func (s *anthropicStreamConverter) start(id string, model string) {
+	s.started = true
+	s.writeEvent("message_start", anthropicStreamEvent{
+		Type: "message_start",
+		Message: &anthropicMessagesResponse{
+			ID:      id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   model,
+			Content: []anthropicContentBlock{},
+		},
+	})
+}
+
+// finish closes the open content block and writes the final events, the usage is only known
+// after the last choice.
+func (s *anthropicStreamConverter) finish() {
+	if s.finished {
+		return
+	}
+	if !s.started {
+		s.start("", "")
+	}
+	s.finished = true
+	s.closeBlock()
+
+	stopReason := s.stopReason
+	if len(stopReason) == 0 {
+		stopReason = "end_turn"
+	}
+	usage := anthropicUsage{}
+	if s.usage != nil {
+		usage = toAnthropicUsage(*s.usage)
+	}
+
+	s.writeEvent("message_delta", anthropicStreamEvent{
+		Type:  "message_delta",
+		Delta: anthropicMessageDelta{StopReason: stopReason},
+		Usage: &usage,
+	})
+	s.writeEvent("message_stop", anthropicStreamEvent{Type: "message_stop"})
+}
+
+func (s *anthropicStreamConverter) openBlock(blockType string, contentBlock map[string]any) {
+	if s.blockType == blockType {
+		return
+	}
+	s.closeBlock()
+	s.blockType = blockType
+	index := s.blockIndex
+	s.writeEvent("content_block_start", anthropicStreamEvent{
+		Type:         "content_block_start",
+		Index:        &index,
+		ContentBlock: contentBlock,
+	})
+}
+
+func (s *anthropicStreamConverter) closeBlock() {
+	if len(s.blockType) == 0 {
+		return
+	}
+	index := s.blockIndex
+	s.writeEvent("content_block_stop", anthropicStreamEvent{
+		Type:  "content_block_stop",
+		Index: &index,
+	})
+	s.blockType = ""
+	s.blockIndex++
+}
+
+func (s *anthropicStreamConverter) writeDelta(delta map[string]any) {
+	index := s.blockIndex
+	s.writeEvent("content_block_delta", anthropicStreamEvent{
+		Type:  "content_block_delta",
+		Index: &index,
+		Delta: delta,
+	})
+}
+
+func (s *anthropicStreamConverter) writeEvent(name string, event anthropicStreamEvent) {
+	data, err := sonic.Marshal(event)
+	if err != nil {
+		return
+	}
+	s.out.WriteString("event: " + name + "\n")
+	s.out.WriteString("data: ")
+	s.out.Write(data)
+	s.out.WriteString("\n\n")
+}
@@ -0,0 +1,262 @@
+package ai
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicClientAdapterToChatRequest(t *testing.T) {
+	adapter, err := GetClientAdapter("anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", adapter.Name())
+
+	body := []byte(`{
+		"model": "claude-sonnet",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "You are helpful."}],
+		"stop_sequences": ["END"],
+		"stream": true,
+		"temperature": 0.5,
+		"metadata": {"user_id": "user-1"},
+		"thinking": {"type": "enabled", "budget_tokens": 8000},
+		"tools": [{"name": "get_weather", "description": "Get the weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any", "disable_parallel_tool_use": true},
+		"messages": [
+			{"role": "user", "content": "What is the weather in Taipei?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "...", "signature": "sig"},
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Taipei"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "sunny"}]},
+				{"type": "text", "text": "And this picture?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aGVsbG8="}}
+			]}
+		]
+	}`)
+
+	chatReq, err := adapter.ToChatRequest(body)
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet", chatReq.Model)
+	assert.True(t, chatReq.Stream)
+	require.NotNil(t, chatReq.MaxTokens)
+	assert.Equal(t, 1024, *chatReq.MaxTokens)
+	assert.Equal(t, []string{"END"}, chatReq.Stop)
+	assert.InDelta(t, 0.5, *chatReq.Temperature, 0)
+	assert.Equal(t, "medium", chatReq.Reasoning.Effort)
+	assert.Equal(t, "required", chatReq.ToolChoice)
+	require.NotNil(t, chatReq.ParallelToolCalls)
+	assert.False(t, *chatReq.ParallelToolCalls)
+	assert.Equal(t, map[string]any{"user": "user-1"}, chatReq.UnknownFields)
+
+	require.Len(t, chatReq.Tools, 1)
+	assert.Equal(t, "function", chatReq.Tools[0].Type)
+	assert.Equal(t, "get_weather", chatReq.Tools[0].Function.Name)
+	assert.JSONEq(t, `{"type":"object"}`, string(chatReq.Tools[0].Function.Parameters))
+
+	require.Len(t, chatReq.Messages, 5)
+	assert.Equal(t, Message{Role: "system", Content: "You are helpful."}, chatReq.Messages[0])
+	assert.Equal(t, Message{Role: "user", Content: "What is the weather in Taipei?"}, chatReq.Messages[1])
+
+	assistant := chatReq.Messages[2]
+	assert.Equal(t, "assistant", assistant.Role)
+	assert.Equal(t, "Let me check.", assistant.Content)
+	require.Len(t, assistant.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", assistant.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", assistant.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Taipei"}`, assistant.ToolCalls[0].Function.Arguments)
+
+	// tool results directly follow the tool calls
+	assert.Equal(t, Message{Role: "tool", ToolCallID: "toolu_1", Content: "sunny"}, chatReq.Messages[3])
+	assert.Equal(t, Message{Role: "user", Content: []ContentPart{
+		{Type: "text", Text: "And this picture?"},
+		{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,aGVsbG8="}},
+	}}, chatReq.Messages[4])
+
+	chatReq, err = adapter.ToChatRequest([]byte(`{"model":"claude","messages":[{"role":"user","content":"hi"}],` +
+		`"tool_choice":{"type":"tool","name":"get_weather"}}`))
+	require.NoError(t, err)
+	assert.Nil(t, chatReq.MaxTokens)
+	assert.Equal(t, map[string]any{
+		"type":     "function",
+		"function": map[string]any{"name": "get_weather"},
+	}, chatReq.ToolChoice)
+
+	invalid := []string{
+		`{"model":"claude"`,
+		`{"messages":[{"role":"user","content":"hi"}]}`,
+		`{"model":"claude","messages":[]}`,
+		`{"model":"claude","messages":[{"role":"system","content":"hi"}]}`,
+		`{"model":"claude","messages":[{"role":"user","content":[{"type":"document"}]}]}`,
+		`{"model":"claude","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"web_search_20250305","name":"web_search"}]}`,
+	}
+	for _, body := range invalid {
+		_, err = adapter.ToChatRequest([]byte(body))
+		assert.Error(t, err, body)
+	}
+
+	_, err = adapter.ToResponsesRequest([]byte(`{}`))
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusNotFound, aiErr.StatusCode)
+}
+
+func TestAnthropicClientAdapterToClientChatResponse(t *testing.T) {
+	adapter := NewAnthropicClientAdapter()
+
+	resp, err := adapter.ToClientChatResponse(&ChatResponse{
+		ID:    "chatcmpl-1",
+		Model: "claude-sonnet",
+		Choices: []Choice{{
+			Message: Message{
+				Role:    "assistant",
+				Content: "Let me check.",
+				ToolCalls: []ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Taipei"}`},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: Usage{
+			PromptTokens:        100,
+			CompletionTokens:    20,
+			TotalTokens:         120,
+			PromptTokensDetails: &PromptTokensDetails{CachedTokens: 40},
+		},
+	})
+	require.NoError(t, err)
+
+	b, err := sonic.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-1",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Taipei"}}
+		],
+		"stop_reason": "tool_use",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 60, "output_tokens": 20, "cache_read_input_tokens": 40}
+	}`, string(b))
+
+	_, err = adapter.ToClientResponsesResponse(&ResponsesResponse{})
+	assert.Error(t, err)
+}
+
+func TestAnthropicClientAdapterToClientError(t *testing.T) {
+	adapter := NewAnthropicClientAdapter()
+
+	tests := []struct {
+		err      *AIError
+		expected string
+	}{
+		{err: &AIError{Type: "rate_limit_error", StatusCode: http.StatusTooManyRequests}, expected: "rate_limit_error"},
+		{err: &AIError{Type: "invalid_request_error", StatusCode: http.StatusNotFound}, expected: "invalid_request_error"},
+		{err: &AIError{Type: "provider_error", StatusCode: http.StatusUnauthorized}, expected: "authentication_error"},
+		{err: &AIError{Type: "internal_error", StatusCode: http.StatusBadGateway}, expected: "api_error"},
+		{err: &AIError{Type: "upstream_error", StatusCode: http.StatusServiceUnavailable}, expected: "overloaded_error"},
+	}
+
+	for _, tt := range tests {
+		tt.err.Message = "boom"
+		resp, err := adapter.ToClientError(tt.err)
+		require.NoError(t, err)
+		assert.Equal(t, &AnthropicErrorResponse{
+			Type:  "error",
+			Error: AnthropicErrorDetail{Type: tt.expected, Message: "boom"},
+		}, resp)
+	}
+}
+
+func TestAnthropicClientAdapterToClientStreamError(t *testing.T) {
+	adapter := NewAnthropicClientAdapter()
+
+	data, err := adapter.ToClientStreamError(&AIError{Message: "boom", StatusCode: http.StatusServiceUnavailable})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"boom\"}}\n\n",
+		string(data),
+	)
+}
+
+func TestAnthropicStreamConverter(t *testing.T) {
+	adapter := NewAnthropicClientAdapter()
+
+	chunks := []string{
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Hmm"}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"content":" world"}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"tool_calls":[{"id":"","type":"","function":{"name":"","arguments":"{\"city\":"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{"tool_calls":[{"id":"","type":"","function":{"name":"","arguments":"\"Taipei\"}"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"chatcmpl-1","model":"claude-sonnet","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+	}
+	var input strings.Builder
+	for _, chunk := range chunks {
+		input.WriteString("data: " + chunk + "\n\n")
+	}
+	input.WriteString("data: [DONE]\n\n")
+
+	stream := adapter.StreamConverter(io.NopCloser(strings.NewReader(input.String())))
+	defer stream.Close()
+	out, err := io.ReadAll(stream)
+	require.NoError(t, err)
+
+	type event struct {
+		name string
+		data string
+	}
+	var events []event
+	for block := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n\n") {
+		name, data, found := strings.Cut(block, "\n")
+		require.True(t, found)
+		events = append(events, event{
+			name: strings.TrimPrefix(name, "event: "),
+			data: strings.TrimPrefix(data, "data: "),
+		})
+	}
+
+	expected := []event{
+		{"message_start", `{"type":"message_start","message":{"id":"chatcmpl-1","type":"message","role":"assistant","model":"claude-sonnet","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Hmm"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":" world"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":1}`},
+		{"content_block_start", `{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"call_1","name":"get_weather","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"Taipei\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":2}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"input_tokens":10,"output_tokens":5}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}
+	require.Len(t, events, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].name, events[i].name)
+		assert.JSONEq(t, expected[i].data, events[i].data)
+	}
+}
+
+func TestAnthropicStreamConverterEmptyStream(t *testing.T) {
+	stream := NewAnthropicClientAdapter().StreamConverter(io.NopCloser(strings.NewReader("")))
+	out, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "event: message_start\n")
+	assert.Contains(t, string(out), `"stop_reason":"end_turn"`)
+	assert.True(t, strings.HasSuffix(string(out), "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+}
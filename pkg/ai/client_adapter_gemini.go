@@ -0,0 +1,559 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+func init() {
+	RegisterClientAdapter("gemini", func() ClientAdapter {
+		return NewGeminiClientAdapter()
+	})
+}
+
+// GeminiClientAdapter implements ClientAdapter for the Gemini API, e.g.
+// `/v1beta/models/{model}:generateContent` and `/v1beta/models/{model}:streamGenerateContent?alt=sse`.
+type GeminiClientAdapter struct{}
+
+var _ PathClientAdapter = (*GeminiClientAdapter)(nil)
+
+// NewGeminiClientAdapter creates a new GeminiClientAdapter instance.
+func NewGeminiClientAdapter() *GeminiClientAdapter {
+	return &GeminiClientAdapter{}
+}
+
+// Name returns the client protocol name.
+func (a *GeminiClientAdapter) Name() string {
+	return "gemini"
+}
+
+// --- Ingress ---
+
+// ToChatRequestWithPath translates a Gemini request into a canonical ChatRequest. The model and
+// whether the response is streamed are taken from the request path.
+func (a *GeminiClientAdapter) ToChatRequestWithPath(path string, body []byte) (*ChatRequest, error) {
+	chatReq, err := a.ToChatRequest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	_, action, found := strings.Cut(path, "/models/")
+	if !found {
+		return nil, newGeminiRequestError("the model is missing in the request path")
+	}
+	model, method, _ := strings.Cut(action, ":")
+	if len(model) == 0 {
+		return nil, newGeminiRequestError("the model is missing in the request path")
+	}
+
+	switch method {
+	case "generateContent":
+	case "streamGenerateContent":
+		chatReq.Stream = true
+	default:
+		return nil, &AIError{
+			Type:       "not_found_error",
+			Message:    fmt.Sprintf("method '%s' is not supported", method),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	chatReq.Model = model
+	return chatReq, nil
+}
+
+// ToChatRequest translates a Gemini request body into a canonical ChatRequest, the model is
+// only known from the request path, see ToChatRequestWithPath.
+func (a *GeminiClientAdapter) ToChatRequest(body []byte) (*ChatRequest, error) {
+	var req geminiRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	if len(req.Contents) == 0 {
+		return nil, newGeminiRequestError("contents must not be empty")
+	}
+
+	chatReq := &ChatRequest{}
+
+	if req.SystemInstruction != nil {
+		if system := geminiText(req.SystemInstruction.Parts); len(system) > 0 {
+			chatReq.Messages = append(chatReq.Messages, Message{Role: "system", Content: system})
+		}
+	}
+
+	// Gemini clients don't have to send the IDs of function calls, so the calls get IDs which the
+	// responses take in order of the function name
+	pendingCalls := map[string][]string{}
+	callCount := 0
+
+	for i, content := range req.Contents {
+		var messages []Message
+		switch content.Role {
+		case "model":
+			msg := Message{Role: "assistant"}
+			var text strings.Builder
+			for _, part := range content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					call := part.FunctionCall.toToolCall(callCount)
+					callCount++
+					pendingCalls[call.Function.Name] = append(pendingCalls[call.Function.Name], call.ID)
+					msg.ToolCalls = append(msg.ToolCalls, call)
+				case part.Thought:
+				default:
+					text.WriteString(part.Text)
+				}
+			}
+			if text.Len() > 0 || len(msg.ToolCalls) == 0 {
+				msg.Content = text.String()
+			}
+			messages = append(messages, msg)
+		case "user", "function", "":
+			var parts []ContentPart
+			for _, part := range content.Parts {
+				switch {
+				case part.FunctionResponse != nil:
+					id := part.FunctionResponse.ID
+					if pending := pendingCalls[part.FunctionResponse.Name]; len(id) == 0 && len(pending) > 0 {
+						id = pending[0]
+						pendingCalls[part.FunctionResponse.Name] = pending[1:]
+					}
+					messages = append(messages, Message{
+						Role:       "tool",
+						ToolCallID: id,
+						Content:    string(part.FunctionResponse.Response),
+					})
+				case part.InlineData != nil:
+					parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{
+						URL: "data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data,
+					}})
+				case part.FileData != nil:
+					parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: part.FileData.FileURI}})
+				case part.FunctionCall != nil:
+					return nil, newGeminiRequestError(fmt.Sprintf("contents[%d]: function calls must have the model role", i))
+				default:
+					parts = append(parts, ContentPart{Type: "text", Text: part.Text})
+				}
+			}
+			switch {
+			case len(parts) == 1 && parts[0].Type == "text":
+				messages = append(messages, Message{Role: "user", Content: parts[0].Text})
+			case len(parts) > 0:
+				messages = append(messages, Message{Role: "user", Content: parts})
+			}
+		default:
+			return nil, newGeminiRequestError(fmt.Sprintf("contents[%d]: role '%s' is not supported", i, content.Role))
+		}
+		chatReq.Messages = append(chatReq.Messages, messages...)
+	}
+
+	for _, tool := range req.Tools {
+		if len(tool.FunctionDeclarations) == 0 {
+			return nil, newGeminiRequestError("only tools with function declarations are supported")
+		}
+		for _, decl := range tool.FunctionDeclarations {
+			parameters := decl.ParametersJSONSchema
+			if len(parameters) == 0 {
+				parameters = lowerSchemaTypes(decl.Parameters)
+			}
+			chatReq.Tools = append(chatReq.Tools, Tool{
+				Type: "function",
+				Function: FunctionDesc{
+					Name:        decl.Name,
+					Description: decl.Description,
+					Parameters:  parameters,
+				},
+			})
+		}
+	}
+
+	if req.ToolConfig != nil && req.ToolConfig.FunctionCallingConfig != nil {
+		config := req.ToolConfig.FunctionCallingConfig
+		switch strings.ToUpper(config.Mode) {
+		case "AUTO", "":
+			chatReq.ToolChoice = "auto"
+		case "ANY":
+			chatReq.ToolChoice = "required"
+			if len(config.AllowedFunctionNames) == 1 {
+				chatReq.ToolChoice = map[string]any{
+					"type":     "function",
+					"function": map[string]any{"name": config.AllowedFunctionNames[0]},
+				}
+			}
+		case "NONE":
+			chatReq.ToolChoice = "none"
+		}
+	}
+
+	if config := req.GenerationConfig; config != nil {
+		chatReq.Temperature = config.Temperature
+		chatReq.TopP = config.TopP
+		chatReq.MaxTokens = config.MaxOutputTokens
+		chatReq.Stop = config.StopSequences
+
+		if config.ResponseMimeType == "application/json" {
+			schema := config.ResponseJSONSchema
+			if len(schema) == 0 {
+				schema = lowerSchemaTypes(config.ResponseSchema)
+			}
+			if len(schema) > 0 {
+				chatReq.ResponseFormat = map[string]any{
+					"type":        "json_schema",
+					"json_schema": map[string]any{"name": "response", "schema": schema},
+				}
+			} else {
+				chatReq.ResponseFormat = map[string]any{"type": "json_object"}
+			}
+		}
+
+		if thinking := config.ThinkingConfig; thinking != nil && thinking.ThinkingBudget != nil &&
+			*thinking.ThinkingBudget != 0 {
+			effort := "high"
+			if *thinking.ThinkingBudget > 0 {
+				effort = reasoningEffort(*thinking.ThinkingBudget)
+			}
+			chatReq.Reasoning = &Reasoning{Effort: effort}
+		}
+	}
+
+	return chatReq, nil
+}
+
+// lowerSchemaTypes converts a Gemini OpenAPI schema, which uses upper case types such as `OBJECT`,
+// into a JSON schema.
+func lowerSchemaTypes(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return schema
+	}
+	var val any
+	if err := sonic.Unmarshal(schema, &val); err != nil {
+		return schema
+	}
+	var lower func(v any)
+	lower = func(v any) {
+		switch node := v.(type) {
+		case map[string]any:
+			for key, child := range node {
+				if s, ok := child.(string); ok && key == "type" {
+					node[key] = strings.ToLower(s)
+					continue
+				}
+				lower(child)
+			}
+		case []any:
+			for _, child := range node {
+				lower(child)
+			}
+		}
+	}
+	lower(val)
+	b, err := sonic.Marshal(val)
+	if err != nil {
+		return schema
+	}
+	return b
+}
+
+func geminiText(parts []geminiPart) string {
+	var sb strings.Builder
+	for _, part := range parts {
+		if len(part.Text) > 0 && !part.Thought {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+func newGeminiRequestError(message string) *AIError {
+	return &AIError{
+		Type:       "invalid_request_error",
+		Message:    message,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// ToResponsesRequest is not supported, since the Gemini API has no Responses family.
+func (a *GeminiClientAdapter) ToResponsesRequest(_ []byte) (*ResponsesRequest, error) {
+	return nil, &AIError{
+		Type:       "not_found_error",
+		Message:    "Responses API is not supported by the gemini format",
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// --- Egress ---
+
+// ToClientChatResponse translates a canonical ChatResponse into a Gemini GenerateContentResponse.
+func (a *GeminiClientAdapter) ToClientChatResponse(resp *ChatResponse) (any, error) {
+	geminiResp := &geminiResponse{
+		ResponseID:    resp.ID,
+		ModelVersion:  resp.Model,
+		UsageMetadata: toGeminiUsage(resp.Usage),
+	}
+
+	for _, choice := range resp.Choices {
+		content := geminiContent{Role: "model", Parts: []geminiPart{}}
+		if text := contentText(choice.Message.Content); len(text) > 0 {
+			content.Parts = append(content.Parts, geminiPart{Text: text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{
+				ID:   call.ID,
+				Name: call.Function.Name,
+				Args: jsonObject(call.Function.Arguments),
+			}})
+		}
+		geminiResp.Candidates = append(geminiResp.Candidates, geminiCandidate{
+			Content:      content,
+			FinishReason: toGeminiFinishReason(choice.FinishReason),
+			Index:        choice.Index,
+		})
+	}
+
+	return geminiResp, nil
+}
+
+// ToClientResponsesResponse is not supported, since the Gemini API has no Responses family.
+func (a *GeminiClientAdapter) ToClientResponsesResponse(_ *ResponsesResponse) (any, error) {
+	return nil, errors.New("responses API is not supported by the gemini format")
+}
+
+func toGeminiUsage(usage Usage) *geminiUsageMetadata {
+	result := &geminiUsageMetadata{
+		PromptTokenCount:     usage.PromptTokens,
+		CandidatesTokenCount: usage.CompletionTokens,
+		TotalTokenCount:      usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CachedContentTokenCount = usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		result.ThoughtsTokenCount = usage.CompletionTokensDetails.ReasoningTokens
+		result.CandidatesTokenCount = max(usage.CompletionTokens-result.ThoughtsTokenCount, 0)
+	}
+	return result
+}
+
+func toGeminiFinishReason(finishReason string) string {
+	switch finishReason {
+	case "":
+		return ""
+	case "stop", "tool_calls", "function_call":
+		return "STOP"
+	case "length":
+		return "MAX_TOKENS"
+	case "content_filter":
+		return "SAFETY"
+	default:
+		return "OTHER"
+	}
+}
+
+// geminiStatuses maps HTTP status codes to the status of Gemini errors.
+var geminiStatuses = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "ABORTED",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusNotImplemented:      "UNIMPLEMENTED",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+// ToClientError translates a canonical AIError into the Gemini error format.
+func (a *GeminiClientAdapter) ToClientError(err *AIError) (any, error) {
+	code := err.StatusCode
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	status, found := geminiStatuses[code]
+	if !found {
+		status = "UNKNOWN"
+		if code < http.StatusInternalServerError {
+			status = "FAILED_PRECONDITION"
+		}
+	}
+	return &GeminiErrorResponse{
+		Error: GeminiErrorDetail{
+			Code:    code,
+			Message: err.Message,
+			Status:  status,
+		},
+	}, nil
+}
+
+// ToClientStreamError encodes the error as a data event carrying the Gemini error response.
+// Gemini streams have no terminating event, the stream simply ends after the error.
+func (a *GeminiClientAdapter) ToClientStreamError(err *AIError) ([]byte, error) {
+	resp, _ := a.ToClientError(err)
+	data, marshalErr := sonic.Marshal(resp)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return fmt.Appendf(nil, "data: %s\r\n\r\n", data), nil
+}
+
+// --- Streaming ---
+
+// StreamConverter re-encodes the canonical SSE stream as Gemini GenerateContentResponse events.
+func (a *GeminiClientAdapter) StreamConverter(stream io.ReadCloser) io.ReadCloser {
+	return &geminiStreamConverter{
+		stream: stream,
+		reader: bufio.NewReader(stream),
+	}
+}
+
+// geminiStreamConverter writes an event for every text or thought delta. Gemini sends function
+// calls as a whole, so their streamed arguments are collected and written in the final event with
+// the finish reason and usage.
+type geminiStreamConverter struct {
+	stream io.ReadCloser
+	reader *bufio.Reader
+	out    bytes.Buffer
+	err    error
+
+	id           string
+	model        string
+	finished     bool
+	finishReason string
+	toolCalls    []ToolCall
+	usage        *Usage
+}
+
+func (s *geminiStreamConverter) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && s.err == nil {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.processLine(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.finish()
+			}
+			s.err = err
+		}
+	}
+
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	return 0, s.err
+}
+
+func (s *geminiStreamConverter) Close() error {
+	return s.stream.Close()
+}
+
+func (s *geminiStreamConverter) processLine(line []byte) {
+	data, found := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !found {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("[DONE]")) {
+		s.finish()
+		return
+	}
+
+	var chunk StreamChunk
+	if err := sonic.Unmarshal(data, &chunk); err != nil || s.finished {
+		return
+	}
+	if len(s.id) == 0 {
+		s.id = chunk.ID
+		s.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+
+	choice := chunk.Choices[0]
+	var parts []geminiPart
+	if len(choice.Delta.ReasoningContent) > 0 {
+		parts = append(parts, geminiPart{Text: choice.Delta.ReasoningContent, Thought: true})
+	}
+	if len(choice.Delta.Content) > 0 {
+		parts = append(parts, geminiPart{Text: choice.Delta.Content})
+	}
+	for _, call := range choice.Delta.ToolCalls {
+		// the first chunk of a tool call has its ID, the following chunks only have arguments
+		if len(call.ID) > 0 || len(s.toolCalls) == 0 {
+			s.toolCalls = append(s.toolCalls, call)
+			continue
+		}
+		s.toolCalls[len(s.toolCalls)-1].Function.Arguments += call.Function.Arguments
+	}
+	if choice.FinishReason != nil && len(*choice.FinishReason) > 0 {
+		s.finishReason = *choice.FinishReason
+	}
+
+	if len(parts) > 0 {
+		s.write(&geminiResponse{
+			Candidates:   []geminiCandidate{{Content: geminiContent{Role: "model", Parts: parts}}},
+			ModelVersion: s.model,
+			ResponseID:   s.id,
+		})
+	}
+}
+
+// finish writes the final event, the usage is only known after the last choice.
+func (s *geminiStreamConverter) finish() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+
+	parts := []geminiPart{}
+	for _, call := range s.toolCalls {
+		parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+			ID:   call.ID,
+			Name: call.Function.Name,
+			Args: jsonObject(call.Function.Arguments),
+		}})
+	}
+
+	finishReason := s.finishReason
+	if len(finishReason) == 0 {
+		finishReason = "stop"
+	}
+	resp := &geminiResponse{
+		Candidates: []geminiCandidate{{
+			Content:      geminiContent{Role: "model", Parts: parts},
+			FinishReason: toGeminiFinishReason(finishReason),
+		}},
+		ModelVersion: s.model,
+		ResponseID:   s.id,
+	}
+	if s.usage != nil {
+		resp.UsageMetadata = toGeminiUsage(*s.usage)
+	}
+	s.write(resp)
+}
+
+func (s *geminiStreamConverter) write(resp *geminiResponse) {
+	data, err := sonic.Marshal(resp)
+	if err != nil {
+		return
+	}
+	s.out.WriteString("data: ")
+	s.out.Write(data)
+	s.out.WriteString("\r\n\r\n")
+}
@@ -0,0 +1,245 @@
+package ai
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiClientAdapter_ToChatRequest(t *testing.T) {
+	adapter, err := GetClientAdapter("gemini")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", adapter.Name())
+
+	pathAdapter, ok := adapter.(PathClientAdapter)
+	require.True(t, ok)
+
+	body := []byte(`{
+		"systemInstruction": {"parts": [{"text": "be brief"}]},
+		"contents": [
+			{"role": "user", "parts": [{"text": "what is this?"}, {"inlineData": {"mimeType": "image/png", "data": "iVBOR"}}]},
+			{"role": "model", "parts": [{"text": "hmm", "thought": true}, {"functionCall": {"name": "lookup", "args": {"q": "a"}}}]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "lookup", "response": {"result": "x"}}}]},
+			{"role": "user", "parts": [{"text": "thanks"}]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "lookup", "parameters": {"type": "OBJECT", "properties": {"q": {"type": "STRING"}}}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY", "allowedFunctionNames": ["lookup"]}},
+		"generationConfig": {
+			"temperature": 0.5,
+			"maxOutputTokens": 256,
+			"stopSequences": ["END"],
+			"responseMimeType": "application/json",
+			"thinkingConfig": {"thinkingBudget": 8192}
+		}
+	}`)
+
+	chatReq, err := pathAdapter.ToChatRequestWithPath("/v1beta/models/gemini-2.5-pro:streamGenerateContent", body)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-pro", chatReq.Model)
+	assert.True(t, chatReq.Stream)
+
+	require.Len(t, chatReq.Messages, 5)
+	assert.Equal(t, Message{Role: "system", Content: "be brief"}, chatReq.Messages[0])
+
+	assert.Equal(t, "user", chatReq.Messages[1].Role)
+	parts, ok := chatReq.Messages[1].Content.([]ContentPart)
+	require.True(t, ok)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "data:image/png;base64,iVBOR", parts[1].ImageURL.URL)
+
+	assistant := chatReq.Messages[2]
+	assert.Equal(t, "assistant", assistant.Role)
+	assert.Nil(t, assistant.Content)
+	require.Len(t, assistant.ToolCalls, 1)
+	assert.Equal(t, "lookup", assistant.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"q":"a"}`, assistant.ToolCalls[0].Function.Arguments)
+
+	// function responses are matched with the calls by name
+	tool := chatReq.Messages[3]
+	assert.Equal(t, "tool", tool.Role)
+	assert.Equal(t, assistant.ToolCalls[0].ID, tool.ToolCallID)
+	assert.JSONEq(t, `{"result":"x"}`, tool.Content.(string))
+	assert.Equal(t, Message{Role: "user", Content: "thanks"}, chatReq.Messages[4])
+
+	require.Len(t, chatReq.Tools, 1)
+	assert.JSONEq(t, `{"type":"object","properties":{"q":{"type":"string"}}}`, string(chatReq.Tools[0].Function.Parameters))
+	assert.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "lookup"}}, chatReq.ToolChoice)
+
+	assert.InDelta(t, 0.5, *chatReq.Temperature, 0.001)
+	assert.Equal(t, 256, *chatReq.MaxTokens)
+	assert.Equal(t, []string{"END"}, chatReq.Stop)
+	assert.Equal(t, map[string]any{"type": "json_object"}, chatReq.ResponseFormat)
+	assert.Equal(t, "medium", chatReq.Reasoning.Effort)
+
+	chatReq, err = pathAdapter.ToChatRequestWithPath("/v1beta/models/gemini-2.5-flash:generateContent", body)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-flash", chatReq.Model)
+	assert.False(t, chatReq.Stream)
+}
+
+func TestGeminiClientAdapter_InvalidRequests(t *testing.T) {
+	adapter := NewGeminiClientAdapter()
+	valid := `{"contents": [{"role": "user", "parts": [{"text": "hello"}]}]}`
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "empty contents", path: "/v1beta/models/gemini:generateContent", body: `{"contents": []}`, status: http.StatusBadRequest},
+		{name: "missing model", path: "/v1beta/generateContent", body: valid, status: http.StatusBadRequest},
+		{name: "unknown method", path: "/v1beta/models/gemini:countTokens", body: valid, status: http.StatusNotFound},
+		{name: "unknown role", path: "/v1beta/models/gemini:generateContent", body: `{"contents": [{"role": "robot", "parts": [{"text": "hi"}]}]}`, status: http.StatusBadRequest},
+		{name: "built-in tools", path: "/v1beta/models/gemini:generateContent", body: `{"contents": [{"parts": [{"text": "hi"}]}], "tools": [{"googleSearch": {}}]}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adapter.ToChatRequestWithPath(tt.path, []byte(tt.body))
+			var aiErr *AIError
+			require.ErrorAs(t, err, &aiErr)
+			assert.Equal(t, tt.status, aiErr.StatusCode)
+		})
+	}
+
+	_, err := adapter.ToResponsesRequest([]byte(`{}`))
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusNotFound, aiErr.StatusCode)
+}
+
+func TestGeminiClientAdapter_ToClientChatResponse(t *testing.T) {
+	adapter := NewGeminiClientAdapter()
+
+	result, err := adapter.ToClientChatResponse(&ChatResponse{
+		ID:    "chatcmpl-123",
+		Model: "gpt-4o",
+		Choices: []Choice{{
+			Message: Message{
+				Role:    "assistant",
+				Content: "checking",
+				ToolCalls: []ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: FunctionCall{Name: "lookup", Arguments: `{"q":"a"}`},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: Usage{
+			PromptTokens:            20,
+			CompletionTokens:        10,
+			TotalTokens:             30,
+			PromptTokensDetails:     &PromptTokensDetails{CachedTokens: 8},
+			CompletionTokensDetails: &CompletionTokensDetails{ReasoningTokens: 4},
+		},
+	})
+	require.NoError(t, err)
+
+	b, err := sonic.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [{"text": "checking"}, {"functionCall": {"id": "call_1", "name": "lookup", "args": {"q": "a"}}}]},
+			"finishReason": "STOP",
+			"index": 0
+		}],
+		"usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 6, "thoughtsTokenCount": 4, "cachedContentTokenCount": 8, "totalTokenCount": 30},
+		"modelVersion": "gpt-4o",
+		"responseId": "chatcmpl-123"
+	}`, string(b))
+
+	_, err = adapter.ToClientResponsesResponse(&ResponsesResponse{})
+	assert.Error(t, err)
+}
+
+func TestGeminiClientAdapter_ToClientError(t *testing.T) {
+	adapter := NewGeminiClientAdapter()
+
+	tests := []struct {
+		status     int
+		code       int
+		statusText string
+	}{
+		{status: http.StatusBadRequest, code: 400, statusText: "INVALID_ARGUMENT"},
+		{status: http.StatusUnauthorized, code: 401, statusText: "UNAUTHENTICATED"},
+		{status: http.StatusTooManyRequests, code: 429, statusText: "RESOURCE_EXHAUSTED"},
+		{status: http.StatusBadGateway, code: 502, statusText: "UNKNOWN"},
+		{status: 0, code: 500, statusText: "INTERNAL"},
+	}
+
+	for _, tt := range tests {
+		result, err := adapter.ToClientError(&AIError{Message: "failed", StatusCode: tt.status})
+		require.NoError(t, err)
+		errResp, ok := result.(*GeminiErrorResponse)
+		require.True(t, ok)
+		assert.Equal(t, tt.code, errResp.Error.Code)
+		assert.Equal(t, tt.statusText, errResp.Error.Status)
+		assert.Equal(t, "failed", errResp.Error.Message)
+	}
+}
+
+func TestGeminiClientAdapter_ToClientStreamError(t *testing.T) {
+	adapter := NewGeminiClientAdapter()
+
+	data, err := adapter.ToClientStreamError(&AIError{Message: "failed", StatusCode: http.StatusTooManyRequests})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"data: {\"error\":{\"code\":429,\"message\":\"failed\",\"status\":\"RESOURCE_EXHAUSTED\"}}\r\n\r\n",
+		string(data),
+	)
+	assert.NotContains(t, string(data), "[DONE]")
+}
+
+func TestGeminiClientAdapter_StreamConverter(t *testing.T) {
+	adapter := NewGeminiClientAdapter()
+
+	canonical := strings.Join([]string{
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"hmm"}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"hel"}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"function":{"arguments":"\"a\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	stream := adapter.StreamConverter(io.NopCloser(strings.NewReader(canonical)))
+	defer stream.Close()
+
+	out, err := io.ReadAll(stream)
+	require.NoError(t, err)
+
+	var events []geminiResponse
+	for _, event := range strings.Split(string(out), "\r\n\r\n") {
+		data, found := strings.CutPrefix(event, "data: ")
+		if !found {
+			continue
+		}
+		var resp geminiResponse
+		require.NoError(t, sonic.UnmarshalString(data, &resp))
+		events = append(events, resp)
+	}
+
+	require.Len(t, events, 3)
+	assert.True(t, events[0].Candidates[0].Content.Parts[0].Thought)
+	assert.Equal(t, "hmm", events[0].Candidates[0].Content.Parts[0].Text)
+	assert.Equal(t, "hel", events[1].Candidates[0].Content.Parts[0].Text)
+	assert.Equal(t, "chatcmpl-1", events[1].ResponseID)
+
+	last := events[2]
+	assert.Equal(t, "STOP", last.Candidates[0].FinishReason)
+	require.Len(t, last.Candidates[0].Content.Parts, 1)
+	call := last.Candidates[0].Content.Parts[0].FunctionCall
+	require.NotNil(t, call)
+	assert.Equal(t, "lookup", call.Name)
+	assert.JSONEq(t, `{"q":"a"}`, string(call.Args))
+	require.NotNil(t, last.UsageMetadata)
+	assert.Equal(t, 5, last.UsageMetadata.PromptTokenCount)
+	assert.Equal(t, 3, last.UsageMetadata.CandidatesTokenCount)
+}
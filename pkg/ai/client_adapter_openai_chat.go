@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/bytedance/sonic"
@@ -60,6 +61,16 @@ func (a *OpenAIChatClientAdapter) StreamConverter(stream io.ReadCloser) io.ReadC
 	return stream
 }
 
+// ToClientStreamError encodes the error as a data event followed by the `[DONE]` event.
+func (a *OpenAIChatClientAdapter) ToClientStreamError(err *AIError) ([]byte, error) {
+	resp, _ := a.ToClientError(err)
+	data, marshalErr := sonic.Marshal(resp)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return fmt.Appendf(nil, "data: %s\n\ndata: [DONE]\n\n", data), nil
+}
+
 // OpenAIErrorResponse represents the standard error response returned by OpenAI APIs.
 type OpenAIErrorResponse struct {
 	Error OpenAIErrorDetail `json:"error"`
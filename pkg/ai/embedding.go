@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/bytedance/sonic"
+)
+
+// --- Embeddings ---
+
+// EmbeddingRequest represents a canonical embeddings request, aligned with the OpenAI Embeddings API.
+type EmbeddingRequest struct {
+	Model          string `json:"model"`
+	Input          any    `json:"input"` // string OR []string
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	Dimensions     *int   `json:"dimensions,omitempty"`
+	User           string `json:"user,omitempty"`
+}
+
+// EmbeddingResponse represents a canonical embeddings response.
+type EmbeddingResponse struct {
+	Object string      `json:"object"` // "list"
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  Usage       `json:"usage"`
+}
+
+// Embedding is the vector of a single input.
+type Embedding struct {
+	Object    string    `json:"object"` // "embedding"
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EmbeddingAdapter is implemented by LLM adapters whose provider can create embeddings.
+type EmbeddingAdapter interface {
+	// Embeddings executes an embeddings request.
+	Embeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or zero if their lengths differ
+// or one of them is a zero vector.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// ParseEmbeddingRequest parses the body of an embeddings request.
+func ParseEmbeddingRequest(body []byte) (*EmbeddingRequest, error) {
+	var req EmbeddingRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Input == nil {
+		return nil, errors.New("input is required")
+	}
+	return &req, nil
+}
+
+// Inputs returns the inputs of the request, which is a string or a list of strings.
+func (r *EmbeddingRequest) Inputs() []string {
+	switch val := r.Input.(type) {
+	case string:
+		return []string{val}
+	case []string:
+		return val
+	case []any:
+		inputs := make([]string, 0, len(val))
+		for _, item := range val {
+			if text, ok := item.(string); ok {
+				inputs = append(inputs, text)
+			}
+		}
+		return inputs
+	default:
+		return nil
+	}
+}
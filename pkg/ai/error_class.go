@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error classes of failed upstream requests, a virtual model falls back to another target by them.
+const (
+	ErrorClassRateLimited           = "rate_limited"
+	ErrorClassOverloaded            = "overloaded"
+	ErrorClassContextLengthExceeded = "context_length_exceeded"
+	ErrorClassServerError           = "server_error"
+)
+
+// statusOverloaded is the non-standard status code which Anthropic returns when it is overloaded.
+const statusOverloaded = 529
+
+// contextLengthMessages are the phrases providers use when a prompt exceeds the context window
+// of the model without returning the `context_length_exceeded` code.
+var contextLengthMessages = []string{
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"input token count",
+}
+
+// ClassifyError returns the error class of a failed upstream request, or an empty string if the
+// request itself is wrong, e.g. an invalid request or authentication error which would fail on
+// any target. Errors which aren't an AIError, such as connection errors and timeouts, are server
+// errors. Responses rejected by a response filter aren't classified either.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var filterErr *ResponseFilterError
+	if errors.As(err, &filterErr) {
+		return ""
+	}
+
+	var aiErr *AIError
+	if !errors.As(err, &aiErr) {
+		return ErrorClassServerError
+	}
+
+	if aiErr.Code.UnwrapOr("") == ErrorClassContextLengthExceeded {
+		return ErrorClassContextLengthExceeded
+	}
+	if aiErr.StatusCode == http.StatusBadRequest || aiErr.StatusCode == http.StatusRequestEntityTooLarge {
+		message := strings.ToLower(aiErr.Message)
+		for _, phrase := range contextLengthMessages {
+			if strings.Contains(message, phrase) {
+				return ErrorClassContextLengthExceeded
+			}
+		}
+	}
+
+	switch {
+	case aiErr.StatusCode == http.StatusTooManyRequests || aiErr.Type == "rate_limit_error":
+		return ErrorClassRateLimited
+	case aiErr.StatusCode == http.StatusServiceUnavailable || aiErr.StatusCode == statusOverloaded ||
+		aiErr.Type == "overloaded_error":
+		return ErrorClassOverloaded
+	case aiErr.StatusCode >= http.StatusInternalServerError:
+		return ErrorClassServerError
+	}
+	return ""
+}
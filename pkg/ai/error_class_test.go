@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nite-coder/bifrost/internal/pkg/optional"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "nil", err: nil, expected: ""},
+		{name: "plain error", err: errors.New("connection refused"), expected: ErrorClassServerError},
+		{
+			name:     "rate limited",
+			err:      &AIError{Type: "rate_limit_error", StatusCode: http.StatusTooManyRequests},
+			expected: ErrorClassRateLimited,
+		},
+		{
+			name:     "wrapped",
+			err:      fmt.Errorf("upstream: %w", &AIError{StatusCode: http.StatusTooManyRequests}),
+			expected: ErrorClassRateLimited,
+		},
+		{
+			name: "response filter",
+			err: &ResponseFilterError{
+				Err: &AIError{Type: "api_error", StatusCode: http.StatusBadGateway},
+			},
+			expected: "",
+		},
+		{name: "service unavailable", err: &AIError{StatusCode: http.StatusServiceUnavailable}, expected: ErrorClassOverloaded},
+		{name: "anthropic overloaded", err: &AIError{Type: "overloaded_error", StatusCode: 529}, expected: ErrorClassOverloaded},
+		{
+			name: "context length code",
+			err: &AIError{
+				Type:       "invalid_request_error",
+				StatusCode: http.StatusBadRequest,
+				Code:       optional.Some("context_length_exceeded"),
+			},
+			expected: ErrorClassContextLengthExceeded,
+		},
+		{
+			name:     "context length message",
+			err:      &AIError{Message: "prompt is too long: 210000 tokens > 200000 maximum", StatusCode: http.StatusBadRequest},
+			expected: ErrorClassContextLengthExceeded,
+		},
+		{name: "timeout", err: &AIError{Type: "timeout_error", StatusCode: http.StatusGatewayTimeout}, expected: ErrorClassServerError},
+		{name: "server error", err: &AIError{StatusCode: http.StatusInternalServerError}, expected: ErrorClassServerError},
+		{name: "invalid request", err: &AIError{StatusCode: http.StatusBadRequest, Message: "invalid model"}, expected: ""},
+		{name: "authentication", err: &AIError{StatusCode: http.StatusUnauthorized}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"github.com/bytedance/sonic"
+)
+
+const (
+	// charsPerToken is the average number of characters of a token in English text.
+	charsPerToken = 4
+	// tokensPerMessage is the overhead of the role and separators of a message.
+	tokensPerMessage = 4
+	// tokensPerImage is the cost of a low detail image, the smallest cost of an image.
+	tokensPerImage = 85
+)
+
+// EstimatePromptTokens estimates the prompt tokens of a request before it is sent to a provider,
+// e.g. to enforce token quotas. The estimate is based on the length of the text, since the
+// tokenizer depends on the model, so the actual usage reported by the provider is authoritative.
+func EstimatePromptTokens(req *ChatRequest) int {
+	if req == nil {
+		return 0
+	}
+
+	chars := 0
+	tokens := 0
+	for _, msg := range req.Messages {
+		tokens += tokensPerMessage
+		for _, part := range contentParts(msg.Content) {
+			switch part.Type {
+			case "text":
+				chars += len(part.Text)
+			case "image_url":
+				tokens += tokensPerImage
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			chars += len(call.Function.Name) + len(call.Function.Arguments)
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		if b, err := sonic.Marshal(req.Tools); err == nil {
+			chars += len(b)
+		}
+	}
+
+	return tokens + (chars+charsPerToken-1)/charsPerToken
+}
+
+// EstimateTextTokens estimates the tokens of plain texts, e.g. the inputs of an embeddings request.
+func EstimateTextTokens(texts ...string) int {
+	chars := 0
+	for _, text := range texts {
+		chars += len(text)
+	}
+	return (chars + charsPerToken - 1) / charsPerToken
+}
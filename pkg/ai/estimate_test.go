@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimatePromptTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimatePromptTokens(nil))
+
+	req := &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: []ContentPart{
+				{Type: "text", Text: "what is this?"},
+				{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/cat.png"}},
+			}},
+		},
+	}
+	// 2 messages, 1 image and 21 characters of text
+	assert.Equal(t, 2*tokensPerMessage+tokensPerImage+6, EstimatePromptTokens(req))
+
+	req.Tools = []Tool{{Type: "function", Function: FunctionDesc{Name: "lookup"}}}
+	assert.Greater(t, EstimatePromptTokens(req), 2*tokensPerMessage+tokensPerImage+6)
+}
+
+func TestEstimateTextTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTextTokens())
+	assert.Equal(t, 3, EstimateTextTokens("hello", "world!"))
+}
@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ResponseFilter inspects chat responses before they are sent to the client, e.g. to redact
+// sensitive content. A filter may modify the response, or return an error to reject it.
+type ResponseFilter interface {
+	// FilterChatResponse filters the response of a unary request.
+	FilterChatResponse(ctx context.Context, resp *ChatResponse) error
+	// FilterChatChunk filters a chunk of a streaming request. An error ends the stream.
+	FilterChatChunk(ctx context.Context, chunk *StreamChunk) error
+}
+
+// ResponseFilterError is the error of a response filter which rejected a response. The target
+// served the request, so the request isn't retried on the fallback targets of the virtual model.
+type ResponseFilterError struct {
+	Err error
+}
+
+func (e *ResponseFilterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResponseFilterError) Unwrap() error {
+	return e.Err
+}
+
+// AddResponseFilter registers a filter which the AI proxy applies to the chat response of the request.
+func AddResponseFilter(c *app.RequestContext, filter ResponseFilter) {
+	c.Set(ContextKeyResponseFilters, append(slices.Clone(ResponseFilters(c)), filter))
+}
+
+// ResponseFilters returns the response filters registered for the request.
+func ResponseFilters(c *app.RequestContext) []ResponseFilter {
+	val, found := c.Get(ContextKeyResponseFilters)
+	if !found {
+		return nil
+	}
+	filters, _ := val.([]ResponseFilter)
+	return filters
+}
+
+// FilteredStream is a decorator for io.ReadCloser that applies response filters to the chunks of
+// a canonical SSE stream. Chunks are only re-encoded when a filter changes their content.
+type FilteredStream struct {
+	io.ReadCloser
+
+	ctx     context.Context
+	filters []ResponseFilter
+	readBuf []byte
+	in      []byte
+	out     []byte
+	err     error
+}
+
+// NewFilteredStream creates a new stream decorator which applies the filters to every chunk.
+func NewFilteredStream(ctx context.Context, stream io.ReadCloser, filters []ResponseFilter) io.ReadCloser {
+	if len(filters) == 0 {
+		return stream
+	}
+	return &FilteredStream{
+		ReadCloser: stream,
+		ctx:        ctx,
+		filters:    filters,
+		readBuf:    make([]byte, 4096),
+	}
+}
+
+// Read implements the io.Reader interface. The events which were filtered before a filter
+// rejected the stream are still returned, followed by the error of the filter.
+func (s *FilteredStream) Read(p []byte) (int, error) {
+	const eventDelimiterLen = 2
+
+	for len(s.out) == 0 && s.err == nil {
+		n, err := s.ReadCloser.Read(s.readBuf)
+		if n > 0 {
+			s.in = append(s.in, s.readBuf[:n]...)
+			for s.err == nil {
+				idx := bytes.Index(s.in, []byte("\n\n"))
+				if idx == -1 {
+					break
+				}
+				event, err := s.filterEvent(s.in[:idx])
+				if err != nil {
+					s.err = err
+					break
+				}
+				s.out = append(s.out, event...)
+				s.out = append(s.out, '\n', '\n')
+				s.in = s.in[idx+eventDelimiterLen:]
+			}
+		}
+		if err != nil && s.err == nil {
+			s.out = append(s.out, s.in...)
+			s.in = nil
+			s.err = err
+		}
+	}
+
+	if len(s.out) > 0 {
+		n := copy(p, s.out)
+		s.out = s.out[n:]
+		return n, nil
+	}
+	return 0, s.err
+}
+
+func (s *FilteredStream) filterEvent(event []byte) ([]byte, error) {
+	lines := bytes.Split(event, []byte("\n"))
+	for i, line := range lines {
+		data, found := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data: "))
+		if !found || bytes.Equal(data, []byte("[DONE]")) {
+			continue
+		}
+
+		var chunk StreamChunk
+		if err := sonic.Unmarshal(data, &chunk); err != nil || len(chunk.Choices) == 0 {
+			continue
+		}
+		contents := make([]string, len(chunk.Choices))
+		for j := range chunk.Choices {
+			contents[j] = chunk.Choices[j].Delta.Content
+		}
+
+		for _, filter := range s.filters {
+			if err := filter.FilterChatChunk(s.ctx, &chunk); err != nil {
+				return nil, &ResponseFilterError{Err: err}
+			}
+		}
+
+		changed := false
+		for j := range chunk.Choices {
+			if chunk.Choices[j].Delta.Content != contents[j] {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		b, err := sonic.Marshal(chunk)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = append([]byte("data: "), b...)
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type replaceFilter struct {
+	old, new string
+	err      error
+}
+
+func (f *replaceFilter) FilterChatResponse(_ context.Context, resp *ChatResponse) error {
+	for i := range resp.Choices {
+		resp.Choices[i].Message.MapText(func(text string) string {
+			return strings.ReplaceAll(text, f.old, f.new)
+		})
+	}
+	return f.err
+}
+
+func (f *replaceFilter) FilterChatChunk(_ context.Context, chunk *StreamChunk) error {
+	for i := range chunk.Choices {
+		if strings.Contains(chunk.Choices[i].Delta.Content, "stop") && f.err != nil {
+			return f.err
+		}
+		chunk.Choices[i].Delta.Content = strings.ReplaceAll(chunk.Choices[i].Delta.Content, f.old, f.new)
+	}
+	return nil
+}
+
+func TestResponseFilters(t *testing.T) {
+	c := app.NewContext(0)
+	assert.Empty(t, ResponseFilters(c))
+
+	filter := &replaceFilter{}
+	AddResponseFilter(c, filter)
+	AddResponseFilter(c, filter)
+	assert.Len(t, ResponseFilters(c), 2)
+}
+
+func TestFilteredStream(t *testing.T) {
+	const (
+		unchanged = `data: {"id":"1","object":"chat.completion.chunk","extra":true,"choices":[{"index":0,"delta":{"content":"hello"},"finish_reason":null}]}`
+		changed   = `data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"secret"},"finish_reason":null}]}`
+		stopped   = `data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"stop"},"finish_reason":null}]}`
+	)
+
+	t.Run("no filters", func(t *testing.T) {
+		source := io.NopCloser(strings.NewReader(changed))
+		assert.Equal(t, source, NewFilteredStream(context.Background(), source, nil))
+	})
+
+	t.Run("filter chunks", func(t *testing.T) {
+		source := io.NopCloser(strings.NewReader(unchanged + "\n\n" + changed + "\n\n" + "data: [DONE]\n\n"))
+		stream := NewFilteredStream(context.Background(), source, []ResponseFilter{
+			&replaceFilter{old: "secret", new: "***"},
+		})
+
+		b, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		events := strings.Split(string(b), "\n\n")
+		require.Len(t, events, 4)
+		// chunks which the filters don't change are passed through as is
+		assert.Equal(t, unchanged, events[0])
+		assert.Contains(t, events[1], `"content":"***"`)
+		assert.Equal(t, "data: [DONE]", events[2])
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		errRejected := errors.New("rejected")
+		source := io.NopCloser(strings.NewReader(unchanged + "\n\n" + stopped + "\n\n" + changed + "\n\n"))
+		stream := NewFilteredStream(context.Background(), source, []ResponseFilter{
+			&replaceFilter{err: errRejected},
+		})
+
+		b, err := io.ReadAll(stream)
+		require.ErrorIs(t, err, errRejected)
+		assert.Equal(t, unchanged+"\n\n", string(b))
+	})
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+// --- Image Generations ---
+
+// ImageRequest represents a canonical image generation request, aligned with the OpenAI Images API.
+type ImageRequest struct {
+	Model          string         `json:"model"`
+	Prompt         string         `json:"prompt"`
+	N              *int           `json:"n,omitempty"`
+	Size           string         `json:"size,omitempty"`
+	Quality        string         `json:"quality,omitempty"`
+	Style          string         `json:"style,omitempty"`
+	ResponseFormat string         `json:"response_format,omitempty"`
+	User           string         `json:"user,omitempty"`
+	UnknownFields  map[string]any `json:"-"` // Collects unmapped fields for passthrough
+}
+
+// UnmarshalJSON implements custom unmarshaling to capture unknown fields.
+// IMPORTANT: When adding new fields to ImageRequest, add a corresponding delete(raw, "field_name") below.
+func (r *ImageRequest) UnmarshalJSON(data []byte) error {
+	type Alias ImageRequest
+	var aux Alias
+	if err := sonic.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*r = ImageRequest(aux)
+
+	var raw map[string]any
+	if err := sonic.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	delete(raw, "model")
+	delete(raw, "prompt")
+	delete(raw, "n")
+	delete(raw, "size")
+	delete(raw, "quality")
+	delete(raw, "style")
+	delete(raw, "response_format")
+	delete(raw, "user")
+
+	r.UnknownFields = raw
+	return nil
+}
+
+// MarshalJSON implements custom marshaling to flatten unknown fields.
+func (r *ImageRequest) MarshalJSON() ([]byte, error) {
+	type Alias ImageRequest
+	aux := (Alias)(*r)
+
+	b, err := sonic.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.UnknownFields) == 0 {
+		return b, nil
+	}
+
+	var m map[string]any
+	if err := sonic.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	maps.Copy(m, r.UnknownFields)
+
+	return sonic.Marshal(m)
+}
+
+// ImageResponse represents a canonical image generation response.
+type ImageResponse struct {
+	Created int64       `json:"created"`
+	Data    []ImageData `json:"data"`
+	Usage   Usage       `json:"-"` // Token usage of models which report it, e.g. gpt-image-1
+}
+
+// ImageData is a single generated image.
+type ImageData struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// CalculateCost calculates the cost of the tokens and the generated images.
+func (r *ImageResponse) CalculateCost(p *config.AIPricingOptions) {
+	if p == nil {
+		return
+	}
+	r.Usage.CalculateCost(p)
+	r.Usage.OutputCost += float64(len(r.Data)) * p.PerImage
+}
+
+// ImageAdapter is implemented by LLM adapters whose provider can generate images.
+type ImageAdapter interface {
+	// Images executes an image generation request.
+	Images(ctx context.Context, req *ImageRequest) (*ImageResponse, error)
+}
+
+// ParseImageRequest parses the body of an image generation request.
+func ParseImageRequest(body []byte) (*ImageRequest, error) {
+	var req ImageRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Prompt == "" {
+		return nil, errors.New("prompt is required")
+	}
+	return &req, nil
+}
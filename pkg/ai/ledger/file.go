@@ -0,0 +1,73 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+const defaultFileMaxSizeMB = 100
+
+// FileSink writes the records to a JSONL file, which is rotated by its size.
+type FileSink struct {
+	logger *lumberjack.Logger
+	mu     sync.Mutex
+}
+
+// NewFileSink creates a file sink.
+func NewFileSink(opts config.AIUsageSinkOptions) (*FileSink, error) {
+	if opts.Path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultFileMaxSizeMB
+	}
+
+	return &FileSink{
+		logger: &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+			Compress:   opts.Compress,
+			LocalTime:  true,
+		},
+	}, nil
+}
+
+// Write appends the records to the file, one JSON object per line.
+func (s *FileSink) Write(_ context.Context, records []Record) error {
+	var buf []byte
+	for _, record := range records {
+		b, err := sonic.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.logger.Write(buf)
+	return err
+}
+
+// Rotate closes the file, renames it with a timestamp and opens a new one.
+func (s *FileSink) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logger.Rotate()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logger.Close()
+}
@@ -0,0 +1,250 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nite-coder/bifrost/internal/pkg/safety"
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	dropWarnInterval     = 10 * time.Second
+	writeAttempts        = 3
+	writeRetryBackoff    = 100 * time.Millisecond
+)
+
+// Record is the usage record of an AI request.
+type Record struct {
+	Timestamp        time.Time `json:"timestamp"`
+	TraceID          string    `json:"trace_id,omitempty"`
+	RouteID          string    `json:"route_id,omitempty"`
+	Consumer         string    `json:"consumer,omitempty"`
+	User             string    `json:"user,omitempty"`
+	Model            string    `json:"model"`
+	Target           string    `json:"target"`
+	Family           string    `json:"family,omitempty"`
+	Stream           bool      `json:"stream"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CachedTokens     int       `json:"cached_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	ReasoningTokens  int       `json:"reasoning_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	InputCost        float64   `json:"input_cost"`
+	OutputCost       float64   `json:"output_cost"`
+	TotalCost        float64   `json:"total_cost"`
+	LatencyMS        int64     `json:"latency_ms"`
+}
+
+// NewRecord creates the usage record of a request from the usage reported to the observers.
+func NewRecord(metadata ai.UsageMetadata, usage ai.Usage) Record {
+	record := Record{
+		Timestamp:        timecache.Now(),
+		TraceID:          metadata.TraceID,
+		RouteID:          metadata.RouteID,
+		Consumer:         metadata.Consumer,
+		User:             metadata.UserID,
+		Model:            metadata.Model,
+		Target:           metadata.Provider,
+		Family:           metadata.Family,
+		Stream:           metadata.Stream,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		InputCost:        usage.InputCost,
+		OutputCost:       usage.OutputCost,
+		TotalCost:        usage.InputCost + usage.OutputCost,
+		LatencyMS:        metadata.Latency.Milliseconds(),
+	}
+	if usage.PromptTokensDetails != nil {
+		record.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		record.ReasoningTokens = usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return record
+}
+
+// Sink writes batches of usage records to a storage.
+type Sink interface {
+	// Write writes a batch of records. The slice is reused after Write returns.
+	Write(ctx context.Context, records []Record) error
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+// Ledger is a usage observer which writes a record of every request to a sink. Records are
+// buffered and written in batches by a background goroutine, so the request path is never
+// blocked; records are dropped when the buffer is full. Failed writes are retried with backoff
+// before the batch is given up.
+type Ledger struct {
+	name          string
+	sink          Sink
+	records       chan Record
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Uint64
+	failed        atomic.Uint64
+	lastWarn      atomic.Int64
+	mu            sync.RWMutex // Protects the records channel from being closed while records are added
+	closed        bool
+	done          chan struct{}
+}
+
+// New creates a ledger whose sink is defined by the options.
+func New(name string, opts config.AIUsageSinkOptions) (*Ledger, error) {
+	var sink Sink
+	var err error
+	switch opts.Type {
+	case "file":
+		sink, err = NewFileSink(opts)
+	case "redis":
+		sink, err = NewRedisSink(opts)
+	case "webhook":
+		sink, err = NewWebhookSink(opts)
+	default:
+		err = fmt.Errorf("type '%s' is not supported", opts.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage sink '%s': %w", name, err)
+	}
+	return NewLedger(name, sink, opts), nil
+}
+
+// NewLedger creates a ledger which writes to the sink, and starts its background goroutine.
+func NewLedger(name string, sink Sink, opts config.AIUsageSinkOptions) *Ledger {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+
+	l := &Ledger{
+		name:          name,
+		sink:          sink,
+		records:       make(chan Record, opts.BufferSize),
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+		done:          make(chan struct{}),
+	}
+	go safety.Go(context.Background(), l.run)
+	return l
+}
+
+// OnUsage implements the ai.UsageObserver interface.
+func (l *Ledger) OnUsage(_ context.Context, metadata ai.UsageMetadata, usage ai.Usage) {
+	l.Add(NewRecord(metadata, usage))
+}
+
+// Add enqueues a record without blocking, the record is dropped if the buffer is full.
+func (l *Ledger) Add(record Record) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.records <- record:
+	default:
+		dropped := l.dropped.Add(1)
+		now := time.Now().UnixNano()
+		last := l.lastWarn.Load()
+		if now-last >= int64(dropWarnInterval) && l.lastWarn.CompareAndSwap(last, now) {
+			slog.Warn("ai usage ledger: buffer is full, records are dropped", "sink", l.name, "dropped", dropped)
+		}
+	}
+}
+
+// Dropped returns the number of records which were dropped because the buffer was full.
+func (l *Ledger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Failed returns the number of records which were lost because the sink failed to write them.
+func (l *Ledger) Failed() uint64 {
+	return l.failed.Load()
+}
+
+// Close stops accepting records, writes the buffered records and closes the sink. It returns
+// when the records are written or the context is done.
+func (l *Ledger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.records)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return l.sink.Close()
+}
+
+func (l *Ledger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, l.batchSize)
+	for {
+		select {
+		case record, ok := <-l.records:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= l.batchSize {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			l.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (l *Ledger) flush(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var err error
+	backoff := writeRetryBackoff
+	for attempt := 1; attempt <= writeAttempts; attempt++ {
+		err = l.sink.Write(context.Background(), batch)
+		if err == nil {
+			return
+		}
+		if attempt < writeAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	failed := l.failed.Add(uint64(len(batch)))
+	if !errors.Is(err, context.Canceled) {
+		slog.Error("ai usage ledger: failed to write records",
+			"sink", l.name, "count", len(batch), "failed", failed, "error", err)
+	}
+}
@@ -0,0 +1,227 @@
+package ledger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+type memorySink struct {
+	mu       sync.Mutex
+	batches  [][]Record
+	block    chan struct{}
+	failures int // the number of writes which fail before the sink recovers, negative fails all
+	closed   bool
+}
+
+func (s *memorySink) Write(_ context.Context, records []Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures != 0 {
+		s.failures--
+		return errors.New("sink is unavailable")
+	}
+	s.batches = append(s.batches, append([]Record(nil), records...))
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *memorySink) records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for _, batch := range s.batches {
+		records = append(records, batch...)
+	}
+	return records
+}
+
+func TestNewRecord(t *testing.T) {
+	record := NewRecord(ai.UsageMetadata{
+		Model:    "gpt-4o",
+		UserID:   "alice",
+		Consumer: "billing-app",
+		RouteID:  "chat",
+		TraceID:  "trace-1",
+		Provider: "openai-official",
+		Family:   ai.FamilyChat,
+		Stream:   true,
+		Latency:  1500 * time.Millisecond,
+	}, ai.Usage{
+		PromptTokens:            100,
+		CompletionTokens:        50,
+		TotalTokens:             150,
+		PromptTokensDetails:     &ai.PromptTokensDetails{CachedTokens: 40},
+		CompletionTokensDetails: &ai.CompletionTokensDetails{ReasoningTokens: 20},
+		InputCost:               0.25,
+		OutputCost:              0.5,
+	})
+
+	assert.Equal(t, "billing-app", record.Consumer)
+	assert.Equal(t, "alice", record.User)
+	assert.Equal(t, "gpt-4o", record.Model)
+	assert.Equal(t, "openai-official", record.Target)
+	assert.True(t, record.Stream)
+	assert.Equal(t, 40, record.CachedTokens)
+	assert.Equal(t, 20, record.ReasoningTokens)
+	assert.InDelta(t, 0.75, record.TotalCost, 1e-9)
+	assert.Equal(t, int64(1500), record.LatencyMS)
+	assert.False(t, record.Timestamp.IsZero())
+}
+
+func TestLedgerBatching(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLedger("test", sink, config.AIUsageSinkOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	for range 5 {
+		l.OnUsage(context.Background(), ai.UsageMetadata{Model: "gpt-4o"}, ai.Usage{TotalTokens: 1})
+	}
+	require.Eventually(t, func() bool { return len(sink.records()) == 4 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, l.Close(context.Background()))
+	assert.Len(t, sink.records(), 5)
+	assert.True(t, sink.closed)
+
+	// records are ignored after the ledger is closed
+	l.Add(Record{})
+	assert.Len(t, sink.records(), 5)
+}
+
+func TestLedgerFlushInterval(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLedger("test", sink, config.AIUsageSinkOptions{FlushInterval: 10 * time.Millisecond})
+	defer func() { _ = l.Close(context.Background()) }()
+
+	l.Add(Record{Model: "gpt-4o"})
+	require.Eventually(t, func() bool { return len(sink.records()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestLedgerDrop(t *testing.T) {
+	sink := &memorySink{block: make(chan struct{})}
+	l := NewLedger("test", sink, config.AIUsageSinkOptions{BufferSize: 2, BatchSize: 1})
+
+	// the first record blocks the sink, the next two fill the buffer
+	l.Add(Record{})
+	require.Eventually(t, func() bool { return len(l.records) == 0 }, time.Second, time.Millisecond)
+	l.Add(Record{})
+	l.Add(Record{})
+	l.Add(Record{})
+	assert.Equal(t, uint64(1), l.Dropped())
+
+	close(sink.block)
+	require.NoError(t, l.Close(context.Background()))
+	assert.Len(t, sink.records(), 3)
+}
+
+func TestLedgerRetry(t *testing.T) {
+	sink := &memorySink{failures: writeAttempts - 1}
+	l := NewLedger("test", sink, config.AIUsageSinkOptions{})
+
+	// the batch is written once the sink recovers
+	l.Add(Record{})
+	require.NoError(t, l.Close(context.Background()))
+	assert.Len(t, sink.records(), 1)
+	assert.Zero(t, l.Failed())
+
+	// the batch is given up and counted when every attempt fails
+	sink = &memorySink{failures: -1}
+	l = NewLedger("test", sink, config.AIUsageSinkOptions{})
+	l.Add(Record{})
+	l.Add(Record{})
+	require.NoError(t, l.Close(context.Background()))
+	assert.Empty(t, sink.records())
+	assert.Equal(t, uint64(2), l.Failed())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	sink, err := NewFileSink(config.AIUsageSinkOptions{Path: path})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(context.Background(), []Record{{Model: "a"}, {Model: "b"}}))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var models []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		require.NoError(t, sonic.Unmarshal(scanner.Bytes(), &record))
+		models = append(models, record.Model)
+	}
+	assert.Equal(t, []string{"a", "b"}, models)
+
+	_, err = NewFileSink(config.AIUsageSinkOptions{})
+	assert.Error(t, err)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []Record
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = nil
+		_ = sonic.Unmarshal(body, &received)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	sink, err := NewWebhookSink(config.AIUsageSinkOptions{
+		URL:     ts.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(context.Background(), []Record{{Model: "a"}, {Model: "b"}}))
+	mu.Lock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "b", received[1].Model)
+	status = http.StatusInternalServerError
+	mu.Unlock()
+
+	assert.Error(t, sink.Write(context.Background(), []Record{{Model: "a"}}))
+}
+
+func TestNew(t *testing.T) {
+	l, err := New("file", config.AIUsageSinkOptions{Type: "file", Path: filepath.Join(t.TempDir(), "usage.jsonl")})
+	require.NoError(t, err)
+	require.NoError(t, l.Close(context.Background()))
+
+	_, err = New("redis", config.AIUsageSinkOptions{Type: "redis", RedisID: "missing"})
+	assert.Error(t, err)
+
+	_, err = New("kafka", config.AIUsageSinkOptions{Type: "kafka"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+	redisconn "github.com/nite-coder/bifrost/pkg/connector/redis"
+)
+
+const defaultRedisStream = "bifrost:ai_usage"
+
+// RedisSink adds the records to a redis stream, each record is an entry whose `record` field
+// is the JSON of the record.
+type RedisSink struct {
+	client redis.UniversalClient
+	stream string
+	maxLen int64
+}
+
+// NewRedisSink creates a redis sink, the redis must be defined in the redis options.
+func NewRedisSink(opts config.AIUsageSinkOptions) (*RedisSink, error) {
+	if opts.RedisID == "" {
+		return nil, errors.New("redis_id cannot be empty")
+	}
+	client, found := redisconn.Get(opts.RedisID)
+	if !found {
+		return nil, fmt.Errorf("redis '%s' was not found", opts.RedisID)
+	}
+
+	stream := opts.Stream
+	if stream == "" {
+		stream = defaultRedisStream
+	}
+	return &RedisSink{
+		client: client,
+		stream: stream,
+		maxLen: opts.MaxLen,
+	}, nil
+}
+
+// Write adds the records to the stream in a pipeline.
+func (s *RedisSink) Write(ctx context.Context, records []Record) error {
+	pipe := s.client.Pipeline()
+	for _, record := range records {
+		b, err := sonic.Marshal(record)
+		if err != nil {
+			return err
+		}
+		args := &redis.XAddArgs{
+			Stream: s.stream,
+			Values: []any{"record", b},
+		}
+		if s.maxLen > 0 {
+			args.MaxLen = s.maxLen
+			args.Approx = true
+		}
+		pipe.XAdd(ctx, args)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Close does nothing, the redis client is shared with other components.
+func (s *RedisSink) Close() error {
+	return nil
+}
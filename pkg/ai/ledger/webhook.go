@@ -0,0 +1,82 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// WebhookSink posts each batch of records to an HTTP endpoint as a JSON array.
+type WebhookSink struct {
+	client  *client.Client
+	url     string
+	headers map[string]string
+	timeout time.Duration
+}
+
+// NewWebhookSink creates a webhook sink.
+func NewWebhookSink(opts config.AIUsageSinkOptions) (*WebhookSink, error) {
+	if opts.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+	httpClient, err := client.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &WebhookSink{
+		client:  httpClient,
+		url:     opts.URL,
+		headers: opts.Headers,
+		timeout: timeout,
+	}, nil
+}
+
+// Write posts the records, any status other than 2xx is an error.
+func (s *WebhookSink) Write(ctx context.Context, records []Record) error {
+	body, err := sonic.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(s.url)
+	req.Header.SetContentTypeBytes([]byte("application/json"))
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	req.SetBody(body)
+
+	if err := s.client.DoTimeout(ctx, req, resp, s.timeout); err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	if resp.StatusCode() < http.StatusOK || resp.StatusCode() >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+// Close closes the idle connections of the webhook.
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
 
 	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
 )
 
 // LLMAdapterOptions defines dependencies required to initialize an LLMAdapter.
@@ -13,6 +16,73 @@ type LLMAdapterOptions struct {
 	HTTPClient *client.Client
 	APIKey     string
 	BaseURL    string
+	// URLTemplate overrides the request URL of the adapter, it supports the `{base_url}`,
+	// `{model}`, `{deployment}` and `{method}` placeholders.
+	URLTemplate string
+	// AuthHeader and AuthScheme override the header carrying the API key and its prefix.
+	AuthHeader  string
+	AuthScheme  string
+	Headers     map[string]string
+	QueryParams map[string]string
+	// Deployments maps models to deployment names, a model without a deployment is its own deployment.
+	Deployments map[string]string
+}
+
+// requestURL returns the URL of an upstream request. Without a URL template, the path of the adapter
+// is appended to the base URL, the path can use the same placeholders as the template.
+func (opts *LLMAdapterOptions) requestURL(path string, model string, method string) string {
+	template := opts.URLTemplate
+	if len(template) == 0 {
+		template = "{base_url}" + path
+	}
+
+	deployment := model
+	if name, found := opts.Deployments[model]; found {
+		deployment = name
+	}
+
+	uri := strings.NewReplacer(
+		"{base_url}", strings.TrimSuffix(opts.BaseURL, "/"),
+		"{model}", url.PathEscape(model),
+		"{deployment}", url.PathEscape(deployment),
+		"{method}", method,
+	).Replace(template)
+
+	if len(opts.QueryParams) == 0 {
+		return uri
+	}
+	query := url.Values{}
+	for key, val := range opts.QueryParams {
+		query.Set(key, val)
+	}
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + query.Encode()
+}
+
+// setHeaders sets the API key and the extra headers of an upstream request. The header and scheme
+// are the defaults of the adapter, e.g. `Authorization` and `Bearer`.
+func (opts *LLMAdapterOptions) setHeaders(req *protocol.Request, header string, scheme string) {
+	if len(opts.AuthHeader) > 0 {
+		header = opts.AuthHeader
+		scheme = opts.AuthScheme
+	} else if len(opts.AuthScheme) > 0 {
+		scheme = opts.AuthScheme
+	}
+
+	if len(opts.APIKey) > 0 {
+		if len(scheme) > 0 {
+			req.Header.Set(header, scheme+" "+opts.APIKey)
+		} else {
+			req.Header.Set(header, opts.APIKey)
+		}
+	}
+
+	for key, val := range opts.Headers {
+		req.Header.Set(key, val)
+	}
 }
 
 // AdapterFactory is a function type that creates a specific LLMAdapter instance.
@@ -0,0 +1,869 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/google/uuid"
+
+	"github.com/nite-coder/bifrost/internal/pkg/optional"
+)
+
+func init() {
+	RegisterLLMAdapter("gemini", func(opts LLMAdapterOptions) (LLMAdapter, error) {
+		return NewGeminiAdapter(opts), nil
+	})
+}
+
+// --- Gemini API wire types ---
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings    json.RawMessage         `json:"safetySettings,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" or "model"
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description,omitempty"`
+	Parameters           json.RawMessage `json:"parameters,omitempty"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig *geminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"` // "AUTO", "ANY" or "NONE"
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature        *float64              `json:"temperature,omitempty"`
+	TopP               *float64              `json:"topP,omitempty"`
+	MaxOutputTokens    *int                  `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string              `json:"stopSequences,omitempty"`
+	ResponseMimeType   string                `json:"responseMimeType,omitempty"`
+	ResponseSchema     json.RawMessage       `json:"responseSchema,omitempty"`
+	ResponseJSONSchema json.RawMessage       `json:"responseJsonSchema,omitempty"`
+	ThinkingConfig     *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates,omitempty"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string                `json:"modelVersion,omitempty"`
+	ResponseID     string                `json:"responseId,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
+// GeminiErrorResponse represents the error response returned by Gemini APIs.
+type GeminiErrorResponse struct {
+	Error GeminiErrorDetail `json:"error"`
+}
+
+// GeminiErrorDetail represents details of the Gemini error.
+type GeminiErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"` // e.g. "INVALID_ARGUMENT"
+}
+
+// GeminiAdapter implements LLMAdapter for Google's Gemini API (generateContent and streamGenerateContent).
+type GeminiAdapter struct {
+	client  *client.Client
+	options LLMAdapterOptions
+}
+
+// NewGeminiAdapter creates a new instance of GeminiAdapter.
+func NewGeminiAdapter(opts LLMAdapterOptions) *GeminiAdapter {
+	return &GeminiAdapter{
+		client:  opts.HTTPClient,
+		options: opts,
+	}
+}
+
+// Name returns the name of the adapter.
+func (a *GeminiAdapter) Name() string { return "gemini" }
+
+func (a *GeminiAdapter) newRequest(req *protocol.Request, chatReq *ChatRequest, method string) error {
+	geminiReq, err := toGeminiRequest(chatReq)
+	if err != nil {
+		return err
+	}
+
+	body, err := sonic.Marshal(geminiReq)
+	if err != nil {
+		return fmt.Errorf("gemini: failed to marshal request: %w", err)
+	}
+
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(a.options.requestURL("/models/{deployment}:{method}", chatReq.Model, method))
+	req.Header.SetContentTypeBytes([]byte("application/json"))
+	a.options.setHeaders(req, "x-goog-api-key", "")
+	req.SetBody(body)
+	return nil
+}
+
+// Chat sends a unary generateContent request to Gemini.
+func (a *GeminiAdapter) Chat(ctx context.Context, chatReq *ChatRequest) (*ChatResponse, error) {
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	err := a.newRequest(req, chatReq, "generateContent")
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.client.Do(ctx, req, resp)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: request failed: %w", err)
+	}
+
+	var respBody []byte
+	if resp.IsBodyStream() {
+		respBody, err = io.ReadAll(resp.BodyStream())
+		if err != nil {
+			return nil, fmt.Errorf("gemini: failed to read response body stream: %w", err)
+		}
+	} else {
+		respBody = resp.Body()
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, withRetryAfter(parseGeminiError(resp.StatusCode(), respBody), &resp.Header)
+	}
+
+	var geminiResp geminiResponse
+	if err := sonic.Unmarshal(respBody, &geminiResp); err != nil {
+		return nil, fmt.Errorf("gemini: failed to unmarshal response: %w", err)
+	}
+
+	return geminiResp.toChatResponse(chatReq.Model)
+}
+
+// StreamChat sends a streamGenerateContent request to Gemini and translates its SSE events
+// into canonical chunks.
+func (a *GeminiAdapter) StreamChat(ctx context.Context, chatReq *ChatRequest) (io.ReadCloser, error) {
+	// We allocate directly to avoid pool reuse issues since the response is read asynchronously.
+	req := &protocol.Request{}
+	resp := &protocol.Response{}
+
+	err := a.newRequest(req, chatReq, "streamGenerateContent")
+	if err != nil {
+		return nil, err
+	}
+	req.URI().QueryArgs().Set("alt", "sse")
+
+	err = a.client.Do(ctx, req, resp)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: request failed: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		var respBody []byte
+		if resp.IsBodyStream() {
+			respBody, _ = io.ReadAll(resp.BodyStream())
+		} else {
+			respBody = resp.Body()
+		}
+		return nil, withRetryAfter(parseGeminiError(resp.StatusCode(), respBody), &resp.Header)
+	}
+
+	var stream io.ReadCloser
+	if resp.IsBodyStream() {
+		stream = &responseStreamCloser{
+			reader: resp.BodyStream(),
+			req:    req,
+			resp:   resp,
+		}
+	} else {
+		bodyBytes := bytes.Clone(resp.Body())
+		req.Reset()
+		resp.Reset()
+		stream = io.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
+	return &geminiStreamReader{
+		stream:  stream,
+		reader:  bufio.NewReader(stream),
+		model:   chatReq.Model,
+		created: time.Now().Unix(),
+	}, nil
+}
+
+// Responses sends a batch responses request to Gemini.
+func (a *GeminiAdapter) Responses(_ context.Context, _ *ResponsesRequest) (*ResponsesResponse, error) {
+	return nil, &AIError{
+		Type:       "invalid_request_error",
+		Message:    "Responses API is not supported by gemini adapter",
+		StatusCode: http.StatusNotImplemented,
+		Provider:   "gemini",
+	}
+}
+
+// StreamResponses sends a streaming responses request to Gemini.
+func (a *GeminiAdapter) StreamResponses(_ context.Context, _ *ResponsesRequest) (io.ReadCloser, error) {
+	return nil, &AIError{
+		Type:       "invalid_request_error",
+		Message:    "Responses API streaming is not supported by gemini adapter",
+		StatusCode: http.StatusNotImplemented,
+		Provider:   "gemini",
+	}
+}
+
+type geminiEmbedRequest struct {
+	Requests []geminiEmbedContentRequest `json:"requests"`
+}
+
+type geminiEmbedContentRequest struct {
+	Model                string        `json:"model"`
+	Content              geminiContent `json:"content"`
+	OutputDimensionality *int          `json:"outputDimensionality,omitempty"`
+}
+
+type geminiEmbedResponse struct {
+	Embeddings []struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// Embeddings sends a batchEmbedContents request to Gemini. Gemini doesn't report the usage of
+// embeddings, so the prompt tokens are estimated from the inputs.
+func (a *GeminiAdapter) Embeddings(ctx context.Context, embeddingReq *EmbeddingRequest) (*EmbeddingResponse, error) {
+	inputs := embeddingReq.Inputs()
+	geminiReq := geminiEmbedRequest{
+		Requests: make([]geminiEmbedContentRequest, 0, len(inputs)),
+	}
+	for _, input := range inputs {
+		geminiReq.Requests = append(geminiReq.Requests, geminiEmbedContentRequest{
+			Model:                "models/" + embeddingReq.Model,
+			Content:              geminiContent{Parts: []geminiPart{{Text: input}}},
+			OutputDimensionality: embeddingReq.Dimensions,
+		})
+	}
+
+	body, err := sonic.Marshal(geminiReq)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to marshal request: %w", err)
+	}
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(a.options.requestURL("/models/{deployment}:{method}", embeddingReq.Model, "batchEmbedContents"))
+	req.Header.SetContentTypeBytes([]byte("application/json"))
+	a.options.setHeaders(req, "x-goog-api-key", "")
+	req.SetBody(body)
+
+	err = a.client.Do(ctx, req, resp)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: request failed: %w", err)
+	}
+
+	respBody := resp.Body()
+	if resp.StatusCode() != http.StatusOK {
+		return nil, withRetryAfter(parseGeminiError(resp.StatusCode(), respBody), &resp.Header)
+	}
+
+	var geminiResp geminiEmbedResponse
+	if err := sonic.Unmarshal(respBody, &geminiResp); err != nil {
+		return nil, fmt.Errorf("gemini: failed to unmarshal response: %w", err)
+	}
+
+	tokens := EstimateTextTokens(inputs...)
+	embeddingResp := &EmbeddingResponse{
+		Object: "list",
+		Data:   make([]Embedding, 0, len(geminiResp.Embeddings)),
+		Model:  embeddingReq.Model,
+		Usage:  Usage{PromptTokens: tokens, TotalTokens: tokens},
+	}
+	for i, embedding := range geminiResp.Embeddings {
+		embeddingResp.Data = append(embeddingResp.Data, Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: embedding.Values,
+		})
+	}
+	return embeddingResp, nil
+}
+
+// --- Canonical -> Gemini ---
+
+// toGeminiRequest translates a canonical ChatRequest into a Gemini request. System messages become
+// the system instruction, assistant messages use the "model" role and tool results are sent as
+// function responses of the "user" role.
+func toGeminiRequest(chatReq *ChatRequest) (*geminiRequest, error) {
+	req := &geminiRequest{}
+
+	// function responses refer to the name of the function, tool messages only have the call ID
+	toolNames := map[string]string{}
+	var system []geminiPart
+
+	for _, msg := range chatReq.Messages {
+		var content geminiContent
+		switch msg.Role {
+		case "system", "developer":
+			if text := contentText(msg.Content); len(text) > 0 {
+				system = append(system, geminiPart{Text: text})
+			}
+			continue
+		case "user":
+			parts, err := toGeminiParts(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			content = geminiContent{Role: "user", Parts: parts}
+		case "assistant":
+			content.Role = "model"
+			if text := contentText(msg.Content); len(text) > 0 {
+				content.Parts = append(content.Parts, geminiPart{Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: call.Function.Name,
+					Args: jsonObject(call.Function.Arguments),
+				}})
+			}
+		case "tool":
+			text := contentText(msg.Content)
+			response := json.RawMessage(text)
+			if !json.Valid(response) || !bytes.HasPrefix(bytes.TrimSpace(response), []byte("{")) {
+				response, _ = sonic.Marshal(map[string]any{"content": text})
+			}
+			content = geminiContent{Role: "user", Parts: []geminiPart{{FunctionResponse: &geminiFunctionResponse{
+				Name:     toolNames[msg.ToolCallID],
+				Response: response,
+			}}}}
+		default:
+			return nil, &AIError{
+				Type:       "invalid_request_error",
+				Message:    fmt.Sprintf("message role '%s' is not supported by gemini", msg.Role),
+				StatusCode: http.StatusBadRequest,
+				Provider:   "gemini",
+			}
+		}
+
+		if len(content.Parts) == 0 {
+			continue
+		}
+
+		// consecutive contents of the same role are merged, e.g. the responses of parallel function calls
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == content.Role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, content.Parts...)
+			continue
+		}
+		req.Contents = append(req.Contents, content)
+	}
+
+	if len(system) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if len(chatReq.Tools) > 0 {
+		tool := geminiTool{}
+		for _, t := range chatReq.Tools {
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, geminiFunctionDeclaration{
+				Name:                 t.Function.Name,
+				Description:          t.Function.Description,
+				ParametersJSONSchema: t.Function.Parameters,
+			})
+		}
+		req.Tools = []geminiTool{tool}
+	}
+
+	if config := toGeminiFunctionCallingConfig(chatReq.ToolChoice); config != nil {
+		req.ToolConfig = &geminiToolConfig{FunctionCallingConfig: config}
+	}
+
+	generationConfig := &geminiGenerationConfig{
+		Temperature:     chatReq.Temperature,
+		TopP:            chatReq.TopP,
+		MaxOutputTokens: chatReq.MaxTokens,
+		StopSequences:   chatReq.Stop,
+	}
+	if chatReq.Reasoning != nil {
+		budget := reasoningBudget(chatReq.Reasoning.Effort)
+		generationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: &budget, IncludeThoughts: true}
+	}
+	if format, ok := chatReq.ResponseFormat.(map[string]any); ok {
+		switch format["type"] {
+		case "json_object":
+			generationConfig.ResponseMimeType = "application/json"
+		case "json_schema":
+			generationConfig.ResponseMimeType = "application/json"
+			if schema, ok := format["json_schema"].(map[string]any); ok && schema["schema"] != nil {
+				generationConfig.ResponseJSONSchema, _ = sonic.Marshal(schema["schema"])
+			}
+		}
+	}
+	if generationConfig.Temperature != nil || generationConfig.TopP != nil || generationConfig.MaxOutputTokens != nil ||
+		len(generationConfig.StopSequences) > 0 || generationConfig.ThinkingConfig != nil ||
+		len(generationConfig.ResponseMimeType) > 0 {
+		req.GenerationConfig = generationConfig
+	}
+
+	return req, nil
+}
+
+func toGeminiParts(content any) ([]geminiPart, error) {
+	if text, ok := content.(string); ok {
+		return []geminiPart{{Text: text}}, nil
+	}
+
+	var parts []geminiPart
+	for _, part := range contentParts(content) {
+		switch part.Type {
+		case "text":
+			parts = append(parts, geminiPart{Text: part.Text})
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			if mimeType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: mimeType, Data: data}})
+				continue
+			}
+			parts = append(parts, geminiPart{FileData: &geminiFileData{
+				MimeType: mime.TypeByExtension(path.Ext(part.ImageURL.URL)),
+				FileURI:  part.ImageURL.URL,
+			}})
+		default:
+			return nil, &AIError{
+				Type:       "invalid_request_error",
+				Message:    fmt.Sprintf("content type '%s' is not supported by gemini", part.Type),
+				StatusCode: http.StatusBadRequest,
+				Provider:   "gemini",
+			}
+		}
+	}
+	return parts, nil
+}
+
+func toGeminiFunctionCallingConfig(toolChoice any) *geminiFunctionCallingConfig {
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "auto":
+			return &geminiFunctionCallingConfig{Mode: "AUTO"}
+		case "required":
+			return &geminiFunctionCallingConfig{Mode: "ANY"}
+		case "none":
+			return &geminiFunctionCallingConfig{Mode: "NONE"}
+		}
+	case map[string]any:
+		if function, ok := choice["function"].(map[string]any); ok {
+			if name, ok := function["name"].(string); ok {
+				return &geminiFunctionCallingConfig{Mode: "ANY", AllowedFunctionNames: []string{name}}
+			}
+		}
+	}
+	return nil
+}
+
+// contentParts returns canonical message content as parts, the content is a string, a list of
+// parts, or a list decoded from JSON.
+func contentParts(content any) []ContentPart {
+	switch val := content.(type) {
+	case nil:
+		return nil
+	case string:
+		return []ContentPart{{Type: "text", Text: val}}
+	case []ContentPart:
+		return val
+	default:
+		b, err := sonic.Marshal(val)
+		if err != nil {
+			return nil
+		}
+		var parts []ContentPart
+		_ = sonic.Unmarshal(b, &parts)
+		return parts
+	}
+}
+
+// parseDataURL parses a base64 data URL, e.g. `data:image/png;base64,iVBOR...`.
+func parseDataURL(url string) (mimeType string, data string, ok bool) {
+	rest, found := strings.CutPrefix(url, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mimeType, found = strings.CutSuffix(meta, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mimeType, data, true
+}
+
+// jsonObject returns the JSON arguments of a tool call, or an empty object if they are invalid.
+func jsonObject(arguments string) json.RawMessage {
+	if json.Valid([]byte(arguments)) && strings.HasPrefix(strings.TrimSpace(arguments), "{") {
+		return json.RawMessage(arguments)
+	}
+	return json.RawMessage("{}")
+}
+
+// --- Gemini -> Canonical ---
+
+func (r *geminiResponse) toChatResponse(model string) (*ChatResponse, error) {
+	if len(r.Candidates) == 0 {
+		if r.PromptFeedback != nil && len(r.PromptFeedback.BlockReason) > 0 {
+			return nil, &AIError{
+				Type:       "invalid_request_error",
+				Message:    "The prompt was blocked by gemini: " + r.PromptFeedback.BlockReason,
+				StatusCode: http.StatusBadRequest,
+				Provider:   "gemini",
+				Code:       optional.Some("content_filter"),
+			}
+		}
+		return nil, errors.New("gemini: response has no candidates")
+	}
+
+	candidate := r.Candidates[0]
+	msg := Message{Role: "assistant"}
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			msg.ToolCalls = append(msg.ToolCalls, part.FunctionCall.toToolCall(len(msg.ToolCalls)))
+		case part.Thought:
+			// the thoughts are only counted in the usage, since the message has no field for them
+		default:
+			text.WriteString(part.Text)
+		}
+	}
+	msg.Content = text.String()
+
+	resp := &ChatResponse{
+		ID:      r.ResponseID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      msg,
+			FinishReason: geminiFinishReason(candidate.FinishReason, len(msg.ToolCalls) > 0),
+		}},
+	}
+	if len(resp.ID) == 0 {
+		resp.ID = "chatcmpl-" + uuid.NewString()
+	}
+	if r.UsageMetadata != nil {
+		resp.Usage = r.UsageMetadata.toUsage()
+	}
+	return resp, nil
+}
+
+// toToolCall converts a function call, Gemini only returns an ID for some models, so an ID
+// is generated from the index otherwise.
+func (f *geminiFunctionCall) toToolCall(index int) ToolCall {
+	id := f.ID
+	if len(id) == 0 {
+		id = fmt.Sprintf("call_%d_%s", index, f.Name)
+	}
+	arguments := "{}"
+	if len(f.Args) > 0 {
+		arguments = string(f.Args)
+	}
+	return ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: FunctionCall{Name: f.Name, Arguments: arguments},
+	}
+}
+
+func (u *geminiUsageMetadata) toUsage() Usage {
+	usage := Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+	}
+	if u.CachedContentTokenCount > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.CachedContentTokenCount}
+	}
+	if u.ThoughtsTokenCount > 0 {
+		usage.CompletionTokensDetails = &CompletionTokensDetails{ReasoningTokens: u.ThoughtsTokenCount}
+	}
+	return usage
+}
+
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// geminiErrorTypes maps the status of Gemini errors to the canonical error types.
+var geminiErrorTypes = map[string]string{
+	"INVALID_ARGUMENT":    "invalid_request_error",
+	"FAILED_PRECONDITION": "invalid_request_error",
+	"OUT_OF_RANGE":        "invalid_request_error",
+	"UNAUTHENTICATED":     "authentication_error",
+	"PERMISSION_DENIED":   "permission_error",
+	"NOT_FOUND":           "not_found_error",
+	"RESOURCE_EXHAUSTED":  "rate_limit_error",
+}
+
+func parseGeminiError(statusCode int, body []byte) error {
+	var errResp GeminiErrorResponse
+
+	if err := sonic.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		errType, found := geminiErrorTypes[errResp.Error.Status]
+		if !found {
+			errType = "api_error"
+		}
+		aiErr := &AIError{
+			Type:       errType,
+			Message:    errResp.Error.Message,
+			StatusCode: statusCode,
+			Provider:   "gemini",
+		}
+		if len(errResp.Error.Status) > 0 {
+			aiErr.Code = optional.Some(strings.ToLower(errResp.Error.Status))
+		}
+		return aiErr
+	}
+
+	// SECURITY: Log full upstream error details internally, return generic error to prevent leakage
+	slog.ErrorContext(context.Background(), "upstream returned non-standard error",
+		"status_code", statusCode,
+		"body", string(body),
+		"provider", "gemini",
+	)
+
+	return fmt.Errorf("upstream error: status %d", statusCode)
+}
+
+// geminiStreamReader reads the SSE events of a Gemini stream and writes canonical
+// chat.completion.chunk lines. Gemini repeats the usage in every event, so it is only written
+// once in a final chunk, like OpenAI's `include_usage`.
+type geminiStreamReader struct {
+	stream  io.ReadCloser
+	reader  *bufio.Reader
+	out     bytes.Buffer
+	err     error
+	model   string
+	created int64
+
+	id        string
+	started   bool
+	toolCalls int
+	usage     *geminiUsageMetadata
+}
+
+func (s *geminiStreamReader) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && s.err == nil {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.processLine(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.finish()
+			}
+			s.err = err
+		}
+	}
+
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	return 0, s.err
+}
+
+func (s *geminiStreamReader) Close() error {
+	return s.stream.Close()
+}
+
+func (s *geminiStreamReader) processLine(line []byte) {
+	data, found := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !found {
+		return
+	}
+
+	var resp geminiResponse
+	if err := sonic.Unmarshal(bytes.TrimSpace(data), &resp); err != nil {
+		return
+	}
+
+	if len(s.id) == 0 {
+		s.id = resp.ResponseID
+		if len(s.id) == 0 {
+			s.id = "chatcmpl-" + uuid.NewString()
+		}
+	}
+	if resp.UsageMetadata != nil {
+		s.usage = resp.UsageMetadata
+	}
+
+	choice := StreamChoice{Index: 0}
+	if !s.started {
+		s.started = true
+		choice.Delta.Role = "assistant"
+	}
+
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && len(resp.PromptFeedback.BlockReason) > 0 {
+			reason := "content_filter"
+			choice.FinishReason = &reason
+			s.writeChunk(choice)
+		}
+		return
+	}
+
+	candidate := resp.Candidates[0]
+	var text, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, part.FunctionCall.toToolCall(s.toolCalls))
+			s.toolCalls++
+		case part.Thought:
+			thoughts.WriteString(part.Text)
+		default:
+			text.WriteString(part.Text)
+		}
+	}
+	choice.Delta.Content = text.String()
+	choice.Delta.ReasoningContent = thoughts.String()
+
+	if len(candidate.FinishReason) > 0 {
+		reason := geminiFinishReason(candidate.FinishReason, s.toolCalls > 0)
+		choice.FinishReason = &reason
+	}
+
+	s.writeChunk(choice)
+}
+
+func (s *geminiStreamReader) finish() {
+	if s.usage != nil {
+		usage := s.usage.toUsage()
+		s.write(StreamChunk{
+			ID:      s.id,
+			Object:  "chat.completion.chunk",
+			Created: s.created,
+			Model:   s.model,
+			Choices: []StreamChoice{},
+			Usage:   &usage,
+		})
+	}
+	s.out.WriteString("data: [DONE]\n\n")
+}
+
+func (s *geminiStreamReader) writeChunk(choice StreamChoice) {
+	s.write(StreamChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []StreamChoice{choice},
+	})
+}
+
+func (s *geminiStreamReader) write(chunk StreamChunk) {
+	data, err := sonic.Marshal(chunk)
+	if err != nil {
+		return
+	}
+	s.out.WriteString("data: ")
+	s.out.Write(data)
+	s.out.WriteString("\n\n")
+}
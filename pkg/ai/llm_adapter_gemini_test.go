@@ -0,0 +1,339 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGeminiTestAdapter(t *testing.T, handler http.HandlerFunc) *GeminiAdapter {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	require.NoError(t, err)
+
+	adapter, err := GetAdapter("gemini", LLMAdapterOptions{
+		HTTPClient: httpClient,
+		APIKey:     "test-key",
+		BaseURL:    ts.URL + "/v1beta",
+	})
+	require.NoError(t, err)
+	return adapter.(*GeminiAdapter)
+}
+
+func TestGeminiAdapter_Chat_Success(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1beta/models/gemini-2.5-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var req geminiRequest
+		if !assert.NoError(t, sonic.Unmarshal(body, &req)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		require.NotNil(t, req.SystemInstruction)
+		assert.Equal(t, "be brief", req.SystemInstruction.Parts[0].Text)
+		require.Len(t, req.Contents, 1)
+		assert.Equal(t, "user", req.Contents[0].Role)
+		assert.Equal(t, "hello", req.Contents[0].Parts[0].Text)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "thinking", "thought": true}, {"text": "hello client"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "thoughtsTokenCount": 3, "cachedContentTokenCount": 4, "totalTokenCount": 18},
+			"responseId": "resp-123"
+		}`))
+	})
+
+	resp, err := adapter.Chat(context.Background(), &ChatRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "hello"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "resp-123", resp.ID)
+	assert.Equal(t, "gemini-2.5-flash", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "assistant", resp.Choices[0].Message.Role)
+	assert.Equal(t, "hello client", resp.Choices[0].Message.Content)
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+
+	assert.Equal(t, 10, resp.Usage.PromptTokens)
+	assert.Equal(t, 8, resp.Usage.CompletionTokens)
+	assert.Equal(t, 18, resp.Usage.TotalTokens)
+	require.NotNil(t, resp.Usage.PromptTokensDetails)
+	assert.Equal(t, 4, resp.Usage.PromptTokensDetails.CachedTokens)
+	require.NotNil(t, resp.Usage.CompletionTokensDetails)
+	assert.Equal(t, 3, resp.Usage.CompletionTokensDetails.ReasoningTokens)
+}
+
+func TestGeminiAdapter_Chat_FunctionCalls(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "get_weather", "args": {"city": "Taipei"}}}]}, "finishReason": "STOP"}]
+		}`))
+	})
+
+	resp, err := adapter.Chat(context.Background(), &ChatRequest{
+		Model:    "gemini-2.5-flash",
+		Messages: []Message{{Role: "user", Content: "weather?"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	call := resp.Choices[0].Message.ToolCalls[0]
+	assert.Equal(t, "call_0_get_weather", call.ID)
+	assert.Equal(t, "function", call.Type)
+	assert.Equal(t, "get_weather", call.Function.Name)
+	assert.JSONEq(t, `{"city":"Taipei"}`, call.Function.Arguments)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+}
+
+func TestGeminiAdapter_Chat_Blocked(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"promptFeedback": {"blockReason": "SAFETY"}, "usageMetadata": {"promptTokenCount": 7, "totalTokenCount": 7}}`))
+	})
+
+	_, err := adapter.Chat(context.Background(), &ChatRequest{
+		Model:    "gemini-2.5-flash",
+		Messages: []Message{{Role: "user", Content: "hello"}},
+	})
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusBadRequest, aiErr.StatusCode)
+	assert.Equal(t, "content_filter", aiErr.Code.Unwrap())
+	assert.Contains(t, aiErr.Message, "SAFETY")
+}
+
+func TestGeminiAdapter_Chat_Error(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED"}}`))
+	})
+
+	_, err := adapter.Chat(context.Background(), &ChatRequest{Model: "gemini-2.5-flash"})
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusTooManyRequests, aiErr.StatusCode)
+	assert.Equal(t, "rate_limit_error", aiErr.Type)
+	assert.Equal(t, "Resource has been exhausted", aiErr.Message)
+	assert.Equal(t, "resource_exhausted", aiErr.Code.Unwrap())
+	assert.Equal(t, "gemini", aiErr.Provider)
+}
+
+func TestGeminiAdapter_StreamChat_Success(t *testing.T) {
+	events := []string{
+		`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "hmm", "thought": true}]}}], "usageMetadata": {"promptTokenCount": 10, "totalTokenCount": 10}, "responseId": "resp-123"}`,
+		`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "hel"}]}}], "usageMetadata": {"promptTokenCount": 10, "totalTokenCount": 10}, "responseId": "resp-123"}`,
+		`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "lo"}]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 2, "thoughtsTokenCount": 1, "cachedContentTokenCount": 6, "totalTokenCount": 13}, "responseId": "resp-123"}`,
+	}
+
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-2.5-flash:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range events {
+			_, _ = w.Write([]byte(event + "\r\n\r\n"))
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		}
+	})
+
+	stream, err := adapter.StreamChat(context.Background(), &ChatRequest{
+		Model:    "gemini-2.5-flash",
+		Stream:   true,
+		Messages: []Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	buf := new(bytes.Buffer)
+	_, err = io.Copy(buf, stream)
+	require.NoError(t, err)
+
+	var chunks []StreamChunk
+	for _, line := range strings.Split(buf.String(), "\n\n") {
+		data, found := strings.CutPrefix(line, "data: ")
+		if !found || data == "[DONE]" {
+			continue
+		}
+		var chunk StreamChunk
+		require.NoError(t, sonic.UnmarshalString(data, &chunk))
+		chunks = append(chunks, chunk)
+	}
+	assert.True(t, strings.HasSuffix(buf.String(), "data: [DONE]\n\n"))
+
+	require.Len(t, chunks, 4)
+	assert.Equal(t, "resp-123", chunks[0].ID)
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "hmm", chunks[0].Choices[0].Delta.ReasoningContent)
+	assert.Equal(t, "hel", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, "lo", chunks[2].Choices[0].Delta.Content)
+	require.NotNil(t, chunks[2].Choices[0].FinishReason)
+	assert.Equal(t, "stop", *chunks[2].Choices[0].FinishReason)
+
+	// the usage is only sent once, in the last chunk
+	for _, chunk := range chunks[:3] {
+		assert.Nil(t, chunk.Usage)
+	}
+	usage := chunks[3].Usage
+	require.NotNil(t, usage)
+	assert.Empty(t, chunks[3].Choices)
+	assert.Equal(t, 10, usage.PromptTokens)
+	assert.Equal(t, 3, usage.CompletionTokens)
+	assert.Equal(t, 6, usage.PromptTokensDetails.CachedTokens)
+	assert.Equal(t, 1, usage.CompletionTokensDetails.ReasoningTokens)
+}
+
+func TestGeminiAdapter_StreamChat_Error(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "API key not valid", "status": "INVALID_ARGUMENT"}}`))
+	})
+
+	_, err := adapter.StreamChat(context.Background(), &ChatRequest{Model: "gemini-2.5-flash", Stream: true})
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusBadRequest, aiErr.StatusCode)
+	assert.Equal(t, "invalid_request_error", aiErr.Type)
+}
+
+func TestGeminiAdapter_Embeddings(t *testing.T) {
+	adapter := newGeminiTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-embedding-001:batchEmbedContents", r.URL.Path)
+
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.JSONEq(t, `{"requests":[`+
+			`{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"hello"}]}},`+
+			`{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"world"}]}}]}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`))
+	})
+
+	resp, err := adapter.Embeddings(context.Background(), &EmbeddingRequest{
+		Model: "gemini-embedding-001",
+		Input: []any{"hello", "world"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "list", resp.Object)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float64{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 3, resp.Usage.PromptTokens)
+}
+
+func TestGeminiAdapter_UnsupportedResponses(t *testing.T) {
+	adapter := NewGeminiAdapter(LLMAdapterOptions{})
+	_, err := adapter.Responses(context.Background(), &ResponsesRequest{})
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusNotImplemented, aiErr.StatusCode)
+
+	_, err = adapter.StreamResponses(context.Background(), &ResponsesRequest{})
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusNotImplemented, aiErr.StatusCode)
+}
+
+func TestToGeminiRequest(t *testing.T) {
+	temperature := 0.2
+	maxTokens := 100
+
+	req, err := toGeminiRequest(&ChatRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: []ContentPart{
+				{Type: "text", Text: "what is this?"},
+				{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,iVBOR"}},
+			}},
+			{Role: "assistant", ToolCalls: []ToolCall{
+				{ID: "call_1", Type: "function", Function: FunctionCall{Name: "lookup", Arguments: `{"q":"a"}`}},
+				{ID: "call_2", Type: "function", Function: FunctionCall{Name: "search", Arguments: `{"q":"b"}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: `{"result":"x"}`},
+			{Role: "tool", ToolCallID: "call_2", Content: "plain"},
+		},
+		Tools: []Tool{{Type: "function", Function: FunctionDesc{
+			Name:       "lookup",
+			Parameters: []byte(`{"type":"object"}`),
+		}}},
+		ToolChoice:     map[string]any{"type": "function", "function": map[string]any{"name": "lookup"}},
+		Temperature:    &temperature,
+		MaxTokens:      &maxTokens,
+		Reasoning:      &Reasoning{Effort: "low"},
+		ResponseFormat: map[string]any{"type": "json_schema", "json_schema": map[string]any{"schema": map[string]any{"type": "object"}}},
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, req.SystemInstruction)
+	assert.Equal(t, "be brief", req.SystemInstruction.Parts[0].Text)
+
+	require.Len(t, req.Contents, 3)
+	assert.Equal(t, "user", req.Contents[0].Role)
+	require.Len(t, req.Contents[0].Parts, 2)
+	require.NotNil(t, req.Contents[0].Parts[1].InlineData)
+	assert.Equal(t, "image/png", req.Contents[0].Parts[1].InlineData.MimeType)
+	assert.Equal(t, "iVBOR", req.Contents[0].Parts[1].InlineData.Data)
+
+	assert.Equal(t, "model", req.Contents[1].Role)
+	require.Len(t, req.Contents[1].Parts, 2)
+	assert.Equal(t, "lookup", req.Contents[1].Parts[0].FunctionCall.Name)
+
+	// the responses of parallel function calls are merged into one content
+	assert.Equal(t, "user", req.Contents[2].Role)
+	require.Len(t, req.Contents[2].Parts, 2)
+	assert.Equal(t, "lookup", req.Contents[2].Parts[0].FunctionResponse.Name)
+	assert.JSONEq(t, `{"result":"x"}`, string(req.Contents[2].Parts[0].FunctionResponse.Response))
+	assert.Equal(t, "search", req.Contents[2].Parts[1].FunctionResponse.Name)
+	assert.JSONEq(t, `{"content":"plain"}`, string(req.Contents[2].Parts[1].FunctionResponse.Response))
+
+	require.Len(t, req.Tools, 1)
+	assert.JSONEq(t, `{"type":"object"}`, string(req.Tools[0].FunctionDeclarations[0].ParametersJSONSchema))
+	assert.Equal(t, "ANY", req.ToolConfig.FunctionCallingConfig.Mode)
+	assert.Equal(t, []string{"lookup"}, req.ToolConfig.FunctionCallingConfig.AllowedFunctionNames)
+
+	config := req.GenerationConfig
+	require.NotNil(t, config)
+	assert.InDelta(t, 0.2, *config.Temperature, 0.001)
+	assert.Equal(t, 100, *config.MaxOutputTokens)
+	assert.Equal(t, 1024, *config.ThinkingConfig.ThinkingBudget)
+	assert.Equal(t, "application/json", config.ResponseMimeType)
+	assert.JSONEq(t, `{"type":"object"}`, string(config.ResponseJSONSchema))
+
+	_, err = toGeminiRequest(&ChatRequest{Messages: []Message{{Role: "function", Content: "x"}}})
+	var aiErr *AIError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, http.StatusBadRequest, aiErr.StatusCode)
+}
@@ -24,16 +24,14 @@ func init() {
 // OpenAIChatAdapter implements LLMAdapter for OpenAI's Chat Completions API.
 type OpenAIChatAdapter struct {
 	client  *client.Client
-	apiKey  string
-	baseURL string
+	options LLMAdapterOptions
 }
 
 // NewOpenAIChatAdapter creates a new instance of OpenAIChatAdapter.
 func NewOpenAIChatAdapter(opts LLMAdapterOptions) *OpenAIChatAdapter {
 	return &OpenAIChatAdapter{
 		client:  opts.HTTPClient,
-		apiKey:  opts.APIKey,
-		baseURL: opts.BaseURL,
+		options: opts,
 	}
 }
 
@@ -42,23 +40,45 @@ func (a *OpenAIChatAdapter) Name() string { return "openai-chat" }
 
 // Chat sends a unary chat completion request to OpenAI.
 func (a *OpenAIChatAdapter) Chat(ctx context.Context, chatReq *ChatRequest) (*ChatResponse, error) {
+	body, err := sonic.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to marshal request: %w", err)
+	}
+
+	respBody, err := a.post(ctx, "/chat/completions", chatReq.Model, "chat/completions", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var chatResp ChatResponse
+	if err := sonic.Unmarshal(respBody, &chatResp); err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to unmarshal response: %w", err)
+	}
+
+	return &chatResp, nil
+}
+
+// post sends a unary request to OpenAI and returns the body of the successful response.
+func (a *OpenAIChatAdapter) post(
+	ctx context.Context,
+	path string,
+	model string,
+	method string,
+	contentType string,
+	body []byte,
+) ([]byte, error) {
 	req := protocol.AcquireRequest()
 	defer protocol.ReleaseRequest(req)
 	resp := protocol.AcquireResponse()
 	defer protocol.ReleaseResponse(resp)
 
 	req.Header.SetMethod(http.MethodPost)
-	req.SetRequestURI(a.baseURL + "/chat/completions")
-	req.Header.SetContentTypeBytes([]byte("application/json"))
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-
-	body, err := sonic.Marshal(chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("openai-chat: failed to marshal request: %w", err)
-	}
+	req.SetRequestURI(a.options.requestURL(path, model, method))
+	req.Header.SetContentTypeBytes([]byte(contentType))
+	a.options.setHeaders(req, "Authorization", "Bearer")
 	req.SetBody(body)
 
-	err = a.client.Do(ctx, req, resp)
+	err := a.client.Do(ctx, req, resp)
 	if err != nil {
 		return nil, fmt.Errorf("openai-chat: request failed: %w", err)
 	}
@@ -70,19 +90,14 @@ func (a *OpenAIChatAdapter) Chat(ctx context.Context, chatReq *ChatRequest) (*Ch
 			return nil, fmt.Errorf("openai-chat: failed to read response body stream: %w", err)
 		}
 	} else {
-		respBody = resp.Body()
+		// the body is released with the response
+		respBody = bytes.Clone(resp.Body())
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, parseError(resp.StatusCode(), respBody)
-	}
-
-	var chatResp ChatResponse
-	if err := sonic.Unmarshal(respBody, &chatResp); err != nil {
-		return nil, fmt.Errorf("openai-chat: failed to unmarshal response: %w", err)
+		return nil, withRetryAfter(parseError(resp.StatusCode(), respBody), &resp.Header)
 	}
-
-	return &chatResp, nil
+	return respBody, nil
 }
 
 // responseStreamCloser wraps a stream reader and handles close operations safely.
@@ -98,7 +113,11 @@ func (r *responseStreamCloser) Read(p []byte) (n int, err error) {
 
 func (r *responseStreamCloser) Close() error {
 	var err error
-	if closer, ok := r.reader.(io.ReadCloser); ok {
+	if r.resp != nil {
+		// the response owns the body stream, closing the stream directly as well would release it
+		// to the pool of hertz twice when the response is reset
+		err = r.resp.CloseBodyStream()
+	} else if closer, ok := r.reader.(io.ReadCloser); ok {
 		err = closer.Close()
 	}
 	if r.req != nil {
@@ -117,12 +136,9 @@ func (a *OpenAIChatAdapter) StreamChat(ctx context.Context, chatReq *ChatRequest
 	resp := &protocol.Response{}
 
 	req.Header.SetMethod(http.MethodPost)
-	req.SetRequestURI(a.baseURL + "/chat/completions")
+	req.SetRequestURI(a.options.requestURL("/chat/completions", chatReq.Model, "chat/completions"))
 	req.Header.SetContentTypeBytes([]byte("application/json"))
-
-	if len(a.apiKey) > 0 {
-		req.Header.Set("Authorization", "Bearer "+a.apiKey)
-	}
+	a.options.setHeaders(req, "Authorization", "Bearer")
 
 	body, err := sonic.Marshal(chatReq)
 	if err != nil {
@@ -142,7 +158,7 @@ func (a *OpenAIChatAdapter) StreamChat(ctx context.Context, chatReq *ChatRequest
 		} else {
 			respBody = resp.Body()
 		}
-		return nil, parseError(resp.StatusCode(), respBody)
+		return nil, withRetryAfter(parseError(resp.StatusCode(), respBody), &resp.Header)
 	}
 
 	if resp.IsBodyStream() {
@@ -159,6 +175,126 @@ func (a *OpenAIChatAdapter) StreamChat(ctx context.Context, chatReq *ChatRequest
 	return io.NopCloser(bytes.NewReader(bodyBytes)), nil
 }
 
+// Embeddings sends an embeddings request to OpenAI.
+func (a *OpenAIChatAdapter) Embeddings(ctx context.Context, embeddingReq *EmbeddingRequest) (*EmbeddingResponse, error) {
+	body, err := sonic.Marshal(embeddingReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to marshal request: %w", err)
+	}
+
+	respBody, err := a.post(ctx, "/embeddings", embeddingReq.Model, "embeddings", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var embeddingResp EmbeddingResponse
+	if err := sonic.Unmarshal(respBody, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to unmarshal response: %w", err)
+	}
+
+	return &embeddingResp, nil
+}
+
+// openAIMediaUsage is the usage of the image and audio APIs, which report either tokens or the
+// duration of the audio.
+type openAIMediaUsage struct {
+	Type         string  `json:"type"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalTokens  int     `json:"total_tokens"`
+	Seconds      float64 `json:"seconds"`
+}
+
+func (u *openAIMediaUsage) toUsage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+type openAIImageResponse struct {
+	Created int64             `json:"created"`
+	Data    []ImageData       `json:"data"`
+	Usage   *openAIMediaUsage `json:"usage"`
+}
+
+// Images sends an image generation request to OpenAI.
+func (a *OpenAIChatAdapter) Images(ctx context.Context, imageReq *ImageRequest) (*ImageResponse, error) {
+	body, err := sonic.Marshal(imageReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to marshal request: %w", err)
+	}
+
+	respBody, err := a.post(ctx, "/images/generations", imageReq.Model, "images/generations", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var imageResp openAIImageResponse
+	if err := sonic.Unmarshal(respBody, &imageResp); err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to unmarshal response: %w", err)
+	}
+
+	return &ImageResponse{
+		Created: imageResp.Created,
+		Data:    imageResp.Data,
+		Usage:   imageResp.Usage.toUsage(),
+	}, nil
+}
+
+type openAITranscriptionResponse struct {
+	Text     string            `json:"text"`
+	Language string            `json:"language"`
+	Duration float64           `json:"duration"`
+	Segments any               `json:"segments"`
+	Words    any               `json:"words"`
+	Usage    *openAIMediaUsage `json:"usage"`
+}
+
+// Transcriptions sends an audio transcription request to OpenAI.
+func (a *OpenAIChatAdapter) Transcriptions(
+	ctx context.Context,
+	transcriptionReq *TranscriptionRequest,
+) (*TranscriptionResponse, error) {
+	contentType, body, err := transcriptionReq.MultipartBody()
+	if err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to encode request: %w", err)
+	}
+
+	respBody, err := a.post(
+		ctx, "/audio/transcriptions", transcriptionReq.Model, "audio/transcriptions", contentType, body,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if transcriptionReq.IsText() {
+		return &TranscriptionResponse{Text: string(respBody)}, nil
+	}
+
+	var transcriptionResp openAITranscriptionResponse
+	if err := sonic.Unmarshal(respBody, &transcriptionResp); err != nil {
+		return nil, fmt.Errorf("openai-chat: failed to unmarshal response: %w", err)
+	}
+
+	duration := transcriptionResp.Duration
+	if duration == 0 && transcriptionResp.Usage != nil && transcriptionResp.Usage.Type == "duration" {
+		duration = transcriptionResp.Usage.Seconds
+	}
+	return &TranscriptionResponse{
+		Text:     transcriptionResp.Text,
+		Language: transcriptionResp.Language,
+		Duration: duration,
+		Segments: transcriptionResp.Segments,
+		Words:    transcriptionResp.Words,
+		Usage:    transcriptionResp.Usage.toUsage(),
+	}, nil
+}
+
 // Responses sends a batch responses request to OpenAI.
 func (a *OpenAIChatAdapter) Responses(_ context.Context, _ *ResponsesRequest) (*ResponsesResponse, error) {
 	return nil, &AIError{
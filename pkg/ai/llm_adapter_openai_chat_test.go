@@ -192,6 +192,138 @@ func TestOpenAIChatAdapter_Chat_Error(t *testing.T) {
 	assert.Equal(t, "model_not_found", aiErr.Code.Unwrap())
 }
 
+func TestOpenAIChatAdapter_Embeddings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var embeddingReq EmbeddingRequest
+		if !assert.NoError(t, sonic.Unmarshal(body, &embeddingReq)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "text-embedding-3-small", embeddingReq.Model)
+		assert.Equal(t, "hello", embeddingReq.Input)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","model":"text-embedding-3-small",` +
+			`"data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],` +
+			`"usage":{"prompt_tokens":1,"total_tokens":1}}`))
+	}))
+	defer ts.Close()
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	require.NoError(t, err)
+
+	adapter, err := GetAdapter("openai-chat", LLMAdapterOptions{
+		HTTPClient: httpClient,
+		APIKey:     "test-key",
+		BaseURL:    ts.URL,
+	})
+	require.NoError(t, err)
+
+	embeddingAdapter, ok := adapter.(EmbeddingAdapter)
+	require.True(t, ok)
+
+	resp, err := embeddingAdapter.Embeddings(context.Background(), &EmbeddingRequest{
+		Model: "text-embedding-3-small",
+		Input: "hello",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, []float64{0.1, 0.2}, resp.Data[0].Embedding)
+	assert.Equal(t, 1, resp.Usage.PromptTokens)
+}
+
+func TestOpenAIChatAdapter_Images(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/generations", r.URL.Path)
+
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.JSONEq(t, `{"model":"dall-e-3","prompt":"a cat","n":1,"background":"transparent"}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"created":1,"data":[{"url":"https://example.com/cat.png","revised_prompt":"a cat"}]}`))
+	}))
+	defer ts.Close()
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	require.NoError(t, err)
+
+	adapter := NewOpenAIChatAdapter(LLMAdapterOptions{HTTPClient: httpClient, APIKey: "test-key", BaseURL: ts.URL})
+
+	n := 1
+	resp, err := adapter.Images(context.Background(), &ImageRequest{
+		Model:         "dall-e-3",
+		Prompt:        "a cat",
+		N:             &n,
+		UnknownFields: map[string]any{"background": "transparent"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "https://example.com/cat.png", resp.Data[0].URL)
+	assert.Equal(t, Usage{}, resp.Usage)
+}
+
+func TestOpenAIChatAdapter_Transcriptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		if !assert.NoError(t, r.ParseMultipartForm(1<<20)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, []string{"word"}, r.MultipartForm.Value["timestamp_granularities[]"])
+		file, header, err := r.FormFile("file")
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "audio", string(data))
+		assert.Equal(t, "speech.mp3", header.Filename)
+
+		if r.FormValue("response_format") == "srt" {
+			_, _ = w.Write([]byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"hello","usage":{"type":"duration","seconds":3}}`))
+	}))
+	defer ts.Close()
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	require.NoError(t, err)
+
+	adapter := NewOpenAIChatAdapter(LLMAdapterOptions{HTTPClient: httpClient, APIKey: "test-key", BaseURL: ts.URL})
+
+	req := &TranscriptionRequest{
+		Model:    "whisper-1",
+		File:     []byte("audio"),
+		FileName: "speech.mp3",
+		Fields:   map[string][]string{"timestamp_granularities[]": {"word"}},
+	}
+	resp, err := adapter.Transcriptions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Text)
+	assert.InDelta(t, 3.0, resp.Duration, 0.0001)
+
+	req.ResponseFormat = "srt"
+	resp, err = adapter.Transcriptions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n00:00:00,000 --> 00:00:01,000\nhello\n", resp.Text)
+}
+
 func TestOpenAIChatAdapter_StreamChat_Success(t *testing.T) {
 	chunks := []string{
 		`data: {"id":"chat-123","choices":[{"index":0,"delta":{"role":"assistant","content":"hel"}}]}`,
@@ -297,3 +429,39 @@ func parseErrorForTest(statusCode int, body []byte) error {
 	}
 	return fmt.Errorf("upstream error: status %d", statusCode)
 }
+
+func TestOpenAIChatAdapter_ProviderOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/gpt-4o-prod/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-10-21", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "test-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Equal(t, "bifrost", r.Header.Get("X-Client"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chat-123","choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer ts.Close()
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	require.NoError(t, err)
+
+	adapter := NewOpenAIChatAdapter(LLMAdapterOptions{
+		HTTPClient:  httpClient,
+		APIKey:      "test-key",
+		BaseURL:     ts.URL,
+		URLTemplate: "{base_url}/openai/deployments/{deployment}/chat/completions",
+		AuthHeader:  "api-key",
+		Headers:     map[string]string{"X-Client": "bifrost"},
+		QueryParams: map[string]string{"api-version": "2024-10-21"},
+		Deployments: map[string]string{"gpt-4o": "gpt-4o-prod"},
+	})
+
+	resp, err := adapter.Chat(context.Background(), &ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", resp.Choices[0].Message.Content)
+
+	stream, err := adapter.StreamChat(context.Background(), &ChatRequest{Model: "gpt-4o", Stream: true})
+	require.NoError(t, err)
+	_ = stream.Close()
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLLMAdapterOptions_RequestURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  LLMAdapterOptions
+		path     string
+		model    string
+		method   string
+		expected string
+	}{
+		{
+			name:     "default path",
+			options:  LLMAdapterOptions{BaseURL: "https://api.openai.com/v1"},
+			path:     "/chat/completions",
+			model:    "gpt-4o",
+			expected: "https://api.openai.com/v1/chat/completions",
+		},
+		{
+			name: "template with deployment and query",
+			options: LLMAdapterOptions{
+				BaseURL:     "https://example.openai.azure.com/",
+				URLTemplate: "{base_url}/openai/deployments/{deployment}/chat/completions",
+				QueryParams: map[string]string{"api-version": "2024-10-21"},
+				Deployments: map[string]string{"gpt-4o": "prod"},
+			},
+			model:    "gpt-4o",
+			expected: "https://example.openai.azure.com/openai/deployments/prod/chat/completions?api-version=2024-10-21",
+		},
+		{
+			name: "model without deployment",
+			options: LLMAdapterOptions{
+				BaseURL:     "https://example.openai.azure.com",
+				URLTemplate: "{base_url}/openai/deployments/{deployment}/chat/completions?api-version=1",
+				QueryParams: map[string]string{"b": "2", "a": "1"},
+			},
+			model:    "gpt-4o-mini",
+			expected: "https://example.openai.azure.com/openai/deployments/gpt-4o-mini/chat/completions?api-version=1&a=1&b=2",
+		},
+		{
+			name:     "method placeholder",
+			options:  LLMAdapterOptions{BaseURL: "https://generativelanguage.googleapis.com/v1beta"},
+			path:     "/models/{deployment}:{method}",
+			model:    "gemini-2.5-flash",
+			method:   "generateContent",
+			expected: "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.options.requestURL(tt.path, tt.model, tt.method))
+		})
+	}
+}
+
+func TestLLMAdapterOptions_SetHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  LLMAdapterOptions
+		header   string
+		expected string
+	}{
+		{name: "default", options: LLMAdapterOptions{APIKey: "key"}, header: "Authorization", expected: "Bearer key"},
+		{name: "scheme", options: LLMAdapterOptions{APIKey: "key", AuthScheme: "Token"}, header: "Authorization", expected: "Token key"},
+		{name: "header", options: LLMAdapterOptions{APIKey: "key", AuthHeader: "api-key"}, header: "api-key", expected: "key"},
+		{name: "no key", options: LLMAdapterOptions{}, header: "Authorization", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &protocol.Request{}
+			tt.options.Headers = map[string]string{"X-Tenant": "a"}
+			tt.options.setHeaders(req, "Authorization", "Bearer")
+			assert.Equal(t, tt.expected, string(req.Header.Peek(tt.header)))
+			assert.Equal(t, "a", string(req.Header.Peek("X-Tenant")))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+func TestParseEmbeddingRequest(t *testing.T) {
+	req, err := ParseEmbeddingRequest([]byte(`{"model":"embed","input":"hello","dimensions":256}`))
+	require.NoError(t, err)
+	assert.Equal(t, "embed", req.Model)
+	assert.Equal(t, []string{"hello"}, req.Inputs())
+	require.NotNil(t, req.Dimensions)
+	assert.Equal(t, 256, *req.Dimensions)
+
+	req, err = ParseEmbeddingRequest([]byte(`{"model":"embed","input":["a","b"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, req.Inputs())
+
+	_, err = ParseEmbeddingRequest([]byte(`{"model":"embed"}`))
+	require.Error(t, err)
+}
+
+func TestParseImageRequest(t *testing.T) {
+	req, err := ParseImageRequest([]byte(`{"model":"dall-e-3","prompt":"a cat","size":"1024x1024","background":"auto"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "a cat", req.Prompt)
+	assert.Equal(t, "1024x1024", req.Size)
+	assert.Equal(t, map[string]any{"background": "auto"}, req.UnknownFields)
+
+	_, err = ParseImageRequest([]byte(`{"model":"dall-e-3"}`))
+	require.Error(t, err)
+}
+
+func TestParseTranscriptionRequest(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("model", "whisper-1"))
+	require.NoError(t, writer.WriteField("temperature", "0.2"))
+	require.NoError(t, writer.WriteField("response_format", "vtt"))
+	require.NoError(t, writer.WriteField("timestamp_granularities[]", "segment"))
+	part, err := writer.CreateFormFile("file", "speech.wav")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("audio"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req, err := ParseTranscriptionRequest(writer.FormDataContentType(), body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "whisper-1", req.Model)
+	assert.Equal(t, []byte("audio"), req.File)
+	assert.Equal(t, "speech.wav", req.FileName)
+	require.NotNil(t, req.Temperature)
+	assert.InDelta(t, 0.2, *req.Temperature, 0.0001)
+	assert.True(t, req.IsText())
+	assert.Equal(t, []string{"segment"}, req.Fields["timestamp_granularities[]"])
+
+	contentType, encoded, err := req.MultipartBody()
+	require.NoError(t, err)
+	roundTrip, err := ParseTranscriptionRequest(contentType, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, req, roundTrip)
+
+	_, err = ParseTranscriptionRequest("application/json", []byte(`{}`))
+	require.Error(t, err)
+}
+
+func TestMediaCalculateCost(t *testing.T) {
+	pricing := &config.AIPricingOptions{
+		InputPerMtok: 1_000_000,
+		PerImage:     0.04,
+		PerMinute:    0.006,
+	}
+
+	image := &ImageResponse{Data: []ImageData{{URL: "a"}, {URL: "b"}}}
+	image.CalculateCost(pricing)
+	assert.InDelta(t, 0.08, image.Usage.OutputCost, 0.0001)
+
+	transcription := &TranscriptionResponse{Duration: 30, Usage: Usage{PromptTokens: 1}}
+	transcription.CalculateCost(pricing)
+	assert.InDelta(t, 1.003, transcription.Usage.InputCost, 0.0001)
+}
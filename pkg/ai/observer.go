@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"slices"
+	"sync"
 
 	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
 )
 
 // UsageObserver defines the contract for components that monitor AI usage.
@@ -17,6 +20,51 @@ type UsageObserver interface {
 	OnUsage(ctx context.Context, metadata UsageMetadata, usage Usage)
 }
 
+// AddUsageObserver registers an observer which the AI proxy notifies of the usage of the request,
+// e.g. by middlewares which enforce quotas.
+func AddUsageObserver(c *app.RequestContext, observer UsageObserver) {
+	c.Set(ContextKeyUsageObservers, append(slices.Clone(UsageObservers(c)), observer))
+}
+
+// UsageObservers returns the usage observers registered for the request.
+func UsageObservers(c *app.RequestContext) []UsageObserver {
+	val, found := c.Get(ContextKeyUsageObservers)
+	if !found {
+		return nil
+	}
+	observers, _ := val.([]UsageObserver)
+	return observers
+}
+
+var (
+	globalObserversMu sync.RWMutex
+	globalObservers   []UsageObserver
+)
+
+// RegisterUsageObserver registers an observer which the AI proxy notifies of the usage of every
+// request, e.g. usage ledgers.
+func RegisterUsageObserver(observer UsageObserver) {
+	globalObserversMu.Lock()
+	defer globalObserversMu.Unlock()
+	globalObservers = append(slices.Clone(globalObservers), observer)
+}
+
+// UnregisterUsageObserver removes an observer registered by RegisterUsageObserver.
+func UnregisterUsageObserver(observer UsageObserver) {
+	globalObserversMu.Lock()
+	defer globalObserversMu.Unlock()
+	globalObservers = slices.DeleteFunc(slices.Clone(globalObservers), func(o UsageObserver) bool {
+		return o == observer
+	})
+}
+
+// GlobalUsageObservers returns the observers registered by RegisterUsageObserver.
+func GlobalUsageObservers() []UsageObserver {
+	globalObserversMu.RLock()
+	defer globalObserversMu.RUnlock()
+	return globalObservers
+}
+
 // ObservedStream is a decorator for io.ReadCloser that intercepts SSE chunks
 // to extract usage data before passing them to the client.
 type ObservedStream struct {
@@ -75,3 +75,19 @@ func TestObservedStream(t *testing.T) {
 	assert.Equal(t, 10, observer.usages[1].CompletionTokens)
 	assert.Equal(t, 20, observer.usages[1].TotalTokens)
 }
+
+func TestRegisterUsageObserver(t *testing.T) {
+	o1 := &mockUsageObserver{}
+	o2 := &mockUsageObserver{}
+	RegisterUsageObserver(o1)
+	RegisterUsageObserver(o2)
+	observers := GlobalUsageObservers()
+	assert.Len(t, observers, 2)
+
+	UnregisterUsageObserver(o1)
+	assert.Equal(t, []UsageObserver{o2}, GlobalUsageObservers())
+	assert.Len(t, observers, 2)
+
+	UnregisterUsageObserver(o2)
+	assert.Empty(t, GlobalUsageObservers())
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+// rateLimitResetHeaders are the headers in which OpenAI-compatible providers return the time
+// until their rate limits reset, e.g. `6m0s` or `20ms`.
+var rateLimitResetHeaders = []string{
+	"x-ratelimit-reset-requests",
+	"x-ratelimit-reset-tokens",
+}
+
+// withRetryAfter sets the RetryAfter of a rate limit error from the headers of the response.
+func withRetryAfter(err error, header *protocol.ResponseHeader) error {
+	var aiErr *AIError
+	if errors.As(err, &aiErr) && aiErr.StatusCode == http.StatusTooManyRequests {
+		aiErr.RetryAfter = parseRetryAfter(header)
+	}
+	return err
+}
+
+// parseRetryAfter returns how long the provider asks clients to wait according to the
+// `retry-after-ms`, `Retry-After` and rate limit reset headers, or zero if none is set.
+func parseRetryAfter(header *protocol.ResponseHeader) time.Duration {
+	if val := header.Get("retry-after-ms"); val != "" {
+		if ms, err := strconv.ParseFloat(val, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	if val := header.Get("Retry-After"); val != "" {
+		if secs, err := strconv.ParseFloat(val, 64); err == nil && secs > 0 {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if at, err := http.ParseTime(val); err == nil {
+			return max(at.Sub(timecache.Now()), 0)
+		}
+	}
+
+	var reset time.Duration
+	for _, key := range rateLimitResetHeaders {
+		if d, err := time.ParseDuration(header.Get(key)); err == nil {
+			reset = max(reset, d)
+		}
+	}
+	return reset
+}
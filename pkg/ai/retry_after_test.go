@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	newHeader := func(kv ...string) *protocol.ResponseHeader {
+		header := &protocol.ResponseHeader{}
+		for i := 0; i < len(kv); i += 2 {
+			header.Set(kv[i], kv[i+1])
+		}
+		return header
+	}
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter(newHeader()))
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter(newHeader("retry-after-ms", "1500", "Retry-After", "9")))
+	assert.Equal(t, 20*time.Second, parseRetryAfter(newHeader("Retry-After", "20")))
+	assert.Equal(t, 6*time.Minute, parseRetryAfter(newHeader(
+		"x-ratelimit-reset-requests", "120ms",
+		"x-ratelimit-reset-tokens", "6m0s",
+	)))
+
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	d := parseRetryAfter(newHeader("Retry-After", at))
+	assert.InDelta(t, time.Minute.Seconds(), d.Seconds(), 2)
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	header := &protocol.ResponseHeader{}
+	header.Set("Retry-After", "3")
+
+	err := withRetryAfter(&AIError{StatusCode: http.StatusTooManyRequests}, header)
+	assert.Equal(t, 3*time.Second, err.(*AIError).RetryAfter) //nolint:errorlint
+
+	err = withRetryAfter(&AIError{StatusCode: http.StatusInternalServerError}, header)
+	assert.Equal(t, time.Duration(0), err.(*AIError).RetryAfter) //nolint:errorlint
+}
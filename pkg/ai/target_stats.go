@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsDecay is the weight of a new sample in the moving averages of TargetStats.
+const statsDecay = 0.3
+
+// FailurePenalty is the time to the first byte which TargetStats records for a failed request
+// that failed sooner, so that a target which fails fast doesn't look like the fastest target.
+const FailurePenalty = 30 * time.Second
+
+var targetStats sync.Map
+
+// TargetStats holds the runtime statistics of an AI target ("provider/model"), which the AI
+// balancers use to select targets. The AI proxy records them for every request.
+type TargetStats struct {
+	mu       sync.RWMutex
+	ttfb     float64 // exponentially weighted moving average in seconds
+	tps      float64 // exponentially weighted moving average of the generation speed
+	inFlight atomic.Int64
+}
+
+// StatsOf returns the statistics of the target, which are shared by every virtual model.
+func StatsOf(target string) *TargetStats {
+	if val, found := targetStats.Load(target); found {
+		stats, _ := val.(*TargetStats)
+		return stats
+	}
+	val, _ := targetStats.LoadOrStore(target, &TargetStats{})
+	stats, _ := val.(*TargetStats)
+	return stats
+}
+
+// Begin marks the start of a request to the target.
+func (s *TargetStats) Begin() {
+	s.inFlight.Add(1)
+}
+
+// Done marks the end of a request to the target.
+func (s *TargetStats) Done() {
+	s.inFlight.Add(-1)
+}
+
+// InFlight returns the number of requests being served by the target.
+func (s *TargetStats) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// ObserveTTFB records the time to the first byte of a response. The first byte of a unary
+// response is the whole response.
+func (s *TargetStats) ObserveTTFB(ttfb time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttfb = ewma(s.ttfb, ttfb.Seconds())
+}
+
+// ObserveFailure records a failed request, which took elapsed until it failed, as a response with
+// the time to the first byte of elapsed or FailurePenalty, whichever is longer.
+func (s *TargetStats) ObserveFailure(elapsed time.Duration) {
+	s.ObserveTTFB(max(elapsed, FailurePenalty))
+}
+
+// ObserveTPS records the generation speed of a stream in tokens per second.
+func (s *TargetStats) ObserveTPS(tps float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tps = ewma(s.tps, tps)
+}
+
+// TTFB returns the moving average of the time to the first byte, or zero if nothing was recorded.
+func (s *TargetStats) TTFB() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Duration(s.ttfb * float64(time.Second))
+}
+
+// TPS returns the moving average of the generation speed, or zero if nothing was recorded.
+func (s *TargetStats) TPS() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tps
+}
+
+func ewma(avg float64, sample float64) float64 {
+	if avg == 0 {
+		return sample
+	}
+	return statsDecay*sample + (1-statsDecay)*avg
+}
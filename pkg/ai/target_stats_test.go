@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetStats(t *testing.T) {
+	stats := StatsOf("test-provider/model")
+	assert.Same(t, stats, StatsOf("test-provider/model"))
+
+	stats.Begin()
+	stats.Begin()
+	stats.Done()
+	assert.Equal(t, int64(1), stats.InFlight())
+
+	assert.Equal(t, time.Duration(0), stats.TTFB())
+	stats.ObserveTTFB(time.Second)
+	assert.Equal(t, time.Second, stats.TTFB())
+	stats.ObserveTTFB(2 * time.Second)
+	assert.InDelta(t, 1.3, stats.TTFB().Seconds(), 1e-6)
+
+	failed := StatsOf("test-provider/failed")
+	failed.ObserveFailure(10 * time.Millisecond)
+	assert.Equal(t, FailurePenalty, failed.TTFB())
+	failed.ObserveFailure(2 * FailurePenalty)
+	assert.InDelta(t, 1.3*FailurePenalty.Seconds(), failed.TTFB().Seconds(), 1e-6)
+
+	stats.ObserveTPS(100)
+	stats.ObserveTPS(50)
+	assert.InDelta(t, 85, stats.TPS(), 1e-6)
+}
@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strconv"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+// --- Audio Transcriptions ---
+
+// maxTranscriptionMemory is the size of the form parts which are kept in memory while parsing.
+const maxTranscriptionMemory = 32 << 20
+
+const secondsPerMinute = 60
+
+// TranscriptionRequest represents a canonical audio transcription request, aligned with the
+// OpenAI Audio API. The request is sent as a multipart form.
+type TranscriptionRequest struct {
+	Model          string
+	File           []byte
+	FileName       string
+	Language       string
+	Prompt         string
+	ResponseFormat string
+	Temperature    *float64
+	// Fields collects the other form fields for passthrough, e.g. `timestamp_granularities[]`.
+	Fields map[string][]string
+}
+
+// IsText reports whether the response format is plain text instead of JSON.
+func (r *TranscriptionRequest) IsText() bool {
+	switch r.ResponseFormat {
+	case "text", "srt", "vtt":
+		return true
+	default:
+		return false
+	}
+}
+
+// TranscriptionResponse represents a canonical audio transcription response. The text of
+// plain text response formats, such as `srt`, is kept in Text.
+type TranscriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"` // seconds
+	Segments any     `json:"segments,omitempty"`
+	Words    any     `json:"words,omitempty"`
+	Usage    Usage   `json:"-"` // Token usage of models which report it, e.g. gpt-4o-transcribe
+}
+
+// CalculateCost calculates the cost of the tokens and the duration of the audio.
+func (r *TranscriptionResponse) CalculateCost(p *config.AIPricingOptions) {
+	if p == nil {
+		return
+	}
+	r.Usage.CalculateCost(p)
+	r.Usage.InputCost += r.Duration / secondsPerMinute * p.PerMinute
+}
+
+// TranscriptionAdapter is implemented by LLM adapters whose provider can transcribe audio.
+type TranscriptionAdapter interface {
+	// Transcriptions executes an audio transcription request.
+	Transcriptions(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error)
+}
+
+// ParseTranscriptionRequest parses the multipart form of an audio transcription request.
+func ParseTranscriptionRequest(contentType string, body []byte) (*TranscriptionRequest, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, errors.New("content type must be multipart/form-data")
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(maxTranscriptionMemory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multipart form: %w", err)
+	}
+	defer func() { _ = form.RemoveAll() }()
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return nil, errors.New("file is required")
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &TranscriptionRequest{
+		File:     data,
+		FileName: files[0].Filename,
+		Fields:   make(map[string][]string),
+	}
+	for key, values := range form.Value {
+		if len(values) == 0 {
+			continue
+		}
+		switch key {
+		case "model":
+			req.Model = values[0]
+		case "language":
+			req.Language = values[0]
+		case "prompt":
+			req.Prompt = values[0]
+		case "response_format":
+			req.ResponseFormat = values[0]
+		case "temperature":
+			temperature, err := strconv.ParseFloat(values[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid temperature '%s'", values[0])
+			}
+			req.Temperature = &temperature
+		default:
+			req.Fields[key] = values
+		}
+	}
+	return req, nil
+}
+
+// MultipartBody encodes the request as a multipart form, it returns the content type and the body.
+func (r *TranscriptionRequest) MultipartBody() (string, []byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fileName := r.FileName
+	if fileName == "" {
+		fileName = "audio"
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := part.Write(r.File); err != nil {
+		return "", nil, err
+	}
+
+	fields := [][2]string{
+		{"model", r.Model},
+		{"language", r.Language},
+		{"prompt", r.Prompt},
+		{"response_format", r.ResponseFormat},
+	}
+	if r.Temperature != nil {
+		fields = append(fields, [2]string{"temperature", strconv.FormatFloat(*r.Temperature, 'f', -1, 64)})
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return "", nil, err
+		}
+	}
+	for key, values := range r.Fields {
+		for _, val := range values {
+			if err := writer.WriteField(key, val); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", nil, err
+	}
+	return writer.FormDataContentType(), buf.Bytes(), nil
+}
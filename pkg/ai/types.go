@@ -3,6 +3,7 @@ package ai
 import (
 	"encoding/json"
 	"maps"
+	"time"
 
 	"github.com/bytedance/sonic"
 
@@ -19,6 +20,12 @@ const (
 	ContextKeyChatRequest = "ai_chat_request"
 	// ContextKeyResponsesRequest is the context key for the ResponsesRequest object.
 	ContextKeyResponsesRequest = "ai_responses_request"
+	// ContextKeyEmbeddingRequest is the context key for the EmbeddingRequest object.
+	ContextKeyEmbeddingRequest = "ai_embedding_request"
+	// ContextKeyImageRequest is the context key for the ImageRequest object.
+	ContextKeyImageRequest = "ai_image_request"
+	// ContextKeyTranscriptionRequest is the context key for the TranscriptionRequest object.
+	ContextKeyTranscriptionRequest = "ai_transcription_request"
 	// ContextKeyClientAdapter is the context key for the client translator adapter.
 	ContextKeyClientAdapter = "ai_client_adapter"
 	// ContextKeyVirtualModelName is the context key for the original model name from the client.
@@ -29,11 +36,24 @@ const (
 	ContextKeyResponsesResponse = "ai_responses_response"
 	// ContextKeyResponseStream is the context key for the response stream.
 	ContextKeyResponseStream = "ai_response_stream"
+	// ContextKeyUsageObservers is the context key for the usage observers of the request.
+	ContextKeyUsageObservers = "ai_usage_observers"
+	// ContextKeyRecordChatStream is the context key for the flag which asks the proxy to record the
+	// chat response of a streaming request into ContextKeyChatResponse.
+	ContextKeyRecordChatStream = "ai_record_chat_stream"
+	// ContextKeyResponseFilters is the context key for the response filters of the request.
+	ContextKeyResponseFilters = "ai_response_filters"
 
 	// FamilyChat is the chat API family identifier.
 	FamilyChat = "chat"
 	// FamilyResponses is the responses API family identifier.
 	FamilyResponses = "responses"
+	// FamilyEmbeddings is the embeddings API family identifier.
+	FamilyEmbeddings = "embeddings"
+	// FamilyImages is the image generations API family identifier.
+	FamilyImages = "images"
+	// FamilyTranscriptions is the audio transcriptions API family identifier.
+	FamilyTranscriptions = "transcriptions"
 )
 
 // --- Chat Request (Stateless) ---
@@ -134,6 +154,33 @@ type Message struct {
 	ToolCallID string     `json:"tool_call_id,omitempty"` // Required for "tool" role
 }
 
+// Text returns the text of the message content.
+func (m *Message) Text() string {
+	return contentText(m.Content)
+}
+
+// MapText replaces every text of the message content with the result of fn.
+func (m *Message) MapText(fn func(text string) string) {
+	switch val := m.Content.(type) {
+	case string:
+		m.Content = fn(val)
+	case []ContentPart:
+		for i := range val {
+			if val[i].Type == "text" {
+				val[i].Text = fn(val[i].Text)
+			}
+		}
+	case []any:
+		for _, part := range val {
+			if obj, ok := part.(map[string]any); ok {
+				if text, ok := obj["text"].(string); ok {
+					obj["text"] = fn(text)
+				}
+			}
+		}
+	}
+}
+
 // ContentPart represents a single part of a multi-modal message.
 type ContentPart struct {
 	Type     string    `json:"type"` // "text" or "image_url"
@@ -203,10 +250,15 @@ type Choice struct {
 // This allows passing user information, route details, and other
 // metadata to observers without changing interface signatures.
 type UsageMetadata struct {
-	Model    string `json:"model"`    // Logical model name (e.g., "gpt-4o")
-	UserID   string `json:"user_id"`  // User identifier for billing/quota
-	RouteID  string `json:"route_id"` // Bifrost route ID
-	Provider string `json:"provider"` // Target provider ID (e.g., "openai-official")
+	Model    string        `json:"model"`    // Logical model name (e.g., "gpt-4o")
+	UserID   string        `json:"user_id"`  // User identifier for billing/quota
+	Consumer string        `json:"consumer"` // Client application set by auth middlewares
+	RouteID  string        `json:"route_id"` // Bifrost route ID
+	TraceID  string        `json:"trace_id"` // Trace ID of the request
+	Provider string        `json:"provider"` // Target provider ID (e.g., "openai-official")
+	Family   string        `json:"family"`   // API family (e.g., "chat", "embeddings")
+	Stream   bool          `json:"stream"`   // Whether the response was streamed
+	Latency  time.Duration `json:"latency"`  // Time from the upstream request to the usage
 }
 
 // Usage provides token consumption metrics, including extended details for reasoning and caching.
@@ -314,6 +366,8 @@ type AIError struct { //nolint:revive
 	Provider   string                  `json:"provider,omitempty"`
 	Param      optional.Option[string] `json:"param"`
 	Code       optional.Option[string] `json:"code"`
+	// RetryAfter is how long a rate limited provider asks clients to wait, zero if unknown.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *AIError) Error() string {
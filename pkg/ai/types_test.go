@@ -86,3 +86,22 @@ func TestUsage_CalculateCost(t *testing.T) {
 		t.Errorf("expected input cost 0.15, got %f", u2.InputCost)
 	}
 }
+
+func TestMessage_MapText(t *testing.T) {
+	exclaim := func(text string) string { return text + "!" }
+
+	msg := &Message{Content: "hello"}
+	msg.MapText(exclaim)
+	assert.Equal(t, "hello!", msg.Content)
+
+	msg = &Message{Content: []ContentPart{
+		{Type: "text", Text: "look"},
+		{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/cat.png"}},
+	}}
+	msg.MapText(exclaim)
+	assert.Equal(t, "look!", msg.Text())
+
+	msg = &Message{Content: []any{map[string]any{"type": "text", "text": "hi"}}}
+	msg.MapText(exclaim)
+	assert.Equal(t, "hi!", msg.Text())
+}
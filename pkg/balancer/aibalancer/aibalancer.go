@@ -0,0 +1,169 @@
+package aibalancer
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/balancer"
+	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/target"
+)
+
+// expectedOutputTokens is the length of the response which lowest_latency uses to weigh the
+// generation speed of a target against its time to the first byte.
+const expectedOutputTokens = 256
+
+// Init registers the AI balancers with the balancer registry. They select the targets of virtual
+// models by the statistics which the AI proxy records, and the endpoints must be AI targets
+// ("provider/model").
+func Init() error {
+	err := balancer.Register(
+		[]string{"lowest_latency"},
+		func(endpoints []*target.Endpoint, _ any) (balancer.Balancer, error) {
+			return NewLowestLatency(endpoints), nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = balancer.Register(
+		[]string{"least_busy"},
+		func(endpoints []*target.Endpoint, _ any) (balancer.Balancer, error) {
+			return NewLeastBusy(endpoints), nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return balancer.Register(
+		[]string{"lowest_cost"},
+		func(endpoints []*target.Endpoint, params any) (balancer.Balancer, error) {
+			parsed, ok := params.(map[string]any)
+			if !ok {
+				return nil, errors.New("params must be a map")
+			}
+			prices, ok := parsed["pricing"].(map[string]*config.AIPricingOptions)
+			if !ok {
+				return nil, errors.New("pricing is required and must be the pricing of the targets")
+			}
+			return NewLowestCost(endpoints, prices), nil
+		},
+	)
+}
+
+// scoreFunc returns the score of an endpoint for a request, the endpoint with the lowest score
+// is selected.
+type scoreFunc func(hzCtx *app.RequestContext, ep *target.Endpoint) float64
+
+// Balancer selects the available endpoint with the lowest score, ties are broken randomly.
+type Balancer struct {
+	endpoints []*target.Endpoint
+	score     scoreFunc
+}
+
+// NewLowestLatency creates a balancer which selects the target with the lowest estimated latency,
+// the moving average of its time to the first byte plus the time to generate a response of
+// expectedOutputTokens tokens at its moving average speed. Targets without statistics are
+// selected first, so that every target is measured. Failed requests are recorded with a time to
+// the first byte of at least ai.FailurePenalty, so a target which fails fast isn't selected.
+func NewLowestLatency(endpoints []*target.Endpoint) *Balancer {
+	return &Balancer{
+		endpoints: endpoints,
+		score: func(_ *app.RequestContext, ep *target.Endpoint) float64 {
+			stats := ai.StatsOf(ep.Address)
+			latency := stats.TTFB().Seconds()
+			if tps := stats.TPS(); tps > 0 {
+				latency += expectedOutputTokens / tps
+			}
+			return latency
+		},
+	}
+}
+
+// NewLeastBusy creates a balancer which selects the target with the fewest requests in flight
+// relative to its weight.
+func NewLeastBusy(endpoints []*target.Endpoint) *Balancer {
+	return &Balancer{
+		endpoints: endpoints,
+		score: func(_ *app.RequestContext, ep *target.Endpoint) float64 {
+			weight := max(ep.Weight, 1)
+			return float64(ai.StatsOf(ep.Address).InFlight()) / float64(weight)
+		},
+	}
+}
+
+// NewLowestCost creates a balancer which selects the cheapest target by the price of the family
+// of the request: the input plus output price for chat, the input price for embeddings, the price
+// per image for images and the price per minute for transcriptions. Targets without pricing are
+// selected last.
+func NewLowestCost(endpoints []*target.Endpoint, prices map[string]*config.AIPricingOptions) *Balancer {
+	return &Balancer{
+		endpoints: endpoints,
+		score: func(hzCtx *app.RequestContext, ep *target.Endpoint) float64 {
+			price := prices[ep.Address]
+			if price == nil {
+				return math.Inf(1)
+			}
+
+			family := ai.FamilyChat
+			if hzCtx != nil {
+				if val, found := hzCtx.Get(ai.ContextKeyAIFamily); found {
+					if s, ok := val.(string); ok && s != "" {
+						family = s
+					}
+				}
+			}
+
+			switch family {
+			case ai.FamilyEmbeddings:
+				return price.InputPerMtok
+			case ai.FamilyImages:
+				return price.PerImage
+			case ai.FamilyTranscriptions:
+				return price.PerMinute
+			default:
+				return price.InputPerMtok + price.OutputPerMtok
+			}
+		},
+	}
+}
+
+// Select picks the available endpoint with the lowest score, skipping unhealthy endpoints and the
+// endpoints which are suspended because they are rate limited.
+func (b *Balancer) Select(_ context.Context, hzCtx *app.RequestContext) (*target.Endpoint, error) {
+	var selected *target.Endpoint
+	lowest := math.Inf(1)
+	ties := 0
+
+	for _, ep := range b.endpoints {
+		if ep.State != nil && !ep.State.IsAvailable() {
+			continue
+		}
+
+		score := b.score(hzCtx, ep)
+		switch {
+		case selected == nil || score < lowest:
+			selected = ep
+			lowest = score
+			ties = 1
+		case score == lowest:
+			// reservoir sampling selects each tied endpoint with equal probability
+			ties++
+			if rand.Intn(ties) == 0 { //nolint:gosec
+				selected = ep
+			}
+		}
+	}
+
+	if selected == nil {
+		return nil, balancer.ErrNotAvailable
+	}
+	return selected, nil
+}
@@ -0,0 +1,142 @@
+package aibalancer
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/balancer"
+	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/target"
+)
+
+func newEndpoints(addresses ...string) []*target.Endpoint {
+	endpoints := make([]*target.Endpoint, 0, len(addresses))
+	for _, address := range addresses {
+		endpoints = append(endpoints, &target.Endpoint{
+			Address: address,
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		})
+	}
+	return endpoints
+}
+
+func TestLowestLatency(t *testing.T) {
+	endpoints := newEndpoints("latency-a/model", "latency-b/model", "latency-c/model")
+	b := NewLowestLatency(endpoints)
+
+	// targets without statistics are selected first
+	ai.StatsOf("latency-a/model").ObserveTTFB(time.Second)
+	ai.StatsOf("latency-b/model").ObserveTTFB(3 * time.Second)
+	ep, err := b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "latency-c/model", ep.Address)
+
+	// 0.5s + 256 tokens at 64 tps is slower than 1s + 256 tokens at 256 tps
+	ai.StatsOf("latency-c/model").ObserveTTFB(500 * time.Millisecond)
+	ai.StatsOf("latency-c/model").ObserveTPS(64)
+	ai.StatsOf("latency-a/model").ObserveTPS(256)
+	ep, err = b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "latency-a/model", ep.Address)
+
+	// rate limited targets are skipped
+	endpoints[0].State.Suspend(time.Now().Add(time.Minute))
+	ep, err = b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "latency-b/model", ep.Address)
+
+	endpoints[1].State.Suspend(time.Now().Add(time.Minute))
+	endpoints[2].State.Suspend(time.Now().Add(time.Minute))
+	_, err = b.Select(context.Background(), nil)
+	assert.ErrorIs(t, err, balancer.ErrNotAvailable)
+}
+
+func TestLeastBusy(t *testing.T) {
+	endpoints := newEndpoints("busy-a/model", "busy-b/model")
+	endpoints[0].Weight = 4
+	b := NewLeastBusy(endpoints)
+
+	for range 3 {
+		ai.StatsOf("busy-a/model").Begin()
+	}
+	ai.StatsOf("busy-b/model").Begin()
+
+	// 3 requests for weight 4 is less busy than 1 request for weight 1
+	ep, err := b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "busy-a/model", ep.Address)
+
+	ai.StatsOf("busy-a/model").Begin()
+	ai.StatsOf("busy-a/model").Begin()
+	ep, err = b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "busy-b/model", ep.Address)
+}
+
+func TestLeastBusyTies(t *testing.T) {
+	b := NewLeastBusy(newEndpoints("tie-a/model", "tie-b/model"))
+
+	selected := map[string]int{}
+	for range 200 {
+		ep, err := b.Select(context.Background(), nil)
+		require.NoError(t, err)
+		selected[ep.Address]++
+	}
+	assert.Positive(t, selected["tie-a/model"])
+	assert.Positive(t, selected["tie-b/model"])
+}
+
+func TestLowestCost(t *testing.T) {
+	endpoints := newEndpoints("openai/gpt-4o", "openai/gpt-4o-mini", "local/llama")
+	b := NewLowestCost(endpoints, map[string]*config.AIPricingOptions{
+		"openai/gpt-4o":      {InputPerMtok: 2.5, OutputPerMtok: 10, PerImage: 0.04},
+		"openai/gpt-4o-mini": {InputPerMtok: 0.15, OutputPerMtok: 0.6, PerImage: 0.08},
+	})
+
+	hzCtx := app.NewContext(0)
+	ep, err := b.Select(context.Background(), hzCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o-mini", ep.Address)
+
+	hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyImages)
+	ep, err = b.Select(context.Background(), hzCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o", ep.Address)
+
+	// targets without pricing are selected last
+	endpoints[0].State.Suspend(time.Now().Add(time.Minute))
+	endpoints[1].State.Suspend(time.Now().Add(time.Minute))
+	ep, err = b.Select(context.Background(), hzCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "local/llama", ep.Address)
+	assert.True(t, math.IsInf(b.score(hzCtx, ep), 1))
+}
+
+func TestInit(t *testing.T) {
+	require.NoError(t, Init())
+
+	for _, name := range []string{"lowest_latency", "least_busy"} {
+		b, err := balancer.Factory(name)(newEndpoints("p/m"), nil)
+		require.NoError(t, err)
+		assert.NotNil(t, b)
+	}
+
+	factory := balancer.Factory("lowest_cost")
+	_, err := factory(newEndpoints("p/m"), nil)
+	assert.Error(t, err)
+	_, err = factory(newEndpoints("p/m"), map[string]any{"pricing": "cheap"})
+	assert.Error(t, err)
+	b, err := factory(newEndpoints("p/m"), map[string]any{
+		"pricing": map[string]*config.AIPricingOptions{"p/m": {InputPerMtok: 1}},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, b)
+}
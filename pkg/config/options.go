@@ -417,6 +417,8 @@ const (
 	ServiceTypeProxy ServiceType = "proxy"
 	// ServiceTypeAI represents an AI gateway service.
 	ServiceTypeAI ServiceType = "ai"
+	// ServiceTypeStatic represents a service that serves files from a directory.
+	ServiceTypeStatic ServiceType = "static"
 )
 
 // ServiceOptions defines configuration for a service.
@@ -428,10 +430,26 @@ type ServiceOptions struct {
 	URL             string                `json:"url"                yaml:"url"`
 	Middlewares     []MiddlwareOptions    `json:"middlewares"        yaml:"middlewares"`
 	Timeout         ServiceTimeoutOptions `json:"timeout"            yaml:"timeout"`
+	Static          StaticOptions         `json:"static"             yaml:"static"`
 	TLSVerify       bool                  `json:"tls_verify"         yaml:"tls_verify"`
 	PassHostHeader  *bool                 `json:"pass_host_header"   yaml:"pass_host_header"`
 }
 
+// StaticOptions defines the configuration of a static service.
+type StaticOptions struct {
+	// Root is the directory the files are served from.
+	Root string `json:"root"              yaml:"root"`
+	// Index are the files served for a directory, `index.html` by default.
+	Index []string `json:"index"             yaml:"index"`
+	// SPA serves the index file of the root for paths which are not found, so the
+	// client-side router of a single-page app can handle them.
+	SPA bool `json:"spa"               yaml:"spa"`
+	// Precompressed serves the `.br` and `.gz` variants of a file when the client accepts them.
+	Precompressed bool `json:"precompressed"     yaml:"precompressed"`
+	// DirectoryListing renders the content of directories without an index file.
+	DirectoryListing bool `json:"directory_listing" yaml:"directory_listing"`
+}
+
 // IsPassHostHeader returns true if host header should be passed to upstream.
 func (options ServiceOptions) IsPassHostHeader() bool {
 	if options.PassHostHeader == nil || *options.PassHostHeader {
@@ -497,6 +515,32 @@ type DefaultOptions struct {
 type AIOptions struct {
 	Providers   map[string]*AIProvider `json:"providers"    yaml:"providers"`
 	PricingFile string                 `json:"pricing_file" yaml:"pricing_file"`
+	// UsageSinks write a usage record of every AI request, e.g. for billing.
+	UsageSinks map[string]*AIUsageSinkOptions `json:"usage_sinks" yaml:"usage_sinks"`
+}
+
+// AIUsageSinkOptions defines a sink of the usage records of AI requests. The type is `file`, `redis`
+// or `webhook`, and the records are written asynchronously in batches.
+type AIUsageSinkOptions struct {
+	Type string `json:"type" yaml:"type"`
+	// Path is the JSONL file of the file sink, which is rotated when it reaches MaxSize megabytes.
+	Path       string `json:"path"        yaml:"path"`
+	MaxSize    int    `json:"max_size"    yaml:"max_size"`
+	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+	MaxAge     int    `json:"max_age"     yaml:"max_age"`
+	Compress   bool   `json:"compress"    yaml:"compress"`
+	// RedisID and Stream are the redis and the stream of the redis sink.
+	RedisID string `json:"redis_id" yaml:"redis_id"`
+	Stream  string `json:"stream"   yaml:"stream"`
+	MaxLen  int64  `json:"max_len"  yaml:"max_len"`
+	// URL is the endpoint of the webhook sink, which receives a JSON array of records.
+	URL     string            `json:"url"     yaml:"url"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Timeout time.Duration     `json:"timeout" yaml:"timeout"`
+	// BufferSize is the number of records waiting to be written, records are dropped when it's full.
+	BufferSize    int           `json:"buffer_size"    yaml:"buffer_size"`
+	BatchSize     int           `json:"batch_size"     yaml:"batch_size"`
+	FlushInterval time.Duration `json:"flush_interval" yaml:"flush_interval"`
 }
 
 // AIProvider defines options for an LLM provider connection.
@@ -504,17 +548,32 @@ type AIProvider struct {
 	Handler string `json:"handler"  yaml:"handler"`
 	BaseURL string `json:"base_url" yaml:"base_url"`
 	APIKey  string `json:"api_key"  yaml:"api_key"`
+	// URLTemplate overrides the request URL of the handler, e.g.
+	// `{base_url}/openai/deployments/{deployment}/chat/completions`.
+	URLTemplate string `json:"url_template" yaml:"url_template"`
+	// AuthHeader is the header carrying the API key, e.g. `api-key` for Azure OpenAI.
+	AuthHeader string `json:"auth_header" yaml:"auth_header"`
+	// AuthScheme is the prefix of the API key in the auth header, e.g. `Bearer`.
+	AuthScheme  string            `json:"auth_scheme"  yaml:"auth_scheme"`
+	Headers     map[string]string `json:"headers"      yaml:"headers"`
+	QueryParams map[string]string `json:"query_params" yaml:"query_params"`
+	// Deployments maps the models of targets to deployment names, e.g. for Azure OpenAI.
+	Deployments map[string]string `json:"deployments" yaml:"deployments"`
 }
 
 // AIModelOptions defines target distribution and balancing options for a virtual model.
 type AIModelOptions struct {
-	Balancer *AIBalancerOptions `json:"balancer" yaml:"balancer"`
-	Targets  []AITargetOptions  `json:"targets"  yaml:"targets"`
+	Balancer *AIBalancerOptions  `json:"balancer" yaml:"balancer"`
+	Targets  []AITargetOptions   `json:"targets"  yaml:"targets"`
+	Fallback []AIFallbackOptions `json:"fallback" yaml:"fallback"`
 }
 
-// AIBalancerOptions configures the balancing type for virtual models.
+// AIBalancerOptions configures the balancing type for virtual models. Besides the classic
+// balancers, the type can be `lowest_latency`, `lowest_cost` or `least_busy`.
 type AIBalancerOptions struct {
 	Type string `json:"type" yaml:"type"`
+	// SkipRateLimited skips the targets which returned 429 until their rate limits reset.
+	SkipRateLimited bool `json:"skip_rate_limited" yaml:"skip_rate_limited"`
 }
 
 // AITargetOptions configures a target provider/model and its load weight.
@@ -524,9 +583,20 @@ type AITargetOptions struct {
 	Pricing *AIPricingOptions `json:"pricing" yaml:"pricing"`
 }
 
+// AIFallbackOptions configures a target which serves the request when the previous target failed.
+type AIFallbackOptions struct {
+	Target string `json:"target" yaml:"target"`
+	// On lists the error classes which fall back to the target, e.g. `rate_limited`, `overloaded`,
+	// `context_length_exceeded` and `server_error`. All of them fall back if it is empty.
+	On      []string          `json:"on"      yaml:"on"`
+	Pricing *AIPricingOptions `json:"pricing" yaml:"pricing"`
+}
+
 // AIPricingOptions defines the pricing configuration for a model.
 type AIPricingOptions struct {
 	InputPerMtok       float64 `json:"input_per_mtok"        yaml:"input_per_mtok"`
 	OutputPerMtok      float64 `json:"output_per_mtok"       yaml:"output_per_mtok"`
 	CachedInputPerMtok float64 `json:"cached_input_per_mtok" yaml:"cached_input_per_mtok"`
+	PerImage           float64 `json:"per_image"             yaml:"per_image"`
+	PerMinute          float64 `json:"per_minute"            yaml:"per_minute"`
 }
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -266,6 +267,16 @@ func validateMiddlewares(mainOptions Options, mode ValidationMode) error {
 						middlewareID,
 					)
 				}
+
+				err := middleware.Validate(middlewareOptions.Type, middlewareOptions.Params)
+				if err != nil {
+					return fmt.Errorf(
+						"invalid params of middleware '%s' for middleware ID: %s: %w",
+						middlewareOptions.Type,
+						middlewareID,
+						err,
+					)
+				}
 			}
 		}
 	}
@@ -339,6 +350,16 @@ func validateServers(mainOptions Options, mode ValidationMode) error {
 					if hander == nil {
 						return fmt.Errorf("middleware '%s' not found for server ID: %s", m.Type, serverID)
 					}
+
+					err := middleware.Validate(m.Type, m.Params)
+					if err != nil {
+						return fmt.Errorf(
+							"invalid params of middleware '%s' for server ID: %s: %w",
+							m.Type,
+							serverID,
+							err,
+						)
+					}
 				}
 			}
 		}
@@ -407,6 +428,16 @@ func validateRoutes(mainOptions Options, mode ValidationMode) error {
 				if hander == nil {
 					return fmt.Errorf("middleware '%s' not found for route ID: %s", m.Type, route.ID)
 				}
+
+				err := middleware.Validate(m.Type, m.Params)
+				if err != nil {
+					return fmt.Errorf(
+						"invalid params of middleware '%s' for route ID: %s: %w",
+						m.Type,
+						route.ID,
+						err,
+					)
+				}
 			}
 		}
 
@@ -437,47 +468,15 @@ func validateServices(mainOptions Options, mode ValidationMode) error {
 			continue
 		}
 
-		addr, err := url.Parse(service.URL)
-		if err != nil {
-			return err
-		}
-
-		hostname := addr.Hostname()
-
-		// validate
-		if len(hostname) == 0 && service.Type != "ai" {
-			return fmt.Errorf("URL can't empty for service ID: %s", serviceID)
-		}
-
-		// exist upstream
-		if len(hostname) > 0 && hostname[0] != '$' && !strings.EqualFold("localhost", hostname) &&
-			!strings.EqualFold("[::1]", hostname) {
-			_, found := mainOptions.Upstreams[hostname]
-			if !found {
-				if dnsResolver != nil && !mainOptions.SkipResolver {
-					ips, err := dnsResolver.Lookup(context.Background(), hostname)
-					if err != nil {
-						return fmt.Errorf(
-							"failed to lookup host '%s' for service ID '%s': %w",
-							hostname,
-							serviceID,
-							err,
-						)
-					}
-
-					if len(ips) == 0 {
-						return fmt.Errorf(
-							"failed to lookup host '%s' for service ID '%s': no IP found",
-							hostname,
-							serviceID,
-						)
-					}
-				} else {
-					ip := net.ParseIP(hostname)
-					if !IsValidDomain(hostname) && ip == nil {
-						return fmt.Errorf("upstream '%s' not found for service ID: %s", hostname, serviceID)
-					}
-				}
+		if service.Type == ServiceTypeStatic {
+			err := validateStaticService(serviceID, service.Static)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := validateServiceURL(mainOptions, serviceID, service)
+			if err != nil {
+				return err
 			}
 		}
 
@@ -493,6 +492,16 @@ func validateServices(mainOptions Options, mode ValidationMode) error {
 				if hander == nil {
 					return fmt.Errorf("middleware '%s' not found for service ID: %s", m.Type, serviceID)
 				}
+
+				err := middleware.Validate(m.Type, m.Params)
+				if err != nil {
+					return fmt.Errorf(
+						"invalid params of middleware '%s' for service ID: %s: %w",
+						m.Type,
+						serviceID,
+						err,
+					)
+				}
 			}
 		}
 	}
@@ -500,6 +509,69 @@ func validateServices(mainOptions Options, mode ValidationMode) error {
 	return nil
 }
 
+func validateServiceURL(mainOptions Options, serviceID string, service ServiceOptions) error {
+	addr, err := url.Parse(service.URL)
+	if err != nil {
+		return err
+	}
+
+	hostname := addr.Hostname()
+
+	// validate
+	if len(hostname) == 0 && service.Type != "ai" {
+		return fmt.Errorf("URL can't empty for service ID: %s", serviceID)
+	}
+
+	// exist upstream
+	if len(hostname) > 0 && hostname[0] != '$' && !strings.EqualFold("localhost", hostname) &&
+		!strings.EqualFold("[::1]", hostname) {
+		_, found := mainOptions.Upstreams[hostname]
+		if !found {
+			if dnsResolver != nil && !mainOptions.SkipResolver {
+				ips, err := dnsResolver.Lookup(context.Background(), hostname)
+				if err != nil {
+					return fmt.Errorf(
+						"failed to lookup host '%s' for service ID '%s': %w",
+						hostname,
+						serviceID,
+						err,
+					)
+				}
+
+				if len(ips) == 0 {
+					return fmt.Errorf(
+						"failed to lookup host '%s' for service ID '%s': no IP found",
+						hostname,
+						serviceID,
+					)
+				}
+			} else {
+				ip := net.ParseIP(hostname)
+				if !IsValidDomain(hostname) && ip == nil {
+					return fmt.Errorf("upstream '%s' not found for service ID: %s", hostname, serviceID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateStaticService validates the root directory of a static service.
+func validateStaticService(serviceID string, options StaticOptions) error {
+	if len(options.Root) == 0 {
+		return fmt.Errorf("static root can't be empty for service ID: %s", serviceID)
+	}
+
+	info, err := os.Stat(options.Root)
+	if err != nil {
+		return fmt.Errorf("static root '%s' is invalid for service ID '%s': %w", options.Root, serviceID, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("static root '%s' is not a directory for service ID: %s", options.Root, serviceID)
+	}
+	return nil
+}
+
 func validateUpstreams(mainOptions Options, mode ValidationMode) error {
 	for upstreamID, upstreamOptions := range mainOptions.Upstreams {
 		if mode != ModeFull {
@@ -523,6 +595,15 @@ func validateUpstreams(mainOptions Options, mode ValidationMode) error {
 				structure := []string{"upstreams", upstreamID, "strategy"}
 				return newInvalidConfig(structure, upstreamOptions.Balancer.Type, msg)
 			}
+			if slices.Contains(aiBalancers, upstreamOptions.Balancer.Type) {
+				msg := fmt.Sprintf(
+					"balancer strategy '%s' is only supported by AI models, upstream ID: %s",
+					upstreamOptions.Balancer.Type,
+					upstreamID,
+				)
+				structure := []string{"upstreams", upstreamID, "strategy"}
+				return newInvalidConfig(structure, upstreamOptions.Balancer.Type, msg)
+			}
 		}
 
 		switch upstreamOptions.Discovery.Type {
@@ -721,6 +802,13 @@ func addRoute(r *router.Router, routeOptions RouteOptions) error {
 	return nil
 }
 
+// aiErrorClasses are the error classes of failed upstream requests which can fall back to another target.
+var aiErrorClasses = []string{"rate_limited", "overloaded", "context_length_exceeded", "server_error"}
+
+// aiBalancers are the balancers which select targets by the statistics of the AI proxy, so they
+// only support virtual models.
+var aiBalancers = []string{"lowest_latency", "lowest_cost", "least_busy"}
+
 func validateAIConfig(opts Options) error {
 	reModelName := regexp.MustCompile(`^[a-zA-Z0-9_.:-]+$`)
 
@@ -732,6 +820,27 @@ func validateAIConfig(opts Options) error {
 			if provider.BaseURL == "" {
 				return fmt.Errorf("base_url is missing for provider '%s'", name)
 			}
+			if provider.URLTemplate != "" && !strings.HasPrefix(provider.URLTemplate, "{base_url}") {
+				addr, err := url.Parse(provider.URLTemplate)
+				if err != nil || (addr.Scheme != "http" && addr.Scheme != "https") || addr.Host == "" {
+					return fmt.Errorf(
+						"url_template '%s' for provider '%s' must start with '{base_url}' or be an http(s) URL",
+						provider.URLTemplate,
+						name,
+					)
+				}
+			}
+			for header := range provider.Headers {
+				if header == "" {
+					return fmt.Errorf("header name cannot be empty for provider '%s'", name)
+				}
+			}
+		}
+
+		for name, sink := range opts.AI.UsageSinks {
+			if err := validateAIUsageSink(opts, name, sink); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -745,6 +854,9 @@ func validateAIConfig(opts Options) error {
 		if len(model.Targets) == 0 {
 			return fmt.Errorf("targets cannot be empty for model '%s'", name)
 		}
+		if model.Balancer != nil && model.Balancer.Type != "" && balancer.Factory(model.Balancer.Type) == nil {
+			return fmt.Errorf("unsupported balancer '%s' for model '%s'", model.Balancer.Type, name)
+		}
 		for _, target := range model.Targets {
 			parts := strings.Split(target.Target, "/")
 			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
@@ -758,7 +870,62 @@ func validateAIConfig(opts Options) error {
 				return fmt.Errorf("specific provider '%s' not found for model '%s'", providerID, name)
 			}
 		}
+		for _, fallback := range model.Fallback {
+			parts := strings.Split(fallback.Target, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("invalid fallback target format '%s' for model '%s'", fallback.Target, name)
+			}
+			if opts.AI == nil || opts.AI.Providers[parts[0]] == nil {
+				return fmt.Errorf("specific provider '%s' not found for model '%s'", parts[0], name)
+			}
+			for _, class := range fallback.On {
+				if !slices.Contains(aiErrorClasses, class) {
+					return fmt.Errorf(
+						"invalid fallback error class '%s' for model '%s'; only %s are supported",
+						class,
+						name,
+						strings.Join(aiErrorClasses, ", "),
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateAIUsageSink(opts Options, name string, sink *AIUsageSinkOptions) error {
+	if sink == nil {
+		return fmt.Errorf("usage sink '%s' cannot be empty", name)
 	}
 
+	switch sink.Type {
+	case "file":
+		if sink.Path == "" {
+			return fmt.Errorf("path is missing for usage sink '%s'", name)
+		}
+	case "redis":
+		if sink.RedisID == "" {
+			return fmt.Errorf("redis_id is missing for usage sink '%s'", name)
+		}
+		found := slices.ContainsFunc(opts.Redis, func(redis RedisOptions) bool {
+			return redis.ID == sink.RedisID
+		})
+		if !found {
+			return fmt.Errorf("redis '%s' not found for usage sink '%s'", sink.RedisID, name)
+		}
+	case "webhook":
+		addr, err := url.Parse(sink.URL)
+		if err != nil || (addr.Scheme != "http" && addr.Scheme != "https") || addr.Host == "" {
+			return fmt.Errorf("url '%s' for usage sink '%s' must be an http(s) URL", sink.URL, name)
+		}
+	default:
+		return fmt.Errorf("type '%s' is invalid for usage sink '%s'; only file, redis and webhook are supported",
+			sink.Type, name)
+	}
+
+	if sink.BufferSize < 0 || sink.BatchSize < 0 || sink.FlushInterval < 0 {
+		return fmt.Errorf("buffer_size, batch_size and flush_interval cannot be negative for usage sink '%s'", name)
+	}
 	return nil
 }
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
 
+	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/nite-coder/bifrost/pkg/middleware"
 	"github.com/nite-coder/bifrost/pkg/middleware/cors"
 	"github.com/nite-coder/bifrost/pkg/resolver"
 	"github.com/nite-coder/bifrost/pkg/router"
@@ -303,6 +307,35 @@ func TestValidateMiddlewares(t *testing.T) {
 		err := validateMiddlewares(options, ModeFull)
 		require.Error(t, err)
 	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		type params struct {
+			Template string `mapstructure:"template"`
+		}
+		_ = middleware.Register([]string{"validated_test"}, func(_ params) (app.HandlerFunc, error) {
+			return nil, nil
+		})
+		_ = middleware.RegisterValidator([]string{"validated_test"}, func(p params) error {
+			if p.Template == "" {
+				return errors.New("template cannot be empty")
+			}
+			return nil
+		})
+
+		options := NewOptions()
+		options.Middlewares["validated_id"] = MiddlwareOptions{
+			Type:   "validated_test",
+			Params: map[string]any{"template": "{{ .Body }}"},
+		}
+		err := validateMiddlewares(options, ModeFull)
+		require.NoError(t, err)
+
+		options.Middlewares["validated_id"] = MiddlwareOptions{
+			Type: "validated_test",
+		}
+		err = validateMiddlewares(options, ModeFull)
+		require.ErrorContains(t, err, "template cannot be empty")
+	})
 }
 
 func TestValidateServer(t *testing.T) {
@@ -527,6 +560,29 @@ func TestValidateService(t *testing.T) {
 	})
 }
 
+func TestValidateStaticService(t *testing.T) {
+	options := NewOptions()
+	options.Services["static"] = ServiceOptions{
+		Type: ServiceTypeStatic,
+	}
+	err := validateServices(options, ModeFull)
+	require.Error(t, err)
+
+	options.Services["static"] = ServiceOptions{
+		Type:   ServiceTypeStatic,
+		Static: StaticOptions{Root: filepath.Join(t.TempDir(), "missing")},
+	}
+	err = validateServices(options, ModeFull)
+	require.Error(t, err)
+
+	options.Services["static"] = ServiceOptions{
+		Type:   ServiceTypeStatic,
+		Static: StaticOptions{Root: t.TempDir()},
+	}
+	err = validateServices(options, ModeFull)
+	require.NoError(t, err)
+}
+
 func TestValidateUpstream(t *testing.T) {
 	t.Run("upstream target with ip", func(t *testing.T) {
 		options := NewOptions()
@@ -910,6 +966,100 @@ func TestValidateAI(t *testing.T) {
 		assert.Contains(t, err.Error(), "base_url is missing")
 	})
 
+	t.Run("invalid provider url_template", func(t *testing.T) {
+		options := NewOptions()
+		options.AI = &AIOptions{
+			Providers: map[string]*AIProvider{
+				"azure": {
+					Handler:     "openai-chat",
+					BaseURL:     "https://example.openai.azure.com",
+					URLTemplate: "/openai/deployments/{deployment}/chat/completions",
+				},
+			},
+		}
+		err := ValidateConfig(options, ModeFull)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must start with '{base_url}'")
+
+		options.AI.Providers["azure"].URLTemplate = "{base_url}/openai/deployments/{deployment}/chat/completions"
+		err = validateAIConfig(options)
+		require.NoError(t, err)
+
+		options.AI.Providers["azure"].URLTemplate = "https://example.openai.azure.com/openai/deployments/{deployment}/chat/completions"
+		err = validateAIConfig(options)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid model fallback", func(t *testing.T) {
+		options := NewOptions()
+		options.AI = &AIOptions{
+			Providers: map[string]*AIProvider{
+				"p1": {Handler: "openai-chat", BaseURL: "http://localhost"},
+			},
+		}
+		options.Models = map[string]*AIModelOptions{
+			"m1": {
+				Targets:  []AITargetOptions{{Target: "p1/model", Weight: 1}},
+				Fallback: []AIFallbackOptions{{Target: "p2/model"}},
+			},
+		}
+		err := ValidateConfig(options, ModeFull)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "specific provider 'p2' not found")
+
+		options.Models["m1"].Fallback = []AIFallbackOptions{{Target: "p1"}}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid fallback target format")
+
+		options.Models["m1"].Fallback = []AIFallbackOptions{{Target: "p1/mini", On: []string{"timeout"}}}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid fallback error class 'timeout'")
+
+		options.Models["m1"].Fallback = []AIFallbackOptions{{Target: "p1/mini", On: []string{"rate_limited", "server_error"}}}
+		err = validateAIConfig(options)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid usage sink", func(t *testing.T) {
+		options := NewOptions()
+		options.AI = &AIOptions{
+			UsageSinks: map[string]*AIUsageSinkOptions{
+				"ledger": {Type: "kafka"},
+			},
+		}
+		err := validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "type 'kafka' is invalid")
+
+		options.AI.UsageSinks["ledger"] = &AIUsageSinkOptions{Type: "file"}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "path is missing")
+
+		options.AI.UsageSinks["ledger"] = &AIUsageSinkOptions{Type: "redis", RedisID: "usage"}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "redis 'usage' not found")
+
+		options.AI.UsageSinks["ledger"] = &AIUsageSinkOptions{Type: "webhook", URL: "ftp://billing"}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must be an http(s) URL")
+
+		options.AI.UsageSinks["ledger"] = &AIUsageSinkOptions{Type: "file", Path: "usage.jsonl", BatchSize: -1}
+		err = validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be negative")
+
+		options.Redis = []RedisOptions{{ID: "usage", Addrs: []string{"localhost:6379"}}}
+		options.AI.UsageSinks["ledger"] = &AIUsageSinkOptions{Type: "redis", RedisID: "usage"}
+		options.AI.UsageSinks["billing"] = &AIUsageSinkOptions{Type: "webhook", URL: "https://billing.local/usage"}
+		err = validateAIConfig(options)
+		require.NoError(t, err)
+	})
+
 	t.Run("invalid model missing targets", func(t *testing.T) {
 		options := NewOptions()
 		options.Models = map[string]*AIModelOptions{
@@ -922,6 +1072,24 @@ func TestValidateAI(t *testing.T) {
 		assert.Contains(t, err.Error(), "targets cannot be empty")
 	})
 
+	t.Run("invalid model balancer", func(t *testing.T) {
+		options := NewOptions()
+		options.AI = &AIOptions{
+			Providers: map[string]*AIProvider{
+				"p1": {Handler: "openai-chat", BaseURL: "http://localhost"},
+			},
+		}
+		options.Models = map[string]*AIModelOptions{
+			"m1": {
+				Balancer: &AIBalancerOptions{Type: "fastest"},
+				Targets:  []AITargetOptions{{Target: "p1/model", Weight: 1}},
+			},
+		}
+		err := validateAIConfig(options)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported balancer 'fastest'")
+	})
+
 	t.Run("invalid model target format", func(t *testing.T) {
 		options := NewOptions()
 		options.Models = map[string]*AIModelOptions{
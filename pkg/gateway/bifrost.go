@@ -15,6 +15,8 @@ import (
 
 	"github.com/nite-coder/bifrost/internal/pkg/infra"
 	"github.com/nite-coder/bifrost/internal/pkg/safety"
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/ai/ledger"
 	"github.com/nite-coder/bifrost/pkg/config"
 	"github.com/nite-coder/bifrost/pkg/resolver"
 	"github.com/nite-coder/bifrost/pkg/telemetry/metrics"
@@ -56,6 +58,7 @@ const (
 type Bifrost struct {
 	tracerProvider  *sdktrace.TracerProvider
 	metricsProvider *metrics.Provider
+	usageLedgers    []*ledger.Ledger
 	options         *config.Options
 	resolver        *resolver.Resolver
 	zeroDownTime    *infra.ZeroDownTime
@@ -86,8 +89,11 @@ func NewBifrost(mainOptions config.Options, mode BifrostMode) (bifrost *Bifrost,
 		return nil, err
 	}
 
+	// the ledgers are tracked outside of bifrost, which is nil when an error is returned
+	var usageLedgers []*ledger.Ledger
 	defer func() {
 		if err != nil {
+			closeUsageLedgers(context.Background(), usageLedgers)
 			if bifrost != nil {
 				_ = bifrost.ShutdownNow(context.Background())
 			} else if dnsResolver != nil {
@@ -156,6 +162,21 @@ func NewBifrost(mainOptions config.Options, mode BifrostMode) (bifrost *Bifrost,
 			}
 		}
 	}
+	// ai usage ledgers
+	if mainOptions.AI != nil && mode != ModeReload {
+		for name, sinkOptions := range mainOptions.AI.UsageSinks {
+			if sinkOptions == nil {
+				continue
+			}
+			usageLedger, err := ledger.New(name, *sinkOptions)
+			if err != nil {
+				return nil, err
+			}
+			ai.RegisterUsageObserver(usageLedger)
+			usageLedgers = append(usageLedgers, usageLedger)
+		}
+		bifrost.usageLedgers = usageLedgers
+	}
 	if mainOptions.Tracing.Enabled {
 		// otel tracing
 		tp, err := initTracerProvider(mainOptions.Tracing)
@@ -245,6 +266,11 @@ func (b *Bifrost) Service(serviceID string) (*Service, bool) {
 	return result, found
 }
 
+// Options returns the configuration which Bifrost is running with.
+func (b *Bifrost) Options() *config.Options {
+	return b.options
+}
+
 // IsActive returns whether the Bifrost is active or not. It returns true if the Bifrost is active, false otherwise.
 func (b *Bifrost) IsActive() bool {
 	return atomic.LoadUint32(&b.state) == 1
@@ -316,5 +342,16 @@ func (b *Bifrost) doShutdown(ctx context.Context, mode ShutdownMode) error {
 		_ = b.metricsProvider.Shutdown(ctx)
 	}
 
+	closeUsageLedgers(ctx, b.usageLedgers)
+	b.usageLedgers = nil
+
 	return b.zeroDownTime.Close(ctx)
 }
+
+// closeUsageLedgers stops reporting usage to the ledgers and writes their buffered records.
+func closeUsageLedgers(ctx context.Context, usageLedgers []*ledger.Ledger) {
+	for _, usageLedger := range usageLedgers {
+		ai.UnregisterUsageObserver(usageLedger)
+		_ = usageLedger.Close(ctx)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +34,7 @@ import (
 	aiproxy "github.com/nite-coder/bifrost/pkg/proxy/ai"
 	grpcproxy "github.com/nite-coder/bifrost/pkg/proxy/grpc"
 	httpproxy "github.com/nite-coder/bifrost/pkg/proxy/http"
+	"github.com/nite-coder/bifrost/pkg/static"
 	"github.com/nite-coder/bifrost/pkg/target"
 	"github.com/nite-coder/bifrost/pkg/telemetry/metrics"
 	"github.com/nite-coder/bifrost/pkg/timecache"
@@ -46,6 +48,7 @@ type Service struct {
 	bifrost           *Bifrost
 	options           *config.ServiceOptions
 	upstream          *Upstream
+	fileServer        *static.Server
 	dynamicUpstream   string
 	middlewares       []app.HandlerFunc
 	mu                sync.RWMutex
@@ -89,6 +92,10 @@ func (s *Service) Close() error {
 		return true
 	})
 
+	if s.fileServer != nil {
+		_ = s.fileServer.Close()
+	}
+
 	return nil
 }
 
@@ -157,7 +164,14 @@ func newService(bifrost *Bifrost, serviceOptions config.ServiceOptions) (*Servic
 		return nil, err
 	}
 
-	if serviceOptions.Type == config.ServiceTypeAI {
+	switch serviceOptions.Type {
+	case config.ServiceTypeStatic:
+		fileServer, err := static.New(serviceOptions.Static)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create static service '%s': %w", serviceOptions.ID, err)
+		}
+		svc.fileServer = fileServer
+	case config.ServiceTypeAI:
 		svc.dynamicUpstream = variable.Model
 		if svc.bifrost.options.Models != nil && svc.bifrost.upstreamManager != nil {
 			for modelID := range svc.bifrost.options.Models {
@@ -167,7 +181,7 @@ func newService(bifrost *Bifrost, serviceOptions config.ServiceOptions) (*Servic
 				}
 			}
 		}
-	} else {
+	default:
 		if err := svc.resolveUpstreamStrategy(); err != nil {
 			return nil, err
 		}
@@ -230,6 +244,11 @@ func (s *Service) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 		}
 	}
 
+	if s.fileServer != nil {
+		s.fileServer.ServeHTTP(ctx, c)
+		return
+	}
+
 	var myEndpoint *target.Endpoint
 	var err error
 
@@ -319,7 +338,11 @@ func (s *Service) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 	}
 
 	startTime := timecache.Now()
+	errCount := len(c.Errors)
 	myProxy.ServeHTTP(ctx, c)
+	if s.options.Type == config.ServiceTypeAI {
+		s.serveFallback(ctx, c, errCount)
+	}
 	endTime := timecache.Now()
 
 	dur := endTime.Sub(startTime)
@@ -332,6 +355,77 @@ func (s *Service) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 	}
 }
 
+// serveFallback retries a failed AI request on the fallback targets of the virtual model in order.
+// AI proxies return errors before writing the response, so the client never receives the bytes of
+// two targets. `$model_id` is set by the proxy of the target which served the request.
+func (s *Service) serveFallback(ctx context.Context, c *app.RequestContext, errCount int) {
+	modelID, _ := strings.CutPrefix(variable.GetString(variable.UpstreamID, c), "ai:")
+	modelOpts, found := s.bifrost.options.Models[modelID]
+	if !found || modelOpts == nil || len(modelOpts.Fallback) == 0 {
+		return
+	}
+
+	metricsEnabled := s.bifrost.options.Metrics.Prometheus.Enabled || s.bifrost.options.Metrics.OTLP.Enabled
+
+	for _, fallback := range modelOpts.Fallback {
+		if len(c.Errors) <= errCount {
+			return
+		}
+
+		errorClass := ai.ClassifyError(c.Errors.Last().Err)
+		if len(errorClass) == 0 {
+			return
+		}
+		if len(fallback.On) > 0 && !slices.Contains(fallback.On, errorClass) {
+			continue
+		}
+
+		failedTarget := variable.GetString(variable.ModelID, c)
+		if fallback.Target == failedTarget {
+			continue
+		}
+
+		fallbackProxy := s.findProxyByAddress(fallback.Target, "ai:"+modelID)
+		if fallbackProxy == nil {
+			continue
+		}
+
+		log.FromContext(ctx).DebugContext(ctx, "ai request falls back to another target",
+			slog.String("model", modelID),
+			slog.String("model_id", failedTarget),
+			slog.String("fallback_model_id", fallback.Target),
+			slog.String("error_class", errorClass),
+		)
+		if metricsEnabled {
+			metrics.AIFallbacks.WithLabelValues(modelID, failedTarget, fallback.Target, errorClass).Inc()
+		}
+
+		c.Errors = c.Errors[:errCount]
+		fallbackProxy.ServeHTTP(ctx, c)
+	}
+}
+
+// findProxyByAddress returns the proxy of an address, the proxy is created if the address isn't
+// an endpoint of any upstream yet, such as the fallback targets of virtual models.
+func (s *Service) findProxyByAddress(address string, upstreamID string) proxy.Proxy {
+	if val, found := s.proxyByAddress.Load(address); found {
+		if p, ok := val.(proxy.Proxy); ok {
+			return p
+		}
+	}
+
+	p := s.buildProxy(&target.Endpoint{Address: address, Weight: 1}, upstreamID)
+	if p == nil {
+		return nil
+	}
+	actual, loaded := s.proxyByAddress.LoadOrStore(address, p)
+	if loaded {
+		_ = p.Close()
+	}
+	myProxy, _ := actual.(proxy.Proxy)
+	return myProxy
+}
+
 func (s *Service) applyProtocolDefaults() {
 	if len(s.options.Protocol) == 0 && len(s.bifrost.options.Default.Service.Protocol) > 0 {
 		s.options.Protocol = s.bifrost.options.Default.Service.Protocol
@@ -557,15 +651,24 @@ func (s *Service) buildProxy(ep *target.Endpoint, upstreamID string) proxy.Proxy
 				break
 			}
 		}
+		if targetPricing == nil {
+			for _, fallback := range modelOpts.Fallback {
+				if fallback.Target == ep.Address {
+					targetPricing = fallback.Pricing
+					break
+				}
+			}
+		}
 
 		metricsEnabled := s.bifrost.options.Metrics.Prometheus.Enabled || s.bifrost.options.Metrics.OTLP.Enabled
 		p, pErr := aiproxy.NewProxy(aiproxy.ProxyOptions{
-			ID:             ep.Address,
-			Target:         ep.Address,
-			AIOptions:      s.bifrost.options.AI,
-			MetricsEnabled: metricsEnabled,
-			Pricing:        pricing.Resolve(handler, parts[1], targetPricing),
-			Endpoint:       ep,
+			ID:              ep.Address,
+			Target:          ep.Address,
+			AIOptions:       s.bifrost.options.AI,
+			MetricsEnabled:  metricsEnabled,
+			Pricing:         pricing.Resolve(handler, parts[1], targetPricing),
+			Endpoint:        ep,
+			SkipRateLimited: modelOpts.Balancer != nil && modelOpts.Balancer.SkipRateLimited,
 		})
 		if pErr != nil {
 			slog.Error("failed to create AI proxy", "error", pErr)
@@ -2,7 +2,13 @@ package gateway
 
 import (
 	"context"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,8 +17,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/nite-coder/bifrost/pkg/ai"
 	"github.com/nite-coder/bifrost/pkg/balancer/weighted"
 	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/middleware/aiguard"
 	"github.com/nite-coder/bifrost/pkg/proxy"
 	"github.com/nite-coder/bifrost/pkg/resolver"
 	"github.com/nite-coder/bifrost/pkg/target"
@@ -304,6 +312,42 @@ func TestServiceBalancerNil(t *testing.T) {
 	assert.Equal(t, 503, hzCtx.Response.StatusCode())
 }
 
+func TestStaticService(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>home</h1>"), 0o600))
+
+	bifrost := &Bifrost{
+		options: &config.Options{},
+	}
+
+	service, err := newService(bifrost, config.ServiceOptions{
+		ID:   "frontend",
+		Type: config.ServiceTypeStatic,
+		Static: config.StaticOptions{
+			Root: root,
+			SPA:  true,
+		},
+	})
+	require.NoError(t, err)
+	defer service.Close()
+	assert.Nil(t, service.Upstream())
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetRequestURI("http://127.0.0.1:8088/users/1")
+	service.ServeHTTP(context.Background(), hzCtx)
+	assert.Equal(t, 200, hzCtx.Response.StatusCode())
+	body, err := io.ReadAll(hzCtx.Response.BodyStream())
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>home</h1>", string(body))
+
+	_, err = newService(bifrost, config.ServiceOptions{
+		ID:     "frontend",
+		Type:   config.ServiceTypeStatic,
+		Static: config.StaticOptions{Root: filepath.Join(root, "missing")},
+	})
+	assert.Error(t, err)
+}
+
 func TestServiceGetters(t *testing.T) {
 	dnsResolver, err := resolver.NewResolver(resolver.Options{})
 	require.NoError(t, err)
@@ -462,6 +506,149 @@ func TestLoadModels(t *testing.T) {
 	}, time.Second, 5*time.Millisecond, "expected proxies for p1/gpt-3.5 and p2/gpt-3.5")
 }
 
+func TestAIServiceFallback(t *testing.T) {
+	// the balancer may be registered by other tests already
+	_ = weighted.Init()
+
+	newProvider := func(status int, body string) *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	limited := newProvider(http.StatusTooManyRequests,
+		`{"error":{"message":"Rate limit reached","type":"rate_limit_error"}}`)
+	overloaded := newProvider(http.StatusServiceUnavailable,
+		`{"error":{"message":"Service unavailable","type":"api_error"}}`)
+	healthy := newProvider(http.StatusOK,
+		`{"id":"chat-1","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+
+	var guardedRequests atomic.Int32
+	guarded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		guardedRequests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(
+			`{"id":"chat-2","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+		))
+	}))
+	t.Cleanup(guarded.Close)
+	moderation := newProvider(http.StatusInternalServerError, `{"error":{"message":"internal error"}}`)
+
+	dnsResolver, err := resolver.NewResolver(resolver.Options{})
+	require.NoError(t, err)
+
+	bifrost := &Bifrost{
+		resolver: dnsResolver,
+		options: &config.Options{
+			SkipResolver: true,
+			AI: &config.AIOptions{
+				Providers: map[string]*config.AIProvider{
+					"limited":    {Handler: "openai-chat", BaseURL: limited.URL},
+					"overloaded": {Handler: "openai-chat", BaseURL: overloaded.URL},
+					"healthy":    {Handler: "openai-chat", BaseURL: healthy.URL},
+					"guarded":    {Handler: "openai-chat", BaseURL: guarded.URL},
+				},
+			},
+			Models: map[string]*config.AIModelOptions{
+				"gpt-4": {
+					Targets: []config.AITargetOptions{{Target: "limited/gpt-4"}},
+					Fallback: []config.AIFallbackOptions{
+						{Target: "overloaded/gpt-4", On: []string{ai.ErrorClassRateLimited}},
+						{Target: "limited/gpt-4-mini", On: []string{ai.ErrorClassContextLengthExceeded}},
+						{Target: "healthy/gpt-4", On: []string{ai.ErrorClassOverloaded}},
+					},
+				},
+				"gpt-3.5": {
+					Targets: []config.AITargetOptions{{Target: "limited/gpt-3.5"}},
+					Fallback: []config.AIFallbackOptions{
+						{Target: "healthy/gpt-3.5", On: []string{ai.ErrorClassServerError}},
+					},
+				},
+				"gpt-4o": {
+					Targets: []config.AITargetOptions{{Target: "guarded/gpt-4o"}},
+					Fallback: []config.AIFallbackOptions{
+						{Target: "guarded/gpt-4o-mini"},
+					},
+				},
+			},
+		},
+	}
+
+	bifrost.upstreamManager = newUpstreamManager(bifrost)
+	err = bifrost.upstreamManager.Start()
+	require.NoError(t, err)
+	defer func() {
+		_ = bifrost.upstreamManager.Close()
+	}()
+
+	service, err := newService(bifrost, config.ServiceOptions{ID: "aiService", Type: config.ServiceTypeAI})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, exists := service.getBalancer("ai:gpt-3.5")
+		_, guardedExists := service.getBalancer("ai:gpt-4o")
+		return exists && guardedExists
+	}, time.Second, 5*time.Millisecond)
+
+	clientAdapter, err := ai.GetClientAdapter("openai-chat")
+	require.NoError(t, err)
+
+	newContext := func(model string) *app.RequestContext {
+		c := app.NewContext(0)
+		c.Set(ai.ContextKeyClientAdapter, clientAdapter)
+		c.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{
+			Model:    model,
+			Messages: []ai.Message{{Role: "user", Content: "hello"}},
+		})
+		c.Set(ai.ContextKeyVirtualModelName, model)
+		c.Set(variable.Model, model)
+		return c
+	}
+
+	t.Run("falls back through the chain", func(t *testing.T) {
+		c := newContext("gpt-4")
+		service.ServeHTTP(context.Background(), c)
+
+		assert.Empty(t, c.Errors)
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.Contains(t, string(c.Response.Body()), `"hi"`)
+		assert.Equal(t, "healthy/gpt-4", variable.GetString(variable.ModelID, c))
+	})
+
+	t.Run("unmatched error class", func(t *testing.T) {
+		c := newContext("gpt-3.5")
+		service.ServeHTTP(context.Background(), c)
+
+		require.Len(t, c.Errors, 1)
+		assert.Equal(t, ai.ErrorClassRateLimited, ai.ClassifyError(c.Errors.Last().Err))
+		assert.Equal(t, "limited/gpt-3.5", variable.GetString(variable.ModelID, c))
+	})
+
+	t.Run("response rejected by ai_guard", func(t *testing.T) {
+		guard, err := aiguard.NewMiddleware(aiguard.Options{
+			Direction:  aiguard.DirectionResponse,
+			Moderation: &aiguard.ModerationOptions{URL: moderation.URL},
+		})
+		require.NoError(t, err)
+
+		// the guard fails closed because the moderation service is down, the target already
+		// served the request so it isn't sent to the fallback target
+		c := newContext("gpt-4o")
+		c.SetHandlers(app.HandlersChain{guard.ServeHTTP, service.ServeHTTP})
+		c.Next(context.Background())
+
+		require.Len(t, c.Errors, 1)
+		var aiErr *ai.AIError
+		require.ErrorAs(t, c.Errors.Last().Err, &aiErr)
+		assert.Equal(t, http.StatusBadGateway, aiErr.StatusCode)
+		assert.Empty(t, ai.ClassifyError(c.Errors.Last().Err))
+		assert.Equal(t, "guarded/gpt-4o", variable.GetString(variable.ModelID, c))
+		assert.Equal(t, int32(1), guardedRequests.Load())
+	})
+}
+
 func TestSharedUpstreamLifecycle(t *testing.T) {
 	h := testServer(t)
 	defer func() {
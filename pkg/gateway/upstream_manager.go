@@ -1,8 +1,10 @@
 package gateway
 
 import (
+	"strings"
 	"sync"
 
+	"github.com/nite-coder/bifrost/pkg/ai/pricing"
 	"github.com/nite-coder/bifrost/pkg/config"
 )
 
@@ -66,10 +68,16 @@ func (m *UpstreamManager) Start() error {
 				balancerType = modelOpts.Balancer.Type
 			}
 
+			var balancerParams any
+			if balancerType == "lowest_cost" {
+				balancerParams = map[string]any{"pricing": m.targetPricing(modelOpts)}
+			}
+
 			upstreamOpts := config.UpstreamOptions{
 				ID: "ai:" + modelID,
 				Balancer: config.BalancerOptions{
-					Type: balancerType,
+					Type:   balancerType,
+					Params: balancerParams,
 				},
 				Targets: targets,
 			}
@@ -85,6 +93,25 @@ func (m *UpstreamManager) Start() error {
 	return nil
 }
 
+// targetPricing resolves the pricing of the targets of a virtual model for the lowest_cost balancer.
+func (m *UpstreamManager) targetPricing(modelOpts *config.AIModelOptions) map[string]*config.AIPricingOptions {
+	prices := make(map[string]*config.AIPricingOptions, len(modelOpts.Targets))
+	for _, t := range modelOpts.Targets {
+		providerID, model, found := strings.Cut(t.Target, "/")
+		if !found {
+			continue
+		}
+		handler := ""
+		if m.bifrost.options.AI != nil {
+			if prov, ok := m.bifrost.options.AI.Providers[providerID]; ok && prov != nil {
+				handler = prov.Handler
+			}
+		}
+		prices[t.Target] = pricing.Resolve(handler, model, t.Pricing)
+	}
+	return prices
+}
+
 // Close closes all upstreams and clears states.
 func (m *UpstreamManager) Close() error {
 	m.mu.Lock()
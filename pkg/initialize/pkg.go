@@ -1,22 +1,40 @@
 package initialize
 
 import (
+	"github.com/nite-coder/bifrost/pkg/balancer/aibalancer"
 	"github.com/nite-coder/bifrost/pkg/balancer/chash"
 	"github.com/nite-coder/bifrost/pkg/balancer/random"
 	"github.com/nite-coder/bifrost/pkg/balancer/roundrobin"
 	"github.com/nite-coder/bifrost/pkg/balancer/weighted"
+	"github.com/nite-coder/bifrost/pkg/middleware/acl"
 	"github.com/nite-coder/bifrost/pkg/middleware/addprefix"
+	"github.com/nite-coder/bifrost/pkg/middleware/aicache"
+	"github.com/nite-coder/bifrost/pkg/middleware/aiguard"
+	"github.com/nite-coder/bifrost/pkg/middleware/aiquota"
 	"github.com/nite-coder/bifrost/pkg/middleware/aitransformer"
+	"github.com/nite-coder/bifrost/pkg/middleware/basicauth"
+	"github.com/nite-coder/bifrost/pkg/middleware/bodytemplate"
 	"github.com/nite-coder/bifrost/pkg/middleware/buffering"
+	"github.com/nite-coder/bifrost/pkg/middleware/cache"
 	"github.com/nite-coder/bifrost/pkg/middleware/compression"
 	"github.com/nite-coder/bifrost/pkg/middleware/coraza"
 	"github.com/nite-coder/bifrost/pkg/middleware/cors"
+	"github.com/nite-coder/bifrost/pkg/middleware/extauth"
+	"github.com/nite-coder/bifrost/pkg/middleware/graphqlguard"
+	"github.com/nite-coder/bifrost/pkg/middleware/grpctranscode"
+	"github.com/nite-coder/bifrost/pkg/middleware/grpcweb"
+	"github.com/nite-coder/bifrost/pkg/middleware/hmacauth"
 	"github.com/nite-coder/bifrost/pkg/middleware/iprestriction"
+	"github.com/nite-coder/bifrost/pkg/middleware/jsonschema"
 	"github.com/nite-coder/bifrost/pkg/middleware/mirror"
+	"github.com/nite-coder/bifrost/pkg/middleware/oauth2"
+	"github.com/nite-coder/bifrost/pkg/middleware/openapivalidator"
 	"github.com/nite-coder/bifrost/pkg/middleware/parallel"
 	"github.com/nite-coder/bifrost/pkg/middleware/ratelimit"
 	"github.com/nite-coder/bifrost/pkg/middleware/replacepath"
 	"github.com/nite-coder/bifrost/pkg/middleware/replacepathregex"
+	"github.com/nite-coder/bifrost/pkg/middleware/requestdecompression"
+	"github.com/nite-coder/bifrost/pkg/middleware/requestsizelimit"
 	"github.com/nite-coder/bifrost/pkg/middleware/requesttermination"
 	"github.com/nite-coder/bifrost/pkg/middleware/requesttransformer"
 	"github.com/nite-coder/bifrost/pkg/middleware/responsetransformer"
@@ -29,7 +47,22 @@ import (
 // Bifrost initializes all standard middlewares and balancers.
 func Bifrost() error {
 	// middleware
-	err := addprefix.Init()
+	err := acl.Init()
+	if err != nil {
+		return err
+	}
+
+	err = addprefix.Init()
+	if err != nil {
+		return err
+	}
+
+	err = basicauth.Init()
+	if err != nil {
+		return err
+	}
+
+	err = bodytemplate.Init()
 	if err != nil {
 		return err
 	}
@@ -39,6 +72,11 @@ func Bifrost() error {
 		return err
 	}
 
+	err = cache.Init()
+	if err != nil {
+		return err
+	}
+
 	err = compression.Init()
 	if err != nil {
 		return err
@@ -54,16 +92,56 @@ func Bifrost() error {
 		return err
 	}
 
+	err = extauth.Init()
+	if err != nil {
+		return err
+	}
+
+	err = graphqlguard.Init()
+	if err != nil {
+		return err
+	}
+
+	err = grpctranscode.Init()
+	if err != nil {
+		return err
+	}
+
+	err = grpcweb.Init()
+	if err != nil {
+		return err
+	}
+
+	err = hmacauth.Init()
+	if err != nil {
+		return err
+	}
+
 	err = iprestriction.Init()
 	if err != nil {
 		return err
 	}
 
+	err = jsonschema.Init()
+	if err != nil {
+		return err
+	}
+
 	err = mirror.Init()
 	if err != nil {
 		return err
 	}
 
+	err = oauth2.Init()
+	if err != nil {
+		return err
+	}
+
+	err = openapivalidator.Init()
+	if err != nil {
+		return err
+	}
+
 	err = parallel.Init()
 	if err != nil {
 		return err
@@ -84,6 +162,16 @@ func Bifrost() error {
 		return err
 	}
 
+	err = requestdecompression.Init()
+	if err != nil {
+		return err
+	}
+
+	err = requestsizelimit.Init()
+	if err != nil {
+		return err
+	}
+
 	err = requesttermination.Init()
 	if err != nil {
 		return err
@@ -124,7 +212,27 @@ func Bifrost() error {
 		return err
 	}
 
+	err = aiquota.Init()
+	if err != nil {
+		return err
+	}
+
+	err = aicache.Init()
+	if err != nil {
+		return err
+	}
+
+	err = aiguard.Init()
+	if err != nil {
+		return err
+	}
+
 	// balancer
+	err = aibalancer.Init()
+	if err != nil {
+		return err
+	}
+
 	err = chash.Init()
 	if err != nil {
 		return err
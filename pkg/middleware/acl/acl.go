@@ -0,0 +1,141 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/nite-coder/blackbear/pkg/cast"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// Init registers the acl middleware.
+func Init() error {
+	return middleware.Register([]string{"acl"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
+
+// Options defines the configuration for the acl middleware.
+type Options struct {
+	By                       string   `mapstructure:"by"`
+	RejectedHTTPContentType  string   `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string   `mapstructure:"rejected_http_response_body"`
+	Allow                    []string `mapstructure:"allow"`
+	Deny                     []string `mapstructure:"deny"`
+	RejectedHTTPStatusCode   int      `mapstructure:"rejected_http_status_code"`
+}
+
+// ACL is a middleware that allows or denies requests based on the value of a directive,
+// such as the consumer or the groups set by auth middlewares.
+type ACL struct {
+	options *Options
+	allow   map[string]struct{}
+	deny    map[string]struct{}
+}
+
+// NewMiddleware creates a new ACL instance.
+func NewMiddleware(options Options) (*ACL, error) {
+	if len(options.Allow) == 0 && len(options.Deny) == 0 {
+		return nil, errors.New("allow and deny cannot be empty")
+	} else if len(options.Allow) > 0 && len(options.Deny) > 0 {
+		return nil, errors.New("allow and deny cannot be set at the same time")
+	}
+	if options.By == "" {
+		options.By = variable.AuthConsumer
+	}
+	if !variable.IsDirective(options.By) {
+		return nil, errors.New("by must be a directive")
+	}
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusForbidden
+	}
+	if len(options.RejectedHTTPContentType) == 0 {
+		options.RejectedHTTPContentType = "application/json"
+	}
+
+	m := &ACL{
+		options: &options,
+		allow:   make(map[string]struct{}, len(options.Allow)),
+		deny:    make(map[string]struct{}, len(options.Deny)),
+	}
+	for _, val := range options.Allow {
+		m.allow[val] = struct{}{}
+	}
+	for _, val := range options.Deny {
+		m.deny[val] = struct{}{}
+	}
+
+	return m, nil
+}
+
+func (m *ACL) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	values := m.values(c)
+
+	// requests without an identity are always rejected, so a deny list
+	// cannot be bypassed by skipping authentication
+	if len(values) == 0 {
+		m.reject(c)
+		return
+	}
+
+	if len(m.allow) > 0 {
+		for _, val := range values {
+			if _, found := m.allow[val]; found {
+				c.Next(ctx)
+				return
+			}
+		}
+		m.reject(c)
+		return
+	}
+
+	for _, val := range values {
+		if _, found := m.deny[val]; found {
+			m.reject(c)
+			return
+		}
+	}
+	c.Next(ctx)
+}
+
+// values returns the directive value as a list, so list values such as groups
+// match when any of their items matches.
+func (m *ACL) values(c *app.RequestContext) []string {
+	val, found := variable.Get(m.options.By, c)
+	if !found || val == nil {
+		return nil
+	}
+
+	switch v := val.(type) {
+	case []string:
+		return v
+	case []any:
+		result, _ := cast.ToStringSlice(v)
+		return result
+	default:
+		s, _ := cast.ToString(v)
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+}
+
+func (m *ACL) reject(c *app.RequestContext) {
+	c.SetStatusCode(m.options.RejectedHTTPStatusCode)
+	if len(m.options.RejectedHTTPContentType) > 0 {
+		c.SetContentType(m.options.RejectedHTTPContentType)
+	}
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	}
+	c.Abort()
+}
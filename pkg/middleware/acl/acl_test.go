@@ -0,0 +1,134 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+func TestACL(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        Options
+		setup          func(c *app.RequestContext)
+		wantStatusCode int
+		wantBody       string
+		wantNext       bool
+	}{
+		{
+			name:    "allow consumer",
+			options: Options{Allow: []string{"mobile"}},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthConsumer, "mobile")
+			},
+			wantStatusCode: 200,
+			wantNext:       true,
+		},
+		{
+			name:    "consumer not in allow list",
+			options: Options{Allow: []string{"mobile"}, RejectedHTTPResponseBody: "forbidden"},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthConsumer, "web")
+			},
+			wantStatusCode: 403,
+			wantBody:       "forbidden",
+		},
+		{
+			name:    "allow group",
+			options: Options{By: variable.AuthGroups, Allow: []string{"admin"}},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthGroups, []string{"dev", "admin"})
+			},
+			wantStatusCode: 200,
+			wantNext:       true,
+		},
+		{
+			name:    "deny group",
+			options: Options{By: variable.AuthGroups, Deny: []string{"contractor"}},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthGroups, []string{"dev", "contractor"})
+			},
+			wantStatusCode: 403,
+		},
+		{
+			name:    "group not in deny list",
+			options: Options{By: variable.AuthGroups, Deny: []string{"contractor"}},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthGroups, []string{"dev"})
+			},
+			wantStatusCode: 200,
+			wantNext:       true,
+		},
+		{
+			name:    "list claim",
+			options: Options{By: "$auth.claim.roles", Allow: []string{"billing"}},
+			setup: func(c *app.RequestContext) {
+				c.Set(variable.AuthClaims, map[string]any{"roles": []any{"billing"}})
+			},
+			wantStatusCode: 200,
+			wantNext:       true,
+		},
+		{
+			name:    "header directive",
+			options: Options{By: "$http.request.header.x-tenant", Deny: []string{"blocked"}},
+			setup: func(c *app.RequestContext) {
+				c.Request.Header.Set("X-Tenant", "blocked")
+			},
+			wantStatusCode: 403,
+		},
+		{
+			name:           "missing identity with deny list",
+			options:        Options{Deny: []string{"web"}, RejectedHTTPStatusCode: 401},
+			setup:          func(_ *app.RequestContext) {},
+			wantStatusCode: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMiddleware(tt.options)
+			require.NoError(t, err)
+
+			hzCtx := app.NewContext(0)
+			tt.setup(hzCtx)
+
+			called := false
+			hzCtx.SetIndex(-1)
+			hzCtx.SetHandlers([]app.HandlerFunc{m.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+				called = true
+				c.Response.SetStatusCode(200)
+			}})
+			hzCtx.Next(context.Background())
+
+			assert.Equal(t, tt.wantStatusCode, hzCtx.Response.StatusCode())
+			assert.Equal(t, tt.wantNext, called)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, string(hzCtx.Response.Body()))
+				assert.Equal(t, "application/json", string(hzCtx.Response.Header.ContentType()))
+			}
+		})
+	}
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("acl")
+
+	_, err := h(map[string]any{"allow": []string{"admin"}, "by": "$auth.groups"})
+	require.NoError(t, err)
+
+	_, err = NewMiddleware(Options{})
+	require.ErrorContains(t, err, "allow and deny cannot be empty")
+
+	_, err = NewMiddleware(Options{Allow: []string{"a"}, Deny: []string{"b"}})
+	require.ErrorContains(t, err, "cannot be set at the same time")
+
+	_, err = NewMiddleware(Options{Allow: []string{"a"}, By: "consumer"})
+	require.ErrorContains(t, err, "by must be a directive")
+}
@@ -0,0 +1,475 @@
+package aicache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/client"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/connector/redis"
+	"github.com/nite-coder/bifrost/pkg/gateway"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	// StatusHit means the response is served from the cache.
+	StatusHit = "HIT"
+	// StatusMiss means the response is fetched from the model.
+	StatusMiss = "MISS"
+	// StatusBypass means the request is not eligible for caching.
+	StatusBypass = "BYPASS"
+
+	allocationFactor            = 2
+	targetPartsCount            = 2
+	defaultTTL                  = time.Hour
+	defaultMaxEntries           = 10000
+	defaultSimilarityThreshold  = 0.95
+	defaultSemanticMaxEntries   = 1000
+	defaultSemanticMaxNamespace = 10000
+)
+
+// StrategyMode defines where cached responses are stored.
+type StrategyMode string
+
+const (
+	// Memory strategy stores responses in a bounded in-memory LRU.
+	Memory StrategyMode = "memory"
+	// Redis strategy stores responses in redis.
+	Redis StrategyMode = "redis"
+)
+
+// SemanticOptions defines the configuration of the semantic cache.
+type SemanticOptions struct {
+	// EmbeddingModel is the model which embeds the prompts, in the format `provider/model`.
+	EmbeddingModel      string  `mapstructure:"embedding_model"`
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
+	MaxEntries          int     `mapstructure:"max_entries"`
+}
+
+// Options defines the configuration for the ai_cache middleware.
+type Options struct {
+	Strategy   StrategyMode     `mapstructure:"strategy"`
+	RedisID    string           `mapstructure:"redis_id"`
+	CacheBy    string           `mapstructure:"cache_by"`
+	TTL        time.Duration    `mapstructure:"ttl"`
+	MaxEntries int              `mapstructure:"max_entries"`
+	Semantic   *SemanticOptions `mapstructure:"semantic"`
+}
+
+// EmbedFunc returns the embedding of the text.
+type EmbedFunc func(ctx context.Context, text string) ([]float64, error)
+
+// Middleware is a middleware that caches the responses of AI chat completions.
+type Middleware struct {
+	options         *Options
+	store           Store
+	index           Index
+	embed           EmbedFunc
+	embedder        ai.EmbeddingAdapter
+	cacheDirectives []string
+	mu              sync.Mutex
+}
+
+// NewMiddleware creates a new ai_cache middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Strategy == "" {
+		options.Strategy = Memory
+	}
+	if options.TTL < 0 {
+		return nil, errors.New("ttl cannot be negative")
+	}
+	if options.TTL == 0 {
+		options.TTL = defaultTTL
+	}
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = defaultMaxEntries
+	}
+
+	m := &Middleware{
+		options:         &options,
+		cacheDirectives: variable.ParseDirectives(options.CacheBy),
+	}
+
+	semantic := options.Semantic
+	if semantic != nil {
+		parts := strings.SplitN(semantic.EmbeddingModel, "/", targetPartsCount)
+		if len(parts) != targetPartsCount || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("embedding_model '%s' must be in the format 'provider/model'", semantic.EmbeddingModel)
+		}
+		if semantic.SimilarityThreshold == 0 {
+			semantic.SimilarityThreshold = defaultSimilarityThreshold
+		}
+		if semantic.SimilarityThreshold < 0 || semantic.SimilarityThreshold > 1 {
+			return nil, errors.New("similarity_threshold must be between 0 and 1")
+		}
+		if semantic.MaxEntries <= 0 {
+			semantic.MaxEntries = defaultSemanticMaxEntries
+		}
+		m.embed = m.embedWithProvider
+	}
+
+	switch options.Strategy {
+	case Memory:
+		store, err := NewMemoryStore(options.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		m.store = store
+		if semantic != nil {
+			index, err := NewMemoryIndex(defaultSemanticMaxNamespace, semantic.MaxEntries)
+			if err != nil {
+				return nil, err
+			}
+			m.index = index
+		}
+	case Redis:
+		client, found := redis.Get(options.RedisID)
+		if !found {
+			return nil, fmt.Errorf("redis id '%s' not found for ai_cache middleware", options.RedisID)
+		}
+		m.store = NewRedisStore(client)
+		if semantic != nil {
+			m.index = NewRedisIndex(client, semantic.MaxEntries)
+		}
+	default:
+		return nil, fmt.Errorf("strategy '%s' is invalid for ai_cache middleware", options.Strategy)
+	}
+
+	return m, nil
+}
+
+// ServeHTTP serves the chat completion from the cache, otherwise it caches the response of the model.
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	val, found := c.Get(ai.ContextKeyChatRequest)
+	if !found {
+		m.bypass(ctx, c)
+		return
+	}
+	chatReq, ok := val.(*ai.ChatRequest)
+	if !ok {
+		m.bypass(ctx, c)
+		return
+	}
+	adapterVal, _ := c.Get(ai.ContextKeyClientAdapter)
+	clientAdapter, ok := adapterVal.(ai.ClientAdapter)
+	if !ok {
+		m.bypass(ctx, c)
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	prefix := m.cachePrefix(c)
+
+	key, err := requestKey(chatReq, false)
+	if err != nil {
+		logger.WarnContext(ctx, "ai_cache: failed to create cache key", slog.String("error", err.Error()))
+		m.bypass(ctx, c)
+		return
+	}
+	key = prefix + key
+
+	resp, err := m.store.Get(ctx, key)
+	if err != nil {
+		logger.WarnContext(ctx, "ai_cache: failed to get response", slog.String("error", err.Error()))
+	}
+
+	// the semantic cache looks up the last user message among the requests which only differ in it
+	var namespace string
+	var vector []float64
+	if resp == nil && m.embed != nil {
+		namespace, vector = m.lookupSemantic(ctx, chatReq, prefix)
+		if vector != nil {
+			matched, found, err := m.index.Search(ctx, namespace, vector, m.options.Semantic.SimilarityThreshold)
+			if err != nil {
+				logger.WarnContext(ctx, "ai_cache: failed to search index", slog.String("error", err.Error()))
+			} else if found {
+				resp, err = m.store.Get(ctx, matched)
+				if err != nil {
+					logger.WarnContext(ctx, "ai_cache: failed to get response", slog.String("error", err.Error()))
+				}
+			}
+		}
+	}
+
+	if resp != nil {
+		m.serve(c, clientAdapter, chatReq, resp)
+		return
+	}
+
+	c.Set(variable.CacheStatus, StatusMiss)
+	if chatReq.Stream {
+		c.Set(ai.ContextKeyRecordChatStream, true)
+	}
+
+	c.Next(ctx)
+
+	if len(c.Errors) > 0 || c.Response.StatusCode() != http.StatusOK {
+		return
+	}
+	val, found = c.Get(ai.ContextKeyChatResponse)
+	if !found {
+		return
+	}
+	chatResp, ok := val.(*ai.ChatResponse)
+	if !ok {
+		return
+	}
+
+	if err := m.store.Set(ctx, key, chatResp, m.options.TTL); err != nil {
+		logger.WarnContext(ctx, "ai_cache: failed to set response", slog.String("error", err.Error()))
+		return
+	}
+	if vector != nil {
+		if err := m.index.Add(ctx, namespace, key, vector, m.options.TTL); err != nil {
+			logger.WarnContext(ctx, "ai_cache: failed to add embedding", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (m *Middleware) bypass(ctx context.Context, c *app.RequestContext) {
+	c.Set(variable.CacheStatus, StatusBypass)
+	c.Next(ctx)
+}
+
+// serve writes the cached response in the client format. A cache hit doesn't reach a provider,
+// so it reports zero tokens and cost.
+func (m *Middleware) serve(c *app.RequestContext, clientAdapter ai.ClientAdapter, chatReq *ai.ChatRequest, resp *ai.ChatResponse) {
+	resp.Model = chatReq.Model
+	resp.Usage.InputCost = 0
+	resp.Usage.OutputCost = 0
+
+	c.Set(variable.CacheStatus, StatusHit)
+	c.Set(variable.InputTokens, 0)
+	c.Set(variable.OutputTokens, 0)
+	c.Set(variable.InputCachedTokens, 0)
+	c.Set(variable.TotalTokens, 0)
+	c.Set(variable.InputCost, 0.0)
+	c.Set(variable.OutputCost, 0.0)
+	c.Set(variable.TotalCost, 0.0)
+
+	if chatReq.Stream {
+		stream := clientAdapter.StreamConverter(ai.NewChatResponseStream(resp))
+		defer stream.Close()
+
+		body, err := io.ReadAll(stream)
+		if err != nil {
+			_ = c.Error(&ai.AIError{
+				Type:       "api_error",
+				Message:    "failed to replay cached response: " + err.Error(),
+				StatusCode: http.StatusInternalServerError,
+			})
+			c.Abort()
+			return
+		}
+
+		c.SetStatusCode(http.StatusOK)
+		c.Response.Header.SetContentType("text/event-stream")
+		c.Response.Header.Set("Cache-Control", "no-cache")
+		c.Response.SetBody(body)
+		c.Abort()
+		return
+	}
+
+	clientResp, err := clientAdapter.ToClientChatResponse(resp)
+	if err != nil {
+		_ = c.Error(&ai.AIError{
+			Type:       "api_error",
+			Message:    "failed to translate response to client format: " + err.Error(),
+			StatusCode: http.StatusInternalServerError,
+		})
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusOK, clientResp)
+	c.Abort()
+}
+
+// lookupSemantic returns the namespace and the embedding of the last user message of the request.
+func (m *Middleware) lookupSemantic(ctx context.Context, chatReq *ai.ChatRequest, prefix string) (string, []float64) {
+	text := lastUserText(chatReq)
+	if text == "" {
+		return "", nil
+	}
+
+	namespace, err := requestKey(chatReq, true)
+	if err != nil {
+		return "", nil
+	}
+
+	vector, err := m.embed(ctx, text)
+	if err != nil {
+		log.FromContext(ctx).WarnContext(ctx, "ai_cache: failed to embed prompt", slog.String("error", err.Error()))
+		return "", nil
+	}
+	return prefix + namespace, vector
+}
+
+// embedWithProvider embeds the text with the embedding model of the semantic cache, the
+// provider of the model is configured in the `ai` section.
+func (m *Middleware) embedWithProvider(ctx context.Context, text string) ([]float64, error) {
+	embedder, err := m.embeddingAdapter()
+	if err != nil {
+		return nil, err
+	}
+
+	_, model, _ := strings.Cut(m.options.Semantic.EmbeddingModel, "/")
+	resp, err := embedder.Embeddings(ctx, &ai.EmbeddingRequest{
+		Model: model,
+		Input: text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("embedding response is empty")
+	}
+	return resp.Data[0].Embedding, nil
+}
+
+func (m *Middleware) embeddingAdapter() (ai.EmbeddingAdapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.embedder != nil {
+		return m.embedder, nil
+	}
+
+	bifrost := gateway.GetBifrost()
+	if bifrost == nil || bifrost.Options() == nil || bifrost.Options().AI == nil {
+		return nil, errors.New("ai providers configuration is missing")
+	}
+
+	providerID, _, _ := strings.Cut(m.options.Semantic.EmbeddingModel, "/")
+	provider, found := bifrost.Options().AI.Providers[providerID]
+	if !found {
+		return nil, fmt.Errorf("provider '%s' not found", providerID)
+	}
+
+	httpClient, err := client.NewClient(client.WithResponseBodyStream(true))
+	if err != nil {
+		return nil, err
+	}
+	adapter, err := ai.GetAdapter(provider.Handler, ai.LLMAdapterOptions{
+		HTTPClient:  httpClient,
+		APIKey:      provider.APIKey,
+		BaseURL:     provider.BaseURL,
+		URLTemplate: provider.URLTemplate,
+		AuthHeader:  provider.AuthHeader,
+		AuthScheme:  provider.AuthScheme,
+		Headers:     provider.Headers,
+		QueryParams: provider.QueryParams,
+		Deployments: provider.Deployments,
+	})
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := adapter.(ai.EmbeddingAdapter)
+	if !ok {
+		return nil, fmt.Errorf("handler '%s' of provider '%s' doesn't support embeddings", provider.Handler, providerID)
+	}
+	m.embedder = embedder
+	return embedder, nil
+}
+
+func (m *Middleware) cachePrefix(c *app.RequestContext) string {
+	if len(m.options.CacheBy) == 0 {
+		return ""
+	}
+	if len(m.cacheDirectives) == 0 {
+		return m.options.CacheBy + ":"
+	}
+
+	replacements := make([]string, 0, len(m.cacheDirectives)*allocationFactor)
+	for _, key := range m.cacheDirectives {
+		replacements = append(replacements, key, variable.GetString(key, c))
+	}
+	return strings.NewReplacer(replacements...).Replace(m.options.CacheBy) + ":"
+}
+
+// requestKey normalizes the request and returns its hash. Fields which don't change the
+// completion, such as streaming, are dropped, and the JSON object keys are sorted. When
+// withoutLastUser is true, the last user message is dropped, so the hash is the namespace of the
+// requests which only differ in it.
+func requestKey(chatReq *ai.ChatRequest, withoutLastUser bool) (string, error) {
+	normalized := *chatReq
+	normalized.Stream = false
+	normalized.StreamOptions = nil
+	normalized.Extra = nil
+	if len(chatReq.UnknownFields) > 0 {
+		normalized.UnknownFields = maps.Clone(chatReq.UnknownFields)
+		delete(normalized.UnknownFields, "user")
+	}
+	if withoutLastUser {
+		if idx := lastUserIndex(chatReq); idx >= 0 {
+			normalized.Messages = slices.Delete(slices.Clone(chatReq.Messages), idx, idx+1)
+		}
+	}
+
+	data, err := normalized.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	var val any
+	if err := sonic.Unmarshal(data, &val); err != nil {
+		return "", err
+	}
+	// encoding/json sorts the keys of maps
+	data, err = json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func lastUserIndex(chatReq *ai.ChatRequest) int {
+	for i, msg := range slices.Backward(chatReq.Messages) {
+		if msg.Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastUserText(chatReq *ai.ChatRequest) string {
+	idx := lastUserIndex(chatReq)
+	if idx < 0 {
+		return ""
+	}
+	return chatReq.Messages[idx].Text()
+}
+
+// Init registers the ai_cache middleware.
+func Init() error {
+	return middleware.Register([]string{"ai_cache"}, func(option Options) (app.HandlerFunc, error) {
+		switch option.Strategy {
+		case Memory, Redis, "":
+		default:
+			return nil, fmt.Errorf("strategy '%s' is invalid", option.Strategy)
+		}
+
+		m, err := NewMiddleware(option)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
@@ -0,0 +1,255 @@
+package aicache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// stubProxy mimics the AI proxy, it answers with the content and records the response.
+type stubProxy struct {
+	content string
+	calls   int
+	err     error
+}
+
+func (p *stubProxy) ServeHTTP(_ context.Context, c *app.RequestContext) {
+	p.calls++
+	if p.err != nil {
+		_ = c.Error(p.err)
+		return
+	}
+	resp := &ai.ChatResponse{
+		ID:     "chat-123",
+		Object: "chat.completion",
+		Model:  "gpt-4o",
+		Choices: []ai.Choice{
+			{Message: ai.Message{Role: "assistant", Content: p.content}, FinishReason: "stop"},
+		},
+		Usage: ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, InputCost: 0.1, OutputCost: 0.2},
+	}
+	c.Set(ai.ContextKeyChatResponse, resp)
+	c.JSON(200, resp)
+}
+
+func serve(t *testing.T, m app.HandlerFunc, proxy *stubProxy, chatReq *ai.ChatRequest) *app.RequestContext {
+	t.Helper()
+
+	clientAdapter, err := ai.GetClientAdapter("openai-chat")
+	require.NoError(t, err)
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyClientAdapter, clientAdapter)
+	hzCtx.Set(ai.ContextKeyChatRequest, chatReq)
+	hzCtx.SetHandlers(app.HandlersChain{m, proxy.ServeHTTP})
+	hzCtx.Next(context.Background())
+	return hzCtx
+}
+
+func chatRequest(prompt string) *ai.ChatRequest {
+	return &ai.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []ai.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: prompt},
+		},
+	}
+}
+
+func TestAICacheMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("ai_cache")
+
+	t.Run("exact match", func(t *testing.T) {
+		m, err := h(map[string]any{"ttl": "1m"})
+		require.NoError(t, err)
+		proxy := &stubProxy{content: "Paris"}
+
+		c := serve(t, m, proxy, chatRequest("capital of France?"))
+		assert.Equal(t, StatusMiss, c.GetString(variable.CacheStatus))
+
+		c = serve(t, m, proxy, chatRequest("capital of France?"))
+		assert.Equal(t, 1, proxy.calls)
+		assert.Equal(t, StatusHit, c.GetString(variable.CacheStatus))
+		assert.Equal(t, 200, c.Response.StatusCode())
+		assert.Contains(t, string(c.Response.Body()), `"Paris"`)
+		assert.NotContains(t, string(c.Response.Body()), "input_cost")
+		assert.InDelta(t, 0.0, c.GetFloat64(variable.TotalCost), 0.0001)
+
+		serve(t, m, proxy, chatRequest("capital of Japan?"))
+		assert.Equal(t, 2, proxy.calls)
+	})
+
+	t.Run("streaming replay", func(t *testing.T) {
+		m, err := h(map[string]any{})
+		require.NoError(t, err)
+		proxy := &stubProxy{content: "Paris"}
+
+		serve(t, m, proxy, chatRequest("capital of France?"))
+
+		streamReq := chatRequest("capital of France?")
+		streamReq.Stream = true
+		c := serve(t, m, proxy, streamReq)
+		assert.Equal(t, 1, proxy.calls)
+		assert.Equal(t, StatusHit, c.GetString(variable.CacheStatus))
+		assert.Equal(t, "text/event-stream", string(c.Response.Header.ContentType()))
+
+		body := string(c.Response.Body())
+		assert.Contains(t, body, `"content":"Paris"`)
+		assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+	})
+
+	t.Run("streaming miss records the response", func(t *testing.T) {
+		m, err := h(map[string]any{})
+		require.NoError(t, err)
+		proxy := &stubProxy{content: "Paris"}
+
+		streamReq := chatRequest("capital of France?")
+		streamReq.Stream = true
+		c := serve(t, m, proxy, streamReq)
+		assert.True(t, c.GetBool(ai.ContextKeyRecordChatStream))
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		m, err := h(map[string]any{})
+		require.NoError(t, err)
+		proxy := &stubProxy{err: errors.New("upstream error")}
+
+		serve(t, m, proxy, chatRequest("capital of France?"))
+		serve(t, m, proxy, chatRequest("capital of France?"))
+		assert.Equal(t, 2, proxy.calls)
+	})
+
+	t.Run("cache by", func(t *testing.T) {
+		m, err := h(map[string]any{"cache_by": "$http.request.header.x-team"})
+		require.NoError(t, err)
+		proxy := &stubProxy{content: "Paris"}
+
+		clientAdapter, err := ai.GetClientAdapter("openai-chat")
+		require.NoError(t, err)
+		for _, team := range []string{"a", "b", "a"} {
+			hzCtx := app.NewContext(0)
+			hzCtx.Request.Header.Set("x-team", team)
+			hzCtx.Set(ai.ContextKeyClientAdapter, clientAdapter)
+			hzCtx.Set(ai.ContextKeyChatRequest, chatRequest("capital of France?"))
+			hzCtx.SetHandlers(app.HandlersChain{m, proxy.ServeHTTP})
+			hzCtx.Next(context.Background())
+		}
+		assert.Equal(t, 2, proxy.calls)
+	})
+
+	t.Run("bypass without chat request", func(t *testing.T) {
+		m, err := h(map[string]any{})
+		require.NoError(t, err)
+
+		hzCtx := app.NewContext(0)
+		m(context.Background(), hzCtx)
+		assert.Equal(t, StatusBypass, hzCtx.GetString(variable.CacheStatus))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := h(map[string]any{"strategy": "foo"})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{"strategy": "redis", "redis_id": "not_found"})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{"semantic": map[string]any{"embedding_model": "text-embedding-3-small"}})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{"semantic": map[string]any{
+			"embedding_model":      "openai/text-embedding-3-small",
+			"similarity_threshold": 1.5,
+		}})
+		require.Error(t, err)
+	})
+}
+
+func TestAICacheSemantic(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Semantic: &SemanticOptions{
+			EmbeddingModel:      "openai/text-embedding-3-small",
+			SimilarityThreshold: 0.9,
+		},
+	})
+	require.NoError(t, err)
+
+	vectors := map[string][]float64{
+		"What is the capital of France?": {1, 0, 0},
+		"what's the capital of france":   {0.98, 0.1, 0},
+		"How tall is Mount Everest?":     {0, 1, 0},
+	}
+	embedCalls := 0
+	m.embed = func(_ context.Context, text string) ([]float64, error) {
+		embedCalls++
+		return vectors[text], nil
+	}
+	proxy := &stubProxy{content: "Paris"}
+
+	serve(t, m.ServeHTTP, proxy, chatRequest("What is the capital of France?"))
+
+	c := serve(t, m.ServeHTTP, proxy, chatRequest("what's the capital of france"))
+	assert.Equal(t, 1, proxy.calls)
+	assert.Equal(t, StatusHit, c.GetString(variable.CacheStatus))
+	assert.Contains(t, string(c.Response.Body()), `"Paris"`)
+
+	serve(t, m.ServeHTTP, proxy, chatRequest("How tall is Mount Everest?"))
+	assert.Equal(t, 2, proxy.calls)
+
+	// a different conversation before the last user message is another namespace
+	req := chatRequest("what's the capital of france")
+	req.Messages[0].Content = "answer in French"
+	serve(t, m.ServeHTTP, proxy, req)
+	assert.Equal(t, 3, proxy.calls)
+
+	// exact hits don't need an embedding
+	calls := embedCalls
+	serve(t, m.ServeHTTP, proxy, chatRequest("What is the capital of France?"))
+	assert.Equal(t, calls, embedCalls)
+
+	// the exact cache still works when the embedding fails
+	m.embed = func(_ context.Context, _ string) ([]float64, error) {
+		return nil, errors.New("embedding error")
+	}
+	serve(t, m.ServeHTTP, proxy, chatRequest("Who wrote Hamlet?"))
+	c = serve(t, m.ServeHTTP, proxy, chatRequest("Who wrote Hamlet?"))
+	assert.Equal(t, StatusHit, c.GetString(variable.CacheStatus))
+	assert.Equal(t, 4, proxy.calls)
+}
+
+func TestRequestKey(t *testing.T) {
+	base := chatRequest("hello")
+	key, err := requestKey(base, false)
+	require.NoError(t, err)
+
+	streaming := chatRequest("hello")
+	streaming.Stream = true
+	streaming.StreamOptions = &ai.StreamOptions{IncludeUsage: true}
+	streaming.UnknownFields = map[string]any{"user": "alice"}
+	streamingKey, err := requestKey(streaming, false)
+	require.NoError(t, err)
+	assert.Equal(t, key, streamingKey)
+
+	temperature := 0.5
+	other := chatRequest("hello")
+	other.Temperature = &temperature
+	otherKey, err := requestKey(other, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	namespace, err := requestKey(base, true)
+	require.NoError(t, err)
+	otherNamespace, err := requestKey(chatRequest("bye"), true)
+	require.NoError(t, err)
+	assert.Equal(t, namespace, otherNamespace)
+	assert.Len(t, base.Messages, 2)
+}
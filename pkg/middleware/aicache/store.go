@@ -0,0 +1,278 @@
+package aicache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+// Store persists cached chat responses.
+type Store interface {
+	// Get returns the response of the key, or nil when the key is not found.
+	Get(ctx context.Context, key string) (*ai.ChatResponse, error)
+	// Set stores the response, and the response is removed after the ttl.
+	Set(ctx context.Context, key string, resp *ai.ChatResponse, ttl time.Duration) error
+}
+
+// Index finds the cache keys of similar prompts by the embeddings of the prompts.
+type Index interface {
+	// Add adds the embedding of the cache key to the namespace, and the embedding is removed after the ttl.
+	Add(ctx context.Context, namespace string, key string, vector []float64, ttl time.Duration) error
+	// Search returns the cache key whose embedding is the most similar to the vector, if the
+	// similarity reaches the threshold.
+	Search(ctx context.Context, namespace string, vector []float64, threshold float64) (string, bool, error)
+}
+
+type memoryItem struct {
+	expiresAt time.Time
+	resp      *ai.ChatResponse
+}
+
+// MemoryStore keeps responses in a bounded in-memory LRU.
+type MemoryStore struct {
+	lru *lru.Cache[string, memoryItem]
+}
+
+// NewMemoryStore creates a new MemoryStore instance holding at most size responses.
+func NewMemoryStore(size int) (*MemoryStore, error) {
+	c, err := lru.New[string, memoryItem](size)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryStore{lru: c}, nil
+}
+
+// Get returns the response of the key, or nil when the key is not found.
+func (s *MemoryStore) Get(_ context.Context, key string) (*ai.ChatResponse, error) {
+	item, found := s.lru.Get(key)
+	if !found {
+		return nil, nil
+	}
+	if timecache.Now().After(item.expiresAt) {
+		s.lru.Remove(key)
+		return nil, nil
+	}
+	resp := *item.resp
+	return &resp, nil
+}
+
+// Set stores the response, and the response is removed after the ttl.
+func (s *MemoryStore) Set(_ context.Context, key string, resp *ai.ChatResponse, ttl time.Duration) error {
+	s.lru.Add(key, memoryItem{
+		expiresAt: timecache.Now().Add(ttl),
+		resp:      resp,
+	})
+	return nil
+}
+
+type indexEntry struct {
+	ExpiresAt int64     `json:"expires_at"` // unix milliseconds
+	Vector    []float64 `json:"vector"`
+	key       string
+}
+
+func (e *indexEntry) expired(now time.Time) bool {
+	return now.UnixMilli() >= e.ExpiresAt
+}
+
+// search returns the key of the entry which is the most similar to the vector.
+func search(entries []indexEntry, vector []float64, threshold float64, now time.Time) (string, bool) {
+	bestKey := ""
+	bestScore := threshold
+	for i := range entries {
+		if entries[i].expired(now) {
+			continue
+		}
+		score := ai.CosineSimilarity(entries[i].Vector, vector)
+		if score >= bestScore {
+			bestKey = entries[i].key
+			bestScore = score
+		}
+	}
+	return bestKey, bestKey != ""
+}
+
+// MemoryIndex keeps embeddings in memory. The namespaces are kept in a bounded LRU, and each
+// namespace holds at most size embeddings.
+type MemoryIndex struct {
+	namespaces *lru.Cache[string, []indexEntry]
+	size       int
+	mu         sync.Mutex
+}
+
+// NewMemoryIndex creates a new MemoryIndex instance.
+func NewMemoryIndex(namespaces int, size int) (*MemoryIndex, error) {
+	c, err := lru.New[string, []indexEntry](namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryIndex{namespaces: c, size: size}, nil
+}
+
+// Add adds the embedding of the cache key to the namespace, the oldest embedding is dropped
+// when the namespace is full.
+func (idx *MemoryIndex) Add(_ context.Context, namespace string, key string, vector []float64, ttl time.Duration) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	now := timecache.Now()
+	entries, _ := idx.namespaces.Get(namespace)
+
+	kept := make([]indexEntry, 0, len(entries)+1)
+	for _, entry := range entries {
+		if !entry.expired(now) && entry.key != key {
+			kept = append(kept, entry)
+		}
+	}
+	kept = append(kept, indexEntry{key: key, Vector: vector, ExpiresAt: now.Add(ttl).UnixMilli()})
+	if len(kept) > idx.size {
+		kept = kept[len(kept)-idx.size:]
+	}
+	idx.namespaces.Add(namespace, kept)
+	return nil
+}
+
+// Search returns the cache key whose embedding is the most similar to the vector.
+func (idx *MemoryIndex) Search(_ context.Context, namespace string, vector []float64, threshold float64) (string, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entries, found := idx.namespaces.Get(namespace)
+	if !found {
+		return "", false, nil
+	}
+	key, found := search(entries, vector, threshold, timecache.Now())
+	return key, found, nil
+}
+
+const (
+	redisKeyPrefix   = "bifrost:ai_cache:"
+	redisIndexPrefix = "bifrost:ai_cache:index:"
+)
+
+// RedisStore keeps responses in Redis, so the cache is shared across gateway instances.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a new RedisStore instance.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get returns the response of the key, or nil when the key is not found.
+func (s *RedisStore) Get(ctx context.Context, key string) (*ai.ChatResponse, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	resp := &ai.ChatResponse{}
+	if err := sonic.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Set stores the response, and the response is removed after the ttl.
+func (s *RedisStore) Set(ctx context.Context, key string, resp *ai.ChatResponse, ttl time.Duration) error {
+	data, err := sonic.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
+
+// RedisIndex keeps the embeddings of a namespace in a Redis hash, and searches them in the gateway.
+// It suits namespaces of moderate size, since a search reads the whole namespace.
+type RedisIndex struct {
+	client redis.UniversalClient
+	size   int
+}
+
+// NewRedisIndex creates a new RedisIndex instance, each namespace holds at most size embeddings.
+func NewRedisIndex(client redis.UniversalClient, size int) *RedisIndex {
+	return &RedisIndex{client: client, size: size}
+}
+
+// Add adds the embedding of the cache key to the namespace. Expired embeddings are removed when
+// the namespace is full, and the embedding is dropped if the namespace is still full.
+func (idx *RedisIndex) Add(ctx context.Context, namespace string, key string, vector []float64, ttl time.Duration) error {
+	hashKey := redisIndexPrefix + namespace
+	now := timecache.Now()
+
+	count, err := idx.client.HLen(ctx, hashKey).Result()
+	if err != nil {
+		return err
+	}
+	if count >= int64(idx.size) {
+		entries, err := idx.entries(ctx, hashKey)
+		if err != nil {
+			return err
+		}
+		expired := make([]string, 0)
+		for _, entry := range entries {
+			if entry.expired(now) {
+				expired = append(expired, entry.key)
+			}
+		}
+		if len(expired) > 0 {
+			if err := idx.client.HDel(ctx, hashKey, expired...).Err(); err != nil {
+				return err
+			}
+		}
+		if count-int64(len(expired)) >= int64(idx.size) {
+			return nil
+		}
+	}
+
+	data, err := sonic.Marshal(indexEntry{Vector: vector, ExpiresAt: now.Add(ttl).UnixMilli()})
+	if err != nil {
+		return err
+	}
+
+	pipe := idx.client.TxPipeline()
+	pipe.HSet(ctx, hashKey, key, data)
+	pipe.PExpire(ctx, hashKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Search returns the cache key whose embedding is the most similar to the vector.
+func (idx *RedisIndex) Search(ctx context.Context, namespace string, vector []float64, threshold float64) (string, bool, error) {
+	entries, err := idx.entries(ctx, redisIndexPrefix+namespace)
+	if err != nil {
+		return "", false, err
+	}
+	key, found := search(entries, vector, threshold, timecache.Now())
+	return key, found, nil
+}
+
+func (idx *RedisIndex) entries(ctx context.Context, hashKey string) ([]indexEntry, error) {
+	fields, err := idx.client.HGetAll(ctx, hashKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]indexEntry, 0, len(fields))
+	for key, data := range fields {
+		var entry indexEntry
+		if err := sonic.UnmarshalString(data, &entry); err != nil {
+			continue
+		}
+		entry.key = key
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
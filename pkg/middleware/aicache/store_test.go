@@ -0,0 +1,71 @@
+package aicache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemoryStore(10)
+	require.NoError(t, err)
+
+	resp, err := store.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	require.NoError(t, store.Set(ctx, "foo", &ai.ChatResponse{ID: "chat-1"}, time.Minute))
+	resp, err = store.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, "chat-1", resp.ID)
+
+	require.NoError(t, store.Set(ctx, "bar", &ai.ChatResponse{ID: "chat-2"}, -time.Second))
+	resp, err = store.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+}
+
+func TestMemoryIndex(t *testing.T) {
+	ctx := context.Background()
+	index, err := NewMemoryIndex(10, 2)
+	require.NoError(t, err)
+
+	_, found, err := index.Search(ctx, "ns", []float64{1, 0}, 0.9)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, index.Add(ctx, "ns", "a", []float64{1, 0}, time.Minute))
+	require.NoError(t, index.Add(ctx, "ns", "b", []float64{0, 1}, time.Minute))
+
+	key, found, err := index.Search(ctx, "ns", []float64{0.9, 0.1}, 0.9)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "a", key)
+
+	_, found, err = index.Search(ctx, "ns", []float64{1, 1}, 0.9)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, found, err = index.Search(ctx, "other", []float64{1, 0}, 0.9)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// the oldest embedding is dropped when the namespace is full
+	require.NoError(t, index.Add(ctx, "ns", "c", []float64{-1, 0}, time.Minute))
+	_, found, err = index.Search(ctx, "ns", []float64{1, 0}, 0.9)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// expired embeddings are skipped
+	require.NoError(t, index.Add(ctx, "expired", "d", []float64{1, 0}, -time.Second))
+	_, found, err = index.Search(ctx, "expired", []float64{1, 0}, 0.9)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,283 @@
+package aiguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/internal/pkg/optional"
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// maxHoldback is the maximum size of the text which is held back from a stream, so that a
+// sensitive content split across deltas can still be detected.
+const maxHoldback = 256
+
+// holdbackPattern matches the tail of a delta which may continue in the next delta: the last word,
+// and a run of digits and separators before it, such as a card number.
+var holdbackPattern = regexp.MustCompile(`(?:\d[\d \-]*)?\S*$`)
+
+// Direction defines which traffic the middleware inspects.
+type Direction string
+
+const (
+	// DirectionRequest inspects the prompts.
+	DirectionRequest Direction = "request"
+	// DirectionResponse inspects the responses of the model.
+	DirectionResponse Direction = "response"
+	// DirectionBoth inspects both prompts and responses.
+	DirectionBoth Direction = "both"
+)
+
+// Options defines the configuration for the ai_guard middleware.
+type Options struct {
+	Direction  Direction          `mapstructure:"direction"`
+	Detectors  []DetectorOptions  `mapstructure:"detectors"`
+	Moderation *ModerationOptions `mapstructure:"moderation"`
+}
+
+// Middleware inspects the prompts and the responses of chat completions, and blocks, masks or
+// logs the sensitive content which its detectors find.
+type Middleware struct {
+	options   *Options
+	detectors []*detector
+	moderate  ModerateFunc
+}
+
+// NewMiddleware creates a new ai_guard middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	switch options.Direction {
+	case "":
+		options.Direction = DirectionBoth
+	case DirectionRequest, DirectionResponse, DirectionBoth:
+	default:
+		return nil, fmt.Errorf("direction '%s' is invalid", options.Direction)
+	}
+	if len(options.Detectors) == 0 && options.Moderation == nil {
+		return nil, errors.New("detectors or moderation must be set")
+	}
+
+	m := &Middleware{
+		options:   &options,
+		detectors: make([]*detector, 0, len(options.Detectors)),
+	}
+	for _, opts := range options.Detectors {
+		d, err := newDetector(opts)
+		if err != nil {
+			return nil, err
+		}
+		m.detectors = append(m.detectors, d)
+	}
+
+	if options.Moderation != nil {
+		if options.Moderation.URL == "" {
+			return nil, errors.New("moderation url cannot be empty")
+		}
+		moderate, err := newModerator(options.Moderation)
+		if err != nil {
+			return nil, err
+		}
+		m.moderate = moderate
+	}
+	return m, nil
+}
+
+// ServeHTTP inspects the chat request which ai_transformer parsed, and registers a response filter
+// which inspects the response.
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	val, found := c.Get(ai.ContextKeyChatRequest)
+	if !found {
+		c.Next(ctx)
+		return
+	}
+	chatReq, ok := val.(*ai.ChatRequest)
+	if !ok {
+		c.Next(ctx)
+		return
+	}
+
+	g := &guard{middleware: m, holdback: make(map[int]string)}
+
+	if m.options.Direction != DirectionResponse {
+		if err := g.filterRequest(ctx, chatReq); err != nil {
+			c.Set(variable.GuardDetections, g.detections)
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+	}
+	if m.options.Direction != DirectionRequest {
+		ai.AddResponseFilter(c, g)
+	}
+
+	c.Set(variable.GuardDetections, g.detections)
+	c.Next(ctx)
+	c.Set(variable.GuardDetections, g.detections)
+}
+
+// guard inspects the traffic of a request and counts its detections.
+type guard struct {
+	middleware *Middleware
+	// holdback is the text held back from the stream of each choice.
+	holdback   map[int]string
+	detections int
+}
+
+// scan runs the detectors on the text and returns the masked text, or an error if a detector
+// whose action is block finds a match.
+func (g *guard) scan(ctx context.Context, text string, direction Direction) (string, error) {
+	for _, d := range g.middleware.detectors {
+		result, count := d.detect(text)
+		if count == 0 {
+			continue
+		}
+		g.detections += count
+		log.FromContext(ctx).Warn("ai_guard: sensitive content detected",
+			"detector", d.name,
+			"direction", direction,
+			"action", d.action,
+			"count", count,
+		)
+		if d.action == Block {
+			return "", newPolicyError(direction, fmt.Sprintf("%s detected", d.name))
+		}
+		text = result
+	}
+	return text, nil
+}
+
+func (g *guard) scanMessage(ctx context.Context, msg *ai.Message, direction Direction) error {
+	var scanErr error
+	msg.MapText(func(text string) string {
+		if scanErr != nil || text == "" {
+			return text
+		}
+		result, err := g.scan(ctx, text, direction)
+		if err != nil {
+			scanErr = err
+			return text
+		}
+		return result
+	})
+	return scanErr
+}
+
+// checkModeration sends the texts to the moderation service.
+func (g *guard) checkModeration(ctx context.Context, texts []string, direction Direction) error {
+	moderate := g.middleware.moderate
+	if moderate == nil || len(texts) == 0 {
+		return nil
+	}
+
+	categories, err := moderate(ctx, strings.Join(texts, "\n"))
+	if err != nil {
+		if g.middleware.options.Moderation.FailOpen {
+			log.FromContext(ctx).Warn("ai_guard: moderation failed", "error", err)
+			return nil
+		}
+		return &ai.AIError{
+			Type:       "api_error",
+			Message:    "The moderation service is unavailable.",
+			StatusCode: http.StatusBadGateway,
+		}
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+
+	g.detections++
+	log.FromContext(ctx).Warn("ai_guard: content flagged by moderation",
+		"direction", direction,
+		"categories", categories,
+	)
+	return newPolicyError(direction, "flagged as "+strings.Join(categories, ", "))
+}
+
+func (g *guard) filterRequest(ctx context.Context, chatReq *ai.ChatRequest) error {
+	texts := make([]string, 0, len(chatReq.Messages))
+	for i := range chatReq.Messages {
+		if err := g.scanMessage(ctx, &chatReq.Messages[i], DirectionRequest); err != nil {
+			return err
+		}
+		if text := chatReq.Messages[i].Text(); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return g.checkModeration(ctx, texts, DirectionRequest)
+}
+
+// FilterChatResponse inspects the messages of a unary response.
+func (g *guard) FilterChatResponse(ctx context.Context, resp *ai.ChatResponse) error {
+	texts := make([]string, 0, len(resp.Choices))
+	for i := range resp.Choices {
+		if err := g.scanMessage(ctx, &resp.Choices[i].Message, DirectionResponse); err != nil {
+			return err
+		}
+		if text := resp.Choices[i].Message.Text(); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return g.checkModeration(ctx, texts, DirectionResponse)
+}
+
+// FilterChatChunk inspects the deltas of a stream. The tail of each delta is held back until the
+// next delta, so that the matches split across deltas are detected too. Streamed responses aren't
+// sent to the moderation service, since they can't be rejected once they are sent.
+func (g *guard) FilterChatChunk(ctx context.Context, chunk *ai.StreamChunk) error {
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		text := g.holdback[choice.Index] + choice.Delta.Content
+
+		held := ""
+		if choice.FinishReason == nil {
+			loc := holdbackPattern.FindStringIndex(text)
+			if loc != nil && len(text)-loc[0] <= maxHoldback {
+				text, held = text[:loc[0]], text[loc[0]:]
+			}
+		}
+		g.holdback[choice.Index] = held
+
+		if text == "" {
+			choice.Delta.Content = ""
+			continue
+		}
+		result, err := g.scan(ctx, text, DirectionResponse)
+		if err != nil {
+			return err
+		}
+		choice.Delta.Content = result
+	}
+	return nil
+}
+
+func newPolicyError(direction Direction, reason string) *ai.AIError {
+	subject := "request"
+	if direction == DirectionResponse {
+		subject = "response"
+	}
+	return &ai.AIError{
+		Type:       "invalid_request_error",
+		Message:    fmt.Sprintf("The %s was rejected by the content policy: %s.", subject, reason),
+		StatusCode: http.StatusBadRequest,
+		Code:       optional.Some("content_policy_violation"),
+	}
+}
+
+// Init registers the ai_guard middleware.
+func Init() error {
+	return middleware.Register([]string{"ai_guard"}, func(option Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(option)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
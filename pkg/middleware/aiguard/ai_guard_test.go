@@ -0,0 +1,273 @@
+package aiguard
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// serve runs the middleware in front of a stub AI proxy which applies the response filters to resp.
+func serve(t *testing.T, m app.HandlerFunc, prompt string, resp *ai.ChatResponse) *app.RequestContext {
+	t.Helper()
+
+	proxy := func(ctx context.Context, c *app.RequestContext) {
+		if resp == nil {
+			return
+		}
+		for _, filter := range ai.ResponseFilters(c) {
+			if err := filter.FilterChatResponse(ctx, resp); err != nil {
+				_ = c.Error(err)
+				return
+			}
+		}
+	}
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []ai.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: prompt},
+		},
+	})
+	hzCtx.SetHandlers(app.HandlersChain{m, proxy})
+	hzCtx.Next(context.Background())
+	return hzCtx
+}
+
+func chatRequest(c *app.RequestContext) *ai.ChatRequest {
+	val, _ := c.Get(ai.ContextKeyChatRequest)
+	chatReq, _ := val.(*ai.ChatRequest)
+	return chatReq
+}
+
+func policyError(t *testing.T, c *app.RequestContext) *ai.AIError {
+	t.Helper()
+	require.Len(t, c.Errors, 1)
+	var aiErr *ai.AIError
+	require.True(t, errors.As(c.Errors[0].Err, &aiErr))
+	return aiErr
+}
+
+func TestAIGuardMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("ai_guard")
+
+	m, err := h(map[string]any{
+		"detectors": []map[string]any{
+			{"name": "email"},
+			{"name": "api_key", "replacement": "[SECRET]"},
+			{"name": "credit_card", "action": "block"},
+			{"name": "employee_id", "pattern": `EMP-\d{6}`, "action": "log"},
+			{"name": "codename", "words": []string{"Project Titan"}, "action": "block"},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("mask request", func(t *testing.T) {
+		c := serve(t, m, "mail bob@example.com the key sk-abcdefghijklmnopqrstuvwx for EMP-123456", nil)
+		assert.Empty(t, c.Errors)
+		assert.Equal(t, "mail [EMAIL] the key [SECRET] for EMP-123456", chatRequest(c).Messages[1].Content)
+		assert.Equal(t, "be brief", chatRequest(c).Messages[0].Content)
+		assert.Equal(t, 3, c.GetInt(variable.GuardDetections))
+	})
+
+	t.Run("block request", func(t *testing.T) {
+		c := serve(t, m, "charge 4111 1111 1111 1111 please", nil)
+		aiErr := policyError(t, c)
+		assert.Equal(t, http.StatusBadRequest, aiErr.StatusCode)
+		assert.Equal(t, "content_policy_violation", aiErr.Code.UnwrapOr(""))
+		assert.Contains(t, aiErr.Message, "credit_card")
+		assert.True(t, c.IsAborted())
+		assert.Equal(t, 1, c.GetInt(variable.GuardDetections))
+
+		c = serve(t, m, "what is project titan?", nil)
+		aiErr = policyError(t, c)
+		assert.Contains(t, aiErr.Message, "codename")
+	})
+
+	t.Run("invalid card number", func(t *testing.T) {
+		c := serve(t, m, "order 1234 5678 9012 3456", nil)
+		assert.Empty(t, c.Errors)
+		assert.Equal(t, 0, c.GetInt(variable.GuardDetections))
+	})
+
+	t.Run("mask response", func(t *testing.T) {
+		resp := &ai.ChatResponse{Choices: []ai.Choice{
+			{Message: ai.Message{Role: "assistant", Content: "contact alice@example.com"}},
+		}}
+		c := serve(t, m, "hello", resp)
+		assert.Empty(t, c.Errors)
+		assert.Equal(t, "contact [EMAIL]", resp.Choices[0].Message.Content)
+		assert.Equal(t, 1, c.GetInt(variable.GuardDetections))
+	})
+
+	t.Run("block response", func(t *testing.T) {
+		resp := &ai.ChatResponse{Choices: []ai.Choice{
+			{Message: ai.Message{Role: "assistant", Content: "use 4111-1111-1111-1111"}},
+		}}
+		c := serve(t, m, "hello", resp)
+		aiErr := policyError(t, c)
+		assert.Contains(t, aiErr.Message, "response")
+	})
+}
+
+func TestAIGuardDirection(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Direction: DirectionResponse,
+		Detectors: []DetectorOptions{{Name: "email"}},
+	})
+	require.NoError(t, err)
+
+	resp := &ai.ChatResponse{Choices: []ai.Choice{
+		{Message: ai.Message{Role: "assistant", Content: "bob@example.com"}},
+	}}
+	c := serve(t, m.ServeHTTP, "alice@example.com", resp)
+	assert.Equal(t, "alice@example.com", chatRequest(c).Messages[1].Content)
+	assert.Equal(t, "[EMAIL]", resp.Choices[0].Message.Content)
+}
+
+func TestAIGuardStream(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Detectors: []DetectorOptions{
+			{Name: "email"},
+			{Name: "credit_card", Action: Block},
+		},
+	})
+	require.NoError(t, err)
+
+	stop := "stop"
+	chunk := func(content string, finish *string) string {
+		b, _ := sonic.Marshal(ai.StreamChunk{
+			Object:  "chat.completion.chunk",
+			Choices: []ai.StreamChoice{{Delta: ai.StreamDelta{Content: content}, FinishReason: finish}},
+		})
+		return "data: " + string(b) + "\n\n"
+	}
+
+	read := func(t *testing.T, events ...string) (string, error) {
+		t.Helper()
+		c := app.NewContext(0)
+		c.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o"})
+		var stream io.ReadCloser
+		proxy := func(ctx context.Context, c *app.RequestContext) {
+			source := io.NopCloser(strings.NewReader(strings.Join(events, "")))
+			stream = ai.NewFilteredStream(ctx, source, ai.ResponseFilters(c))
+		}
+		c.SetHandlers(app.HandlersChain{m.ServeHTTP, proxy})
+		c.Next(context.Background())
+
+		b, err := io.ReadAll(stream)
+		return string(b), err
+	}
+
+	content := func(t *testing.T, out string) string {
+		t.Helper()
+		var sb strings.Builder
+		for event := range strings.SplitSeq(out, "\n\n") {
+			data, found := strings.CutPrefix(event, "data: ")
+			if !found || data == "[DONE]" {
+				continue
+			}
+			var chunk ai.StreamChunk
+			require.NoError(t, sonic.UnmarshalString(data, &chunk))
+			sb.WriteString(chunk.Choices[0].Delta.Content)
+		}
+		return sb.String()
+	}
+
+	t.Run("mask split across deltas", func(t *testing.T) {
+		out, err := read(t, chunk("write to bob@exa", nil), chunk("mple.com today", nil),
+			chunk("", &stop), "data: [DONE]\n\n")
+		require.NoError(t, err)
+		assert.Equal(t, "write to [EMAIL] today", content(t, out))
+		assert.Contains(t, out, "data: [DONE]")
+	})
+
+	t.Run("block split across deltas", func(t *testing.T) {
+		out, err := read(t, chunk("card 4111 1111", nil), chunk(" 1111 1111 ok", nil), chunk("", &stop))
+		var aiErr *ai.AIError
+		require.ErrorAs(t, err, &aiErr)
+		assert.Equal(t, "content_policy_violation", aiErr.Code.UnwrapOr(""))
+		assert.NotContains(t, out, "4111")
+	})
+}
+
+func TestAIGuardModeration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		var req moderationRequest
+		_ = sonic.Unmarshal(body, &req)
+
+		switch {
+		case strings.Contains(req.Input, "fail"):
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.Contains(req.Input, "attack"):
+			_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"results":[{"flagged":false,"categories":{"violence":false}}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	newGuard := func(t *testing.T, failOpen bool) app.HandlerFunc {
+		t.Helper()
+		m, err := NewMiddleware(Options{
+			Detectors:  []DetectorOptions{{Name: "email"}},
+			Moderation: &ModerationOptions{URL: ts.URL, APIKey: "test-key", FailOpen: failOpen},
+		})
+		require.NoError(t, err)
+		return m.ServeHTTP
+	}
+
+	c := serve(t, newGuard(t, false), "hello", nil)
+	assert.Empty(t, c.Errors)
+
+	c = serve(t, newGuard(t, false), "plan an attack", nil)
+	aiErr := policyError(t, c)
+	assert.Contains(t, aiErr.Message, "violence")
+	assert.Equal(t, 1, c.GetInt(variable.GuardDetections))
+
+	c = serve(t, newGuard(t, false), "fail", nil)
+	aiErr = policyError(t, c)
+	assert.Equal(t, http.StatusBadGateway, aiErr.StatusCode)
+
+	c = serve(t, newGuard(t, true), "fail", nil)
+	assert.Empty(t, c.Errors)
+}
+
+func TestNewMiddlewareErrors(t *testing.T) {
+	cases := []Options{
+		{},
+		{Direction: "sideways", Detectors: []DetectorOptions{{Name: "email"}}},
+		{Detectors: []DetectorOptions{{Name: "unknown"}}},
+		{Detectors: []DetectorOptions{{Name: "email", Action: "drop"}}},
+		{Detectors: []DetectorOptions{{Name: "custom", Pattern: "("}}},
+		{Detectors: []DetectorOptions{{Name: "custom", Pattern: "a", Words: []string{"b"}}}},
+		{Moderation: &ModerationOptions{}},
+	}
+	for _, opts := range cases {
+		_, err := NewMiddleware(opts)
+		assert.Error(t, err)
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	assert.True(t, luhn("4111 1111 1111 1111"))
+	assert.True(t, luhn("5500-0000-0000-0004"))
+	assert.False(t, luhn("4111 1111 1111 1112"))
+}
@@ -0,0 +1,149 @@
+package aiguard
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Action defines what the middleware does when a detector finds a match.
+type Action string
+
+const (
+	// Block rejects the request or the response.
+	Block Action = "block"
+	// Mask replaces the matches with the replacement.
+	Mask Action = "mask"
+	// Log only logs and counts the matches.
+	Log Action = "log"
+)
+
+// DetectorOptions defines a detector of sensitive content. A detector matches either the regular
+// expression of Pattern or the words of Words. Without both, the Name selects a built-in detector:
+// `email`, `credit_card` or `api_key`.
+type DetectorOptions struct {
+	Name        string   `mapstructure:"name"`
+	Pattern     string   `mapstructure:"pattern"`
+	Words       []string `mapstructure:"words"`
+	Action      Action   `mapstructure:"action"`
+	Replacement string   `mapstructure:"replacement"`
+}
+
+type builtinDetector struct {
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+var builtinDetectors = map[string]builtinDetector{
+	"email": {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"credit_card": {
+		pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		validate: luhn,
+	},
+	"api_key": {
+		pattern: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_\-]{20,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_\-]{35}|` +
+			`gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9\-]{10,})\b`),
+	},
+}
+
+// detector finds sensitive content in texts.
+type detector struct {
+	name        string
+	action      Action
+	replacement string
+	pattern     *regexp.Regexp
+	validate    func(match string) bool
+}
+
+func newDetector(opts DetectorOptions) (*detector, error) {
+	if opts.Name == "" {
+		return nil, errors.New("detector name cannot be empty")
+	}
+
+	d := &detector{
+		name:        opts.Name,
+		action:      opts.Action,
+		replacement: opts.Replacement,
+	}
+	switch d.action {
+	case "":
+		d.action = Mask
+	case Block, Mask, Log:
+	default:
+		return nil, fmt.Errorf("action '%s' of detector '%s' is invalid", opts.Action, opts.Name)
+	}
+	if d.replacement == "" {
+		d.replacement = "[" + strings.ToUpper(opts.Name) + "]"
+	}
+
+	switch {
+	case opts.Pattern != "" && len(opts.Words) > 0:
+		return nil, fmt.Errorf("detector '%s' cannot have both pattern and words", opts.Name)
+	case opts.Pattern != "":
+		pattern, err := regexp.Compile(opts.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern of detector '%s' is invalid: %w", opts.Name, err)
+		}
+		d.pattern = pattern
+	case len(opts.Words) > 0:
+		words := make([]string, 0, len(opts.Words))
+		for _, word := range opts.Words {
+			if word = strings.TrimSpace(word); word != "" {
+				words = append(words, regexp.QuoteMeta(word))
+			}
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("words of detector '%s' cannot be empty", opts.Name)
+		}
+		d.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)
+	default:
+		builtin, found := builtinDetectors[opts.Name]
+		if !found {
+			return nil, fmt.Errorf("detector '%s' requires a pattern or words", opts.Name)
+		}
+		d.pattern = builtin.pattern
+		d.validate = builtin.validate
+	}
+	return d, nil
+}
+
+// detect returns the text whose matches are masked if the action is mask, and the number of matches.
+func (d *detector) detect(text string) (string, int) {
+	count := 0
+	result := d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if d.validate != nil && !d.validate(match) {
+			return match
+		}
+		count++
+		if d.action == Mask {
+			return d.replacement
+		}
+		return match
+	})
+	return result, count
+}
+
+// luhn reports whether the digits of the number pass the Luhn checksum of payment card numbers.
+func luhn(number string) bool {
+	sum := 0
+	digits := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		ch := number[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		digit := int(ch - '0')
+		if digits%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
@@ -0,0 +1,104 @@
+package aiguard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+const defaultModerationTimeout = 3 * time.Second
+
+// ModerationOptions defines the external moderation service. The service must implement the
+// OpenAI moderation API.
+type ModerationOptions struct {
+	URL      string        `mapstructure:"url"`
+	APIKey   string        `mapstructure:"api_key"`
+	Model    string        `mapstructure:"model"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	FailOpen bool          `mapstructure:"fail_open"`
+}
+
+// ModerateFunc checks the text and returns the flagged categories, the text is flagged if any
+// category is returned.
+type ModerateFunc func(ctx context.Context, text string) ([]string, error)
+
+type moderationRequest struct {
+	Model string `json:"model,omitempty"`
+	Input string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// newModerator creates a ModerateFunc which calls the moderation service.
+func newModerator(opts *ModerationOptions) (ModerateFunc, error) {
+	httpClient, err := client.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultModerationTimeout
+	}
+
+	return func(ctx context.Context, text string) ([]string, error) {
+		body, err := sonic.Marshal(moderationRequest{Model: opts.Model, Input: text})
+		if err != nil {
+			return nil, err
+		}
+
+		req := protocol.AcquireRequest()
+		defer protocol.ReleaseRequest(req)
+		resp := protocol.AcquireResponse()
+		defer protocol.ReleaseResponse(resp)
+
+		req.Header.SetMethod(http.MethodPost)
+		req.SetRequestURI(opts.URL)
+		req.Header.SetContentTypeBytes([]byte("application/json"))
+		if opts.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+		}
+		req.SetBody(body)
+
+		if err := httpClient.DoTimeout(ctx, req, resp, timeout); err != nil {
+			return nil, fmt.Errorf("moderation request failed: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("moderation service returned status %d", resp.StatusCode())
+		}
+
+		var result moderationResponse
+		if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal moderation response: %w", err)
+		}
+
+		var categories []string
+		for _, item := range result.Results {
+			if !item.Flagged {
+				continue
+			}
+			count := len(categories)
+			for category, flagged := range item.Categories {
+				if flagged {
+					categories = append(categories, category)
+				}
+			}
+			if len(categories) == count {
+				categories = append(categories, "flagged")
+			}
+		}
+		sort.Strings(categories)
+		return categories, nil
+	}, nil
+}
@@ -0,0 +1,337 @@
+package aiquota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/internal/pkg/optional"
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/connector/redis"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	allocationFactor = 2
+	// charsPerToken is the rough number of characters per token used to estimate the prompt
+	// tokens of requests which aren't chat completions.
+	charsPerToken = 4
+)
+
+// Store defines the interface of the counters which quotas are enforced with.
+type Store interface {
+	// Get returns the value of the counter, or zero if the counter doesn't exist.
+	Get(ctx context.Context, key string) (float64, error)
+	// IncrBy increments the counter by val and returns the new value. The counter is created
+	// with the ttl if it doesn't exist.
+	IncrBy(ctx context.Context, key string, val float64, ttl time.Duration) (float64, error)
+}
+
+// StrategyMode defines where the quota counters are stored.
+type StrategyMode string
+
+const (
+	// Local strategy stores the counters in local memory.
+	Local StrategyMode = "local"
+	// Redis strategy stores the counters in redis, so quotas are shared by all gateway instances.
+	Redis StrategyMode = "redis"
+)
+
+// Options defines the configuration for the ai_quota middleware.
+type Options struct {
+	Strategy          StrategyMode `mapstructure:"strategy"`
+	LimitBy           string       `mapstructure:"limit_by"`
+	RedisID           string       `mapstructure:"redis_id"`
+	TokensPerMinute   uint64       `mapstructure:"tokens_per_minute"`
+	RequestsPerMinute uint64       `mapstructure:"requests_per_minute"`
+	SpendPerDay       float64      `mapstructure:"spend_per_day"`
+	SpendPerMonth     float64      `mapstructure:"spend_per_month"`
+}
+
+// Middleware enforces token, request and spend quotas on AI consumers.
+type Middleware struct {
+	options    *Options
+	store      Store
+	directives []string
+}
+
+// NewMiddleware creates a new ai_quota middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.TokensPerMinute == 0 && options.RequestsPerMinute == 0 &&
+		options.SpendPerDay <= 0 && options.SpendPerMonth <= 0 {
+		return nil, errors.New("at least one of tokens_per_minute, requests_per_minute, spend_per_day or spend_per_month must be set")
+	}
+	if options.SpendPerDay < 0 || options.SpendPerMonth < 0 {
+		return nil, errors.New("spend_per_day and spend_per_month cannot be negative")
+	}
+
+	m := &Middleware{
+		options:    &options,
+		directives: variable.ParseDirectives(options.LimitBy),
+	}
+	switch options.Strategy {
+	case Local:
+		m.store = NewLocalStore()
+	case Redis:
+		client, found := redis.Get(options.RedisID)
+		if !found {
+			return nil, fmt.Errorf("redis id '%s' not found for ai_quota middleware", options.RedisID)
+		}
+		m.store = NewRedisStore(client)
+	default:
+		return nil, fmt.Errorf("strategy '%s' is invalid for ai_quota middleware", options.Strategy)
+	}
+	return m, nil
+}
+
+// ServeHTTP rejects the request if the consumer exceeds one of its quotas, otherwise it reserves the
+// estimated prompt tokens and reconciles them with the actual usage after the request.
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	key := m.options.LimitBy
+	vals := buildReplacer(m.directives, c)
+	if len(vals) > 0 {
+		replacer := strings.NewReplacer(vals...)
+		key = replacer.Replace(key)
+	}
+	if len(key) == 0 {
+		c.Next(ctx)
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	now := timecache.Now()
+
+	if aiErr := m.checkSpend(ctx, key, now); aiErr != nil {
+		m.reject(c, aiErr, 0)
+		return
+	}
+
+	minute := now.Truncate(time.Minute)
+	retryAfter := minute.Add(time.Minute).Sub(now)
+
+	if m.options.RequestsPerMinute > 0 {
+		requests, err := m.store.IncrBy(ctx, windowKey(key, "rpm", minute), 1, time.Minute)
+		if err != nil {
+			logger.Warn("ai_quota: store error", "error", err)
+		} else if requests > float64(m.options.RequestsPerMinute) {
+			m.reject(c, newRateLimitError("requests per minute"), retryAfter)
+			return
+		}
+	}
+
+	tpmKey := windowKey(key, "tpm", minute)
+	estimated := 0
+	if m.options.TokensPerMinute > 0 {
+		estimated = estimatePromptTokens(c)
+		tokens, err := m.store.IncrBy(ctx, tpmKey, float64(estimated), time.Minute)
+		if err != nil {
+			logger.Warn("ai_quota: store error", "error", err)
+			estimated = 0
+		} else if tokens > float64(m.options.TokensPerMinute) {
+			_, _ = m.store.IncrBy(ctx, tpmKey, -float64(estimated), time.Minute)
+			m.reject(c, newRateLimitError("tokens per minute"), retryAfter)
+			return
+		}
+	}
+
+	observer := &usageObserver{}
+	ai.AddUsageObserver(c, observer)
+
+	c.Next(ctx)
+
+	usage, found := observer.total()
+	if m.options.TokensPerMinute > 0 {
+		// the reserved tokens are refunded when the request didn't reach a model
+		delta := -estimated
+		if found {
+			delta = usage.TotalTokens - estimated
+		}
+		if delta != 0 {
+			if ttl := minute.Add(time.Minute).Sub(timecache.Now()); ttl > 0 {
+				if _, err := m.store.IncrBy(ctx, tpmKey, float64(delta), ttl); err != nil {
+					logger.Warn("ai_quota: store error", "error", err)
+				}
+			}
+		}
+	}
+
+	cost := usage.InputCost + usage.OutputCost
+	if found && cost > 0 {
+		m.addSpend(ctx, key, cost)
+	}
+}
+
+func (m *Middleware) checkSpend(ctx context.Context, key string, now time.Time) *ai.AIError {
+	type spendQuota struct {
+		limit  float64
+		key    string
+		period string
+	}
+
+	quotas := []spendQuota{
+		{limit: m.options.SpendPerDay, key: dayKey(key, now), period: "day"},
+		{limit: m.options.SpendPerMonth, key: monthKey(key, now), period: "month"},
+	}
+	for _, quota := range quotas {
+		if quota.limit <= 0 {
+			continue
+		}
+		spend, err := m.store.Get(ctx, quota.key)
+		if err != nil {
+			log.FromContext(ctx).Warn("ai_quota: store error", "error", err)
+			continue
+		}
+		if spend >= quota.limit {
+			return &ai.AIError{
+				Type:       "insufficient_quota",
+				Message:    fmt.Sprintf("You exceeded your spend quota per %s.", quota.period),
+				StatusCode: http.StatusTooManyRequests,
+				Code:       optional.Some("insufficient_quota"),
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Middleware) addSpend(ctx context.Context, key string, cost float64) {
+	now := timecache.Now().UTC()
+	if m.options.SpendPerDay > 0 {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		_, err := m.store.IncrBy(ctx, dayKey(key, now), cost, day.AddDate(0, 0, 1).Sub(now))
+		if err != nil {
+			log.FromContext(ctx).Warn("ai_quota: store error", "error", err)
+		}
+	}
+	if m.options.SpendPerMonth > 0 {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		_, err := m.store.IncrBy(ctx, monthKey(key, now), cost, month.AddDate(0, 1, 0).Sub(now))
+		if err != nil {
+			log.FromContext(ctx).Warn("ai_quota: store error", "error", err)
+		}
+	}
+}
+
+func (m *Middleware) reject(c *app.RequestContext, aiErr *ai.AIError, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		c.Response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	_ = c.Error(aiErr)
+	c.Abort()
+}
+
+func newRateLimitError(quota string) *ai.AIError {
+	return &ai.AIError{
+		Type:       "rate_limit_error",
+		Message:    fmt.Sprintf("Rate limit reached for %s.", quota),
+		StatusCode: http.StatusTooManyRequests,
+		Code:       optional.Some("rate_limit_exceeded"),
+	}
+}
+
+// estimatePromptTokens estimates the prompt tokens of the request which ai_transformer parsed.
+func estimatePromptTokens(c *app.RequestContext) int {
+	if val, found := c.Get(ai.ContextKeyChatRequest); found {
+		if chatReq, ok := val.(*ai.ChatRequest); ok {
+			return ai.EstimatePromptTokens(chatReq)
+		}
+	}
+	if val, found := c.Get(ai.ContextKeyEmbeddingRequest); found {
+		if embeddingReq, ok := val.(*ai.EmbeddingRequest); ok {
+			return ai.EstimateTextTokens(embeddingReq.Inputs()...)
+		}
+	}
+	if val, found := c.Get(ai.ContextKeyImageRequest); found {
+		if imageReq, ok := val.(*ai.ImageRequest); ok {
+			return ai.EstimateTextTokens(imageReq.Prompt)
+		}
+	}
+	if val, found := c.Get(ai.ContextKeyTranscriptionRequest); found {
+		// the audio file isn't text, so only the prompt is counted
+		if transcriptionReq, ok := val.(*ai.TranscriptionRequest); ok {
+			return ai.EstimateTextTokens(transcriptionReq.Prompt)
+		}
+	}
+	return (len(c.Request.Body()) + charsPerToken - 1) / charsPerToken
+}
+
+func windowKey(key, quota string, window time.Time) string {
+	return "ai_quota:" + key + ":" + quota + ":" + strconv.FormatInt(window.Unix(), 10)
+}
+
+func dayKey(key string, now time.Time) string {
+	return "ai_quota:" + key + ":spend_day:" + now.UTC().Format("20060102")
+}
+
+func monthKey(key string, now time.Time) string {
+	return "ai_quota:" + key + ":spend_month:" + now.UTC().Format("200601")
+}
+
+func buildReplacer(directives []string, c *app.RequestContext) []string {
+	if len(directives) == 0 {
+		return nil
+	}
+	replacements := make([]string, 0, len(directives)*allocationFactor)
+	for _, key := range directives {
+		val := variable.GetString(key, c)
+		replacements = append(replacements, key, val)
+	}
+	return replacements
+}
+
+// usageObserver captures the usage which the AI proxy reports for the request.
+type usageObserver struct {
+	usage ai.Usage
+	found bool
+	mu    sync.Mutex
+}
+
+func (o *usageObserver) OnUsage(_ context.Context, _ ai.UsageMetadata, usage ai.Usage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.found = true
+	o.usage.PromptTokens += usage.PromptTokens
+	o.usage.CompletionTokens += usage.CompletionTokens
+	o.usage.TotalTokens += usage.TotalTokens
+	o.usage.InputCost += usage.InputCost
+	o.usage.OutputCost += usage.OutputCost
+}
+
+func (o *usageObserver) total() (ai.Usage, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.usage, o.found
+}
+
+// Init registers the ai_quota middleware.
+func Init() error {
+	return middleware.Register([]string{"ai_quota"}, func(option Options) (app.HandlerFunc, error) {
+		if len(option.LimitBy) == 0 {
+			return nil, errors.New("limit_by cannot be empty")
+		}
+
+		switch option.Strategy {
+		case Local, Redis:
+		case "":
+			return nil, errors.New("strategy cannot be empty")
+		default:
+			return nil, fmt.Errorf("strategy '%s' is invalid", option.Strategy)
+		}
+
+		m, err := NewMiddleware(option)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
@@ -0,0 +1,178 @@
+package aiquota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+// serve runs the middleware in front of a stub AI proxy which reports the usage, if any.
+func serve(t *testing.T, m app.HandlerFunc, usage *ai.Usage) *app.RequestContext {
+	t.Helper()
+
+	proxy := func(ctx context.Context, c *app.RequestContext) {
+		if usage == nil {
+			return
+		}
+		for _, observer := range ai.UsageObservers(c) {
+			observer.OnUsage(ctx, ai.UsageMetadata{Model: "gpt-4o"}, *usage)
+		}
+	}
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []ai.Message{
+			{Role: "user", Content: "hello"},
+		},
+	})
+	hzCtx.SetHandlers(app.HandlersChain{m, proxy})
+	hzCtx.Next(context.Background())
+	return hzCtx
+}
+
+func quotaError(t *testing.T, c *app.RequestContext) *ai.AIError {
+	t.Helper()
+	require.Len(t, c.Errors, 1)
+	var aiErr *ai.AIError
+	require.True(t, errors.As(c.Errors[0].Err, &aiErr))
+	return aiErr
+}
+
+func TestAIQuotaMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("ai_quota")
+
+	t.Run("tokens per minute", func(t *testing.T) {
+		m, err := h(map[string]any{
+			"strategy":          "local",
+			"limit_by":          "consumer:tpm",
+			"tokens_per_minute": 100,
+		})
+		require.NoError(t, err)
+
+		c := serve(t, m, &ai.Usage{TotalTokens: 80})
+		assert.Empty(t, c.Errors)
+
+		c = serve(t, m, &ai.Usage{TotalTokens: 30})
+		assert.Empty(t, c.Errors)
+
+		c = serve(t, m, &ai.Usage{TotalTokens: 10})
+		aiErr := quotaError(t, c)
+		assert.Equal(t, "rate_limit_error", aiErr.Type)
+		assert.Equal(t, 429, aiErr.StatusCode)
+		assert.Equal(t, "rate_limit_exceeded", aiErr.Code.UnwrapOr(""))
+		assert.NotEmpty(t, c.Response.Header.Get("Retry-After"))
+		assert.True(t, c.IsAborted())
+	})
+
+	t.Run("requests per minute", func(t *testing.T) {
+		m, err := h(map[string]any{
+			"strategy":            "local",
+			"limit_by":            "consumer:rpm",
+			"requests_per_minute": 2,
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, serve(t, m, nil).Errors)
+		assert.Empty(t, serve(t, m, nil).Errors)
+
+		aiErr := quotaError(t, serve(t, m, nil))
+		assert.Equal(t, "rate_limit_error", aiErr.Type)
+	})
+
+	t.Run("spend per day", func(t *testing.T) {
+		m, err := h(map[string]any{
+			"strategy":      "local",
+			"limit_by":      "consumer:spend",
+			"spend_per_day": 1.0,
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, serve(t, m, &ai.Usage{InputCost: 0.4, OutputCost: 0.2}).Errors)
+		assert.Empty(t, serve(t, m, &ai.Usage{InputCost: 0.4, OutputCost: 0.2}).Errors)
+
+		aiErr := quotaError(t, serve(t, m, &ai.Usage{InputCost: 0.4, OutputCost: 0.2}))
+		assert.Equal(t, "insufficient_quota", aiErr.Type)
+		assert.Equal(t, 429, aiErr.StatusCode)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := h(map[string]any{
+			"strategy": "local",
+			"limit_by": "consumer:none",
+		})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{
+			"strategy":          "local",
+			"tokens_per_minute": 100,
+		})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{
+			"strategy":          "foo",
+			"limit_by":          "consumer:foo",
+			"tokens_per_minute": 100,
+		})
+		require.Error(t, err)
+
+		_, err = h(map[string]any{
+			"strategy":          "redis",
+			"limit_by":          "consumer:foo",
+			"redis_id":          "not_found",
+			"tokens_per_minute": 100,
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestAIQuotaRefund(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Strategy:        Local,
+		LimitBy:         "consumer:refund",
+		TokensPerMinute: 100,
+	})
+	require.NoError(t, err)
+
+	// the request doesn't reach a model, so the estimated prompt tokens are refunded
+	c := serve(t, m.ServeHTTP, nil)
+	assert.Empty(t, c.Errors)
+
+	minute := time.Now().Truncate(time.Minute)
+	tokens, err := m.store.Get(context.Background(), windowKey("consumer:refund", "tpm", minute))
+	require.NoError(t, err)
+	assert.InDelta(t, 0, tokens, 0.0001)
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore()
+
+	val, err := store.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.InDelta(t, 0, val, 0.0001)
+
+	val, err = store.IncrBy(ctx, "foo", 1.5, time.Minute)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, val, 0.0001)
+
+	val, err = store.IncrBy(ctx, "foo", -0.5, time.Minute)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, val, 0.0001)
+
+	_, err = store.IncrBy(ctx, "bar", 1, 10*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	val, err = store.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.InDelta(t, 0, val, 0.0001)
+}
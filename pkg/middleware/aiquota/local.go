@@ -0,0 +1,53 @@
+package aiquota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nite-coder/blackbear/pkg/cache/v2"
+)
+
+// LocalStore stores the quota counters in local memory.
+type LocalStore struct {
+	cache *cache.Cache[string, *counter]
+	mu    sync.Mutex
+}
+
+type counter struct {
+	value float64
+}
+
+// NewLocalStore creates a new LocalStore instance.
+func NewLocalStore() *LocalStore {
+	const defaultCacheCleanupInterval = 10 * time.Minute
+	return &LocalStore{
+		cache: cache.NewCache[string, *counter](defaultCacheCleanupInterval),
+	}
+}
+
+// Get returns the value of the counter, or zero if the counter doesn't exist.
+func (s *LocalStore) Get(_ context.Context, key string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	val, found := s.cache.Get(key)
+	if !found {
+		return 0, nil
+	}
+	return val.value, nil
+}
+
+// IncrBy increments the counter by val and returns the new value.
+func (s *LocalStore) IncrBy(_ context.Context, key string, val float64, ttl time.Duration) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, found := s.cache.Get(key)
+	if !found {
+		item = &counter{}
+		s.cache.PutWithTTL(key, item, ttl)
+	}
+	item.value += val
+	return item.value, nil
+}
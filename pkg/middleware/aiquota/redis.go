@@ -0,0 +1,80 @@
+package aiquota
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nite-coder/blackbear/pkg/cast"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisStore stores the quota counters in redis.
+type RedisStore struct {
+	client redis.UniversalClient
+	script *redis.Script
+}
+
+// NewRedisStore creates a new RedisStore instance.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{
+		client: client,
+		script: redis.NewScript(luaScript),
+	}
+}
+
+const (
+	luaScript = `
+	local key = KEYS[1]
+	local val, ttl = ARGV[1], tonumber(ARGV[2])
+
+	local current = redis.call("INCRBYFLOAT", key, val)
+	if redis.call("PTTL", key) < 0 then
+		redis.call("PEXPIRE", key, ttl)
+	end
+
+	return current
+	`
+)
+
+// Get returns the value of the counter, or zero if the counter doesn't exist.
+func (s *RedisStore) Get(ctx context.Context, key string) (float64, error) {
+	val, err := s.client.Get(ctx, key).Float64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return val, err
+}
+
+// IncrBy increments the counter by val and returns the new value.
+func (s *RedisStore) IncrBy(ctx context.Context, key string, val float64, ttl time.Duration) (float64, error) {
+	var err error
+
+	tracer := otel.Tracer("bifrost")
+	if tracer != nil {
+		spanOptions := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindClient),
+		}
+
+		_, span := tracer.Start(ctx, "ai_quota_redis", spanOptions...)
+
+		defer func() {
+			if err != nil {
+				span.SetStatus(otelcodes.Error, "")
+			}
+
+			span.End()
+		}()
+	}
+
+	result, err := s.script.Run(ctx, s.client, []string{key}, val, max(ttl.Milliseconds(), 1)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := cast.ToFloat64(result)
+	return current, err
+}
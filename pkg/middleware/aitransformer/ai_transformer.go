@@ -58,13 +58,26 @@ func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 
 	path := string(c.Request.Path())
 	family := ai.FamilyChat
-	if strings.HasSuffix(path, "/responses") {
+	switch {
+	case strings.HasSuffix(path, "/responses"):
 		family = ai.FamilyResponses
+	case strings.HasSuffix(path, "/embeddings"):
+		family = ai.FamilyEmbeddings
+	case strings.HasSuffix(path, "/images/generations"):
+		family = ai.FamilyImages
+	case strings.HasSuffix(path, "/audio/transcriptions"):
+		family = ai.FamilyTranscriptions
 	}
 
 	switch family {
 	case ai.FamilyChat:
-		chatReq, err := adapter.ToChatRequest(c.Request.Body())
+		var chatReq *ai.ChatRequest
+		var err error
+		if pathAdapter, ok := adapter.(ai.PathClientAdapter); ok {
+			chatReq, err = pathAdapter.ToChatRequestWithPath(path, c.Request.Body())
+		} else {
+			chatReq, err = adapter.ToChatRequest(c.Request.Body())
+		}
 		if err != nil {
 			abortWithAIError(c, adapter, err)
 			return
@@ -83,6 +96,37 @@ func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 		c.Set(ai.ContextKeyVirtualModelName, respReq.Model)
 		c.Set(variable.Model, respReq.Model)
 		c.Set(ai.ContextKeyAIFamily, ai.FamilyResponses)
+	case ai.FamilyEmbeddings:
+		embeddingReq, err := ai.ParseEmbeddingRequest(c.Request.Body())
+		if err != nil {
+			abortWithAIError(c, adapter, err)
+			return
+		}
+		c.Set(ai.ContextKeyEmbeddingRequest, embeddingReq)
+		c.Set(ai.ContextKeyVirtualModelName, embeddingReq.Model)
+		c.Set(variable.Model, embeddingReq.Model)
+		c.Set(ai.ContextKeyAIFamily, ai.FamilyEmbeddings)
+	case ai.FamilyImages:
+		imageReq, err := ai.ParseImageRequest(c.Request.Body())
+		if err != nil {
+			abortWithAIError(c, adapter, err)
+			return
+		}
+		c.Set(ai.ContextKeyImageRequest, imageReq)
+		c.Set(ai.ContextKeyVirtualModelName, imageReq.Model)
+		c.Set(variable.Model, imageReq.Model)
+		c.Set(ai.ContextKeyAIFamily, ai.FamilyImages)
+	case ai.FamilyTranscriptions:
+		transcriptionReq, err := ai.ParseTranscriptionRequest(
+			string(c.Request.Header.ContentType()), c.Request.Body())
+		if err != nil {
+			abortWithAIError(c, adapter, err)
+			return
+		}
+		c.Set(ai.ContextKeyTranscriptionRequest, transcriptionReq)
+		c.Set(ai.ContextKeyVirtualModelName, transcriptionReq.Model)
+		c.Set(variable.Model, transcriptionReq.Model)
+		c.Set(ai.ContextKeyAIFamily, ai.FamilyTranscriptions)
 	default:
 	}
 
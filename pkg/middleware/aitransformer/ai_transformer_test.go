@@ -1,9 +1,11 @@
 package aitransformer
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
+	"mime/multipart"
 	"sync"
 	"testing"
 
@@ -53,6 +55,10 @@ func (m *MockClientAdapter) StreamConverter(stream io.ReadCloser) io.ReadCloser
 	return stream
 }
 
+func (m *MockClientAdapter) ToClientStreamError(_ *ai.AIError) ([]byte, error) {
+	return nil, nil
+}
+
 func (m *MockClientAdapter) ToClientError(err *ai.AIError) (any, error) {
 	if m.toClientErrorFunc != nil {
 		return m.toClientErrorFunc(err)
@@ -124,6 +130,81 @@ func TestAITransformer_FamilyDetection(t *testing.T) {
 	assert.Equal(t, ai.FamilyChat, familyVal)
 }
 
+func TestAITransformer_MediaFamilies(t *testing.T) {
+	setupMockClient(t)
+	m := NewMiddleware(Options{Format: "mock-client"})
+
+	t.Run("embeddings", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetBody([]byte(`{"model":"embed","input":["hello","world"]}`))
+		hzCtx.Request.SetRequestURI("/v1/embeddings")
+
+		m.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, ai.FamilyEmbeddings, hzCtx.GetString(ai.ContextKeyAIFamily))
+		assert.Equal(t, "embed", hzCtx.GetString(ai.ContextKeyVirtualModelName))
+		reqVal, exists := hzCtx.Get(ai.ContextKeyEmbeddingRequest)
+		require.True(t, exists)
+		embeddingReq, ok := reqVal.(*ai.EmbeddingRequest)
+		require.True(t, ok)
+		assert.Equal(t, []string{"hello", "world"}, embeddingReq.Inputs())
+	})
+
+	t.Run("images", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetBody([]byte(`{"model":"painter","prompt":"a cat","n":2}`))
+		hzCtx.Request.SetRequestURI("/v1/images/generations")
+
+		m.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, ai.FamilyImages, hzCtx.GetString(ai.ContextKeyAIFamily))
+		assert.Equal(t, "painter", hzCtx.GetString(variable.Model))
+		reqVal, exists := hzCtx.Get(ai.ContextKeyImageRequest)
+		require.True(t, exists)
+		imageReq, ok := reqVal.(*ai.ImageRequest)
+		require.True(t, ok)
+		assert.Equal(t, "a cat", imageReq.Prompt)
+	})
+
+	t.Run("transcriptions", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("model", "whisper"))
+		part, err := writer.CreateFormFile("file", "audio.mp3")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("audio"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetBody(body.Bytes())
+		hzCtx.Request.Header.SetContentTypeBytes([]byte(writer.FormDataContentType()))
+		hzCtx.Request.SetRequestURI("/v1/audio/transcriptions")
+
+		m.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, ai.FamilyTranscriptions, hzCtx.GetString(ai.ContextKeyAIFamily))
+		assert.Equal(t, "whisper", hzCtx.GetString(ai.ContextKeyVirtualModelName))
+		reqVal, exists := hzCtx.Get(ai.ContextKeyTranscriptionRequest)
+		require.True(t, exists)
+		transcriptionReq, ok := reqVal.(*ai.TranscriptionRequest)
+		require.True(t, ok)
+		assert.Equal(t, []byte("audio"), transcriptionReq.File)
+		assert.Equal(t, "audio.mp3", transcriptionReq.FileName)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetBody([]byte(`{"model":"painter"}`))
+		hzCtx.Request.SetRequestURI("/v1/images/generations")
+
+		m.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, 400, hzCtx.Response.StatusCode())
+		assert.True(t, hzCtx.IsAborted())
+	})
+}
+
 func TestAITransformer_EgressError(t *testing.T) {
 	setupMockClient(t)
 	m := NewMiddleware(Options{Format: "mock-client"})
@@ -198,3 +279,21 @@ func TestAITransformer_EgressPlainError(t *testing.T) {
 	bodyStr := string(hzCtx.Response.Body())
 	assert.NotContains(t, bodyStr, "upstream returned HTML error page")
 }
+
+func TestAITransformer_PathClientAdapter(t *testing.T) {
+	m := NewMiddleware(Options{Format: "gemini"})
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetBody([]byte(`{"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`))
+	hzCtx.Request.SetRequestURI("/v1beta/models/gemini-2.5-flash:streamGenerateContent?alt=sse")
+
+	m.ServeHTTP(context.Background(), hzCtx)
+
+	chatReqVal, exists := hzCtx.Get(ai.ContextKeyChatRequest)
+	require.True(t, exists)
+	chatReq, ok := chatReqVal.(*ai.ChatRequest)
+	require.True(t, ok)
+	assert.Equal(t, "gemini-2.5-flash", chatReq.Model)
+	assert.True(t, chatReq.Stream)
+	assert.Equal(t, "gemini-2.5-flash", hzCtx.GetString(variable.Model))
+}
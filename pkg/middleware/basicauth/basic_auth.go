@@ -0,0 +1,289 @@
+package basicauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/nite-coder/bifrost/internal/pkg/safety"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	defaultRealm           = "Restricted"
+	defaultRefreshInterval = 900 * time.Millisecond
+)
+
+// Init registers the basic_auth middleware.
+func Init() error {
+	return middleware.Register([]string{"basic_auth"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
+
+// Options defines the configuration for the basic_auth middleware.
+type Options struct {
+	Users                    map[string]string `mapstructure:"users"`
+	Realm                    string            `mapstructure:"realm"`
+	HtpasswdFile             string            `mapstructure:"htpasswd_file"`
+	RejectedHTTPContentType  string            `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string            `mapstructure:"rejected_http_response_body"`
+	RejectedHTTPStatusCode   int               `mapstructure:"rejected_http_status_code"`
+	StripAuthorization       bool              `mapstructure:"strip_authorization"`
+}
+
+// credentials is an immutable snapshot of the users known to the middleware.
+type credentials struct {
+	users map[string]string
+	// verified remembers the digest of the last password verified for each user,
+	// so slow hashes such as bcrypt are not recomputed on every request.
+	verified sync.Map
+}
+
+// Middleware authenticates requests with HTTP Basic authentication.
+type Middleware struct {
+	options   *Options
+	users     *credentials
+	htpasswd  *htpasswdFile
+	challenge string
+}
+
+// NewMiddleware creates a new basic_auth middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if len(options.Users) == 0 && options.HtpasswdFile == "" {
+		return nil, errors.New("users and htpasswd_file cannot both be empty")
+	}
+	if options.Realm == "" {
+		options.Realm = defaultRealm
+	}
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusUnauthorized
+	}
+
+	for user, hash := range options.Users {
+		if !isSupportedHash(hash) {
+			return nil, fmt.Errorf("the password hash of user '%s' is not supported, use bcrypt, apr1 or sha", user)
+		}
+	}
+
+	m := &Middleware{
+		options:   &options,
+		users:     &credentials{users: options.Users},
+		challenge: fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, options.Realm),
+	}
+
+	if options.HtpasswdFile != "" {
+		htpasswd, err := openHtpasswdFile(options.HtpasswdFile)
+		if err != nil {
+			return nil, err
+		}
+		m.htpasswd = htpasswd
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	user, password, ok := basicCredentials(c)
+	if !ok {
+		m.reject(c)
+		return
+	}
+
+	// inline users take precedence over the entries of the htpasswd file
+	creds := m.users
+	hash, found := creds.users[user]
+	if !found && m.htpasswd != nil {
+		creds = m.htpasswd.credentials.Load()
+		hash, found = creds.users[user]
+	}
+	if !found {
+		m.reject(c)
+		return
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	if val, found := creds.verified.Load(user); !found || val.([sha256.Size]byte) != digest {
+		valid, err := verifyPassword(hash, password)
+		if err != nil {
+			log.FromContext(ctx).Warn("basic_auth: failed to verify password", "user", user, "error", err)
+		}
+		if !valid {
+			m.reject(c)
+			return
+		}
+		creds.verified.Store(user, digest)
+	}
+
+	c.Set(variable.AuthUser, user)
+	if m.options.StripAuthorization {
+		c.Request.Header.Del("Authorization")
+	}
+
+	c.Next(ctx)
+}
+
+func (m *Middleware) reject(c *app.RequestContext) {
+	c.Response.Header.Set("WWW-Authenticate", m.challenge)
+	c.SetStatusCode(m.options.RejectedHTTPStatusCode)
+	if len(m.options.RejectedHTTPContentType) > 0 {
+		c.SetContentType(m.options.RejectedHTTPContentType)
+	}
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	}
+	c.Abort()
+}
+
+// htpasswdFile is an htpasswd file shared by all the middlewares which use it, so that each file
+// is watched once however often the configuration is reloaded.
+type htpasswdFile struct {
+	path        string
+	credentials atomic.Pointer[credentials]
+}
+
+var (
+	htpasswdFilesMu sync.Mutex
+	htpasswdFiles   = map[string]*htpasswdFile{}
+)
+
+// openHtpasswdFile loads the htpasswd file and starts watching it, or reloads the file if it is
+// already watched.
+func openHtpasswdFile(name string) (*htpasswdFile, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	htpasswdFilesMu.Lock()
+	defer htpasswdFilesMu.Unlock()
+
+	f, found := htpasswdFiles[path]
+	if !found {
+		f = &htpasswdFile{path: path}
+	}
+
+	err = f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		err = f.watch()
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch htpasswd file: %w", err)
+		}
+		htpasswdFiles[path] = f
+	}
+
+	return f, nil
+}
+
+// load builds a new credentials snapshot from the htpasswd file.
+func (f *htpasswdFile) load() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open htpasswd file: %w", err)
+	}
+	defer file.Close()
+
+	users, err := parseHtpasswd(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse htpasswd file '%s': %w", f.path, err)
+	}
+
+	f.credentials.Store(&credentials{users: users})
+	return nil
+}
+
+// watch reloads the htpasswd file when it changes. The parent directory is watched
+// because many editors and tools replace the file instead of writing it in place.
+func (f *htpasswdFile) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = watcher.Add(filepath.Dir(f.path))
+	if err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	go safety.Go(context.Background(), func() {
+		defer watcher.Close()
+		isUpdate := false
+		timer := time.NewTimer(defaultRefreshInterval)
+		defer timer.Stop()
+		for {
+			timer.Reset(defaultRefreshInterval)
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == f.path &&
+					event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0 {
+					isUpdate = true
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("basic_auth: htpasswd file watcher error", "error", err)
+			case <-timer.C:
+				if !isUpdate {
+					continue
+				}
+				isUpdate = false
+				err := f.load()
+				if err != nil {
+					// keep serving the previous credentials
+					slog.Error("basic_auth: failed to reload htpasswd file", "error", err)
+					continue
+				}
+				slog.Info("basic_auth: htpasswd file reloaded", "path", f.path)
+			}
+		}
+	})
+
+	return nil
+}
+
+func basicCredentials(c *app.RequestContext) (string, string, bool) {
+	auth := string(c.Request.Header.Peek("Authorization"))
+	const prefix = "basic "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+
+	user, password, ok := strings.Cut(string(decoded), ":")
+	if !ok || user == "" {
+		return "", "", false
+	}
+	return user, password, true
+}
@@ -0,0 +1,125 @@
+package basicauth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func serve(m app.HandlerFunc, authorization string) (*app.RequestContext, bool) {
+	c := app.NewContext(0)
+	if authorization != "" {
+		c.Request.Header.Set("Authorization", authorization)
+	}
+
+	called := false
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m, func(_ context.Context, c *app.RequestContext) {
+		called = true
+		c.SetStatusCode(http.StatusOK)
+	}})
+	c.Next(context.Background())
+
+	return c, called
+}
+
+func TestBasicAuth(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("basic_auth")
+
+	m, err := h(map[string]any{
+		"realm": "internal",
+		"users": map[string]any{
+			"alice": "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/",
+		},
+		"strip_authorization":         true,
+		"rejected_http_response_body": "unauthorized",
+	})
+	require.NoError(t, err)
+
+	t.Run("valid credentials", func(t *testing.T) {
+		for range 2 {
+			c, called := serve(m, basicAuth("alice", "myPassword"))
+			assert.True(t, called)
+			assert.Equal(t, "alice", c.GetString(variable.AuthUser))
+			assert.Equal(t, "alice", variable.GetString(variable.AuthUser, c))
+			assert.Empty(t, c.Request.Header.Peek("Authorization"))
+		}
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		for _, authorization := range []string{
+			"",
+			"Bearer token",
+			"Basic !!!",
+			basicAuth("alice", "wrong"),
+			basicAuth("mallory", "myPassword"),
+		} {
+			c, called := serve(m, authorization)
+			assert.False(t, called, authorization)
+			assert.Equal(t, http.StatusUnauthorized, c.Response.StatusCode())
+			assert.Equal(t, `Basic realm="internal", charset="UTF-8"`, string(c.Response.Header.Peek("WWW-Authenticate")))
+			assert.Equal(t, "unauthorized", string(c.Response.Body()))
+		}
+	})
+}
+
+func TestBasicAuthHtpasswdReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	require.NoError(t, os.WriteFile(path, []byte("bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0o600))
+
+	m, err := NewMiddleware(Options{HtpasswdFile: path})
+	require.NoError(t, err)
+
+	_, called := serve(m.ServeHTTP, basicAuth("bob", "password"))
+	assert.True(t, called)
+
+	require.NoError(t, os.WriteFile(path, []byte("alice:$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/\n"), 0o600))
+
+	assert.Eventually(t, func() bool {
+		_, called := serve(m.ServeHTTP, basicAuth("alice", "myPassword"))
+		return called
+	}, 5*time.Second, 100*time.Millisecond)
+
+	_, called = serve(m.ServeHTTP, basicAuth("bob", "password"))
+	assert.False(t, called)
+
+	// middlewares created by configuration reloads share the watched file
+	reloaded, err := NewMiddleware(Options{HtpasswdFile: path})
+	require.NoError(t, err)
+	assert.Same(t, m.htpasswd, reloaded.htpasswd)
+
+	require.NoError(t, os.WriteFile(path, []byte("bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		_, called := serve(reloaded.ServeHTTP, basicAuth("bob", "password"))
+		return called
+	}, 5*time.Second, 100*time.Millisecond)
+	_, called = serve(m.ServeHTTP, basicAuth("bob", "password"))
+	assert.True(t, called)
+}
+
+func TestNewMiddlewareValidation(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	require.ErrorContains(t, err, "cannot both be empty")
+
+	_, err = NewMiddleware(Options{Users: map[string]string{"alice": "plaintext"}})
+	require.ErrorContains(t, err, "not supported")
+
+	_, err = NewMiddleware(Options{HtpasswdFile: filepath.Join(t.TempDir(), "missing")})
+	require.ErrorContains(t, err, "failed to open htpasswd file")
+}
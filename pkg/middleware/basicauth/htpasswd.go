@@ -0,0 +1,166 @@
+package basicauth
+
+import (
+	"bufio"
+	"crypto/md5"  //nolint:gosec // required by the APR1 htpasswd format
+	"crypto/sha1" //nolint:gosec // required by the {SHA} htpasswd format
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	apr1Prefix   = "$apr1$"
+	shaPrefix    = "{SHA}"
+	apr1SaltSize = 8
+	apr1Rounds   = 1000
+	md5Size      = 16
+	itoa64       = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var errUnsupportedHash = errors.New("unsupported password hash")
+
+// parseHtpasswd reads username:hash lines. Blank lines and comments are ignored,
+// entries with unsupported hash formats are skipped with a warning.
+func parseHtpasswd(r io.Reader) (map[string]string, error) {
+	users := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, hash, found := strings.Cut(line, ":")
+		if !found || user == "" || hash == "" {
+			return nil, fmt.Errorf("invalid htpasswd entry at line %d", lineNumber)
+		}
+
+		if !isSupportedHash(hash) {
+			slog.Warn("basic_auth: unsupported password hash in htpasswd file, entry is skipped",
+				"user", user,
+				"line", lineNumber,
+			)
+			continue
+		}
+
+		users[user] = hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func isSupportedHash(hash string) bool {
+	return isBcrypt(hash) || strings.HasPrefix(hash, apr1Prefix) || strings.HasPrefix(hash, shaPrefix)
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$")
+}
+
+// verifyPassword checks a password against a bcrypt, APR1 or SHA1 htpasswd hash.
+func verifyPassword(hash string, password string) (bool, error) {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, apr1Prefix):
+		salt := strings.TrimPrefix(hash, apr1Prefix)
+		salt, _, _ = strings.Cut(salt, "$")
+		return constantTimeEqual(apr1(password, salt), hash), nil
+	case strings.HasPrefix(hash, shaPrefix):
+		sum := sha1.Sum([]byte(password)) //nolint:gosec // required by the {SHA} htpasswd format
+		return constantTimeEqual(shaPrefix+base64.StdEncoding.EncodeToString(sum[:]), hash), nil
+	default:
+		return false, errUnsupportedHash
+	}
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// apr1 computes the Apache variant of the MD5-crypt algorithm.
+func apr1(password, salt string) string {
+	if len(salt) > apr1SaltSize {
+		salt = salt[:apr1SaltSize]
+	}
+	pw := []byte(password)
+
+	alt := md5.New() //nolint:gosec // required by the APR1 htpasswd format
+	alt.Write(pw)
+	alt.Write([]byte(salt))
+	alt.Write(pw)
+	altSum := alt.Sum(nil)
+
+	h := md5.New() //nolint:gosec // required by the APR1 htpasswd format
+	h.Write(pw)
+	h.Write([]byte(apr1Prefix))
+	h.Write([]byte(salt))
+	for i := len(pw); i > 0; i -= md5Size {
+		h.Write(altSum[:min(i, md5Size)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := range apr1Rounds {
+		r := md5.New() //nolint:gosec // required by the APR1 htpasswd format
+		if i&1 != 0 {
+			r.Write(pw)
+		} else {
+			r.Write(final)
+		}
+		if i%3 != 0 {
+			r.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			r.Write(pw)
+		}
+		if i&1 != 0 {
+			r.Write(final)
+		} else {
+			r.Write(pw)
+		}
+		final = r.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(apr1Prefix)
+	sb.WriteString(salt)
+	sb.WriteByte('$')
+	for _, idx := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		v := uint32(final[idx[0]])<<16 | uint32(final[idx[1]])<<8 | uint32(final[idx[2]])
+		to64(&sb, v, 4)
+	}
+	to64(&sb, uint32(final[11]), 2)
+
+	return sb.String()
+}
+
+func to64(sb *strings.Builder, v uint32, n int) {
+	for ; n > 0; n-- {
+		sb.WriteByte(itoa64[v&0x3f])
+		v >>= 6
+	}
+}
@@ -0,0 +1,57 @@
+package basicauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{name: "bcrypt", hash: string(bcryptHash), password: "secret", want: true},
+		{name: "bcrypt mismatch", hash: string(bcryptHash), password: "wrong", want: false},
+		{name: "apr1", hash: "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/", password: "myPassword", want: true},
+		{name: "apr1 mismatch", hash: "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/", password: "mypassword", want: false},
+		{name: "sha", hash: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", password: "password", want: true},
+		{name: "sha mismatch", hash: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", password: "Password", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyPassword(tt.hash, tt.password)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = verifyPassword("plaintext", "plaintext")
+	require.ErrorIs(t, err, errUnsupportedHash)
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	content := `
+# comment
+alice:$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/
+bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+carol:rqXexS6ZhobKA
+`
+	users, err := parseHtpasswd(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Contains(t, users, "alice")
+	assert.Contains(t, users, "bob")
+
+	_, err = parseHtpasswd(strings.NewReader("invalid"))
+	require.ErrorContains(t, err, "line 1")
+}
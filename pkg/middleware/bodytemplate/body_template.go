@@ -0,0 +1,253 @@
+package bodytemplate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/nite-coder/blackbear/pkg/cast"
+
+	"github.com/nite-coder/bifrost/internal/pkg/jsonbody"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+// numbers are decoded as json.Number, so large integers are rendered as they are
+var jsonAPI = sonic.Config{UseNumber: true, SortMapKeys: true}.Froze()
+
+var funcs = template.FuncMap{
+	"json": toJSON,
+}
+
+// TemplateOptions defines the template used to render a body.
+type TemplateOptions struct {
+	// Template is a Go text/template which renders the new body.
+	Template string `mapstructure:"template"`
+	// ContentType replaces the content type of the rendered body, if set.
+	ContentType string `mapstructure:"content_type"`
+}
+
+// RequestTemplateOptions defines the template used to render a request body.
+type RequestTemplateOptions struct {
+	TemplateOptions `mapstructure:",squash"`
+	// Methods are the request methods whose bodies are rendered, requests with other methods
+	// such as GET or CORS preflight requests are passed through.
+	Methods []string `mapstructure:"methods"`
+}
+
+// Options defines the configuration for the body_template middleware.
+type Options struct {
+	Request  RequestTemplateOptions `mapstructure:"request"`
+	Response TemplateOptions        `mapstructure:"response"`
+}
+
+// Middleware is a middleware that rewrites the request and response bodies with templates.
+type Middleware struct {
+	options  *Options
+	request  *template.Template
+	response *template.Template
+	methods  map[string]struct{}
+}
+
+// templateData is the data passed to a template.
+type templateData struct {
+	c *app.RequestContext
+	// Body is the parsed JSON body, it is nil when the body is not a JSON document.
+	Body any
+	// Raw is the original body.
+	Raw string
+	// StatusCode is the status code of the upstream response, it is 0 for requests.
+	StatusCode int
+}
+
+// Var returns the value of a variable or directive, e.g. `{{ .Var "$auth.claim.tenant" }}`.
+func (d *templateData) Var(key string) any {
+	val, _ := variable.Get(key, d.c)
+	return val
+}
+
+// NewMiddleware creates a new body_template middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Request.Template == "" && options.Response.Template == "" {
+		return nil, errors.New("request.template or response.template must be set for body_template middleware")
+	}
+
+	if len(options.Request.Methods) == 0 {
+		options.Request.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+	}
+
+	m := &Middleware{
+		options: &options,
+		methods: make(map[string]struct{}, len(options.Request.Methods)),
+	}
+	for _, method := range options.Request.Methods {
+		m.methods[strings.ToUpper(method)] = struct{}{}
+	}
+
+	var err error
+	if options.Request.Template != "" {
+		m.request, err = compile("request", options.Request.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Response.Template != "" {
+		m.response, err = compile("response", options.Response.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	if m.request != nil && !m.rewriteRequest(ctx, c) {
+		return
+	}
+
+	if m.response == nil {
+		return
+	}
+
+	c.Next(ctx)
+	m.rewriteResponse(ctx, c)
+}
+
+func (m *Middleware) rewriteRequest(ctx context.Context, c *app.RequestContext) bool {
+	if _, found := m.methods[cast.B2S(c.Request.Method())]; !found {
+		return true
+	}
+
+	// compressed bodies cannot be rendered
+	if len(c.Request.Header.Peek("Content-Encoding")) > 0 {
+		return true
+	}
+
+	body := c.Request.Body()
+	data := newTemplateData(c, body, jsonbody.IsJSON(c.Request.Header.ContentType()))
+
+	var buf bytes.Buffer
+	err := m.request.Execute(&buf, data)
+	if err != nil {
+		log.FromContext(ctx).Warn("body_template: failed to render request body", "error", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return false
+	}
+
+	c.Request.SetBody(buf.Bytes())
+	c.Request.Header.SetContentLength(buf.Len())
+	if m.options.Request.ContentType != "" {
+		c.Request.Header.SetContentTypeBytes([]byte(m.options.Request.ContentType))
+	}
+	return true
+}
+
+func (m *Middleware) rewriteResponse(ctx context.Context, c *app.RequestContext) {
+	if len(c.Response.Header.ContentEncoding()) > 0 || c.Request.Header.IsHead() {
+		return
+	}
+
+	statusCode := c.Response.StatusCode()
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		return
+	}
+
+	// streamed responses such as server-sent events are passed through as they are, reading
+	// them would buffer the whole stream
+	if c.Response.IsBodyStream() || !isRenderable(c.Response.Header.ContentType()) {
+		return
+	}
+
+	body := c.Response.Body()
+	data := newTemplateData(c, body, jsonbody.IsJSON(c.Response.Header.ContentType()))
+	data.StatusCode = statusCode
+
+	var buf bytes.Buffer
+	err := m.response.Execute(&buf, data)
+	if err != nil {
+		log.FromContext(ctx).Warn("body_template: failed to render response body", "error", err)
+		c.Response.ResetBody()
+		c.Response.Header.SetContentLength(0)
+		c.Response.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+
+	c.Response.SetBody(buf.Bytes())
+	c.Response.Header.SetContentLength(buf.Len())
+	if m.options.Response.ContentType != "" {
+		c.Response.Header.SetContentType(m.options.Response.ContentType)
+	}
+}
+
+func newTemplateData(c *app.RequestContext, body []byte, isJSON bool) *templateData {
+	data := &templateData{
+		c:   c,
+		Raw: string(body),
+	}
+
+	if isJSON && len(body) > 0 {
+		var parsed any
+		if err := jsonAPI.Unmarshal(body, &parsed); err == nil {
+			data.Body = parsed
+		}
+	}
+	return data
+}
+
+// isRenderable reports whether a response body of the content type can be rendered, only JSON
+// and text bodies are rendered.
+func isRenderable(contentType []byte) bool {
+	if jsonbody.IsJSON(contentType) {
+		return true
+	}
+	mediaType, _, _ := bytes.Cut(contentType, []byte(";"))
+	mediaType = bytes.ToLower(bytes.TrimSpace(mediaType))
+	return bytes.HasPrefix(mediaType, []byte("text/")) && !bytes.Equal(mediaType, []byte("text/event-stream"))
+}
+
+// compile parses a template. Each middleware instance keeps its own templates, so the templates
+// of an old configuration are released with it on reload.
+func compile(name string, source string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template for body_template middleware: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// toJSON encodes a value as JSON, e.g. `{{ json .Body.name }}` renders `"john"`.
+func toJSON(val any) (string, error) {
+	b, err := jsonAPI.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Init registers the body_template middleware.
+func Init() error {
+	err := middleware.RegisterValidator([]string{"body_template"}, func(opts Options) error {
+		_, err := NewMiddleware(opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return middleware.Register([]string{"body_template"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
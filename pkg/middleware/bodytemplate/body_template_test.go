@@ -0,0 +1,217 @@
+package bodytemplate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func TestRequestTemplate(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("body_template")
+
+	handler, err := h(map[string]any{
+		"request": map[string]any{
+			"template":     `{"fullName":{{ json .Body.name }},"id":{{ .Body.id }},"tenant":{{ json (.Var "$var.tenant") }}}`,
+			"content_type": "application/vnd.api.v2+json",
+		},
+	})
+	require.NoError(t, err)
+
+	c := app.NewContext(0)
+	c.Set("tenant", "acme")
+	c.Request.SetMethod(http.MethodPost)
+	c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	c.Request.SetBodyString(`{"name":"john \"jj\"","id":12345678901234567890}`)
+
+	handler(context.Background(), c)
+
+	body := c.Request.Body()
+	assert.JSONEq(t, `{"fullName":"john \"jj\"","id":12345678901234567890,"tenant":"acme"}`, string(body))
+	assert.Equal(t, len(body), c.Request.Header.ContentLength())
+	assert.Equal(t, "application/vnd.api.v2+json", string(c.Request.Header.ContentType()))
+	assert.False(t, c.IsAborted())
+
+	// bodiless methods and CORS preflight requests are passed through
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		c = app.NewContext(0)
+		c.Request.SetMethod(method)
+		handler(context.Background(), c)
+		assert.Empty(t, c.Request.Body(), method)
+		assert.False(t, c.IsAborted(), method)
+	}
+
+	// the methods option replaces the default methods
+	handler, err = h(map[string]any{
+		"request": map[string]any{
+			"template": `{"id":{{ .Body.id }}}`,
+			"methods":  []string{"delete"},
+		},
+	})
+	require.NoError(t, err)
+
+	c = app.NewContext(0)
+	c.Request.SetMethod(http.MethodDelete)
+	c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	c.Request.SetBodyString(`{"id":1,"name":"john"}`)
+	handler(context.Background(), c)
+	assert.JSONEq(t, `{"id":1}`, string(c.Request.Body()))
+
+	c = app.NewContext(0)
+	c.Request.SetMethod(http.MethodPost)
+	c.Request.SetBodyString(`{"id":1,"name":"john"}`)
+	handler(context.Background(), c)
+	assert.Equal(t, `{"id":1,"name":"john"}`, string(c.Request.Body()))
+}
+
+func TestResponseTemplate(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Response: TemplateOptions{
+			Template: `{{ if eq .StatusCode 200 }}{"data":{{ json .Body }}}{{ else }}{"error":{{ json .Raw }}}{{ end }}`,
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "json body",
+			statusCode:  http.StatusOK,
+			contentType: "application/json",
+			body:        `{"id":1}`,
+			want:        `{"data":{"id":1}}`,
+		},
+		{
+			name:        "non json body",
+			statusCode:  http.StatusBadGateway,
+			contentType: "text/plain",
+			body:        "bad gateway",
+			want:        `{"error":"bad gateway"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.NewContext(0)
+			c.Request.SetMethod(http.MethodGet)
+			c.SetIndex(-1)
+			c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+				c.Response.SetStatusCode(tt.statusCode)
+				c.Response.Header.SetContentType(tt.contentType)
+				c.Response.SetBodyString(tt.body)
+			}})
+			c.Next(context.Background())
+
+			assert.Equal(t, tt.statusCode, c.Response.StatusCode())
+			assert.JSONEq(t, tt.want, string(c.Response.Body()))
+			assert.Equal(t, len(tt.want), c.Response.Header.ContentLength())
+		})
+	}
+}
+
+func TestResponsePassThrough(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Response: TemplateOptions{
+			Template: `{"data":{{ json .Raw }}}`,
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		contentType string
+		stream      bool
+	}{
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+		},
+		{
+			name:        "body stream",
+			contentType: "application/json",
+			stream:      true,
+		},
+		{
+			name:        "binary body",
+			contentType: "application/octet-stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "data: hello\n\n"
+			c := app.NewContext(0)
+			c.Request.SetMethod(http.MethodGet)
+			c.SetIndex(-1)
+			c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+				c.Response.Header.SetContentType(tt.contentType)
+				if tt.stream {
+					c.Response.SetBodyStream(strings.NewReader(body), -1)
+					return
+				}
+				c.Response.SetBodyString(body)
+			}})
+			c.Next(context.Background())
+
+			assert.Equal(t, tt.stream, c.Response.IsBodyStream())
+			assert.Equal(t, body, string(c.Response.Body()))
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Request: RequestTemplateOptions{
+			TemplateOptions: TemplateOptions{
+				Template: `{{ .Body.name.first }}`,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var called bool
+	c := app.NewContext(0)
+	c.Request.SetMethod(http.MethodPost)
+	c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	c.Request.SetBodyString(`{"name":"john"}`)
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, func(_ context.Context, _ *app.RequestContext) {
+		called = true
+	}})
+	c.Next(context.Background())
+
+	assert.Equal(t, http.StatusInternalServerError, c.Response.StatusCode())
+	assert.False(t, called)
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	require.Error(t, err)
+
+	_, err = NewMiddleware(Options{Request: RequestTemplateOptions{TemplateOptions: TemplateOptions{Template: "{{ .Body "}}})
+	require.Error(t, err)
+
+	// each instance owns its templates, so they are released with the old configuration on reload
+	m1, err := NewMiddleware(Options{Response: TemplateOptions{Template: "{{ .Raw }}"}})
+	require.NoError(t, err)
+	m2, err := NewMiddleware(Options{Response: TemplateOptions{Template: "{{ .Raw }}"}})
+	require.NoError(t, err)
+	assert.NotSame(t, m1.response, m2.response)
+
+	_ = Init()
+	require.Error(t, middleware.Validate("body_template", map[string]any{
+		"response": map[string]any{"template": "{{ if }}"},
+	}))
+}
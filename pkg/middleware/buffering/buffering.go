@@ -2,10 +2,12 @@ package buffering
 
 import (
 	"context"
+	"io"
 	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
 
+	"github.com/nite-coder/bifrost/internal/pkg/bodylimit"
 	"github.com/nite-coder/bifrost/pkg/middleware"
 )
 
@@ -36,18 +38,32 @@ func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 	// Check content length header if present
 	contentLength := c.Request.Header.ContentLength()
 	if contentLength > 0 && int64(contentLength) > m.config.MaxRequestBodySize {
-		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		bodylimit.Reject(c)
 		return
 	}
 
-	// Read the entire body to buffer it
-	body := c.Request.Body()
+	if !c.Request.IsBodyStream() {
+		if int64(len(c.Request.Body())) > m.config.MaxRequestBodySize {
+			bodylimit.Reject(c)
+			return
+		}
+		c.Next(ctx)
+		return
+	}
 
-	// Verify the actual body size (especially important if Content-Length was missing or incorrect)
-	if int64(len(body)) > m.config.MaxRequestBodySize {
-		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+	// Read the entire body to buffer it, but never more than the limit
+	// (especially important if Content-Length was missing or incorrect)
+	reader := bodylimit.NewReader(c.Request.BodyStream(), m.config.MaxRequestBodySize)
+	body, err := io.ReadAll(reader)
+	if reader.Exceeded() {
+		bodylimit.Reject(c)
+		return
+	}
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
+	c.Request.SetBody(body)
 
 	c.Next(ctx)
 }
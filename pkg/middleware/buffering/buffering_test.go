@@ -81,6 +81,7 @@ func TestBufferingMiddleware(t *testing.T) {
 		w := ut.PerformRequest(router, "POST", "/", &ut.Body{Body: strings.NewReader(body), Len: -1})
 
 		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.True(t, w.Result().Header.ConnectionClose())
 	})
 
 	t.Run("should work when registered via factory", func(t *testing.T) {
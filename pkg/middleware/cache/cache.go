@@ -0,0 +1,613 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/nite-coder/blackbear/pkg/cast"
+
+	"github.com/nite-coder/bifrost/internal/pkg/safety"
+	"github.com/nite-coder/bifrost/pkg/connector/redis"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	// StatusHit means the response is served from a fresh cache entry.
+	StatusHit = "HIT"
+	// StatusMiss means the response is fetched from the upstream.
+	StatusMiss = "MISS"
+	// StatusStale means a stale entry is served because of stale-while-revalidate or stale-if-error.
+	StatusStale = "STALE"
+	// StatusRevalidated means a stale entry is served after the upstream confirmed it with a 304.
+	StatusRevalidated = "REVALIDATED"
+	// StatusBypass means the request is not eligible for caching.
+	StatusBypass = "BYPASS"
+
+	allocationFactor      = 2
+	defaultCacheKey       = "$http.request.method:$http.request.host$http.request.uri"
+	defaultMaxEntries     = 10000
+	defaultMaxBodySize    = 1024 * 1024
+	defaultKeepStale      = 10 * time.Minute
+	defaultLockTimeout    = 5 * time.Second
+	headerConnection      = "Connection"
+	headerKeepAlive       = "Keep-Alive"
+	headerTransferEncode  = "Transfer-Encoding"
+	headerContentLength   = "Content-Length"
+	headerUpgradeProtocol = "Upgrade"
+)
+
+// StrategyMode defines where cache entries are stored.
+type StrategyMode string
+
+const (
+	// Memory strategy stores entries in a bounded in-memory LRU.
+	Memory StrategyMode = "memory"
+	// Redis strategy stores entries in redis.
+	Redis StrategyMode = "redis"
+)
+
+// Options defines the configuration for the cache middleware.
+type Options struct {
+	Strategy             StrategyMode  `mapstructure:"strategy"`
+	RedisID              string        `mapstructure:"redis_id"`
+	CacheKey             string        `mapstructure:"cache_key"`
+	Methods              []string      `mapstructure:"methods"`
+	StatusCodes          []int         `mapstructure:"status_codes"`
+	MaxEntries           int           `mapstructure:"max_entries"`
+	MaxBodySize          int           `mapstructure:"max_body_size"`
+	DefaultTTL           time.Duration `mapstructure:"default_ttl"`
+	MaxTTL               time.Duration `mapstructure:"max_ttl"`
+	StaleWhileRevalidate time.Duration `mapstructure:"stale_while_revalidate"`
+	StaleIfError         time.Duration `mapstructure:"stale_if_error"`
+	KeepStale            time.Duration `mapstructure:"keep_stale"`
+	LockTimeout          time.Duration `mapstructure:"lock_timeout"`
+}
+
+// call is an in-flight upstream request which concurrent misses of the same key wait for.
+type call struct {
+	done       chan struct{}
+	entry      *Entry
+	variantKey string
+	status     string
+}
+
+// Middleware is a middleware that caches upstream responses.
+type Middleware struct {
+	options       *Options
+	store         Store
+	methods       map[string]struct{}
+	statusCodes   map[int]struct{}
+	calls         map[string]*call
+	keyDirectives []string
+	mu            sync.Mutex
+}
+
+// NewMiddleware creates a new cache middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Strategy == "" {
+		options.Strategy = Memory
+	}
+	if options.CacheKey == "" {
+		options.CacheKey = defaultCacheKey
+	}
+	if len(options.Methods) == 0 {
+		options.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if len(options.StatusCodes) == 0 {
+		options.StatusCodes = []int{
+			http.StatusOK,
+			http.StatusNonAuthoritativeInfo,
+			http.StatusNoContent,
+			http.StatusMultipleChoices,
+			http.StatusMovedPermanently,
+			http.StatusPermanentRedirect,
+			http.StatusNotFound,
+			http.StatusGone,
+		}
+	}
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = defaultMaxEntries
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = defaultMaxBodySize
+	}
+	if options.KeepStale == 0 {
+		options.KeepStale = defaultKeepStale
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = defaultLockTimeout
+	}
+	if options.DefaultTTL < 0 || options.MaxTTL < 0 || options.StaleWhileRevalidate < 0 || options.StaleIfError < 0 {
+		return nil, errors.New("default_ttl, max_ttl, stale_while_revalidate and stale_if_error cannot be negative")
+	}
+
+	m := &Middleware{
+		options:       &options,
+		methods:       make(map[string]struct{}, len(options.Methods)),
+		statusCodes:   make(map[int]struct{}, len(options.StatusCodes)),
+		calls:         make(map[string]*call),
+		keyDirectives: variable.ParseDirectives(options.CacheKey),
+	}
+	for _, method := range options.Methods {
+		m.methods[strings.ToUpper(method)] = struct{}{}
+	}
+	for _, code := range options.StatusCodes {
+		m.statusCodes[code] = struct{}{}
+	}
+
+	switch options.Strategy {
+	case Memory:
+		store, err := NewMemoryStore(options.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		m.store = store
+	case Redis:
+		client, found := redis.Get(options.RedisID)
+		if !found {
+			return nil, fmt.Errorf("redis id '%s' not found for cache middleware", options.RedisID)
+		}
+		m.store = NewRedisStore(client)
+	default:
+		return nil, fmt.Errorf("strategy '%s' is invalid for cache middleware", options.Strategy)
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	if _, found := m.methods[cast.B2S(c.Request.Method())]; !found ||
+		strings.Contains(c.Request.Header.Get(headerConnection), headerUpgradeProtocol) {
+		m.bypass(ctx, c)
+		return
+	}
+
+	reqCC := requestCacheControl(&c.Request)
+	if reqCC.noStore {
+		m.bypass(ctx, c)
+		return
+	}
+
+	key := m.cacheKey(c)
+	now := timecache.Now()
+
+	entry := m.lookup(ctx, c, key)
+	if entry != nil && !reqCC.noCache {
+		if entry.isFresh(now) && (!reqCC.hasMaxAge || entry.age(now) <= reqCC.maxAge) {
+			m.serve(c, entry, StatusHit, now)
+			return
+		}
+
+		if entry.canServeStaleWhileRevalidate(now) {
+			m.revalidateInBackground(ctx, c, key, entry)
+			m.serve(c, entry, StatusStale, now)
+			return
+		}
+	}
+
+	m.fetch(ctx, c, key, entry)
+}
+
+func (m *Middleware) bypass(ctx context.Context, c *app.RequestContext) {
+	c.Set(variable.CacheStatus, StatusBypass)
+	c.Next(ctx)
+}
+
+func (m *Middleware) cacheKey(c *app.RequestContext) string {
+	if len(m.keyDirectives) == 0 {
+		return m.options.CacheKey
+	}
+
+	replacements := make([]string, 0, len(m.keyDirectives)*allocationFactor)
+	for _, key := range m.keyDirectives {
+		replacements = append(replacements, key, variable.GetString(key, c))
+	}
+	return strings.NewReplacer(replacements...).Replace(m.options.CacheKey)
+}
+
+// variantKey returns the key of the response variant selected by the Vary header names.
+func variantKey(key string, vary []string, c *app.RequestContext) string {
+	if len(vary) == 0 {
+		return key
+	}
+
+	var sb strings.Builder
+	sb.WriteString(key)
+	for _, name := range vary {
+		sb.WriteString("|")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(c.Request.Header.Get(name))
+	}
+	return sb.String()
+}
+
+func (m *Middleware) lookup(ctx context.Context, c *app.RequestContext, key string) *Entry {
+	entry, err := m.store.Get(ctx, key)
+	if err != nil {
+		log.FromContext(ctx).WarnContext(ctx, "cache: failed to get entry", slog.String("error", err.Error()))
+		return nil
+	}
+	if entry == nil || !entry.isVaryMarker() {
+		return entry
+	}
+
+	entry, err = m.store.Get(ctx, variantKey(key, entry.Vary, c))
+	if err != nil {
+		log.FromContext(ctx).WarnContext(ctx, "cache: failed to get entry", slog.String("error", err.Error()))
+		return nil
+	}
+	return entry
+}
+
+// fetch sends the request to the upstream. Concurrent misses of the same key
+// wait for the first request and share its response instead of reaching the upstream.
+func (m *Middleware) fetch(ctx context.Context, c *app.RequestContext, key string, stale *Entry) {
+	m.mu.Lock()
+	if inflight, found := m.calls[key]; found {
+		m.mu.Unlock()
+
+		timer := time.NewTimer(m.options.LockTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-inflight.done:
+			if inflight.entry != nil && variantKey(key, inflight.entry.Vary, c) == inflight.variantKey {
+				status := inflight.status
+				if status != StatusStale {
+					status = StatusHit
+				}
+				m.serve(c, inflight.entry, status, timecache.Now())
+				return
+			}
+		case <-timer.C:
+		}
+
+		_, _, _ = m.forward(ctx, c, key, stale)
+		return
+	}
+
+	cl := &call{done: make(chan struct{})}
+	m.calls[key] = cl
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.calls, key)
+		m.mu.Unlock()
+		close(cl.done)
+	}()
+
+	cl.entry, cl.variantKey, cl.status = m.forward(ctx, c, key, stale)
+}
+
+// forward runs the rest of the handlers and stores the upstream response when it is cacheable.
+// The stale entry, if any, is revalidated with the upstream using its validators.
+func (m *Middleware) forward(ctx context.Context, c *app.RequestContext, key string, stale *Entry) (*Entry, string, string) {
+	// conditional headers of the client are answered by the cache, so the upstream
+	// always returns a full response which can be stored
+	ifNoneMatch := c.Request.Header.Get(headerIfNoneMatch)
+	ifModifiedSince := c.Request.Header.Get(headerIfModifiedSince)
+	c.Request.Header.Del(headerIfNoneMatch)
+	c.Request.Header.Del(headerIfModifiedSince)
+
+	revalidate := stale != nil && stale.hasValidators()
+	if revalidate {
+		if stale.ETag != "" {
+			c.Request.Header.Set(headerIfNoneMatch, stale.ETag)
+		}
+		if stale.LastModified != "" {
+			c.Request.Header.Set(headerIfModifiedSince, stale.LastModified)
+		}
+	}
+
+	c.Next(ctx)
+
+	c.Request.Header.Del(headerIfNoneMatch)
+	c.Request.Header.Del(headerIfModifiedSince)
+	if ifNoneMatch != "" {
+		c.Request.Header.Set(headerIfNoneMatch, ifNoneMatch)
+	}
+	if ifModifiedSince != "" {
+		c.Request.Header.Set(headerIfModifiedSince, ifModifiedSince)
+	}
+
+	now := timecache.Now()
+	statusCode := c.Response.StatusCode()
+
+	if revalidate && statusCode == http.StatusNotModified {
+		entry := m.refresh(stale, &c.Response.Header, now)
+		vk := variantKey(key, entry.Vary, c)
+		m.save(ctx, key, vk, entry)
+		c.Response.Header.Reset()
+		m.serve(c, entry, StatusRevalidated, now)
+		return entry, vk, StatusRevalidated
+	}
+
+	if stale != nil && statusCode >= http.StatusInternalServerError && stale.canServeStaleIfError(now) {
+		c.Response.Header.Reset()
+		m.serve(c, stale, StatusStale, now)
+		return stale, variantKey(key, stale.Vary, c), StatusStale
+	}
+
+	c.Set(variable.CacheStatus, StatusMiss)
+
+	entry := m.newEntry(c, now)
+	if entry == nil {
+		return nil, "", StatusMiss
+	}
+
+	vk := variantKey(key, entry.Vary, c)
+	m.save(ctx, key, vk, entry)
+
+	if entry.StatusCode == http.StatusOK && notModified(&c.Request, entry) {
+		c.Response.ResetBody()
+		c.Response.SetStatusCode(http.StatusNotModified)
+	}
+	return entry, vk, StatusMiss
+}
+
+// revalidateInBackground refreshes the stale entry with a copy of the request,
+// while the stale entry is served to the client.
+func (m *Middleware) revalidateInBackground(ctx context.Context, c *app.RequestContext, key string, stale *Entry) {
+	m.mu.Lock()
+	_, inflight := m.calls[key]
+	m.mu.Unlock()
+	if inflight {
+		return
+	}
+
+	handlers := c.Handlers()
+	cp := c.Copy()
+	cp.SetHandlers(handlers[int(c.GetIndex())+1:])
+	cp.SetIndex(-1)
+
+	bgCtx := context.WithoutCancel(ctx)
+	go safety.Go(bgCtx, func() {
+		m.fetch(bgCtx, cp, key, stale)
+	})
+}
+
+// newEntry builds an entry from the upstream response, it returns nil when the response cannot be stored.
+func (m *Middleware) newEntry(c *app.RequestContext, now time.Time) *Entry {
+	resp := &c.Response
+
+	if _, found := m.statusCodes[resp.StatusCode()]; !found {
+		return nil
+	}
+
+	cc := parseCacheControl(resp.Header.Peek(headerCacheControl))
+	if cc.noStore || cc.private {
+		return nil
+	}
+
+	// responses to authorized requests are only stored when explicitly allowed for shared caches
+	if len(c.Request.Header.Peek(headerAuthorization)) > 0 && !cc.public && !cc.hasSMaxAge && !cc.mustRevalidate {
+		return nil
+	}
+
+	if len(resp.Header.Peek(headerSetCookie)) > 0 ||
+		bytes.HasPrefix(resp.Header.ContentType(), []byte(contentTypeEventStream)) {
+		return nil
+	}
+
+	vary := parseVary(resp.Header.Get(headerVary))
+	if slices.Contains(vary, "*") {
+		return nil
+	}
+
+	entry := &Entry{
+		StoredAt:             now,
+		StatusCode:           resp.StatusCode(),
+		ETag:                 resp.Header.Get(headerETag),
+		LastModified:         resp.Header.Get(headerLastModified),
+		Vary:                 vary,
+		StaleWhileRevalidate: m.options.StaleWhileRevalidate,
+		StaleIfError:         m.options.StaleIfError,
+	}
+	m.applyCacheControl(entry, &resp.Header, cc, now)
+
+	if age, ok := parseSeconds(resp.Header.Get(headerAge)); ok {
+		entry.InitialAge = age
+	}
+
+	if m.ttl(entry) <= 0 {
+		return nil
+	}
+
+	body, ok := readBody(resp, m.options.MaxBodySize)
+	if !ok {
+		return nil
+	}
+	entry.Body = body
+
+	resp.Header.VisitAll(func(k, v []byte) {
+		key := string(k)
+		if isHopByHopHeader(key) {
+			return
+		}
+		entry.Headers = append(entry.Headers, Header{Key: key, Value: string(v)})
+	})
+
+	return entry
+}
+
+func (m *Middleware) applyCacheControl(entry *Entry, header headerGetter, cc cacheControl, now time.Time) {
+	if cc.noCache {
+		entry.FreshFor = 0
+	} else {
+		entry.FreshFor = m.freshness(header, cc, now)
+	}
+	if cc.hasSWR {
+		entry.StaleWhileRevalidate = cc.staleWhileRevalidate
+	}
+	if cc.hasSIE {
+		entry.StaleIfError = cc.staleIfError
+	}
+	entry.MustRevalidate = cc.noCache || cc.mustRevalidate
+}
+
+// refresh returns a copy of the stale entry updated with the headers of a 304 response.
+func (m *Middleware) refresh(stale *Entry, header *protocol.ResponseHeader, now time.Time) *Entry {
+	entry := *stale
+	entry.StoredAt = now
+	entry.InitialAge = 0
+
+	// a 304 response has no body, so its Content-Type is not the one of the stored response
+	var updated Headers
+	header.VisitAll(func(k, v []byte) {
+		key := string(k)
+		if isHopByHopHeader(key) || strings.EqualFold(key, "Content-Type") {
+			return
+		}
+		updated = append(updated, Header{Key: key, Value: string(v)})
+	})
+
+	entry.Headers = slices.DeleteFunc(slices.Clone(stale.Headers), func(h Header) bool {
+		return updated.Get(h.Key) != ""
+	})
+	entry.Headers = append(entry.Headers, updated...)
+
+	if etag := entry.Headers.Get(headerETag); etag != "" {
+		entry.ETag = etag
+	}
+	if lastModified := entry.Headers.Get(headerLastModified); lastModified != "" {
+		entry.LastModified = lastModified
+	}
+
+	entry.StaleWhileRevalidate = m.options.StaleWhileRevalidate
+	entry.StaleIfError = m.options.StaleIfError
+	m.applyCacheControl(&entry, entry.Headers, parseCacheControl([]byte(entry.Headers.Get(headerCacheControl))), now)
+
+	return &entry
+}
+
+// ttl returns how long the entry is kept in the store. Entries are kept after they
+// become stale for the stale windows, and for revalidation when they have validators.
+func (m *Middleware) ttl(entry *Entry) time.Duration {
+	keep := max(entry.StaleWhileRevalidate, entry.StaleIfError)
+	if entry.hasValidators() {
+		keep = max(keep, m.options.KeepStale)
+	}
+	return entry.FreshFor - entry.InitialAge + keep
+}
+
+func (m *Middleware) save(ctx context.Context, key string, vk string, entry *Entry) {
+	ttl := m.ttl(entry)
+	if ttl <= 0 {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+
+	if vk != key {
+		err := m.store.Set(ctx, key, &Entry{Vary: entry.Vary}, ttl)
+		if err != nil {
+			logger.WarnContext(ctx, "cache: failed to set entry", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	err := m.store.Set(ctx, vk, entry, ttl)
+	if err != nil {
+		logger.WarnContext(ctx, "cache: failed to set entry", slog.String("error", err.Error()))
+	}
+}
+
+// serve writes the entry to the response and stops the handler chain. Headers of
+// an upstream response must be reset by the caller before.
+func (m *Middleware) serve(c *app.RequestContext, entry *Entry, status string, now time.Time) {
+	c.Response.ResetBody()
+	c.Response.SetStatusCode(entry.StatusCode)
+	for _, h := range entry.Headers {
+		c.Response.Header.Add(h.Key, h.Value)
+	}
+	c.Response.Header.Set(headerAge, strconv.FormatInt(int64(entry.age(now)/time.Second), 10))
+	c.Set(variable.CacheStatus, status)
+
+	switch {
+	case entry.StatusCode == http.StatusOK && notModified(&c.Request, entry):
+		c.Response.SetStatusCode(http.StatusNotModified)
+	case !c.Request.Header.IsHead():
+		c.Response.SetBody(entry.Body)
+	}
+
+	c.Abort()
+}
+
+// readBody reads the response body up to the limit. Streamed bodies larger than
+// the limit are left intact for the client and reported as not cacheable.
+func readBody(resp *protocol.Response, limit int) ([]byte, bool) {
+	if !resp.IsBodyStream() {
+		// the response buffer is reused by later requests, so the cached body must be a copy
+		body := resp.Body()
+		if len(body) > limit {
+			return nil, false
+		}
+		return bytes.Clone(body), true
+	}
+
+	if resp.Header.ContentLength() > limit {
+		return nil, false
+	}
+
+	stream := resp.BodyStream()
+	buf, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+	if err != nil || len(buf) > limit {
+		resp.SetBodyStreamNoReset(&readCloser{
+			Reader: io.MultiReader(bytes.NewReader(buf), stream),
+			stream: stream,
+		}, resp.Header.ContentLength())
+		return nil, false
+	}
+
+	resp.SetBody(buf)
+	return buf, true
+}
+
+// readCloser replays the bytes already read from a stream and still closes the underlying stream.
+type readCloser struct {
+	io.Reader
+	stream io.Reader
+}
+
+func (r *readCloser) Close() error {
+	if closer, ok := r.stream.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func isHopByHopHeader(key string) bool {
+	switch http.CanonicalHeaderKey(key) {
+	case headerConnection, headerKeepAlive, headerTransferEncode, headerContentLength, headerAge, headerUpgradeProtocol:
+		return true
+	}
+	return false
+}
+
+// Init registers the cache middleware.
+func Init() error {
+	return middleware.Register([]string{"cache"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+type upstream struct {
+	handler func(c *app.RequestContext)
+	calls   atomic.Int32
+}
+
+func (u *upstream) ServeHTTP(_ context.Context, c *app.RequestContext) {
+	u.calls.Add(1)
+	u.handler(c)
+}
+
+func doRequest(m *Middleware, u *upstream, method string, headers map[string]string) *app.RequestContext {
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.SetRequestURI("http://example.com/products?page=1")
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, u.ServeHTTP})
+	c.Next(context.Background())
+	return c
+}
+
+func TestCacheHit(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.Header.Set("Cache-Control", "max-age=60")
+		c.Response.Header.SetContentType("application/json")
+		c.Response.SetBodyString(`{"id":1}`)
+	}}
+
+	c := doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, `{"id":1}`, string(c.Response.Body()))
+
+	// the response is reused for another request, the cached body must not change
+	c.Response.Reset()
+	c.Response.SetBodyString("XXXXXXXX")
+
+	c = doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusHit, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, `{"id":1}`, string(c.Response.Body()))
+	assert.Equal(t, "application/json", string(c.Response.Header.ContentType()))
+	assert.Equal(t, "max-age=60", c.Response.Header.Get("Cache-Control"))
+	assert.Equal(t, "0", c.Response.Header.Get("Age"))
+	assert.Equal(t, int32(1), u.calls.Load())
+
+	// HEAD is cached under its own key
+	c = doRequest(m, u, http.MethodHead, nil)
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+
+	// the client asks to revalidate
+	c = doRequest(m, u, http.MethodGet, map[string]string{"Cache-Control": "no-cache"})
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, int32(3), u.calls.Load())
+}
+
+func TestCacheBypass(t *testing.T) {
+	m, err := NewMiddleware(Options{DefaultTTL: time.Minute})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.SetBodyString("ok")
+	}}
+
+	c := doRequest(m, u, http.MethodPost, nil)
+	assert.Equal(t, StatusBypass, variable.GetString(variable.CacheStatus, c))
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"Cache-Control": "no-store"})
+	assert.Equal(t, StatusBypass, variable.GetString(variable.CacheStatus, c))
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"Connection": "Upgrade"})
+	assert.Equal(t, StatusBypass, variable.GetString(variable.CacheStatus, c))
+
+	assert.Equal(t, int32(3), u.calls.Load())
+}
+
+func TestCacheNotStored(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		handler func(c *app.RequestContext)
+	}{
+		{
+			name: "no-store",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "no-store")
+			},
+		},
+		{
+			name: "private",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "private, max-age=60")
+			},
+		},
+		{
+			name: "set cookie",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "max-age=60")
+				c.Response.Header.Set("Set-Cookie", "session=1")
+			},
+		},
+		{
+			name: "vary all",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "max-age=60")
+				c.Response.Header.Set("Vary", "*")
+			},
+		},
+		{
+			name: "status code",
+			handler: func(c *app.RequestContext) {
+				c.Response.SetStatusCode(http.StatusInternalServerError)
+				c.Response.Header.Set("Cache-Control", "max-age=60")
+			},
+		},
+		{
+			name:    "authorized request",
+			headers: map[string]string{"Authorization": "Bearer token"},
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "max-age=60")
+			},
+		},
+		{
+			name: "expired",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Expires", "Thu, 01 Jan 1970 00:00:00 GMT")
+			},
+		},
+		{
+			name: "no freshness",
+			handler: func(c *app.RequestContext) {
+				c.Response.SetBodyString("ok")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMiddleware(Options{})
+			require.NoError(t, err)
+
+			u := &upstream{handler: tt.handler}
+			doRequest(m, u, http.MethodGet, tt.headers)
+			c := doRequest(m, u, http.MethodGet, tt.headers)
+			assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+			assert.Equal(t, int32(2), u.calls.Load())
+		})
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.Header.Set("Cache-Control", "max-age=60")
+		c.Response.Header.Set("Vary", "Accept-Language")
+		c.Response.SetBodyString(c.Request.Header.Get("Accept-Language"))
+	}}
+
+	c := doRequest(m, u, http.MethodGet, map[string]string{"Accept-Language": "en"})
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"Accept-Language": "fr"})
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, "fr", string(c.Response.Body()))
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"Accept-Language": "fr"})
+	assert.Equal(t, StatusHit, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, "fr", string(c.Response.Body()))
+
+	assert.Equal(t, int32(2), u.calls.Load())
+}
+
+func TestCacheConditionalRequest(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		assert.Empty(t, c.Request.Header.Get("If-None-Match"))
+		c.Response.Header.Set("Cache-Control", "max-age=60")
+		c.Response.Header.Set("ETag", `"v1"`)
+		c.Response.SetBodyString("hello")
+	}}
+
+	// the conditional headers of the client are not sent upstream on a miss
+	c := doRequest(m, u, http.MethodGet, map[string]string{"If-None-Match": `"v1"`})
+	assert.Equal(t, http.StatusNotModified, c.Response.StatusCode())
+	assert.Equal(t, `"v1"`, c.Request.Header.Get("If-None-Match"))
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"If-None-Match": `W/"v1"`})
+	assert.Equal(t, StatusHit, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, http.StatusNotModified, c.Response.StatusCode())
+	assert.Empty(t, c.Response.Body())
+
+	c = doRequest(m, u, http.MethodGet, map[string]string{"If-None-Match": `"v0"`})
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "hello", string(c.Response.Body()))
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		if c.Request.Header.Get("If-None-Match") == `"v1"` {
+			c.Response.SetStatusCode(http.StatusNotModified)
+			c.Response.Header.Set("Cache-Control", "max-age=60")
+			return
+		}
+		c.Response.Header.Set("Cache-Control", "no-cache")
+		c.Response.Header.Set("ETag", `"v1"`)
+		c.Response.Header.SetContentType("text/plain")
+		c.Response.SetBodyString("hello")
+	}}
+
+	c := doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+
+	c = doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusRevalidated, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "hello", string(c.Response.Body()))
+	assert.Equal(t, "text/plain", string(c.Response.Header.ContentType()))
+	assert.Equal(t, "max-age=60", c.Response.Header.Get("Cache-Control"))
+
+	// the 304 response made the entry fresh again
+	c = doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusHit, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, int32(2), u.calls.Load())
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	var failed atomic.Bool
+	u := &upstream{handler: func(c *app.RequestContext) {
+		if failed.Load() {
+			c.Response.SetStatusCode(http.StatusBadGateway)
+			return
+		}
+		c.Response.Header.Set("Cache-Control", "max-age=0, stale-if-error=60")
+		c.Response.SetBodyString("hello")
+	}}
+
+	doRequest(m, u, http.MethodGet, nil)
+	failed.Store(true)
+
+	c := doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusStale, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "hello", string(c.Response.Body()))
+	assert.Equal(t, int32(2), u.calls.Load())
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	m, err := NewMiddleware(Options{StaleWhileRevalidate: time.Minute})
+	require.NoError(t, err)
+
+	var version atomic.Int32
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.Header.Set("Cache-Control", "max-age=0")
+		if version.Add(1) == 1 {
+			c.Response.SetBodyString("v1")
+			return
+		}
+		c.Response.SetBodyString("v2")
+	}}
+
+	doRequest(m, u, http.MethodGet, nil)
+
+	c := doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusStale, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, "v1", string(c.Response.Body()))
+
+	assert.Eventually(t, func() bool {
+		c := doRequest(m, u, http.MethodGet, nil)
+		return string(c.Response.Body()) == "v2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCacheCollapseRequests(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		time.Sleep(100 * time.Millisecond)
+		c.Response.Header.Set("Cache-Control", "max-age=60")
+		c.Response.SetBodyString("hello")
+	}}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := doRequest(m, u, http.MethodGet, nil)
+			assert.Equal(t, "hello", string(c.Response.Body()))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), u.calls.Load())
+}
+
+func TestCacheMaxBodySize(t *testing.T) {
+	m, err := NewMiddleware(Options{MaxBodySize: 4})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.Header.Set("Cache-Control", "max-age=60")
+		c.Response.SetBodyStream(io.NopCloser(bytes.NewBufferString("hello world")), -1)
+	}}
+
+	c := doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, "hello world", string(c.Response.Body()))
+
+	c = doRequest(m, u, http.MethodGet, nil)
+	assert.Equal(t, StatusMiss, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, int32(2), u.calls.Load())
+}
+
+func TestCacheKey(t *testing.T) {
+	m, err := NewMiddleware(Options{CacheKey: "$http.request.path", DefaultTTL: time.Minute})
+	require.NoError(t, err)
+
+	u := &upstream{handler: func(c *app.RequestContext) {
+		c.Response.SetBodyString("ok")
+	}}
+
+	doRequest(m, u, http.MethodGet, nil)
+
+	// the query string is not part of the key
+	c := app.NewContext(0)
+	c.Request.SetRequestURI("http://example.com/products?page=2")
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, u.ServeHTTP})
+	c.Next(context.Background())
+
+	assert.Equal(t, StatusHit, variable.GetString(variable.CacheStatus, c))
+	assert.Equal(t, int32(1), u.calls.Load())
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("cache")
+
+	_, err := h(map[string]any{"default_ttl": "1m", "methods": []string{"GET"}})
+	require.NoError(t, err)
+
+	_, err = NewMiddleware(Options{Strategy: "disk"})
+	require.ErrorContains(t, err, "strategy 'disk' is invalid")
+
+	_, err = NewMiddleware(Options{Strategy: Redis, RedisID: "not_found"})
+	require.ErrorContains(t, err, "redis id 'not_found' not found")
+
+	_, err = NewMiddleware(Options{DefaultTTL: -time.Second})
+	require.Error(t, err)
+}
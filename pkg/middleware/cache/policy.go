@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/nite-coder/blackbear/pkg/cast"
+)
+
+const (
+	headerAge               = "Age"
+	headerAuthorization     = "Authorization"
+	headerCacheControl      = "Cache-Control"
+	headerDate              = "Date"
+	headerETag              = "ETag"
+	headerExpires           = "Expires"
+	headerIfModifiedSince   = "If-Modified-Since"
+	headerIfNoneMatch       = "If-None-Match"
+	headerLastModified      = "Last-Modified"
+	headerPragma            = "Pragma"
+	headerSetCookie         = "Set-Cookie"
+	headerVary              = "Vary"
+	contentTypeEventStream  = "text/event-stream"
+	directiveNoCache        = "no-cache"
+	directiveNoStore        = "no-store"
+	directivePrivate        = "private"
+	directivePublic         = "public"
+	directiveMaxAge         = "max-age"
+	directiveSMaxAge        = "s-maxage"
+	directiveMustRevalidate = "must-revalidate"
+	directiveProxyRevalid   = "proxy-revalidate"
+	directiveSWR            = "stale-while-revalidate"
+	directiveSIE            = "stale-if-error"
+)
+
+// cacheControl holds the parsed directives of a Cache-Control header.
+type cacheControl struct {
+	maxAge               time.Duration
+	sMaxAge              time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	hasMaxAge            bool
+	hasSMaxAge           bool
+	hasSWR               bool
+	hasSIE               bool
+	noCache              bool
+	noStore              bool
+	private              bool
+	public               bool
+	mustRevalidate       bool
+}
+
+func parseCacheControl(value []byte) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(cast.B2S(value), ",") {
+		name, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+
+		switch name {
+		case directiveNoCache:
+			cc.noCache = true
+		case directiveNoStore:
+			cc.noStore = true
+		case directivePrivate:
+			cc.private = true
+		case directivePublic:
+			cc.public = true
+		case directiveMustRevalidate, directiveProxyRevalid:
+			cc.mustRevalidate = true
+		case directiveMaxAge:
+			cc.maxAge, cc.hasMaxAge = parseSeconds(val)
+		case directiveSMaxAge:
+			cc.sMaxAge, cc.hasSMaxAge = parseSeconds(val)
+		case directiveSWR:
+			cc.staleWhileRevalidate, cc.hasSWR = parseSeconds(val)
+		case directiveSIE:
+			cc.staleIfError, cc.hasSIE = parseSeconds(val)
+		}
+	}
+	return cc
+}
+
+func parseSeconds(val string) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// requestCacheControl returns the Cache-Control directives of the client request.
+// A legacy "Pragma: no-cache" is treated as "Cache-Control: no-cache".
+func requestCacheControl(req *protocol.Request) cacheControl {
+	cc := parseCacheControl(req.Header.Peek(headerCacheControl))
+	if !cc.noCache && strings.Contains(strings.ToLower(req.Header.Get(headerPragma)), directiveNoCache) {
+		cc.noCache = true
+	}
+	return cc
+}
+
+// headerGetter is implemented by both the upstream response headers and the stored entry headers.
+type headerGetter interface {
+	Get(key string) string
+}
+
+// freshness returns how long a response stays fresh, following the precedence
+// s-maxage, max-age, Expires and then the configured default.
+func (m *Middleware) freshness(header headerGetter, cc cacheControl, now time.Time) time.Duration {
+	var ttl time.Duration
+
+	switch {
+	case cc.hasSMaxAge:
+		ttl = cc.sMaxAge
+	case cc.hasMaxAge:
+		ttl = cc.maxAge
+	case header.Get(headerExpires) != "":
+		expires, err := http.ParseTime(header.Get(headerExpires))
+		if err != nil {
+			// an invalid Expires means the response is already expired
+			return 0
+		}
+		date := now
+		if d, err := http.ParseTime(header.Get(headerDate)); err == nil {
+			date = d
+		}
+		ttl = max(expires.Sub(date), 0)
+	default:
+		ttl = m.options.DefaultTTL
+	}
+
+	if m.options.MaxTTL > 0 && ttl > m.options.MaxTTL {
+		ttl = m.options.MaxTTL
+	}
+	return ttl
+}
+
+// parseVary returns the normalized header names of a Vary header.
+func parseVary(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+// notModified reports whether the conditional headers of the client request
+// match the entry, so a 304 response can be sent instead of the body.
+func notModified(req *protocol.Request, entry *Entry) bool {
+	if inm := req.Header.Get(headerIfNoneMatch); inm != "" {
+		if entry.ETag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || weakETag(tag) == weakETag(entry.ETag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := req.Header.Get(headerIfModifiedSince); ims != "" && entry.LastModified != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		modified, err := http.ParseTime(entry.LastModified)
+		if err != nil {
+			return false
+		}
+		return !modified.After(since)
+	}
+
+	return false
+}
+
+// weakETag strips the weak validator prefix, because If-None-Match uses the weak comparison.
+func weakETag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
+}
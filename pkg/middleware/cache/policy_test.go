@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl([]byte(`public, max-age=60, s-maxage="120", stale-while-revalidate=30, stale-if-error=abc, Must-Revalidate`))
+	assert.True(t, cc.public)
+	assert.True(t, cc.hasMaxAge)
+	assert.Equal(t, time.Minute, cc.maxAge)
+	assert.True(t, cc.hasSMaxAge)
+	assert.Equal(t, 2*time.Minute, cc.sMaxAge)
+	assert.Equal(t, 30*time.Second, cc.staleWhileRevalidate)
+	assert.False(t, cc.hasSIE)
+	assert.True(t, cc.mustRevalidate)
+	assert.False(t, cc.noCache)
+
+	req := &protocol.Request{}
+	req.Header.Set("Pragma", "no-cache")
+	assert.True(t, requestCacheControl(req).noCache)
+}
+
+func TestFreshness(t *testing.T) {
+	m, err := NewMiddleware(Options{DefaultTTL: 10 * time.Second, MaxTTL: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers Headers
+		want    time.Duration
+	}{
+		{
+			name:    "s-maxage wins over max-age",
+			headers: Headers{{Key: "Cache-Control", Value: "max-age=60, s-maxage=120"}},
+			want:    2 * time.Minute,
+		},
+		{
+			name: "expires relative to date",
+			headers: Headers{
+				{Key: "Date", Value: "Mon, 01 Jan 2024 00:00:00 GMT"},
+				{Key: "Expires", Value: "Mon, 01 Jan 2024 00:05:00 GMT"},
+			},
+			want: 5 * time.Minute,
+		},
+		{
+			name:    "invalid expires",
+			headers: Headers{{Key: "Expires", Value: "0"}},
+			want:    0,
+		},
+		{
+			name:    "default ttl",
+			headers: Headers{},
+			want:    10 * time.Second,
+		},
+		{
+			name:    "max ttl",
+			headers: Headers{{Key: "Cache-Control", Value: "max-age=86400"}},
+			want:    time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := parseCacheControl([]byte(tt.headers.Get("Cache-Control")))
+			assert.Equal(t, tt.want, m.freshness(tt.headers, cc, now))
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	entry := &Entry{ETag: `"abc"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}
+
+	req := &protocol.Request{}
+	req.Header.Set("If-None-Match", `"xyz", W/"abc"`)
+	assert.True(t, notModified(req, entry))
+
+	req = &protocol.Request{}
+	req.Header.Set("If-None-Match", `"xyz"`)
+	req.Header.Set("If-Modified-Since", "Mon, 01 Jan 2024 00:00:00 GMT")
+	assert.False(t, notModified(req, entry), "If-None-Match takes precedence")
+
+	req = &protocol.Request{}
+	req.Header.Set("If-Modified-Since", "Tue, 02 Jan 2024 00:00:00 GMT")
+	assert.True(t, notModified(req, entry))
+
+	req = &protocol.Request{}
+	req.Header.Set("If-Modified-Since", "Sun, 31 Dec 2023 00:00:00 GMT")
+	assert.False(t, notModified(req, entry))
+
+	assert.False(t, notModified(&protocol.Request{}, entry))
+}
+
+func TestParseVary(t *testing.T) {
+	assert.Nil(t, parseVary(""))
+	assert.Equal(t, []string{"Accept-Encoding", "Accept-Language"}, parseVary("accept-encoding, Accept-Language,"))
+}
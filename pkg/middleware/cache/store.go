@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+// Header is a single response header stored with a cache entry.
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Headers is the list of response headers stored with a cache entry.
+type Headers []Header
+
+// Get returns the first value of the header, the key is case-insensitive.
+func (h Headers) Get(key string) string {
+	for _, header := range h {
+		if strings.EqualFold(header.Key, key) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// Entry is a cached upstream response.
+type Entry struct {
+	StoredAt             time.Time     `json:"stored_at"`
+	ETag                 string        `json:"etag,omitempty"`
+	LastModified         string        `json:"last_modified,omitempty"`
+	Headers              Headers       `json:"headers,omitempty"`
+	Vary                 []string      `json:"vary,omitempty"`
+	Body                 []byte        `json:"body,omitempty"`
+	StatusCode           int           `json:"status_code"`
+	InitialAge           time.Duration `json:"initial_age,omitempty"`
+	FreshFor             time.Duration `json:"fresh_for"`
+	StaleWhileRevalidate time.Duration `json:"stale_while_revalidate,omitempty"`
+	StaleIfError         time.Duration `json:"stale_if_error,omitempty"`
+	MustRevalidate       bool          `json:"must_revalidate,omitempty"`
+}
+
+// isVaryMarker reports whether the entry only records the Vary header names of
+// a resource, while the responses are stored under a key per variant.
+func (e *Entry) isVaryMarker() bool {
+	return e.StatusCode == 0
+}
+
+func (e *Entry) age(now time.Time) time.Duration {
+	return max(now.Sub(e.StoredAt), 0) + e.InitialAge
+}
+
+func (e *Entry) isFresh(now time.Time) bool {
+	return e.age(now) < e.FreshFor
+}
+
+func (e *Entry) staleFor(now time.Time) time.Duration {
+	return e.age(now) - e.FreshFor
+}
+
+func (e *Entry) canServeStaleWhileRevalidate(now time.Time) bool {
+	return !e.MustRevalidate && e.staleFor(now) < e.StaleWhileRevalidate
+}
+
+func (e *Entry) canServeStaleIfError(now time.Time) bool {
+	return !e.MustRevalidate && e.staleFor(now) < e.StaleIfError
+}
+
+func (e *Entry) hasValidators() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// Store persists cache entries.
+type Store interface {
+	// Get returns the entry of the key, or nil when the key is not found.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Set stores the entry, and the entry is removed after the ttl.
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+}
+
+type memoryItem struct {
+	expiresAt time.Time
+	entry     *Entry
+}
+
+// MemoryStore keeps entries in a bounded in-memory LRU.
+type MemoryStore struct {
+	lru *lru.Cache[string, memoryItem]
+}
+
+// NewMemoryStore creates a new MemoryStore instance holding at most size entries.
+func NewMemoryStore(size int) (*MemoryStore, error) {
+	c, err := lru.New[string, memoryItem](size)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryStore{lru: c}, nil
+}
+
+// Get returns the entry of the key, or nil when the key is not found.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	item, found := s.lru.Get(key)
+	if !found {
+		return nil, nil
+	}
+	if timecache.Now().After(item.expiresAt) {
+		s.lru.Remove(key)
+		return nil, nil
+	}
+	return item.entry, nil
+}
+
+// Set stores the entry, and the entry is removed after the ttl.
+func (s *MemoryStore) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	s.lru.Add(key, memoryItem{
+		expiresAt: timecache.Now().Add(ttl),
+		entry:     entry,
+	})
+	return nil
+}
+
+const redisKeyPrefix = "bifrost:cache:"
+
+// RedisStore keeps entries in Redis, so the cache is shared across gateway instances.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a new RedisStore instance.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get returns the entry of the key, or nil when the key is not found.
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entry := &Entry{}
+	if err := sonic.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Set stores the entry, and the entry is removed after the ttl.
+func (s *RedisStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	data, err := sonic.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
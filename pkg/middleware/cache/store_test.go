@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewMemoryStore(2)
+	require.NoError(t, err)
+
+	entry, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+
+	require.NoError(t, store.Set(ctx, "a", &Entry{StatusCode: 200, Body: []byte("a")}, time.Minute))
+	require.NoError(t, store.Set(ctx, "b", &Entry{StatusCode: 200, Body: []byte("b")}, time.Minute))
+
+	entry, err = store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), entry.Body)
+
+	// "b" is the least recently used entry
+	require.NoError(t, store.Set(ctx, "c", &Entry{StatusCode: 200}, time.Minute))
+	entry, err = store.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+
+	require.NoError(t, store.Set(ctx, "d", &Entry{StatusCode: 200}, 50*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		entry, _ := store.Get(ctx, "d")
+		return entry == nil
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestEntry(t *testing.T) {
+	now := time.Now()
+	entry := &Entry{
+		StoredAt:             now.Add(-30 * time.Second),
+		InitialAge:           10 * time.Second,
+		FreshFor:             time.Minute,
+		StaleWhileRevalidate: 30 * time.Second,
+	}
+	assert.Equal(t, 40*time.Second, entry.age(now))
+	assert.True(t, entry.isFresh(now))
+
+	later := now.Add(40 * time.Second)
+	assert.False(t, entry.isFresh(later))
+	assert.True(t, entry.canServeStaleWhileRevalidate(later))
+	assert.False(t, entry.canServeStaleIfError(later))
+
+	entry.MustRevalidate = true
+	assert.False(t, entry.canServeStaleWhileRevalidate(later))
+}
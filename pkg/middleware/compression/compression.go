@@ -0,0 +1,348 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/compress"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/nite-coder/blackbear/pkg/cast"
+
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+const (
+	encodingGzip          = "gzip"
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerCacheControl    = "Cache-Control"
+	headerContentEncoding = "Content-Encoding"
+	headerContentType     = "Content-Type"
+	headerETag            = "ETag"
+	headerVary            = "Vary"
+	defaultBrotliLevel    = 4
+	defaultZstdLevel      = 3
+)
+
+var (
+	errUnsupportedEncoding = errors.New("unsupported encoding")
+
+	defaultEncodings = []string{encodingBrotli, encodingZstd, encodingGzip, encodingDeflate}
+
+	defaultContentTypes = []string{
+		"text/*",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"application/xhtml+xml",
+		"application/rss+xml",
+		"application/atom+xml",
+		"application/x-javascript",
+		"application/wasm",
+		"image/svg+xml",
+	}
+)
+
+// Options defines the configuration for the compression middleware.
+type Options struct {
+	Level                int      `mapstructure:"level"`
+	BrotliLevel          int      `mapstructure:"brotli_level"`
+	ZstdLevel            int      `mapstructure:"zstd_level"`
+	MinLength            int      `mapstructure:"min_length"`
+	Encodings            []string `mapstructure:"encodings"`
+	ContentTypes         []string `mapstructure:"content_types"`
+	ExcludedPaths        []string `mapstructure:"excluded_paths"`
+	ExcludedPathPrefixes []string `mapstructure:"excluded_path_prefixes"`
+	ExcludedPathRegexes  []string `mapstructure:"excluded_path_regexes"`
+}
+
+// Middleware is a middleware that compresses the response body.
+type Middleware struct {
+	options       *Options
+	pools         map[string]*encoderPool
+	excludedRegex []*regexp.Regexp
+}
+
+// NewMiddleware creates a new CompressionMiddleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Level == 0 {
+		options.Level = compress.CompressDefaultCompression
+	}
+	if options.BrotliLevel == 0 {
+		options.BrotliLevel = defaultBrotliLevel
+	}
+	if options.BrotliLevel < 0 || options.BrotliLevel > 11 {
+		return nil, errors.New("brotli_level must be between 1 and 11")
+	}
+	if options.ZstdLevel == 0 {
+		options.ZstdLevel = defaultZstdLevel
+	}
+	if options.ZstdLevel < 0 || options.ZstdLevel > 22 {
+		return nil, errors.New("zstd_level must be between 1 and 22")
+	}
+	if len(options.Encodings) == 0 {
+		options.Encodings = slices.Clone(defaultEncodings)
+	}
+	if len(options.ContentTypes) == 0 {
+		options.ContentTypes = defaultContentTypes
+	}
+
+	m := &Middleware{
+		options: &options,
+		pools:   make(map[string]*encoderPool, len(options.Encodings)),
+	}
+
+	for i, encoding := range options.Encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		options.Encodings[i] = encoding
+
+		level := options.Level
+		switch encoding {
+		case encodingBrotli:
+			level = options.BrotliLevel
+		case encodingZstd:
+			level = options.ZstdLevel
+		}
+
+		pool, err := newEncoderPool(encoding, level)
+		if err != nil {
+			return nil, fmt.Errorf("encoding '%s' is invalid for compression middleware: %w", encoding, err)
+		}
+		m.pools[encoding] = pool
+	}
+
+	for _, expr := range options.ExcludedPathRegexes {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("excluded_path_regexes '%s' is invalid: %w", expr, err)
+		}
+		m.excludedRegex = append(m.excludedRegex, re)
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	if !m.shouldCompress(&c.Request) {
+		return
+	}
+
+	encoding := m.negotiate(c.Request.Header.Get(headerAcceptEncoding))
+
+	c.Next(ctx)
+
+	if encoding == "" {
+		return
+	}
+
+	addVary(&c.Response)
+
+	if !m.isCompressible(&c.Request, &c.Response) {
+		return
+	}
+
+	pool := m.pools[encoding]
+
+	if c.Response.IsBodyStream() {
+		// unknown length of a chunked body is compressed, as it cannot be checked against min_length
+		if length := c.Response.Header.ContentLength(); length >= 0 && length < m.options.MinLength {
+			return
+		}
+		stream := c.Response.BodyStream()
+		m.setEncodingHeaders(&c.Response, encoding)
+		c.Response.SetBodyStreamNoReset(newStreamReader(stream, pool), -1)
+		return
+	}
+
+	body := c.Response.Body()
+	if len(body) == 0 || len(body) < m.options.MinLength {
+		return
+	}
+
+	compressed, err := pool.compress(body)
+	if err != nil {
+		log.FromContext(ctx).WarnContext(ctx, "compression: failed to compress response body",
+			slog.String("encoding", encoding),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	m.setEncodingHeaders(&c.Response, encoding)
+	c.Response.SetBody(compressed)
+}
+
+func (m *Middleware) shouldCompress(req *protocol.Request) bool {
+	if len(req.Header.Peek(headerAcceptEncoding)) == 0 ||
+		strings.Contains(req.Header.Get("Connection"), "Upgrade") ||
+		strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		return false
+	}
+
+	// Check if the request path is excluded
+	path := cast.B2S(req.URI().RequestURI())
+	for _, excludedPath := range m.options.ExcludedPaths {
+		if strings.EqualFold(path, excludedPath) {
+			return false
+		}
+	}
+
+	reqPath := cast.B2S(req.URI().Path())
+	for _, prefix := range m.options.ExcludedPathPrefixes {
+		if strings.HasPrefix(reqPath, prefix) {
+			return false
+		}
+	}
+	for _, re := range m.excludedRegex {
+		if re.MatchString(reqPath) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// negotiate returns the encoding with the highest q-value in Accept-Encoding.
+// Ties are resolved by the order of the configured encodings. It returns an empty
+// string when none of the configured encodings is acceptable.
+func (m *Middleware) negotiate(acceptEncoding string) string {
+	qvalues := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			name, val, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		qvalues[coding] = q
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, encoding := range m.options.Encodings {
+		q, found := qvalues[encoding]
+		if !found {
+			if wildcard <= 0 {
+				continue
+			}
+			q = wildcard
+			// the wildcard is answered with gzip, which every client sending "*" can decode
+			if encoding != encodingGzip && slices.Contains(m.options.Encodings, encodingGzip) {
+				continue
+			}
+		}
+		if q > bestQ {
+			best = encoding
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// isCompressible reports whether the response can be compressed.
+func (m *Middleware) isCompressible(req *protocol.Request, resp *protocol.Response) bool {
+	// Skip compression if already compressed
+	if len(resp.Header.Peek(headerContentEncoding)) > 0 {
+		return false
+	}
+
+	statusCode := resp.StatusCode()
+	if req.Header.IsHead() || statusCode < http.StatusOK ||
+		statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(resp.Header.Get(headerCacheControl)), "no-transform") {
+		return false
+	}
+
+	return m.isAllowedContentType(resp.Header.ContentType())
+}
+
+func (m *Middleware) isAllowedContentType(contentType []byte) bool {
+	mediaType, _, _ := strings.Cut(cast.B2S(contentType), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" || mediaType == "text/event-stream" {
+		return false
+	}
+
+	for _, allowed := range m.options.ContentTypes {
+		if prefix, found := strings.CutSuffix(allowed, "/*"); found {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Middleware) setEncodingHeaders(resp *protocol.Response, encoding string) {
+	resp.Header.Set(headerContentEncoding, encoding)
+
+	// the compressed representation is no longer byte-for-byte identical
+	if etag := resp.Header.Get(headerETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set(headerETag, "W/"+etag)
+	}
+}
+
+// addVary adds Accept-Encoding to the Vary header, because the response depends on it
+// even when it is not compressed.
+func addVary(resp *protocol.Response) {
+	vary := resp.Header.Peek(headerVary)
+	if len(vary) == 0 {
+		resp.Header.Set(headerVary, headerAcceptEncoding)
+		return
+	}
+
+	for name := range bytes.SplitSeq(vary, []byte(",")) {
+		name = bytes.TrimSpace(name)
+		if bytes.EqualFold(name, []byte(headerAcceptEncoding)) || bytes.Equal(name, []byte("*")) {
+			return
+		}
+	}
+	resp.Header.Set(headerVary, string(vary)+", "+headerAcceptEncoding)
+}
+
+// Init registers the compression middleware.
+func Init() error {
+	return middleware.Register([]string{"compression"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
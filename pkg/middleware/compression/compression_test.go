@@ -0,0 +1,429 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func newRequestContext(method, path string, headers map[string]string, body []byte) *app.RequestContext {
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.URI().SetPath(path)
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	if body != nil {
+		c.Response.SetBody(body)
+	}
+	return c
+}
+
+func TestCompressesResponse(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("compression")
+	mw, err := h(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+	}
+	c := newRequestContext("POST", "/test", headers, []byte("hello"))
+
+	// Set a response body before calling ServeHTTP
+	c.Response.SetBody([]byte("hello world"))
+
+	mw(ctx, c)
+
+	assert.Equal(t, "gzip", string(c.Response.Header.Peek(headerContentEncoding)))
+	assert.Equal(t, "Accept-Encoding", string(c.Response.Header.Peek(headerVary)))
+	assert.NotEqual(t, []byte("hello world"), c.Response.Body())
+	assert.NotEmpty(t, c.Response.Body())
+}
+
+func TestSkipsIfAlreadyCompressed(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+	}
+	c := newRequestContext("GET", "/test", headers, []byte("hello world"))
+
+	// Set a response body before calling ServeHTTP
+	c.Response.SetBody([]byte("hello world"))
+	c.Response.Header.Set(headerContentEncoding, "gzip")
+
+	mw.ServeHTTP(ctx, c)
+
+	// Should not double compress, so body remains unchanged
+	assert.Equal(t, "gzip", string(c.Response.Header.Peek(headerContentEncoding)))
+	assert.Equal(t, []byte("hello world"), c.Response.Body())
+}
+
+func TestSkipsIfNotAcceptedEncoding(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "compress",
+	}
+	c := newRequestContext("GET", "/test", headers, nil)
+
+	c.Response.SetBody([]byte("hello world"))
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+	assert.Equal(t, []byte("hello world"), c.Response.Body())
+}
+
+func TestSkipsIfConnectionUpgrade(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+		"Connection":      "Upgrade",
+	}
+	c := newRequestContext("GET", "/test", headers, nil)
+
+	c.Response.SetBody([]byte("hello world"))
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+	assert.Equal(t, []byte("hello world"), c.Response.Body())
+}
+
+func TestSkipsIfEventStream(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+		"Accept":          "text/event-stream",
+	}
+	c := newRequestContext("GET", "/test", headers, nil)
+
+	c.Response.SetBody([]byte("hello world"))
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+	assert.Equal(t, []byte("hello world"), c.Response.Body())
+}
+
+func TestSkipsIfExcludedPath(t *testing.T) {
+	opts := Options{
+		Level:         1,
+		ExcludedPaths: []string{"/excluded"},
+	}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+	}
+	c := newRequestContext("GET", "/excluded", headers, nil)
+
+	c.Response.SetBody([]byte("hello world"))
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+	assert.Equal(t, []byte("hello world"), c.Response.Body())
+}
+
+func TestNoBody(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "gzip",
+	}
+	c := newRequestContext("POST", "/test", headers, nil)
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+	assert.Equal(t, "Accept-Encoding", string(c.Response.Header.Peek(headerVary)))
+	assert.Empty(t, c.Response.Body())
+}
+
+func TestWildcardAcceptEncoding(t *testing.T) {
+	opts := Options{Level: 1}
+	mw, err := NewMiddleware(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	headers := map[string]string{
+		"Accept-Encoding": "*",
+	}
+	c := newRequestContext("GET", "/test", headers, nil)
+
+	c.Response.SetBody([]byte("hello world"))
+
+	mw.ServeHTTP(ctx, c)
+
+	assert.Equal(t, "gzip", string(c.Response.Header.Peek(headerContentEncoding)))
+	assert.Equal(t, "Accept-Encoding", string(c.Response.Header.Peek(headerVary)))
+	assert.NotEqual(t, []byte("hello world"), c.Response.Body())
+	assert.NotEmpty(t, c.Response.Body())
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case encodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case encodingZstd:
+		dec, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer dec.Close()
+		r = dec
+	case encodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = gr
+	case encodingDeflate:
+		r = flate.NewReader(bytes.NewReader(body))
+	}
+
+	result, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(result)
+}
+
+func TestNegotiate(t *testing.T) {
+	mw, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "gzip, deflate, br, zstd", want: "br"},
+		{acceptEncoding: "gzip, deflate", want: "gzip"},
+		{acceptEncoding: "gzip;q=0.5, zstd;q=0.8", want: "zstd"},
+		{acceptEncoding: "br;q=0, gzip;q=0.1", want: "gzip"},
+		{acceptEncoding: "DEFLATE", want: "deflate"},
+		{acceptEncoding: "*", want: "gzip"},
+		{acceptEncoding: "br;q=0.2, *;q=0.5", want: "gzip"},
+		{acceptEncoding: "*;q=0", want: ""},
+		{acceptEncoding: "identity", want: ""},
+		{acceptEncoding: "gzip;q=invalid", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tt.want, mw.negotiate(tt.acceptEncoding))
+		})
+	}
+
+	mw, err = NewMiddleware(Options{Encodings: []string{"zstd", "br"}})
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", mw.negotiate("br, zstd"))
+	assert.Empty(t, mw.negotiate("gzip"))
+	assert.Equal(t, "zstd", mw.negotiate("*"))
+}
+
+func TestCompressesAllEncodings(t *testing.T) {
+	mw, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	content := strings.Repeat("hello bifrost ", 100)
+
+	for _, encoding := range []string{encodingBrotli, encodingZstd, encodingGzip, encodingDeflate} {
+		t.Run(encoding, func(t *testing.T) {
+			c := newRequestContext("GET", "/test", map[string]string{"Accept-Encoding": encoding}, nil)
+			c.SetIndex(-1)
+			c.SetHandlers([]app.HandlerFunc{mw.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+				c.Response.Header.SetContentType("application/json; charset=utf-8")
+				c.Response.Header.Set("ETag", `"v1"`)
+				c.Response.SetBodyString(content)
+			}})
+			c.Next(context.Background())
+
+			assert.Equal(t, encoding, c.Response.Header.Get(headerContentEncoding))
+			assert.Equal(t, `W/"v1"`, c.Response.Header.Get(headerETag))
+			assert.Less(t, len(c.Response.Body()), len(content))
+			assert.Equal(t, content, decode(t, encoding, c.Response.Body()))
+		})
+	}
+}
+
+func TestCompressesStream(t *testing.T) {
+	mw, err := NewMiddleware(Options{MinLength: 1024})
+	require.NoError(t, err)
+
+	content := strings.Repeat("chunk ", 100)
+
+	c := newRequestContext("GET", "/stream", map[string]string{"Accept-Encoding": "zstd"}, nil)
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{mw.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+		c.Response.Header.SetContentType("text/plain")
+		c.Response.SetBodyStream(io.NopCloser(strings.NewReader(content)), -1)
+	}})
+	c.Next(context.Background())
+
+	assert.Equal(t, "zstd", c.Response.Header.Get(headerContentEncoding))
+	assert.True(t, c.Response.IsBodyStream())
+	assert.Equal(t, -1, c.Response.Header.ContentLength())
+	assert.Equal(t, content, decode(t, encodingZstd, c.Response.Body()))
+}
+
+func TestSkipsResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		path    string
+		handler func(c *app.RequestContext)
+	}{
+		{
+			name:    "below min length",
+			options: Options{MinLength: 1024},
+			handler: func(c *app.RequestContext) {
+				c.Response.SetBodyString("hello world")
+			},
+		},
+		{
+			name:    "stream below min length",
+			options: Options{MinLength: 1024},
+			handler: func(c *app.RequestContext) {
+				c.Response.SetBodyStream(strings.NewReader("hello world"), len("hello world"))
+			},
+		},
+		{
+			name: "content type not allowed",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.SetContentType("image/png")
+				c.Response.SetBodyString("hello world")
+			},
+		},
+		{
+			name:    "custom content types",
+			options: Options{ContentTypes: []string{"application/json"}},
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.SetContentType("text/html")
+				c.Response.SetBodyString("hello world")
+			},
+		},
+		{
+			name: "no-transform",
+			handler: func(c *app.RequestContext) {
+				c.Response.Header.Set("Cache-Control", "no-transform")
+				c.Response.SetBodyString("hello world")
+			},
+		},
+		{
+			name: "not modified",
+			handler: func(c *app.RequestContext) {
+				c.Response.SetStatusCode(304)
+			},
+		},
+		{
+			name:    "excluded path prefix",
+			options: Options{ExcludedPathPrefixes: []string{"/static/"}},
+			path:    "/static/app.js",
+			handler: func(c *app.RequestContext) {
+				c.Response.SetBodyString("hello world")
+			},
+		},
+		{
+			name:    "excluded path regex",
+			options: Options{ExcludedPathRegexes: []string{`^/files/.*\.zip$`}},
+			path:    "/files/archive.zip",
+			handler: func(c *app.RequestContext) {
+				c.Response.SetBodyString("hello world")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := NewMiddleware(tt.options)
+			require.NoError(t, err)
+
+			path := tt.path
+			if path == "" {
+				path = "/test"
+			}
+
+			c := newRequestContext("GET", path, map[string]string{"Accept-Encoding": "br, gzip"}, nil)
+			c.SetIndex(-1)
+			c.SetHandlers([]app.HandlerFunc{mw.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+				tt.handler(c)
+			}})
+			c.Next(context.Background())
+
+			assert.Empty(t, c.Response.Header.Peek(headerContentEncoding))
+		})
+	}
+}
+
+func TestVary(t *testing.T) {
+	mw, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+
+	c := newRequestContext("GET", "/test", map[string]string{"Accept-Encoding": "gzip"}, nil)
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{mw.ServeHTTP, func(_ context.Context, c *app.RequestContext) {
+		c.Response.Header.Set("Vary", "Origin")
+		c.Response.SetBodyString("hello world")
+	}})
+	c.Next(context.Background())
+
+	assert.Equal(t, "Origin, Accept-Encoding", c.Response.Header.Get(headerVary))
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := NewMiddleware(Options{Encodings: []string{"lzma"}})
+	require.ErrorContains(t, err, "encoding 'lzma' is invalid")
+
+	_, err = NewMiddleware(Options{ExcludedPathRegexes: []string{"("}})
+	require.ErrorContains(t, err, "excluded_path_regexes")
+
+	_, err = NewMiddleware(Options{BrotliLevel: 12})
+	require.Error(t, err)
+
+	_, err = NewMiddleware(Options{Level: 42})
+	require.Error(t, err)
+
+	_, err = NewMiddleware(Options{ZstdLevel: 23})
+	require.ErrorContains(t, err, "zstd_level")
+}
+
+func TestDefaultEncodingsNotShared(t *testing.T) {
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+	require.NotEmpty(t, m.options.Encodings)
+	assert.NotSame(t, &defaultEncodings[0], &m.options.Encodings[0])
+}
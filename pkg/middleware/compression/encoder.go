@@ -0,0 +1,166 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingBrotli  = "br"
+	encodingZstd    = "zstd"
+	encodingDeflate = "deflate"
+	streamChunkSize = 32 * 1024
+)
+
+// encoder is implemented by the writers of all supported encodings.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoderPool reuses the encoders of one encoding, creating an encoder is expensive for zstd and brotli.
+type encoderPool struct {
+	pool sync.Pool
+}
+
+func newEncoderPool(encoding string, level int) (*encoderPool, error) {
+	var newEncoder func() encoder
+
+	switch encoding {
+	case encodingBrotli:
+		newEncoder = func() encoder {
+			return brotli.NewWriterLevel(io.Discard, level)
+		}
+	case encodingZstd:
+		// the level is validated by the middleware, and EncoderLevelFromZstd maps every zstd level
+		// to a supported one, so the pool never fails to create an encoder
+		newEncoder = func() encoder {
+			w, _ := zstd.NewWriter(io.Discard,
+				zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+				zstd.WithEncoderConcurrency(1),
+				zstd.WithLowerEncoderMem(true),
+			)
+			return w
+		}
+	case encodingGzip:
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, err
+		}
+		newEncoder = func() encoder {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+	case encodingDeflate:
+		if _, err := flate.NewWriter(io.Discard, level); err != nil {
+			return nil, err
+		}
+		newEncoder = func() encoder {
+			w, _ := flate.NewWriter(io.Discard, level)
+			return w
+		}
+	default:
+		return nil, errUnsupportedEncoding
+	}
+
+	return &encoderPool{
+		pool: sync.Pool{
+			New: func() any {
+				return newEncoder()
+			},
+		},
+	}, nil
+}
+
+func (p *encoderPool) get(w io.Writer) encoder {
+	enc, _ := p.pool.Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+func (p *encoderPool) put(enc encoder) {
+	enc.Reset(io.Discard)
+	p.pool.Put(enc)
+}
+
+// compress encodes the whole body at once.
+func (p *encoderPool) compress(body []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(body)/2))
+	enc := p.get(buf)
+	defer p.put(enc)
+
+	if _, err := enc.Write(body); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// streamReader compresses a body stream on the fly. Every chunk read from the
+// upstream is flushed, so the client receives data as soon as the upstream sends it.
+type streamReader struct {
+	src   io.Reader
+	pool  *encoderPool
+	enc   encoder
+	buf   bytes.Buffer
+	chunk []byte
+	done  bool
+}
+
+func newStreamReader(src io.Reader, pool *encoderPool) *streamReader {
+	r := &streamReader{
+		src:   src,
+		pool:  pool,
+		chunk: make([]byte, streamChunkSize),
+	}
+	r.enc = pool.get(&r.buf)
+	return r
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && !r.done {
+		n, err := r.src.Read(r.chunk)
+		if n > 0 {
+			if _, werr := r.enc.Write(r.chunk[:n]); werr != nil {
+				return 0, werr
+			}
+			if ferr := r.enc.Flush(); ferr != nil {
+				return 0, ferr
+			}
+		}
+
+		if err == io.EOF {
+			if cerr := r.enc.Close(); cerr != nil {
+				return 0, cerr
+			}
+			r.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+// Close closes the upstream stream and returns the encoder to the pool.
+func (r *streamReader) Close() error {
+	if r.enc != nil {
+		r.pool.put(r.enc)
+		r.enc = nil
+	}
+	if closer, ok := r.src.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package extauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/nite-coder/blackbear/pkg/cache/v2"
+
+	"github.com/nite-coder/bifrost/pkg/gateway"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	protocolHTTP = "http"
+	protocolGRPC = "grpc"
+
+	defaultTimeout              = time.Second
+	defaultCacheTTL             = 10 * time.Second
+	defaultMaxBodySize          = 8 * 1024
+	defaultCacheCleanupInterval = 10 * time.Minute
+	defaultGRPCMethod           = "/envoy.service.auth.v3.Authorization/Check"
+	allocationFactor            = 2
+)
+
+// Init registers the ext_auth middleware.
+func Init() error {
+	return middleware.Register([]string{"ext_auth"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
+
+// Options defines the configuration for the ext_auth middleware.
+type Options struct {
+	ServiceID                     string        `mapstructure:"service_id"`
+	Protocol                      string        `mapstructure:"protocol"`
+	PathPrefix                    string        `mapstructure:"path_prefix"`
+	GRPCMethod                    string        `mapstructure:"grpc_method"`
+	CacheKey                      string        `mapstructure:"cache_key"`
+	AllowedHeaders                []string      `mapstructure:"allowed_headers"`
+	AllowedUpstreamHeaders        []string      `mapstructure:"allowed_upstream_headers"`
+	AllowedClientHeaders          []string      `mapstructure:"allowed_client_headers"`
+	AllowedClientHeadersOnSuccess []string      `mapstructure:"allowed_client_headers_on_success"`
+	CacheTTL                      time.Duration `mapstructure:"cache_ttl"`
+	Timeout                       time.Duration `mapstructure:"timeout"`
+	MaxBodySize                   int           `mapstructure:"max_body_size"`
+	FailureStatusCode             int           `mapstructure:"failure_status_code"`
+	IncludeBody                   bool          `mapstructure:"include_body"`
+	FailureModeAllow              bool          `mapstructure:"failure_mode_allow"`
+}
+
+type header struct {
+	key   string
+	value string
+}
+
+// decision is the outcome of an authorization check. Decisions may be cached
+// and shared between requests, so they must not be modified once built.
+type decision struct {
+	body                  []byte
+	upstreamHeaders       []header
+	removeUpstreamHeaders []string
+	responseHeaders       []header
+	statusCode            int
+	allowed               bool
+}
+
+// Middleware delegates the authorization of a request to an external service.
+type Middleware struct {
+	options                       *Options
+	cache                         *cache.Cache[string, *decision]
+	check                         func(ctx context.Context, c *app.RequestContext) (*decision, error)
+	allowedHeaders                map[string]bool
+	allowedUpstreamHeaders        map[string]bool
+	allowedClientHeaders          map[string]bool
+	allowedClientHeadersOnSuccess map[string]bool
+	cacheKeyDirectives            []string
+}
+
+// NewMiddleware creates a new ext_auth middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.ServiceID == "" {
+		return nil, errors.New("service_id cannot be empty")
+	}
+
+	if options.Protocol == "" {
+		options.Protocol = protocolHTTP
+	}
+	if options.GRPCMethod == "" {
+		options.GRPCMethod = defaultGRPCMethod
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = defaultMaxBodySize
+	}
+	if options.FailureStatusCode == 0 {
+		options.FailureStatusCode = http.StatusForbidden
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = defaultCacheTTL
+	}
+
+	m := &Middleware{
+		options:                       &options,
+		allowedHeaders:                headerSet(options.AllowedHeaders),
+		allowedUpstreamHeaders:        headerSet(options.AllowedUpstreamHeaders),
+		allowedClientHeaders:          headerSet(options.AllowedClientHeaders),
+		allowedClientHeadersOnSuccess: headerSet(options.AllowedClientHeadersOnSuccess),
+	}
+
+	switch options.Protocol {
+	case protocolHTTP:
+		m.check = m.checkHTTP
+	case protocolGRPC:
+		m.check = m.checkGRPC
+	default:
+		return nil, fmt.Errorf("protocol '%s' is not supported, must be http or grpc", options.Protocol)
+	}
+
+	if options.CacheKey != "" && options.CacheTTL > 0 {
+		m.cacheKeyDirectives = variable.ParseDirectives(options.CacheKey)
+		m.cache = cache.NewCache[string, *decision](defaultCacheCleanupInterval)
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	var key string
+	if m.cache != nil {
+		key = m.cacheKey(c)
+		if key != "" {
+			if d, found := m.cache.Get(key); found {
+				m.apply(ctx, c, d)
+				return
+			}
+		}
+	}
+
+	d, err := m.check(ctx, c)
+	if err != nil {
+		log.FromContext(ctx).Warn("ext_auth: authorization check failed",
+			"service_id", m.options.ServiceID,
+			"error", err,
+		)
+		if m.options.FailureModeAllow {
+			c.Next(ctx)
+			return
+		}
+		c.SetStatusCode(m.options.FailureStatusCode)
+		c.Abort()
+		return
+	}
+
+	if key != "" {
+		m.cache.PutWithTTL(key, d, m.options.CacheTTL)
+	}
+
+	m.apply(ctx, c, d)
+}
+
+func (m *Middleware) apply(ctx context.Context, c *app.RequestContext, d *decision) {
+	if !d.allowed {
+		for _, h := range d.responseHeaders {
+			c.Response.Header.Add(h.key, h.value)
+		}
+		c.SetStatusCode(d.statusCode)
+		if len(d.body) > 0 {
+			c.Response.SetBody(d.body)
+		}
+		c.Abort()
+		return
+	}
+
+	for _, name := range d.removeUpstreamHeaders {
+		c.Request.Header.Del(name)
+	}
+	for _, h := range d.upstreamHeaders {
+		c.Request.Header.Set(h.key, h.value)
+	}
+
+	c.Next(ctx)
+
+	for _, h := range d.responseHeaders {
+		c.Response.Header.Set(h.key, h.value)
+	}
+}
+
+func (m *Middleware) cacheKey(c *app.RequestContext) string {
+	if len(m.cacheKeyDirectives) == 0 {
+		return m.options.CacheKey
+	}
+
+	replacements := make([]string, 0, len(m.cacheKeyDirectives)*allocationFactor)
+	empty := true
+	for _, key := range m.cacheKeyDirectives {
+		val := variable.GetString(key, c)
+		if val != "" {
+			empty = false
+		}
+		replacements = append(replacements, key, val)
+	}
+
+	// requests without any identifying value are never served from the cache
+	if empty {
+		return ""
+	}
+
+	return strings.NewReplacer(replacements...).Replace(m.options.CacheKey)
+}
+
+// invoke sends the authorization request through the configured service, so
+// the auth service benefits from the service's upstreams, balancer and middlewares.
+func (m *Middleware) invoke(ctx context.Context, authCtx *app.RequestContext) error {
+	bifrost := gateway.GetBifrost()
+	if bifrost == nil {
+		return errors.New("bifrost is not initialized")
+	}
+
+	svc, found := bifrost.Service(m.options.ServiceID)
+	if !found {
+		return fmt.Errorf("service '%s' is not found", m.options.ServiceID)
+	}
+
+	middlewares := svc.Middlewares()
+	handlers := make([]app.HandlerFunc, 0, len(middlewares)+1)
+	handlers = append(handlers, middlewares...)
+	handlers = append(handlers, svc.ServeHTTP)
+
+	// the chain runs on the request goroutine, so the deadline is applied to the upstream call
+	// itself: the HTTP client honors the request timeout and the gRPC proxy honors the context.
+	ctx, cancel := context.WithTimeout(ctx, m.options.Timeout)
+	defer cancel()
+	authCtx.Request.SetOptions(config.WithRequestTimeout(m.options.Timeout))
+
+	authCtx.SetIndex(-1)
+	authCtx.SetHandlers(handlers)
+	authCtx.Next(ctx)
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("auth service timed out after %s", m.options.Timeout)
+	}
+	return nil
+}
+
+// requestHeaders returns the request headers forwarded to the auth service.
+func (m *Middleware) requestHeaders(c *app.RequestContext) []header {
+	headers := []header{}
+	c.Request.Header.VisitAll(func(key, value []byte) {
+		name := strings.ToLower(string(key))
+		if skippedHeaders[name] {
+			return
+		}
+		if len(m.allowedHeaders) > 0 && !m.allowedHeaders[name] {
+			return
+		}
+		headers = append(headers, header{key: string(key), value: string(value)})
+	})
+	return headers
+}
+
+// requestBody returns the request body forwarded to the auth service, truncated to max_body_size.
+func (m *Middleware) requestBody(c *app.RequestContext) []byte {
+	if !m.options.IncludeBody {
+		return nil
+	}
+	body := c.Request.Body()
+	if len(body) > m.options.MaxBodySize {
+		body = body[:m.options.MaxBodySize]
+	}
+	return body
+}
+
+// skippedHeaders are never forwarded between the client, the auth service and the upstream.
+var skippedHeaders = map[string]bool{
+	"connection":        true,
+	"content-length":    true,
+	"date":              true,
+	"keep-alive":        true,
+	"server":            true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+func headerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
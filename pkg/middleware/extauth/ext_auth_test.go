@@ -0,0 +1,177 @@
+package extauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/balancer/roundrobin"
+	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/gateway"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func setupBifrost(t *testing.T, serviceID string, serviceOptions config.ServiceOptions) {
+	t.Helper()
+
+	_ = roundrobin.Init()
+	options := config.NewOptions()
+	options.Services[serviceID] = serviceOptions
+
+	bifrost, err := gateway.NewBifrost(options, gateway.ModeNormal)
+	require.NoError(t, err)
+	gateway.SetBifrost(bifrost)
+}
+
+func serve(m app.HandlerFunc, c *app.RequestContext) bool {
+	called := false
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m, func(_ context.Context, c *app.RequestContext) {
+		called = true
+		c.Response.Header.Set("X-Upstream-User", string(c.Request.Header.Peek("X-User-ID")))
+		c.Response.Header.Set("X-Upstream-Debug", string(c.Request.Header.Peek("X-Debug")))
+		c.SetStatusCode(http.StatusOK)
+	}})
+	c.Next(context.Background())
+	return called
+}
+
+func TestExtAuthHTTP(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		assert.Equal(t, "/authz/orders", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Empty(t, r.Header.Get("X-Debug"))
+
+		switch r.Header.Get("Authorization") {
+		case "Bearer good":
+			w.Header().Set("X-User-ID", "42")
+			w.Header().Set("X-Auth-Trace", "abc")
+			w.Header().Set("X-Internal", "secret")
+			w.WriteHeader(http.StatusOK)
+		case "Bearer broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+		}
+	}))
+	defer server.Close()
+
+	setupBifrost(t, "auth_svc", config.ServiceOptions{URL: server.URL})
+
+	_ = Init()
+	h := middleware.Factory("ext_auth")
+
+	m, err := h(map[string]any{
+		"service_id":                        "auth_svc",
+		"path_prefix":                       "/authz",
+		"allowed_headers":                   []string{"Authorization"},
+		"allowed_upstream_headers":          []string{"X-User-ID"},
+		"allowed_client_headers_on_success": []string{"X-Auth-Trace"},
+		"cache_key":                         "$http.request.header.authorization",
+		"cache_ttl":                         "1m",
+	})
+	require.NoError(t, err)
+
+	t.Run("allow", func(t *testing.T) {
+		for range 2 {
+			hzCtx := app.NewContext(0)
+			hzCtx.Request.SetRequestURI("/orders?page=2")
+			hzCtx.Request.Header.Set("Authorization", "Bearer good")
+			hzCtx.Request.Header.Set("X-User-ID", "spoofed")
+			hzCtx.Request.Header.Set("X-Debug", "1")
+
+			assert.True(t, serve(m, hzCtx))
+			assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+			assert.Equal(t, "42", hzCtx.Response.Header.Get("X-Upstream-User"))
+			assert.Equal(t, "abc", hzCtx.Response.Header.Get("X-Auth-Trace"))
+			assert.Empty(t, hzCtx.Response.Header.Get("X-Internal"))
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetRequestURI("/orders?page=2")
+		hzCtx.Request.Header.Set("Authorization", "Bearer bad")
+
+		assert.False(t, serve(m, hzCtx))
+		assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+		assert.Equal(t, "Bearer", hzCtx.Response.Header.Get("WWW-Authenticate"))
+		assert.Equal(t, "application/json", string(hzCtx.Response.Header.ContentType()))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(hzCtx.Response.Body()))
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetRequestURI("/orders?page=2")
+		hzCtx.Request.Header.Set("Authorization", "Bearer broken")
+
+		assert.False(t, serve(m, hzCtx))
+		assert.Equal(t, http.StatusForbidden, hzCtx.Response.StatusCode())
+	})
+
+	t.Run("fail open", func(t *testing.T) {
+		m, err := NewMiddleware(Options{
+			ServiceID:        "auth_svc",
+			PathPrefix:       "/authz",
+			FailureModeAllow: true,
+		})
+		require.NoError(t, err)
+
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetRequestURI("/orders?page=2")
+		hzCtx.Request.Header.Set("Authorization", "Bearer broken")
+
+		assert.True(t, serve(m.ServeHTTP, hzCtx))
+		assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+	})
+}
+
+func TestExtAuthTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	setupBifrost(t, "slow_auth_svc", config.ServiceOptions{URL: server.URL})
+
+	m, err := NewMiddleware(Options{
+		ServiceID:         "slow_auth_svc",
+		Timeout:           50 * time.Millisecond,
+		FailureStatusCode: http.StatusServiceUnavailable,
+	})
+	require.NoError(t, err)
+
+	// the auth request is aborted instead of running on in the background
+	start := time.Now()
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/orders")
+	assert.False(t, serve(m.ServeHTTP, hzCtx))
+	assert.Equal(t, http.StatusServiceUnavailable, hzCtx.Response.StatusCode())
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestNewMiddlewareValidation(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	require.ErrorContains(t, err, "service_id cannot be empty")
+
+	_, err = NewMiddleware(Options{ServiceID: "auth", Protocol: "tcp"})
+	require.ErrorContains(t, err, "protocol 'tcp' is not supported")
+
+	_, err = NewMiddleware(Options{ServiceID: "missing"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,304 @@
+package extauth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const grpcHeaderLen = 5
+
+// Field numbers of the envoy.service.auth.v3 messages. The middleware encodes and
+// decodes the wire format directly, so it works with any ext_authz compatible
+// server (Envoy, OPA, Authorino, ...) without pulling in the Envoy API module.
+const (
+	checkRequestAttributes = 1
+
+	attributeContextSource  = 1
+	attributeContextRequest = 4
+
+	peerAddress          = 1
+	addressSocketAddress = 1
+	socketAddressAddress = 2
+
+	requestHTTP = 2
+
+	httpRequestMethod   = 2
+	httpRequestHeaders  = 3
+	httpRequestPath     = 4
+	httpRequestHost     = 5
+	httpRequestScheme   = 6
+	httpRequestQuery    = 7
+	httpRequestSize     = 9
+	httpRequestProtocol = 10
+	httpRequestBody     = 11
+
+	mapEntryKey   = 1
+	mapEntryValue = 2
+
+	checkResponseStatus         = 1
+	checkResponseDeniedResponse = 2
+	checkResponseOkResponse     = 3
+
+	rpcStatusCode = 1
+
+	deniedResponseStatus  = 1
+	deniedResponseHeaders = 2
+	deniedResponseBody    = 3
+
+	okResponseHeaders               = 2
+	okResponseHeadersToRemove       = 5
+	okResponseResponseHeadersToAdd  = 6
+	headerValueOptionHeader         = 1
+	headerValueKey                  = 1
+	headerValueValue                = 2
+	headerValueRawValue             = 3
+	httpStatusCode                  = 1
+	defaultDeniedResponseStatusCode = http.StatusForbidden
+)
+
+// checkGRPC calls the ext_authz Check method of a gRPC auth service.
+func (m *Middleware) checkGRPC(ctx context.Context, c *app.RequestContext) (*decision, error) {
+	payload := m.encodeCheckRequest(c)
+
+	frame := make([]byte, grpcHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(frame[1:grpcHeaderLen], uint32(len(payload))) //nolint:gosec // bounded by max_body_size
+	copy(frame[grpcHeaderLen:], payload)
+
+	authCtx := app.NewContext(0)
+	req := &authCtx.Request
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(m.options.GRPCMethod)
+	req.Header.SetContentTypeBytes([]byte("application/grpc"))
+	req.SetBody(frame)
+
+	err := m.invoke(ctx, authCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, found := authCtx.Get(variable.GRPCStatusCode); !found {
+		return nil, errors.New("auth service did not return a gRPC response")
+	} else if code, ok := val.(codes.Code); !ok || code != codes.OK {
+		return nil, fmt.Errorf("auth service returned gRPC status %v: %s", val, authCtx.GetString(variable.GRPCMessage))
+	}
+
+	body := authCtx.Response.Body()
+	if len(body) < grpcHeaderLen {
+		return nil, errors.New("auth service returned an invalid gRPC frame")
+	}
+	msgLen := binary.BigEndian.Uint32(body[1:grpcHeaderLen])
+	if uint64(len(body)) < grpcHeaderLen+uint64(msgLen) {
+		return nil, errors.New("auth service returned an invalid gRPC frame")
+	}
+
+	return decodeCheckResponse(body[grpcHeaderLen : grpcHeaderLen+msgLen])
+}
+
+// encodeCheckRequest builds an envoy.service.auth.v3.CheckRequest.
+func (m *Middleware) encodeCheckRequest(c *app.RequestContext) []byte {
+	var httpReq []byte
+	httpReq = appendString(httpReq, httpRequestMethod, string(c.Request.Method()))
+	for _, h := range m.requestHeaders(c) {
+		var entry []byte
+		entry = appendString(entry, mapEntryKey, strings.ToLower(h.key))
+		entry = appendString(entry, mapEntryValue, h.value)
+		httpReq = appendMessage(httpReq, httpRequestHeaders, entry)
+	}
+	httpReq = appendString(httpReq, httpRequestPath, string(c.Request.RequestURI()))
+	httpReq = appendString(httpReq, httpRequestHost, string(c.Request.Host()))
+	httpReq = appendString(httpReq, httpRequestScheme, string(c.Request.Scheme()))
+	httpReq = appendString(httpReq, httpRequestQuery, string(c.Request.QueryString()))
+	httpReq = protowire.AppendTag(httpReq, httpRequestSize, protowire.VarintType)
+	httpReq = protowire.AppendVarint(httpReq, uint64(len(c.Request.Body())))
+	httpReq = appendString(httpReq, httpRequestProtocol, c.Request.Header.GetProtocol())
+	if body := m.requestBody(c); len(body) > 0 {
+		httpReq = appendString(httpReq, httpRequestBody, string(body))
+	}
+
+	var socketAddress []byte
+	socketAddress = appendString(socketAddress, socketAddressAddress, c.ClientIP())
+	var address []byte
+	address = appendMessage(address, addressSocketAddress, socketAddress)
+	var source []byte
+	source = appendMessage(source, peerAddress, address)
+
+	var request []byte
+	request = appendMessage(request, requestHTTP, httpReq)
+
+	var attributes []byte
+	attributes = appendMessage(attributes, attributeContextSource, source)
+	attributes = appendMessage(attributes, attributeContextRequest, request)
+
+	var checkRequest []byte
+	return appendMessage(checkRequest, checkRequestAttributes, attributes)
+}
+
+// decodeCheckResponse parses an envoy.service.auth.v3.CheckResponse.
+func decodeCheckResponse(b []byte) (*decision, error) {
+	d := &decision{allowed: true}
+	var denied, ok []byte
+
+	err := visitFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == checkResponseStatus && typ == protowire.BytesType:
+			return visitFields(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+				if num == rpcStatusCode && typ == protowire.VarintType {
+					d.allowed = codes.Code(n) == codes.OK //nolint:gosec // gRPC status codes are small
+				}
+				return nil
+			})
+		case num == checkResponseDeniedResponse && typ == protowire.BytesType:
+			denied = v
+		case num == checkResponseOkResponse && typ == protowire.BytesType:
+			ok = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !d.allowed {
+		d.statusCode = defaultDeniedResponseStatusCode
+		err = visitFields(denied, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			switch num {
+			case deniedResponseStatus:
+				return visitFields(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+					if num == httpStatusCode && typ == protowire.VarintType && n >= 100 {
+						d.statusCode = int(n) //nolint:gosec // HTTP status codes are small
+					}
+					return nil
+				})
+			case deniedResponseHeaders:
+				h, err := decodeHeaderValueOption(v)
+				if err != nil {
+					return err
+				}
+				d.responseHeaders = append(d.responseHeaders, h)
+			case deniedResponseBody:
+				d.body = append([]byte(nil), v...)
+			}
+			return nil
+		})
+		return d, err
+	}
+
+	err = visitFields(ok, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case okResponseHeaders:
+			h, err := decodeHeaderValueOption(v)
+			if err != nil {
+				return err
+			}
+			d.upstreamHeaders = append(d.upstreamHeaders, h)
+		case okResponseHeadersToRemove:
+			d.removeUpstreamHeaders = append(d.removeUpstreamHeaders, string(v))
+		case okResponseResponseHeadersToAdd:
+			h, err := decodeHeaderValueOption(v)
+			if err != nil {
+				return err
+			}
+			d.responseHeaders = append(d.responseHeaders, h)
+		}
+		return nil
+	})
+
+	return d, err
+}
+
+func decodeHeaderValueOption(b []byte) (header, error) {
+	h := header{}
+	err := visitFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != headerValueOptionHeader || typ != protowire.BytesType {
+			return nil
+		}
+		return visitFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			switch num {
+			case headerValueKey:
+				h.key = string(v)
+			case headerValueValue:
+				h.value = string(v)
+			case headerValueRawValue:
+				if h.value == "" {
+					h.value = string(v)
+				}
+			}
+			return nil
+		})
+	})
+	if err == nil && h.key == "" {
+		err = errors.New("header key cannot be empty")
+	}
+	return h, err
+}
+
+// visitFields calls fn for every field of a protobuf message. Length-delimited
+// fields are passed in v and varint fields in n; other wire types are skipped.
+func visitFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(b)
+		if tagLen < 0 {
+			return protowire.ParseError(tagLen)
+		}
+		b = b[tagLen:]
+
+		var (
+			v   []byte
+			n   uint64
+			err error
+		)
+		valueLen := 0
+		switch typ {
+		case protowire.BytesType:
+			v, valueLen = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			n, valueLen = protowire.ConsumeVarint(b)
+		default:
+			valueLen = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if valueLen < 0 {
+			return protowire.ParseError(valueLen)
+		}
+		b = b[valueLen:]
+
+		if typ == protowire.BytesType || typ == protowire.VarintType {
+			err = fn(num, typ, v, n)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
@@ -0,0 +1,186 @@
+package extauth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	dst, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	*dst = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func headerValueOption(key, value string) []byte {
+	var h []byte
+	h = appendString(h, headerValueKey, key)
+	h = appendString(h, headerValueValue, value)
+	return appendMessage(nil, headerValueOptionHeader, h)
+}
+
+func rpcStatus(code uint64) []byte {
+	var st []byte
+	st = protowire.AppendTag(st, rpcStatusCode, protowire.VarintType)
+	return protowire.AppendVarint(st, code)
+}
+
+// requestHeader extracts a header from an encoded CheckRequest.
+func requestHeader(t *testing.T, checkRequest []byte, name string) string {
+	t.Helper()
+
+	var value string
+	path := []protowire.Number{checkRequestAttributes, attributeContextRequest, requestHTTP}
+
+	var walk func(b []byte, depth int) error
+	walk = func(b []byte, depth int) error {
+		return visitFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			if depth < len(path) {
+				if num == path[depth] {
+					return walk(v, depth+1)
+				}
+				return nil
+			}
+			if num != httpRequestHeaders {
+				return nil
+			}
+			var key, val string
+			err := visitFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+				if num == mapEntryKey {
+					key = string(v)
+				} else {
+					val = string(v)
+				}
+				return nil
+			})
+			if key == name {
+				value = val
+			}
+			return err
+		})
+	}
+	require.NoError(t, walk(checkRequest, 0))
+
+	return value
+}
+
+func TestExtAuthGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			assert.Equal(t, defaultGRPCMethod, method)
+
+			var req []byte
+			err := stream.RecvMsg(&req)
+			if err != nil {
+				return err
+			}
+
+			var resp []byte
+			if requestHeader(t, req, "authorization") == "Bearer good" {
+				var ok []byte
+				ok = appendMessage(ok, okResponseHeaders, headerValueOption("x-user-id", "42"))
+				ok = appendString(ok, okResponseHeadersToRemove, "x-debug")
+				resp = appendMessage(resp, checkResponseStatus, rpcStatus(0))
+				resp = appendMessage(resp, checkResponseOkResponse, ok)
+			} else {
+				var status []byte
+				status = protowire.AppendTag(status, httpStatusCode, protowire.VarintType)
+				status = protowire.AppendVarint(status, http.StatusUnauthorized)
+
+				var denied []byte
+				denied = appendMessage(denied, deniedResponseStatus, status)
+				denied = appendMessage(denied, deniedResponseHeaders, headerValueOption("www-authenticate", "Bearer"))
+				denied = appendString(denied, deniedResponseBody, "denied")
+				resp = appendMessage(resp, checkResponseStatus, rpcStatus(7))
+				resp = appendMessage(resp, checkResponseDeniedResponse, denied)
+			}
+
+			return stream.SendMsg(resp)
+		}),
+	)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	setupBifrost(t, "grpc_auth_svc", config.ServiceOptions{
+		URL:      "http://" + lis.Addr().String(),
+		Protocol: config.ProtocolGRPC,
+	})
+
+	m, err := NewMiddleware(Options{
+		ServiceID: "grpc_auth_svc",
+		Protocol:  protocolGRPC,
+	})
+	require.NoError(t, err)
+
+	t.Run("allow", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetRequestURI("/orders")
+		hzCtx.Request.Header.Set("Authorization", "Bearer good")
+		hzCtx.Request.Header.Set("X-Debug", "1")
+
+		assert.True(t, serve(m.ServeHTTP, hzCtx))
+		assert.Equal(t, "42", hzCtx.Response.Header.Get("X-Upstream-User"))
+		assert.Empty(t, hzCtx.Response.Header.Get("X-Upstream-Debug"))
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetRequestURI("/orders")
+		hzCtx.Request.Header.Set("Authorization", "Bearer bad")
+
+		assert.False(t, serve(m.ServeHTTP, hzCtx))
+		assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+		assert.Equal(t, "Bearer", hzCtx.Response.Header.Get("WWW-Authenticate"))
+		assert.Equal(t, "denied", string(hzCtx.Response.Body()))
+	})
+}
+
+func TestDecodeCheckResponse(t *testing.T) {
+	d, err := decodeCheckResponse(nil)
+	require.NoError(t, err)
+	assert.True(t, d.allowed)
+
+	d, err = decodeCheckResponse(appendMessage(nil, checkResponseStatus, rpcStatus(7)))
+	require.NoError(t, err)
+	assert.False(t, d.allowed)
+	assert.Equal(t, http.StatusForbidden, d.statusCode)
+
+	_, err = decodeCheckResponse([]byte{0x0a, 0x05})
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package extauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+// checkHTTP forwards the request metadata to an HTTP auth service. A 2xx response
+// allows the request, 5xx responses are treated as failures and any other status
+// denies the request with the auth service's response.
+func (m *Middleware) checkHTTP(ctx context.Context, c *app.RequestContext) (*decision, error) {
+	authCtx := app.NewContext(0)
+	req := &authCtx.Request
+
+	req.Header.SetMethodBytes(c.Request.Method())
+	req.SetRequestURI(m.options.PathPrefix + string(c.Request.RequestURI()))
+	for _, h := range m.requestHeaders(c) {
+		req.Header.Add(h.key, h.value)
+	}
+	if body := m.requestBody(c); len(body) > 0 {
+		req.SetBody(body)
+	}
+
+	err := m.invoke(ctx, authCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authCtx.Response
+	statusCode := resp.StatusCode()
+	if statusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("auth service returned status code %d", statusCode)
+	}
+
+	if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		d := &decision{allowed: true}
+		resp.Header.VisitAll(func(key, value []byte) {
+			name := strings.ToLower(string(key))
+			if skippedHeaders[name] {
+				return
+			}
+			if m.allowedUpstreamHeaders[name] {
+				// an empty value removes the header from the upstream request
+				if len(value) == 0 {
+					d.removeUpstreamHeaders = append(d.removeUpstreamHeaders, string(key))
+				} else {
+					d.upstreamHeaders = append(d.upstreamHeaders, header{key: string(key), value: string(value)})
+				}
+			}
+			if m.allowedClientHeadersOnSuccess[name] {
+				d.responseHeaders = append(d.responseHeaders, header{key: string(key), value: string(value)})
+			}
+		})
+		return d, nil
+	}
+
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decision{
+		statusCode: statusCode,
+		body:       body,
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		name := strings.ToLower(string(key))
+		if skippedHeaders[name] {
+			return
+		}
+		if len(m.allowedClientHeaders) > 0 && !m.allowedClientHeaders[name] {
+			return
+		}
+		d.responseHeaders = append(d.responseHeaders, header{key: string(key), value: string(value)})
+	})
+
+	return d, nil
+}
+
+func responseBody(resp *protocol.Response) ([]byte, error) {
+	if resp.IsBodyStream() {
+		return io.ReadAll(resp.BodyStream())
+	}
+
+	body := resp.Body()
+	result := make([]byte, len(body))
+	copy(result, body)
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package graphqlguard
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// listSizeArguments are the pagination arguments which multiply the complexity of the selected fields.
+var listSizeArguments = []string{"first", "last"}
+
+// stats are the measures of a selection set.
+type stats struct {
+	depth      int
+	aliases    int
+	complexity int
+}
+
+// analyzer measures the operations of a query document. The measures of each fragment are
+// computed once, so fragments spread many times do not make the analysis expensive.
+type analyzer struct {
+	doc       *ast.QueryDocument
+	variables map[string]any
+	fragments map[string]stats
+	visiting  map[string]bool
+}
+
+func analyze(doc *ast.QueryDocument, variables map[string]any) (stats, error) {
+	a := &analyzer{
+		doc:       doc,
+		variables: variables,
+		fragments: map[string]stats{},
+		visiting:  map[string]bool{},
+	}
+
+	var result stats
+	for _, op := range doc.Operations {
+		s, err := a.selectionSet(op.SelectionSet)
+		if err != nil {
+			return stats{}, err
+		}
+		result.depth = max(result.depth, s.depth)
+		result.aliases = max(result.aliases, s.aliases)
+		result.complexity = max(result.complexity, s.complexity)
+	}
+	return result, nil
+}
+
+func (a *analyzer) selectionSet(set ast.SelectionSet) (stats, error) {
+	var result stats
+	for _, selection := range set {
+		var s stats
+		var err error
+
+		switch sel := selection.(type) {
+		case *ast.Field:
+			s, err = a.field(sel)
+		case *ast.InlineFragment:
+			s, err = a.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			s, err = a.fragment(sel.Name)
+		}
+		if err != nil {
+			return stats{}, err
+		}
+
+		result.depth = max(result.depth, s.depth)
+		result.aliases = add(result.aliases, s.aliases)
+		result.complexity = add(result.complexity, s.complexity)
+	}
+	return result, nil
+}
+
+func (a *analyzer) field(field *ast.Field) (stats, error) {
+	children, err := a.selectionSet(field.SelectionSet)
+	if err != nil {
+		return stats{}, err
+	}
+
+	result := stats{
+		depth:      children.depth + 1,
+		aliases:    children.aliases,
+		complexity: add(1, mul(a.listSize(field), children.complexity)),
+	}
+	if field.Alias != "" && field.Alias != field.Name {
+		result.aliases = add(result.aliases, 1)
+	}
+	return result, nil
+}
+
+func (a *analyzer) fragment(name string) (stats, error) {
+	if s, found := a.fragments[name]; found {
+		return s, nil
+	}
+	if a.visiting[name] {
+		return stats{}, fmt.Errorf("fragment '%s' spreads itself", name)
+	}
+
+	def := a.doc.Fragments.ForName(name)
+	if def == nil {
+		return stats{}, fmt.Errorf("fragment '%s' is not defined", name)
+	}
+
+	a.visiting[name] = true
+	s, err := a.selectionSet(def.SelectionSet)
+	delete(a.visiting, name)
+	if err != nil {
+		return stats{}, err
+	}
+	a.fragments[name] = s
+	return s, nil
+}
+
+// listSize returns the value of the pagination argument of a field, 1 if it has none.
+func (a *analyzer) listSize(field *ast.Field) int {
+	for _, name := range listSizeArguments {
+		arg := field.Arguments.ForName(name)
+		if arg == nil || arg.Value == nil {
+			continue
+		}
+
+		raw := arg.Value.Raw
+		if arg.Value.Kind == ast.Variable {
+			val, found := a.variables[raw]
+			if !found {
+				continue
+			}
+			raw = fmt.Sprint(val)
+		}
+		size, err := strconv.Atoi(raw)
+		if err == nil && size > 0 {
+			return size
+		}
+	}
+	return 1
+}
+
+// add and mul saturate at math.MaxInt, so huge queries cannot overflow the limits.
+func add(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func mul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
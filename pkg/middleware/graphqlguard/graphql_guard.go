@@ -0,0 +1,351 @@
+package graphqlguard
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+const contentTypeGraphQL = "application/graphql"
+
+// numbers are decoded as json.Number, so the pagination arguments keep their exact values
+var jsonAPI = sonic.Config{UseNumber: true}.Froze()
+
+// Options defines the configuration for the graphql_guard middleware.
+type Options struct {
+	// MaxDepth is the maximum depth of the selected fields, 0 means no limit.
+	MaxDepth int `mapstructure:"max_depth"`
+	// MaxAliases is the maximum number of aliases in an operation, 0 means no limit.
+	MaxAliases int `mapstructure:"max_aliases"`
+	// MaxComplexity is the maximum complexity score of an operation, 0 means no limit.
+	MaxComplexity int `mapstructure:"max_complexity"`
+	// Allowlist is the list of allowed queries, other queries are rejected.
+	Allowlist []string `mapstructure:"allowlist"`
+	// AllowlistFile is a JSON file with an array of allowed queries or a persisted query
+	// manifest, an object of the queries by their sha256 hash.
+	AllowlistFile            string `mapstructure:"allowlist_file"`
+	RejectedHTTPContentType  string `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string `mapstructure:"rejected_http_response_body"`
+	RejectedHTTPStatusCode   int    `mapstructure:"rejected_http_status_code"`
+}
+
+// graphqlRequest is a GraphQL request over HTTP.
+type graphqlRequest struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Errors []graphqlError `json:"errors"`
+}
+
+// Middleware is a middleware that limits GraphQL queries.
+type Middleware struct {
+	options *Options
+	// allowed is the set of the allowed queries in their canonical form
+	allowed map[string]struct{}
+	// persisted are the allowed queries by their sha256 hash
+	persisted map[string]string
+}
+
+// NewMiddleware creates a new graphql_guard middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.MaxDepth < 0 || options.MaxAliases < 0 || options.MaxComplexity < 0 {
+		return nil, errors.New("max_depth, max_aliases and max_complexity cannot be negative")
+	}
+
+	m := &Middleware{
+		options: &options,
+	}
+
+	err := m.loadAllowlist()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.MaxDepth == 0 && options.MaxAliases == 0 && options.MaxComplexity == 0 && m.allowed == nil {
+		return nil, errors.New("max_depth, max_aliases, max_complexity or an allowlist must be set for graphql_guard middleware")
+	}
+
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusBadRequest
+	}
+	if len(options.RejectedHTTPContentType) == 0 {
+		options.RejectedHTTPContentType = "application/json"
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) loadAllowlist() error {
+	persisted := map[string]string{}
+	for _, query := range m.options.Allowlist {
+		persisted[hash(query)] = query
+	}
+
+	if m.options.AllowlistFile != "" {
+		data, err := os.ReadFile(m.options.AllowlistFile)
+		if err != nil {
+			return fmt.Errorf("failed to read allowlist file '%s': %w", m.options.AllowlistFile, err)
+		}
+
+		var queries []string
+		manifest := map[string]string{}
+		if err := sonic.Unmarshal(data, &queries); err == nil {
+			for _, query := range queries {
+				persisted[hash(query)] = query
+			}
+		} else if err := sonic.Unmarshal(data, &manifest); err == nil {
+			for key, query := range manifest {
+				persisted[strings.TrimPrefix(key, "sha256:")] = query
+			}
+		} else {
+			return fmt.Errorf(
+				"allowlist file '%s' must be an array of queries or an object of queries by hash",
+				m.options.AllowlistFile,
+			)
+		}
+	}
+
+	if len(persisted) == 0 {
+		return nil
+	}
+
+	m.allowed = make(map[string]struct{}, len(persisted))
+	for key, query := range persisted {
+		doc, err := parser.ParseQuery(&ast.Source{Input: query})
+		if err != nil {
+			return fmt.Errorf("failed to parse allowed query '%s': %w", key, err)
+		}
+		m.allowed[canonical(doc)] = struct{}{}
+	}
+	m.persisted = persisted
+	return nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	// CORS preflight requests carry no query
+	if c.Request.Header.IsOptions() && len(c.Request.Body()) == 0 {
+		c.Next(ctx)
+		return
+	}
+
+	requests, batch, err := parseRequests(c)
+	if err != nil {
+		m.reject(c, err.Error())
+		return
+	}
+
+	// queries of the persisted query hashes are added to the request, so the upstream
+	// receives the full query
+	resolved := false
+	for _, req := range requests {
+		if req.Query != "" || m.persisted == nil {
+			continue
+		}
+		query, found := m.persisted[persistedQueryHash(req)]
+		if !found {
+			m.reject(c, "PersistedQueryNotFound")
+			return
+		}
+		req.Query = query
+		resolved = true
+	}
+
+	for _, req := range requests {
+		if req.Query == "" {
+			// the query is only known by the upstream, e.g. an automatic persisted query
+			continue
+		}
+		if err := m.check(req); err != nil {
+			m.reject(c, err.Error())
+			return
+		}
+	}
+
+	if resolved {
+		err = writeRequests(c, requests, batch)
+		if err != nil {
+			m.reject(c, err.Error())
+			return
+		}
+	}
+
+	c.Next(ctx)
+}
+
+func (m *Middleware) check(req *graphqlRequest) error {
+	doc, err := parser.ParseQuery(&ast.Source{Input: req.Query})
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %s", err.Error())
+	}
+
+	if m.allowed != nil {
+		if _, found := m.allowed[canonical(doc)]; !found {
+			return errors.New("query is not in the allowlist")
+		}
+	}
+
+	s, err := analyze(doc, req.Variables)
+	if err != nil {
+		return err
+	}
+
+	if m.options.MaxDepth > 0 && s.depth > m.options.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the maximum depth of %d", s.depth, m.options.MaxDepth)
+	}
+	if m.options.MaxAliases > 0 && s.aliases > m.options.MaxAliases {
+		return fmt.Errorf("query has %d aliases, the maximum is %d", s.aliases, m.options.MaxAliases)
+	}
+	if m.options.MaxComplexity > 0 && s.complexity > m.options.MaxComplexity {
+		return fmt.Errorf(
+			"query complexity %d exceeds the maximum complexity of %d",
+			s.complexity,
+			m.options.MaxComplexity,
+		)
+	}
+	return nil
+}
+
+func (m *Middleware) reject(c *app.RequestContext, message string) {
+	c.SetStatusCode(m.options.RejectedHTTPStatusCode)
+	c.SetContentType(m.options.RejectedHTTPContentType)
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	} else {
+		body, _ := sonic.Marshal(errorResponse{Errors: []graphqlError{{Message: message}}})
+		c.Response.SetBody(body)
+	}
+	c.Abort()
+}
+
+// parseRequests parses the GraphQL requests of a GET request, a JSON body, which may be a
+// batch of requests, or an application/graphql body.
+func parseRequests(c *app.RequestContext) ([]*graphqlRequest, bool, error) {
+	if c.Request.Header.IsGet() || c.Request.Header.IsHead() {
+		req := &graphqlRequest{
+			Query:         string(c.QueryArgs().Peek("query")),
+			OperationName: string(c.QueryArgs().Peek("operationName")),
+		}
+		if val := c.QueryArgs().Peek("variables"); len(val) > 0 {
+			if err := jsonAPI.Unmarshal(val, &req.Variables); err != nil {
+				return nil, false, errors.New("variables must be a JSON object")
+			}
+		}
+		if val := c.QueryArgs().Peek("extensions"); len(val) > 0 {
+			if err := jsonAPI.Unmarshal(val, &req.Extensions); err != nil {
+				return nil, false, errors.New("extensions must be a JSON object")
+			}
+		}
+		return []*graphqlRequest{req}, false, nil
+	}
+
+	body := bytes.TrimSpace(c.Request.Body())
+	if bytes.HasPrefix(c.Request.Header.ContentType(), []byte(contentTypeGraphQL)) {
+		return []*graphqlRequest{{Query: string(body)}}, false, nil
+	}
+
+	if len(body) > 0 && body[0] == '[' {
+		var requests []*graphqlRequest
+		if err := jsonAPI.Unmarshal(body, &requests); err != nil {
+			return nil, false, errors.New("request body must be a GraphQL request")
+		}
+		for _, req := range requests {
+			if req == nil {
+				return nil, false, errors.New("request body must be a GraphQL request")
+			}
+		}
+		return requests, true, nil
+	}
+
+	req := &graphqlRequest{}
+	if err := jsonAPI.Unmarshal(body, req); err != nil {
+		return nil, false, errors.New("request body must be a GraphQL request")
+	}
+	return []*graphqlRequest{req}, false, nil
+}
+
+// writeRequests writes the requests back after the queries of persisted query hashes were added.
+func writeRequests(c *app.RequestContext, requests []*graphqlRequest, batch bool) error {
+	if bytes.Equal(c.Request.Method(), []byte(http.MethodGet)) {
+		c.Request.URI().QueryArgs().Set("query", requests[0].Query)
+		return nil
+	}
+
+	var body []byte
+	var err error
+	if batch {
+		body, err = jsonAPI.Marshal(requests)
+	} else {
+		body, err = jsonAPI.Marshal(requests[0])
+	}
+	if err != nil {
+		return err
+	}
+	c.Request.SetBody(body)
+	c.Request.Header.SetContentLength(len(body))
+	return nil
+}
+
+// persistedQueryHash returns the hash of an automatic persisted query request,
+// `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"..."}}}`.
+func persistedQueryHash(req *graphqlRequest) string {
+	pq, ok := req.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	val, _ := pq["sha256Hash"].(string)
+	return val
+}
+
+func hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonical formats a query document, so queries which only differ in whitespace and
+// comments are the same.
+func canonical(doc *ast.QueryDocument) string {
+	var sb strings.Builder
+	formatter.NewFormatter(&sb, formatter.WithCompacted()).FormatQueryDocument(doc)
+	return sb.String()
+}
+
+// Init registers the graphql_guard middleware.
+func Init() error {
+	err := middleware.RegisterValidator([]string{"graphql_guard"}, func(opts Options) error {
+		_, err := NewMiddleware(opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return middleware.Register([]string{"graphql_guard"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
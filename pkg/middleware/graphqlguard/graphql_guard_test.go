@@ -0,0 +1,233 @@
+package graphqlguard
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func post(handler app.HandlerFunc, contentType, body string) (*app.RequestContext, bool) {
+	c := app.NewContext(0)
+	c.Request.SetMethod(http.MethodPost)
+	c.Request.SetRequestURI("/graphql")
+	c.Request.Header.SetContentTypeBytes([]byte(contentType))
+	c.Request.SetBodyString(body)
+	return c, run(handler, c)
+}
+
+func get(handler app.HandlerFunc, query url.Values) (*app.RequestContext, bool) {
+	c := app.NewContext(0)
+	c.Request.SetMethod(http.MethodGet)
+	c.Request.SetRequestURI("/graphql?" + query.Encode())
+	return c, run(handler, c)
+}
+
+func run(handler app.HandlerFunc, c *app.RequestContext) bool {
+	called := false
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{handler, func(_ context.Context, _ *app.RequestContext) {
+		called = true
+	}})
+	c.Next(context.Background())
+	return called
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		expected  stats
+	}{
+		{
+			name:     "fields",
+			query:    `{ user { id name } }`,
+			expected: stats{depth: 2, complexity: 3},
+		},
+		{
+			name:     "aliases",
+			query:    `{ a: user { id } b: user { id } }`,
+			expected: stats{depth: 2, aliases: 2, complexity: 4},
+		},
+		{
+			name:     "fragments",
+			query:    `{ user { ...userFields friends { ...userFields } } } fragment userFields on User { id name }`,
+			expected: stats{depth: 3, complexity: 6},
+		},
+		{
+			name:     "inline fragments",
+			query:    `{ node { ... on User { id } } }`,
+			expected: stats{depth: 2, complexity: 2},
+		},
+		{
+			name:  "pagination",
+			query: `{ users(first: 10) { id friends(last: $n) { id } } }`,
+			variables: map[string]any{
+				"n": 5,
+			},
+			expected: stats{depth: 3, complexity: 1 + 10*(1+1+5*1)},
+		},
+		{
+			name:     "operations",
+			query:    `query a { user { id } } query b { x: user { friends { id } } }`,
+			expected: stats{depth: 3, aliases: 1, complexity: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.ParseQuery(&ast.Source{Input: tt.query})
+			require.NoError(t, err)
+			s, err := analyze(doc, tt.variables)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+
+	doc, err := parser.ParseQuery(&ast.Source{Input: `{ ...a } fragment a on Query { ...b } fragment b on Query { ...a }`})
+	require.NoError(t, err)
+	_, err = analyze(doc, nil)
+	assert.Error(t, err)
+
+	doc, err = parser.ParseQuery(&ast.Source{Input: `{ ...missing }`})
+	require.NoError(t, err)
+	_, err = analyze(doc, nil)
+	assert.Error(t, err)
+}
+
+func TestLimits(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("graphql_guard")
+	handler, err := h(map[string]any{
+		"max_depth":      3,
+		"max_aliases":    1,
+		"max_complexity": 20,
+	})
+	require.NoError(t, err)
+
+	_, called := post(handler, "application/json", `{"query":"{ user { id friends { id } } }"}`)
+	assert.True(t, called)
+
+	c, called := post(handler, "application/json", `{"query":"{ user { friends { friends { id } } } }"}`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+	assert.JSONEq(t, `{"errors":[{"message":"query depth 4 exceeds the maximum depth of 3"}]}`, string(c.Response.Body()))
+
+	_, called = post(handler, "application/graphql", `{ a: user { id } b: user { id } }`)
+	assert.False(t, called)
+
+	_, called = post(handler, "application/json",
+		`{"query":"query($n: Int) { users(first: $n) { id name } }","variables":{"n":100}}`)
+	assert.False(t, called)
+
+	// every request of a batch is checked
+	_, called = post(handler, "application/json", `[{"query":"{ user { id } }"},{"query":"{ a: x b: y }"}]`)
+	assert.False(t, called)
+
+	_, called = get(handler, url.Values{"query": {"{ user { id } }"}})
+	assert.True(t, called)
+
+	c, called = post(handler, "application/json", `{"query":"{ user "}`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+
+	_, called = post(handler, "application/json", `not json`)
+	assert.False(t, called)
+}
+
+func TestAllowlist(t *testing.T) {
+	query := "query GetUser($id: ID!) { user(id: $id) { id name } }"
+	manifest := `{"` + hash(query) + `":"` + query + `"}`
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(path, []byte(manifest), 0o600))
+
+	m, err := NewMiddleware(Options{
+		AllowlistFile:            path,
+		RejectedHTTPStatusCode:   http.StatusForbidden,
+		RejectedHTTPContentType:  "text/plain",
+		RejectedHTTPResponseBody: "forbidden",
+	})
+	require.NoError(t, err)
+
+	// whitespace and comments do not matter
+	_, called := post(m.ServeHTTP, "application/graphql", "# get a user\nquery GetUser($id: ID!) {\n  user(id: $id) {\n    id\n    name\n  }\n}")
+	assert.True(t, called)
+
+	c, called := post(m.ServeHTTP, "application/graphql", `{ users { id } }`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, c.Response.StatusCode())
+	assert.Equal(t, "forbidden", string(c.Response.Body()))
+
+	t.Run("persisted queries", func(t *testing.T) {
+		c, called := post(m.ServeHTTP, "application/json",
+			`{"variables":{"id":"1"},"extensions":{"persistedQuery":{"version":1,"sha256Hash":"`+hash(query)+`"}}}`)
+		assert.True(t, called)
+		assert.Contains(t, string(c.Request.Body()), `"query":"`+query+`"`)
+		assert.Contains(t, string(c.Request.Body()), `"variables":{"id":"1"}`)
+
+		extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + hash(query) + `"}}`
+		c, called = get(m.ServeHTTP, url.Values{"extensions": {extensions}})
+		assert.True(t, called)
+		assert.Equal(t, query, string(c.QueryArgs().Peek("query")))
+
+		_, called = post(m.ServeHTTP, "application/json",
+			`{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"unknown"}}}`)
+		assert.False(t, called)
+	})
+}
+
+func TestMethods(t *testing.T) {
+	query := "query GetUser($id: ID!) { user(id: $id) { id name } }"
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"`+hash(query)+`":"`+query+`"}`), 0o600))
+
+	m, err := NewMiddleware(Options{AllowlistFile: path})
+	require.NoError(t, err)
+
+	// CORS preflight requests are passed through
+	c := app.NewContext(0)
+	c.Request.SetMethod(http.MethodOptions)
+	c.Request.SetRequestURI("/graphql")
+	c.Request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	assert.True(t, run(m.ServeHTTP, c))
+
+	// HEAD requests are checked like GET requests
+	c = app.NewContext(0)
+	c.Request.SetMethod(http.MethodHead)
+	c.Request.SetRequestURI("/graphql?" + url.Values{"query": {"{ users { id } }"}}.Encode())
+	assert.False(t, run(m.ServeHTTP, c))
+
+	c = app.NewContext(0)
+	c.Request.SetMethod(http.MethodHead)
+	c.Request.SetRequestURI("/graphql?" + url.Values{"query": {query}}.Encode())
+	assert.True(t, run(m.ServeHTTP, c))
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{MaxDepth: -1})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{Allowlist: []string{"{ user "}})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{AllowlistFile: "testdata/not_found.json"})
+	assert.Error(t, err)
+
+	m, err := NewMiddleware(Options{Allowlist: []string{"{ user { id } }"}})
+	require.NoError(t, err)
+	assert.Len(t, m.persisted, 1)
+}
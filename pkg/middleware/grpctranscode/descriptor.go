@@ -0,0 +1,161 @@
+package grpctranscode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	// the standard error details are resolved when gRPC errors are rendered as JSON
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// loadFiles loads the descriptors from a FileDescriptorSet or compiles them from .proto files.
+func loadFiles(opts *Options) (*protoregistry.Files, error) {
+	if opts.DescriptorSet != "" {
+		return loadDescriptorSet(opts.DescriptorSet)
+	}
+
+	if len(opts.ProtoFiles) == 0 {
+		return nil, errors.New("proto_files or descriptor_set must be set for grpc_transcode middleware")
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(protocompile.CompositeResolver{
+			&protocompile.SourceResolver{ImportPaths: opts.ImportPaths},
+			// well-known imports such as google/api/annotations.proto are linked into the binary
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				if err != nil {
+					return protocompile.SearchResult{}, err
+				}
+				return protocompile.SearchResult{Desc: fd}, nil
+			}),
+		}),
+	}
+
+	compiled, err := compiler.Compile(context.Background(), opts.ProtoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile proto files: %w", err)
+	}
+
+	files := &protoregistry.Files{}
+	for _, fd := range compiled {
+		err = registerFile(files, fd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set '%s': %w", path, err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	err = proto.Unmarshal(data, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set '%s': %w", path, err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load descriptor set '%s', it must be built with --include_imports: %w", path, err)
+	}
+	return files, nil
+}
+
+// registerFile registers the file and its imports.
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+
+	imports := fd.Imports()
+	for i := range imports.Len() {
+		err := registerFile(files, imports.Get(i).FileDescriptor)
+		if err != nil {
+			return err
+		}
+	}
+
+	return files.RegisterFile(fd)
+}
+
+// httpRule returns the google.api.http annotation of the method, if any.
+func httpRule(md protoreflect.MethodDescriptor) *annotations.HttpRule {
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return nil
+	}
+
+	// options of compiled files may keep the extension as unknown fields, so they are
+	// decoded again with the linked extension types
+	data, err := proto.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	decoded := &descriptorpb.MethodOptions{}
+	err = proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}.Unmarshal(data, decoded)
+	if err != nil {
+		return nil
+	}
+
+	rule, ok := proto.GetExtension(decoded, annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	return rule
+}
+
+// resolver resolves the types of the loaded files first and the linked types after, e.g.
+// for the google.rpc error details.
+type resolver struct {
+	types *dynamicpb.Types
+}
+
+func (r *resolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	mt, err := r.types.FindMessageByName(name)
+	if err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r *resolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	mt, err := r.types.FindMessageByURL(url)
+	if err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByURL(url)
+}
+
+func (r *resolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	xt, err := r.types.FindExtensionByName(field)
+	if err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r *resolver) FindExtensionByNumber(
+	message protoreflect.FullName,
+	field protoreflect.FieldNumber,
+) (protoreflect.ExtensionType, error) {
+	xt, err := r.types.FindExtensionByNumber(message, field)
+	if err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
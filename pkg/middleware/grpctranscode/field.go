@@ -0,0 +1,155 @@
+package grpctranscode
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// findField finds a field by its proto name or JSON name.
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return fields.ByJSONName(name)
+}
+
+// setField sets the field addressed by a dot path, e.g. `book.author.name`, from string
+// values of the path or query string. Repeated fields get all values.
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := findField(msg.Descriptor(), name)
+		if fd == nil {
+			return fmt.Errorf("field '%s' not found in message '%s'", path, msg.Descriptor().FullName())
+		}
+
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field '%s' is not a message in message '%s'", name, msg.Descriptor().FullName())
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		if fd.IsMap() {
+			return fmt.Errorf("map field '%s' cannot be set from the path or query string", path)
+		}
+
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, v := range values {
+				val, err := parseValue(msg, fd, v)
+				if err != nil {
+					return err
+				}
+				list.Append(val)
+			}
+			return nil
+		}
+
+		if len(values) == 0 {
+			return nil
+		}
+		val, err := parseValue(msg, fd, values[len(values)-1])
+		if err != nil {
+			return err
+		}
+		msg.Set(fd, val)
+	}
+	return nil
+}
+
+func parseValue(parent protoreflect.Message, fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	invalid := func(err error) (protoreflect.Value, error) {
+		return protoreflect.Value{}, fmt.Errorf("invalid value '%s' for field '%s': %w", s, fd.Name(), err)
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfBool(v), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt32(int32(v)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt64(v), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint32(uint32(v)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint64(v), nil
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat32(float32(v)), nil
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat64(v), nil
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(s)
+			if err != nil {
+				return invalid(err)
+			}
+		}
+		return protoreflect.ValueOfBytes(v), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// well-known types such as Timestamp, Duration and wrappers have a JSON string form
+		var msg protoreflect.Message
+		if fd.IsList() {
+			msg = parent.Mutable(fd).List().NewElement().Message()
+		} else {
+			msg = parent.NewField(fd).Message()
+		}
+		err := protojson.Unmarshal([]byte(s), msg.Interface())
+		if err != nil {
+			err = protojson.Unmarshal([]byte(strconv.Quote(s)), msg.Interface())
+			if err != nil {
+				return invalid(err)
+			}
+		}
+		return protoreflect.ValueOfMessage(msg), nil
+	default:
+		return invalid(fmt.Errorf("unsupported kind '%s'", fd.Kind()))
+	}
+}
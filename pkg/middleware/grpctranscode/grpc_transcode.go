@@ -0,0 +1,547 @@
+package grpctranscode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/nite-coder/blackbear/pkg/cast"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/nite-coder/bifrost/internal/pkg/grpcstatus"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	grpcHeaderLen   = 5
+	contentTypeJSON = "application/json"
+	contentTypeGRPC = "application/grpc"
+)
+
+// RouteOptions maps an HTTP route to a gRPC method explicitly.
+type RouteOptions struct {
+	// Method is the HTTP method, `*` matches every method.
+	Method string `mapstructure:"method"`
+	// Path is a path template, e.g. `/v1/books/{id}`.
+	Path string `mapstructure:"path"`
+	// GRPCMethod is the full gRPC method name, e.g. `helloworld.Greeter/SayHello`.
+	GRPCMethod string `mapstructure:"grpc_method"`
+	// Body is the request field the body is mapped to, `*` maps the body to the whole request.
+	Body string `mapstructure:"body"`
+	// ResponseBody is the response field returned as the body, the whole response if empty.
+	ResponseBody string `mapstructure:"response_body"`
+}
+
+// Options defines the configuration for the grpc_transcode middleware.
+type Options struct {
+	// DescriptorSet is a FileDescriptorSet file, built with `protoc --include_imports -o`.
+	DescriptorSet string `mapstructure:"descriptor_set"`
+	// ProtoFiles are .proto files, relative to one of the import paths.
+	ProtoFiles  []string       `mapstructure:"proto_files"`
+	ImportPaths []string       `mapstructure:"import_paths"`
+	Routes      []RouteOptions `mapstructure:"routes"`
+	// UseProtoNames uses the proto field names instead of the lowerCamelCase JSON names.
+	UseProtoNames bool `mapstructure:"use_proto_names"`
+	// EmitUnpopulated renders fields with zero values.
+	EmitUnpopulated bool `mapstructure:"emit_unpopulated"`
+	// DiscardUnknown ignores unknown fields of the request body instead of rejecting it.
+	DiscardUnknown bool `mapstructure:"discard_unknown"`
+}
+
+// rule binds an HTTP route to a gRPC method.
+type rule struct {
+	method       protoreflect.MethodDescriptor
+	template     *pathTemplate
+	httpMethod   string
+	grpcPath     string
+	body         string
+	responseBody string
+}
+
+// Middleware is a middleware that transcodes JSON/REST requests to gRPC and the gRPC responses back to JSON.
+type Middleware struct {
+	options     *Options
+	marshaler   protojson.MarshalOptions
+	unmarshaler protojson.UnmarshalOptions
+	rules       []*rule
+}
+
+// NewMiddleware creates a new grpc_transcode middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	files, err := loadFiles(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &resolver{types: dynamicpb.NewTypes(files)}
+	m := &Middleware{
+		options: &options,
+		marshaler: protojson.MarshalOptions{
+			UseProtoNames:   options.UseProtoNames,
+			EmitUnpopulated: options.EmitUnpopulated,
+			Resolver:        res,
+		},
+		unmarshaler: protojson.UnmarshalOptions{
+			DiscardUnknown: options.DiscardUnknown,
+			Resolver:       res,
+		},
+	}
+
+	// explicit routes take precedence over the annotations
+	for _, route := range options.Routes {
+		r, err := newExplicitRule(files, route)
+		if err != nil {
+			return nil, err
+		}
+		m.rules = append(m.rules, r)
+	}
+
+	var errs []error
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := range services.Len() {
+			methods := services.Get(i).Methods()
+			for j := range methods.Len() {
+				rules, err := newAnnotatedRules(methods.Get(j))
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				m.rules = append(m.rules, rules...)
+			}
+		}
+		return true
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if len(m.rules) == 0 {
+		return nil, errors.New("no route is mapped to a gRPC method, use routes or google.api.http annotations")
+	}
+
+	return m, nil
+}
+
+func newExplicitRule(files *protoregistry.Files, route RouteOptions) (*rule, error) {
+	name := strings.TrimPrefix(route.GRPCMethod, "/")
+	name = strings.Replace(name, "/", ".", 1)
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("gRPC method '%s' not found: %w", route.GRPCMethod, err)
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a gRPC method", route.GRPCMethod)
+	}
+
+	return newRule(md, route.Method, route.Path, route.Body, route.ResponseBody)
+}
+
+func newAnnotatedRules(md protoreflect.MethodDescriptor) ([]*rule, error) {
+	httpRule := httpRule(md)
+	if httpRule == nil {
+		return nil, nil
+	}
+
+	bindings := append([]*annotations.HttpRule{httpRule}, httpRule.GetAdditionalBindings()...)
+	rules := make([]*rule, 0, len(bindings))
+	for _, binding := range bindings {
+		var method, path string
+		switch pattern := binding.GetPattern().(type) {
+		case *annotations.HttpRule_Get:
+			method, path = http.MethodGet, pattern.Get
+		case *annotations.HttpRule_Put:
+			method, path = http.MethodPut, pattern.Put
+		case *annotations.HttpRule_Post:
+			method, path = http.MethodPost, pattern.Post
+		case *annotations.HttpRule_Delete:
+			method, path = http.MethodDelete, pattern.Delete
+		case *annotations.HttpRule_Patch:
+			method, path = http.MethodPatch, pattern.Patch
+		case *annotations.HttpRule_Custom:
+			method, path = pattern.Custom.GetKind(), pattern.Custom.GetPath()
+		default:
+			return nil, fmt.Errorf("google.api.http annotation of '%s' has no pattern", md.FullName())
+		}
+
+		r, err := newRule(md, method, path, binding.GetBody(), binding.GetResponseBody())
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func newRule(md protoreflect.MethodDescriptor, httpMethod, path, body, responseBody string) (*rule, error) {
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("streaming gRPC method '%s' cannot be transcoded", md.FullName())
+	}
+
+	tmpl, err := parsePathTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	httpMethod = strings.ToUpper(strings.TrimSpace(httpMethod))
+	if httpMethod == "" {
+		return nil, fmt.Errorf("http method cannot be empty for gRPC method '%s'", md.FullName())
+	}
+
+	if body != "" && body != "*" {
+		fd := findField(md.Input(), body)
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("body '%s' must be a message field of '%s'", body, md.Input().FullName())
+		}
+	}
+
+	if responseBody != "" && findField(md.Output(), responseBody) == nil {
+		return nil, fmt.Errorf("response_body '%s' is not a field of '%s'", responseBody, md.Output().FullName())
+	}
+
+	for _, b := range tmpl.bindings {
+		if err := checkFieldPath(md.Input(), b.field); err != nil {
+			return nil, err
+		}
+	}
+
+	return &rule{
+		method:       md,
+		template:     tmpl,
+		httpMethod:   httpMethod,
+		grpcPath:     "/" + string(md.Parent().FullName()) + "/" + string(md.Name()),
+		body:         body,
+		responseBody: responseBody,
+	}, nil
+}
+
+func checkFieldPath(md protoreflect.MessageDescriptor, path string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := findField(md, name)
+		if fd == nil {
+			return fmt.Errorf("field '%s' not found in message '%s'", path, md.FullName())
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field '%s' is not a message in message '%s'", name, md.FullName())
+			}
+			md = fd.Message()
+		}
+	}
+	return nil
+}
+
+func (m *Middleware) match(c *app.RequestContext) (*rule, map[string]string) {
+	method := string(c.Request.Method())
+	path := string(c.Request.URI().Path())
+
+	for _, r := range m.rules {
+		if r.httpMethod != method && r.httpMethod != "*" {
+			continue
+		}
+		if values, ok := r.template.match(path); ok {
+			return r, values
+		}
+	}
+	return nil, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	// native gRPC requests are proxied as they are
+	if bytes.HasPrefix(c.Request.Header.ContentType(), []byte(contentTypeGRPC)) {
+		return
+	}
+
+	r, values := m.match(c)
+	if r == nil {
+		m.writeStatus(c, status(codes.NotFound, "no gRPC method is mapped to the route"))
+		c.Abort()
+		return
+	}
+
+	payload, err := m.buildRequest(c, r, values)
+	if err != nil {
+		m.writeStatus(c, status(codes.InvalidArgument, err.Error()))
+		c.Abort()
+		return
+	}
+
+	frame := make([]byte, grpcHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(frame[1:grpcHeaderLen], uint32(len(payload))) // #nosec G115
+	copy(frame[grpcHeaderLen:], payload)
+
+	c.Request.SetMethod(http.MethodPost)
+	c.Request.URI().SetPath(r.grpcPath)
+	c.Request.URI().SetQueryString("")
+	c.Request.Header.SetContentTypeBytes([]byte(contentTypeGRPC))
+	c.Request.SetBody(frame)
+	c.Request.Header.SetContentLength(len(frame))
+
+	c.Next(ctx)
+
+	m.buildResponse(ctx, c, r)
+}
+
+func (m *Middleware) buildRequest(c *app.RequestContext, r *rule, values map[string]string) ([]byte, error) {
+	msg := dynamicpb.NewMessage(r.method.Input())
+
+	bound := map[string]struct{}{}
+	if r.body != "" {
+		body := c.Request.Body()
+		if len(bytes.TrimSpace(body)) > 0 {
+			target := protoreflect.Message(msg)
+			if r.body != "*" {
+				fd := findField(msg.Descriptor(), r.body)
+				target = msg.Mutable(fd).Message()
+				bound[string(fd.Name())] = struct{}{}
+			}
+			err := m.unmarshaler.Unmarshal(body, target.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("invalid request body: %w", err)
+			}
+		}
+	}
+
+	for field, value := range values {
+		err := setField(msg, field, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		bound[field] = struct{}{}
+	}
+
+	// the fields which are not bound by the path or the body are read from the query string
+	if r.body != "*" {
+		var errs []error
+		queries := map[string][]string{}
+		c.Request.URI().QueryArgs().VisitAll(func(key, value []byte) {
+			queries[string(key)] = append(queries[string(key)], string(value))
+		})
+		for key, vals := range queries {
+			if _, found := bound[key]; found || isBoundParent(bound, key) {
+				continue
+			}
+			if checkFieldPath(msg.Descriptor(), key) != nil {
+				// unknown query parameters are ignored
+				continue
+			}
+			if err := setField(msg, key, vals); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+	}
+
+	return proto.Marshal(msg)
+}
+
+// isBoundParent reports whether a parent field of the key is bound by the path or the body.
+func isBoundParent(bound map[string]struct{}, key string) bool {
+	for {
+		idx := strings.LastIndexByte(key, '.')
+		if idx < 0 {
+			return false
+		}
+		key = key[:idx]
+		if _, found := bound[key]; found {
+			return true
+		}
+	}
+}
+
+func (m *Middleware) buildResponse(ctx context.Context, c *app.RequestContext, r *rule) {
+	code, ok := grpcstatus.FromResponse(c)
+	if !ok {
+		// the request failed before the upstream answered, e.g. it was rejected by a middleware
+		return
+	}
+
+	body := c.Response.Body()
+	payload, ok := parseFrame(body)
+
+	if code != codes.OK {
+		st := &spb.Status{}
+		if !ok || proto.Unmarshal(payload, st) != nil || st.GetCode() == 0 {
+			st = status(code, c.GetString(variable.GRPCMessage))
+		}
+		m.writeStatus(c, st)
+		return
+	}
+
+	msg := dynamicpb.NewMessage(r.method.Output())
+	if !ok || proto.Unmarshal(payload, msg) != nil {
+		log.FromContext(ctx).Warn("grpc_transcode: failed to decode gRPC response")
+		m.writeStatus(c, status(codes.Internal, "failed to decode gRPC response"))
+		return
+	}
+
+	result, err := m.marshalResponse(msg, r)
+	if err != nil {
+		log.FromContext(ctx).Warn("grpc_transcode: failed to encode gRPC response", "error", err)
+		m.writeStatus(c, status(codes.Internal, "failed to encode gRPC response"))
+		return
+	}
+
+	resetGRPCHeaders(c)
+	c.Response.SetStatusCode(http.StatusOK)
+	c.Response.Header.SetContentType(contentTypeJSON)
+	c.Response.SetBody(result)
+}
+
+func (m *Middleware) marshalResponse(msg *dynamicpb.Message, r *rule) ([]byte, error) {
+	if r.responseBody == "" {
+		return m.marshaler.Marshal(msg)
+	}
+
+	fd := findField(msg.Descriptor(), r.responseBody)
+	if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+		return m.marshaler.Marshal(msg.Get(fd).Message().Interface())
+	}
+
+	// scalar, repeated and map fields are rendered by marshaling a message with only that field
+	single := dynamicpb.NewMessage(msg.Descriptor())
+	single.Set(fd, msg.Get(fd))
+	data, err := m.marshaler.Marshal(single)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fd.JSONName()
+	if m.options.UseProtoNames {
+		name = string(fd.Name())
+	}
+	fields := map[string]json.RawMessage{}
+	if err := sonic.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if val, found := fields[name]; found {
+		return val, nil
+	}
+
+	// unpopulated fields are rendered as their zero values
+	switch {
+	case fd.IsList():
+		return []byte("[]"), nil
+	case fd.IsMap():
+		return []byte("{}"), nil
+	default:
+		opts := m.marshaler
+		opts.EmitUnpopulated = true
+		data, err = opts.Marshal(single)
+		if err != nil {
+			return nil, err
+		}
+		if err := sonic.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		return fields[name], nil
+	}
+}
+
+func (m *Middleware) writeStatus(c *app.RequestContext, st *spb.Status) {
+	data, err := m.marshaler.Marshal(st)
+	if err != nil {
+		// details which cannot be resolved are dropped
+		st = status(codes.Code(st.GetCode()), st.GetMessage()) // #nosec G115
+		data, _ = m.marshaler.Marshal(st)
+	}
+
+	resetGRPCHeaders(c)
+	c.Response.SetStatusCode(HTTPStatusFromCode(codes.Code(st.GetCode()))) // #nosec G115
+	c.Response.Header.SetContentType(contentTypeJSON)
+	c.Response.SetBody(data)
+}
+
+func status(code codes.Code, message string) *spb.Status {
+	return &spb.Status{
+		Code:    int32(code), // #nosec G115
+		Message: message,
+	}
+}
+
+func parseFrame(body []byte) ([]byte, bool) {
+	if len(body) < grpcHeaderLen || body[0] != 0 {
+		return nil, false
+	}
+	length := binary.BigEndian.Uint32(body[1:grpcHeaderLen])
+	if uint64(len(body)-grpcHeaderLen) < uint64(length) {
+		return nil, false
+	}
+	return body[grpcHeaderLen : grpcHeaderLen+int(length)], true
+}
+
+// resetGRPCHeaders removes the gRPC specific headers and trailers of the upstream response.
+func resetGRPCHeaders(c *app.RequestContext) {
+	var keys []string
+	c.Response.Header.VisitAll(func(key, _ []byte) {
+		if len(key) > 5 && strings.EqualFold(cast.B2S(key[:5]), "grpc-") {
+			keys = append(keys, string(key))
+		}
+	})
+	for _, key := range keys {
+		c.Response.Header.Del(key)
+	}
+	c.Response.Header.Trailer().Reset()
+}
+
+// HTTPStatusFromCode maps a gRPC status code to an HTTP status code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Init registers the grpc_transcode middleware.
+func Init() error {
+	return middleware.Register([]string{"grpc_transcode"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
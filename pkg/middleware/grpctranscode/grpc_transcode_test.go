@@ -0,0 +1,426 @@
+package grpctranscode
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	grpcproxy "github.com/nite-coder/bifrost/pkg/proxy/grpc"
+	"github.com/nite-coder/bifrost/pkg/target"
+	"github.com/nite-coder/bifrost/pkg/variable"
+	pb "github.com/nite-coder/bifrost/proto"
+)
+
+func newBookstore(t *testing.T) *Middleware {
+	t.Helper()
+
+	m, err := NewMiddleware(Options{
+		ProtoFiles:  []string{"bookstore.proto"},
+		ImportPaths: []string{"testdata"},
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func frame(payload []byte) []byte {
+	b := make([]byte, grpcHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(b[1:grpcHeaderLen], uint32(len(payload)))
+	copy(b[grpcHeaderLen:], payload)
+	return b
+}
+
+// upstream emulates the gRPC proxy, it decodes the request and answers with the reply.
+func upstream(
+	t *testing.T,
+	m *Middleware,
+	input string,
+	reply func(req *dynamicpb.Message) (proto.Message, error),
+) app.HandlerFunc {
+	t.Helper()
+
+	return func(_ context.Context, c *app.RequestContext) {
+		assert.Equal(t, contentTypeGRPC, string(c.Request.Header.ContentType()))
+
+		var md protoreflect.MessageDescriptor
+		for _, r := range m.rules {
+			if string(r.method.Input().FullName()) == input {
+				md = r.method.Input()
+			}
+		}
+		require.NotNil(t, md)
+
+		body := c.Request.Body()
+		req := dynamicpb.NewMessage(md)
+		require.NoError(t, proto.Unmarshal(body[grpcHeaderLen:], req))
+		c.Set("grpc_path", string(c.Request.URI().Path()))
+
+		resp, err := reply(req)
+		if err != nil {
+			st, _ := grpcstatus.FromError(err)
+			c.Set(variable.GRPCStatusCode, st.Code())
+			c.Set(variable.GRPCMessage, st.Message())
+			data, _ := proto.Marshal(st.Proto())
+			c.Response.Header.SetContentType(contentTypeGRPC)
+			_ = c.Response.Header.Trailer().Set("grpc-status", "5")
+			c.Response.SetBody(frame(data))
+			return
+		}
+
+		data, err := proto.Marshal(resp)
+		require.NoError(t, err)
+		c.Set(variable.GRPCStatusCode, codes.OK)
+		c.Response.Header.SetContentType(contentTypeGRPC)
+		c.Response.Header.Set("grpc-server", "test")
+		_ = c.Response.Header.Trailer().Set("grpc-status", "0")
+		c.Response.SetBody(frame(data))
+	}
+}
+
+func perform(m *Middleware, next app.HandlerFunc, method, uri, body string) *app.RequestContext {
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.SetRequestURI(uri)
+	if body != "" {
+		c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+		c.Request.SetBodyString(body)
+	}
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, next})
+	c.Next(context.Background())
+	return c
+}
+
+func field(msg *dynamicpb.Message, name string) protoreflect.Value {
+	return msg.Get(msg.Descriptor().Fields().ByName(protoreflect.Name(name)))
+}
+
+func TestTranscodeAnnotations(t *testing.T) {
+	m := newBookstore(t)
+
+	t.Run("path and query parameters", func(t *testing.T) {
+		var req *dynamicpb.Message
+		next := upstream(t, m, "bookstore.GetBookRequest", func(r *dynamicpb.Message) (proto.Message, error) {
+			req = r
+			book := dynamicpb.NewMessage(r.Descriptor().Fields().ByName("filter").Message())
+			book.Set(book.Descriptor().Fields().ByName("id"), protoreflect.ValueOfInt64(2))
+			book.Set(book.Descriptor().Fields().ByName("title"), protoreflect.ValueOfString("Go"))
+			return book, nil
+		})
+
+		c := perform(
+			m,
+			next,
+			http.MethodGet,
+			"/v1/shelves/1/books/2?includeTags=true&genres=FICTION&genres=0&filter.title=Go&unknown=1",
+			"",
+		)
+
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.Equal(t, "/bookstore.Bookstore/GetBook", c.GetString("grpc_path"))
+		assert.JSONEq(t, `{"id":"2","title":"Go"}`, string(c.Response.Body()))
+		assert.Equal(t, contentTypeJSON, string(c.Response.Header.ContentType()))
+		assert.Empty(t, c.Response.Header.Get("grpc-server"))
+		assert.Empty(t, c.Response.Header.Trailer().Get("grpc-status"))
+
+		assert.Equal(t, int64(1), field(req, "shelf").Int())
+		assert.Equal(t, int64(2), field(req, "id").Int())
+		assert.True(t, field(req, "include_tags").Bool())
+		assert.Equal(t, 2, field(req, "genres").List().Len())
+		assert.Equal(t, protoreflect.EnumNumber(1), field(req, "genres").List().Get(0).Enum())
+		filter := field(req, "filter").Message()
+		assert.Equal(t, "Go", filter.Get(filter.Descriptor().Fields().ByName("title")).String())
+	})
+
+	t.Run("body field", func(t *testing.T) {
+		var req *dynamicpb.Message
+		next := upstream(t, m, "bookstore.CreateBookRequest", func(r *dynamicpb.Message) (proto.Message, error) {
+			req = r
+			return field(r, "book").Message().Interface(), nil
+		})
+
+		c := perform(
+			m,
+			next,
+			http.MethodPost,
+			"/v1/shelves/3/books",
+			`{"title":"Go","publishedAt":"2024-01-02T03:04:05Z","genre":"FICTION"}`,
+		)
+
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.JSONEq(
+			t,
+			`{"title":"Go","publishedAt":"2024-01-02T03:04:05Z","genre":"FICTION"}`,
+			string(c.Response.Body()),
+		)
+		assert.Equal(t, int64(3), field(req, "shelf").Int())
+	})
+
+	t.Run("additional binding with the whole body", func(t *testing.T) {
+		next := upstream(t, m, "bookstore.CreateBookRequest", func(r *dynamicpb.Message) (proto.Message, error) {
+			assert.Equal(t, int64(4), field(r, "shelf").Int())
+			return field(r, "book").Message().Interface(), nil
+		})
+
+		c := perform(m, next, http.MethodPut, "/v1/books:create", `{"shelf":"4","book":{"title":"Go"}}`)
+
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.JSONEq(t, `{"title":"Go"}`, string(c.Response.Body()))
+	})
+
+	t.Run("response body", func(t *testing.T) {
+		next := upstream(t, m, "bookstore.ListBooksRequest", func(r *dynamicpb.Message) (proto.Message, error) {
+			assert.Equal(t, "shelves/1", field(r, "parent").String())
+
+			md := m.rules[0].method.Parent().ParentFile().Messages().ByName("ListBooksResponse")
+			resp := dynamicpb.NewMessage(md)
+			books := resp.Mutable(md.Fields().ByName("books")).List()
+			book := books.NewElement()
+			book.Message().Set(md.Fields().ByName("books").Message().Fields().ByName("title"), protoreflect.ValueOfString("Go"))
+			books.Append(book)
+			return resp, nil
+		})
+
+		c := perform(m, next, http.MethodGet, "/v1/shelves/1/books", "")
+
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.JSONEq(t, `[{"title":"Go"}]`, string(c.Response.Body()))
+	})
+
+	t.Run("grpc error", func(t *testing.T) {
+		next := upstream(t, m, "bookstore.GetBookRequest", func(_ *dynamicpb.Message) (proto.Message, error) {
+			st := grpcstatus.New(codes.NotFound, "book not found")
+			st, _ = st.WithDetails(&errdetails.ErrorInfo{Reason: "BOOK_NOT_FOUND"})
+			return nil, st.Err()
+		})
+
+		c := perform(m, next, http.MethodGet, "/v1/shelves/1/books/2", "")
+
+		assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+		assert.JSONEq(t, `{
+			"code": 5,
+			"message": "book not found",
+			"details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "BOOK_NOT_FOUND"}]
+		}`, string(c.Response.Body()))
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		called := false
+		next := func(_ context.Context, _ *app.RequestContext) {
+			called = true
+		}
+
+		c := perform(m, next, http.MethodGet, "/v1/shelves/abc/books/2", "")
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+		assert.Contains(t, string(c.Response.Body()), `"code":3`)
+
+		c = perform(m, next, http.MethodPost, "/v1/shelves/1/books", `{"unknown":1}`)
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+
+		c = perform(m, next, http.MethodDelete, "/v1/shelves/1/books/2", "")
+		assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+
+		assert.False(t, called)
+	})
+
+	t.Run("native grpc request", func(t *testing.T) {
+		called := false
+		c := app.NewContext(0)
+		c.Request.SetMethod(http.MethodPost)
+		c.Request.SetRequestURI("/bookstore.Bookstore/GetBook")
+		c.Request.Header.SetContentTypeBytes([]byte("application/grpc+proto"))
+		c.SetIndex(-1)
+		c.SetHandlers([]app.HandlerFunc{m.ServeHTTP, func(_ context.Context, _ *app.RequestContext) {
+			called = true
+		}})
+		c.Next(context.Background())
+
+		assert.True(t, called)
+		assert.Equal(t, "/bookstore.Bookstore/GetBook", string(c.Request.URI().Path()))
+	})
+}
+
+type greeterServer struct {
+	pb.UnimplementedGreeterServer
+}
+
+func (s *greeterServer) SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-user-id")) == 0 {
+		return nil, grpcstatus.Error(codes.Unauthenticated, "x-user-id is required")
+	}
+	return &pb.HelloReply{Message: "Hello " + in.GetName()}, nil
+}
+
+func TestTranscodeGRPCProxy(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	defer s.Stop()
+
+	p, err := grpcproxy.New(grpcproxy.Options{
+		Target:  "grpc://" + lis.Addr().String(),
+		Timeout: 3 * time.Second,
+		Endpoint: &target.Endpoint{
+			Address: lis.Addr().String(),
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	_ = Init()
+	h := middleware.Factory("grpc_transcode")
+	handler, err := h(map[string]any{
+		"proto_files":  []string{"hello_world.proto"},
+		"import_paths": []string{"../../../proto"},
+		"routes": []map[string]any{
+			{
+				"method":      "POST",
+				"path":        "/v1/hello/{name}",
+				"grpc_method": "helloworld.Greeter/SayHello",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	run := func(userID string) *app.RequestContext {
+		c := app.NewContext(0)
+		c.Request.SetMethod(http.MethodPost)
+		c.Request.SetRequestURI("/v1/hello/bifrost")
+		if userID != "" {
+			c.Request.Header.Set("x-user-id", userID)
+		}
+		c.SetIndex(-1)
+		c.SetHandlers([]app.HandlerFunc{handler, p.ServeHTTP})
+		c.Next(context.Background())
+		return c
+	}
+
+	c := run("1")
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.JSONEq(t, `{"message":"Hello bifrost"}`, string(c.Response.Body()))
+
+	c = run("")
+	assert.Equal(t, http.StatusUnauthorized, c.Response.StatusCode())
+	assert.JSONEq(t, `{"code":16,"message":"x-user-id is required"}`, string(c.Response.Body()))
+}
+
+func TestDescriptorSet(t *testing.T) {
+	m := newBookstore(t)
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := range fd.Imports().Len() {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(m.rules[0].method.ParentFile())
+
+	data, err := proto.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "bookstore.pb")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	fromSet, err := NewMiddleware(Options{DescriptorSet: path})
+	require.NoError(t, err)
+	assert.Len(t, fromSet.rules, len(m.rules))
+}
+
+func TestNewMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{name: "no files", options: Options{}},
+		{name: "file not found", options: Options{ProtoFiles: []string{"missing.proto"}}},
+		{name: "descriptor set not found", options: Options{DescriptorSet: "missing.pb"}},
+		{
+			name:    "no routes",
+			options: Options{ProtoFiles: []string{"hello_world.proto"}, ImportPaths: []string{"../../../proto"}},
+		},
+		{
+			name: "method not found",
+			options: Options{
+				ProtoFiles:  []string{"bookstore.proto"},
+				ImportPaths: []string{"testdata"},
+				Routes:      []RouteOptions{{Method: "GET", Path: "/books", GRPCMethod: "bookstore.Bookstore/Missing"}},
+			},
+		},
+		{
+			name: "streaming method",
+			options: Options{
+				ProtoFiles:  []string{"bookstore.proto"},
+				ImportPaths: []string{"testdata"},
+				Routes:      []RouteOptions{{Method: "GET", Path: "/watch", GRPCMethod: "bookstore.Bookstore/WatchBooks"}},
+			},
+		},
+		{
+			name: "unknown path field",
+			options: Options{
+				ProtoFiles:  []string{"bookstore.proto"},
+				ImportPaths: []string{"testdata"},
+				Routes:      []RouteOptions{{Method: "GET", Path: "/books/{missing}", GRPCMethod: "bookstore.Bookstore/GetBook"}},
+			},
+		},
+		{
+			name: "body is not a message",
+			options: Options{
+				ProtoFiles:  []string{"bookstore.proto"},
+				ImportPaths: []string{"testdata"},
+				Routes: []RouteOptions{
+					{Method: "POST", Path: "/books", GRPCMethod: "bookstore.Bookstore/GetBook", Body: "shelf"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMiddleware(tt.options)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, HTTPStatusFromCode(codes.OK))
+	assert.Equal(t, 499, HTTPStatusFromCode(codes.Canceled))
+	assert.Equal(t, http.StatusBadRequest, HTTPStatusFromCode(codes.InvalidArgument))
+	assert.Equal(t, http.StatusGatewayTimeout, HTTPStatusFromCode(codes.DeadlineExceeded))
+	assert.Equal(t, http.StatusConflict, HTTPStatusFromCode(codes.AlreadyExists))
+	assert.Equal(t, http.StatusTooManyRequests, HTTPStatusFromCode(codes.ResourceExhausted))
+	assert.Equal(t, http.StatusServiceUnavailable, HTTPStatusFromCode(codes.Unavailable))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromCode(codes.DataLoss))
+}
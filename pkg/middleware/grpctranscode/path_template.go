@@ -0,0 +1,190 @@
+package grpctranscode
+
+import (
+	"fmt"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	// segmentSingle matches one path segment, `*`
+	segmentSingle
+	// segmentMulti matches the rest of the path, `**`
+	segmentMulti
+)
+
+type segment struct {
+	literal string
+	kind    segmentKind
+}
+
+// binding binds the segments [start, end) of a template to a field.
+type binding struct {
+	field string
+	start int
+	end   int
+}
+
+// pathTemplate is a path template of the google.api.http annotation, e.g.
+// `/v1/{name=shelves/*}/books/{book_id}:publish`.
+type pathTemplate struct {
+	verb     string
+	segments []segment
+	bindings []binding
+}
+
+func parsePathTemplate(tmpl string) (*pathTemplate, error) {
+	if !strings.HasPrefix(tmpl, "/") {
+		return nil, fmt.Errorf("path template '%s' must start with '/'", tmpl)
+	}
+
+	t := &pathTemplate{}
+	path := tmpl[1:]
+
+	// the verb follows the last ':' which is not part of a variable
+	if idx := strings.LastIndexByte(path, ':'); idx >= 0 && !strings.Contains(path[idx:], "}") {
+		t.verb = path[idx+1:]
+		path = path[:idx]
+		if t.verb == "" {
+			return nil, fmt.Errorf("path template '%s' has an empty verb", tmpl)
+		}
+	}
+
+	for len(path) > 0 {
+		var part string
+		if path[0] == '{' {
+			end := strings.IndexByte(path, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("path template '%s' has an unclosed variable", tmpl)
+			}
+			part = path[:end+1]
+			path = path[end+1:]
+		} else {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			part = path[:end]
+			path = path[end:]
+		}
+
+		if len(path) > 0 {
+			if path[0] != '/' {
+				return nil, fmt.Errorf("path template '%s' is invalid", tmpl)
+			}
+			path = path[1:]
+			if path == "" {
+				return nil, fmt.Errorf("path template '%s' must not end with '/'", tmpl)
+			}
+		}
+
+		if !strings.HasPrefix(part, "{") {
+			seg, err := parseSegment(tmpl, part)
+			if err != nil {
+				return nil, err
+			}
+			t.segments = append(t.segments, seg)
+			continue
+		}
+
+		field, pattern, found := strings.Cut(part[1:len(part)-1], "=")
+		if field == "" {
+			return nil, fmt.Errorf("path template '%s' has a variable without field", tmpl)
+		}
+		if !found {
+			pattern = "*"
+		}
+
+		b := binding{field: field, start: len(t.segments)}
+		for p := range strings.SplitSeq(pattern, "/") {
+			seg, err := parseSegment(tmpl, p)
+			if err != nil {
+				return nil, err
+			}
+			t.segments = append(t.segments, seg)
+		}
+		b.end = len(t.segments)
+		t.bindings = append(t.bindings, b)
+	}
+
+	for i, seg := range t.segments {
+		if seg.kind == segmentMulti && i != len(t.segments)-1 {
+			return nil, fmt.Errorf("path template '%s' can only have '**' as the last segment", tmpl)
+		}
+	}
+
+	return t, nil
+}
+
+func parseSegment(tmpl string, s string) (segment, error) {
+	switch s {
+	case "":
+		return segment{}, fmt.Errorf("path template '%s' has an empty segment", tmpl)
+	case "*":
+		return segment{kind: segmentSingle}, nil
+	case "**":
+		return segment{kind: segmentMulti}, nil
+	default:
+		if strings.ContainsAny(s, "{}=*") {
+			return segment{}, fmt.Errorf("path template '%s' has an invalid segment '%s'", tmpl, s)
+		}
+		return segment{kind: segmentLiteral, literal: s}, nil
+	}
+}
+
+// match matches the path and returns the values of the bound fields.
+func (t *pathTemplate) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	path = path[1:]
+
+	if t.verb != "" {
+		var found bool
+		path, found = strings.CutSuffix(path, ":"+t.verb)
+		if !found {
+			return nil, false
+		}
+	}
+
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	// the end of each template segment in parts
+	ends := make([]int, len(t.segments))
+	pos := 0
+	for i, seg := range t.segments {
+		switch seg.kind {
+		case segmentLiteral:
+			if pos >= len(parts) || parts[pos] != seg.literal {
+				return nil, false
+			}
+			pos++
+		case segmentSingle:
+			if pos >= len(parts) || parts[pos] == "" {
+				return nil, false
+			}
+			pos++
+		case segmentMulti:
+			pos = len(parts)
+		}
+		ends[i] = pos
+	}
+	if pos != len(parts) {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.bindings))
+	for _, b := range t.bindings {
+		start := 0
+		if b.start > 0 {
+			start = ends[b.start-1]
+		}
+		values[b.field] = strings.Join(parts[start:ends[b.end-1]], "/")
+	}
+	return values, true
+}
@@ -0,0 +1,67 @@
+package grpctranscode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     map[string]string
+		match    bool
+	}{
+		{template: "/v1/books", path: "/v1/books", want: map[string]string{}, match: true},
+		{template: "/v1/books", path: "/v1/books/1", match: false},
+		{template: "/v1/books/{id}", path: "/v1/books/1", want: map[string]string{"id": "1"}, match: true},
+		{template: "/v1/books/{id}", path: "/v1/books/", match: false},
+		{template: "/v1/books/{book.id}", path: "/v1/books/1", want: map[string]string{"book.id": "1"}, match: true},
+		{
+			template: "/v1/{name=shelves/*/books/*}",
+			path:     "/v1/shelves/1/books/2",
+			want:     map[string]string{"name": "shelves/1/books/2"},
+			match:    true,
+		},
+		{template: "/v1/{name=shelves/*}/books", path: "/v1/shelves/1/books", want: map[string]string{"name": "shelves/1"}, match: true},
+		{template: "/v1/{name=shelves/*}/books", path: "/v1/authors/1/books", match: false},
+		{template: "/v1/files/{path=**}", path: "/v1/files/a/b/c.txt", want: map[string]string{"path": "a/b/c.txt"}, match: true},
+		{template: "/v1/*/books", path: "/v1/any/books", want: map[string]string{}, match: true},
+		{template: "/v1/books/{id}:publish", path: "/v1/books/1:publish", want: map[string]string{"id": "1"}, match: true},
+		{template: "/v1/books/{id}:publish", path: "/v1/books/1", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.path, func(t *testing.T) {
+			tmpl, err := parsePathTemplate(tt.template)
+			require.NoError(t, err)
+
+			values, ok := tmpl.match(tt.path)
+			assert.Equal(t, tt.match, ok)
+			if tt.match {
+				assert.Equal(t, tt.want, values)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplateErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"v1/books",
+		"/v1/books/",
+		"/v1//books",
+		"/v1/{id",
+		"/v1/{}",
+		"/v1/books:",
+		"/v1/**/books",
+		"/v1/{id=**}/books",
+		"/v1/bo*ks",
+	} {
+		t.Run(tmpl, func(t *testing.T) {
+			_, err := parsePathTemplate(tmpl)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"google.golang.org/grpc/codes"
+
+	"github.com/nite-coder/bifrost/internal/pkg/grpcstatus"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	grpcHeaderLen = 5
+	// trailerFlag marks a frame which carries the trailers instead of a message.
+	trailerFlag = 0x80
+
+	contentTypeGRPC    = "application/grpc"
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+)
+
+// trailers are moved from the response headers to the trailer frame
+var trailerHeaders = []string{"grpc-status", "grpc-message", "grpc-status-details-bin"}
+
+// Options defines the configuration for the grpc_web middleware.
+type Options struct{}
+
+// Middleware is a middleware that converts gRPC-Web requests to gRPC and the gRPC responses back to gRPC-Web.
+type Middleware struct{}
+
+// NewMiddleware creates a new grpc_web middleware instance.
+func NewMiddleware(_ Options) *Middleware {
+	return &Middleware{}
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	contentType := string(c.Request.Header.ContentType())
+	subtype, isWeb := strings.CutPrefix(contentType, contentTypeWeb)
+	if !isWeb || !bytes.Equal(c.Request.Method(), []byte(http.MethodPost)) {
+		return
+	}
+
+	// application/grpc-web-text+proto is the text mode of application/grpc-web+proto
+	subtype, isText := strings.CutPrefix(subtype, "-text")
+	if subtype != "" && !strings.HasPrefix(subtype, "+") {
+		return
+	}
+
+	if isText {
+		body, err := decodeText(c.Request.Body())
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.SetBody(body)
+		c.Request.Header.SetContentLength(len(body))
+	}
+
+	c.Request.Header.SetContentTypeBytes([]byte(contentTypeGRPC + subtype))
+	c.Request.Header.Del("X-Grpc-Web")
+
+	c.Next(ctx)
+
+	code, ok := grpcstatus.FromResponse(c)
+	if !ok {
+		// the response is not a gRPC response, e.g. the request was rejected by a middleware
+		return
+	}
+
+	trailer := map[string]string{}
+	for _, key := range trailerHeaders {
+		if val := c.Response.Header.Get(key); val != "" {
+			trailer[key] = val
+		}
+		c.Response.Header.Del(key)
+	}
+	c.Response.Header.Trailer().VisitAll(func(key, value []byte) {
+		trailer[strings.ToLower(string(key))] = string(value)
+	})
+	c.Response.Header.Trailer().Reset()
+
+	trailer["grpc-status"] = strconv.Itoa(int(code))
+	if _, found := trailer["grpc-message"]; !found && code != codes.OK {
+		if msg := c.GetString(variable.GRPCMessage); msg != "" {
+			trailer["grpc-message"] = msg
+		}
+	}
+
+	var body []byte
+	if code == codes.OK {
+		// the error responses of the upstream have no message
+		body = append(body, c.Response.Body()...)
+	}
+	body = append(body, trailerFrame(trailer)...)
+
+	responseType := contentTypeWeb
+	if isText {
+		responseType = contentTypeWebText
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+	}
+	if subtype == "" {
+		subtype = "+proto"
+	}
+
+	c.Response.SetStatusCode(http.StatusOK)
+	c.Response.Header.SetContentType(responseType + subtype)
+	c.Response.SetBody(body)
+}
+
+// decodeText decodes a base64 body. Clients may send several base64 chunks with their own padding.
+func decodeText(body []byte) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+	result := make([]byte, 0, base64.StdEncoding.DecodedLen(len(body)))
+	for len(body) > 0 {
+		end := bytes.IndexByte(body, '=')
+		if end < 0 {
+			end = len(body)
+		}
+		for end < len(body) && body[end] == '=' {
+			end++
+		}
+
+		chunk := make([]byte, base64.StdEncoding.DecodedLen(end))
+		n, err := base64.StdEncoding.Decode(chunk, body[:end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk[:n]...)
+		body = body[end:]
+	}
+	if len(result) == 0 {
+		return nil, errors.New("grpc_web: empty request")
+	}
+	return result, nil
+}
+
+// trailerFrame encodes the trailers as an HTTP/1 header block in a frame with the trailer flag.
+func trailerFrame(trailer map[string]string) []byte {
+	keys := make([]string, 0, len(trailer))
+	for key := range trailer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var block bytes.Buffer
+	for _, key := range keys {
+		block.WriteString(key)
+		block.WriteString(":")
+		block.WriteString(trailer[key])
+		block.WriteString("\r\n")
+	}
+
+	frame := make([]byte, grpcHeaderLen+block.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:grpcHeaderLen], uint32(block.Len())) // #nosec G115
+	copy(frame[grpcHeaderLen:], block.Bytes())
+	return frame
+}
+
+// Init registers the grpc_web middleware.
+func Init() error {
+	return middleware.Register([]string{"grpc_web"}, func(opts Options) (app.HandlerFunc, error) {
+		m := NewMiddleware(opts)
+		return m.ServeHTTP, nil
+	})
+}
@@ -0,0 +1,184 @@
+package grpcweb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	grpcproxy "github.com/nite-coder/bifrost/pkg/proxy/grpc"
+	"github.com/nite-coder/bifrost/pkg/target"
+	pb "github.com/nite-coder/bifrost/proto"
+)
+
+type greeterServer struct {
+	pb.UnimplementedGreeterServer
+}
+
+func (s *greeterServer) SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-user-id")) == 0 {
+		return nil, grpcstatus.Error(codes.Unauthenticated, "x-user-id is required")
+	}
+	return &pb.HelloReply{Message: "Hello " + in.GetName()}, nil
+}
+
+func frame(flag byte, payload []byte) []byte {
+	f := make([]byte, grpcHeaderLen+len(payload))
+	f[0] = flag
+	binary.BigEndian.PutUint32(f[1:grpcHeaderLen], uint32(len(payload)))
+	copy(f[grpcHeaderLen:], payload)
+	return f
+}
+
+// readFrames splits a gRPC-Web body into its message and trailer frames.
+func readFrames(t *testing.T, body []byte) ([][]byte, string) {
+	t.Helper()
+	var messages [][]byte
+	var trailer string
+	for len(body) > 0 {
+		require.GreaterOrEqual(t, len(body), grpcHeaderLen)
+		length := int(binary.BigEndian.Uint32(body[1:grpcHeaderLen]))
+		payload := body[grpcHeaderLen : grpcHeaderLen+length]
+		if body[0]&trailerFlag != 0 {
+			trailer = string(payload)
+		} else {
+			messages = append(messages, payload)
+		}
+		body = body[grpcHeaderLen+length:]
+	}
+	return messages, trailer
+}
+
+func TestGRPCWeb(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterGreeterServer(s, &greeterServer{})
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	defer s.Stop()
+
+	p, err := grpcproxy.New(grpcproxy.Options{
+		Target:  "grpc://" + lis.Addr().String(),
+		Timeout: 3 * time.Second,
+		Endpoint: &target.Endpoint{
+			Address: lis.Addr().String(),
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	_ = Init()
+	h := middleware.Factory("grpc_web")
+	handler, err := h(nil)
+	require.NoError(t, err)
+
+	req, err := proto.Marshal(&pb.HelloRequest{Name: "bifrost"})
+	require.NoError(t, err)
+
+	run := func(contentType string, userID string, body []byte) *app.RequestContext {
+		c := app.NewContext(0)
+		c.Request.SetMethod(http.MethodPost)
+		c.Request.SetRequestURI("/helloworld.Greeter/SayHello")
+		c.Request.Header.SetContentTypeBytes([]byte(contentType))
+		c.Request.Header.Set("X-Grpc-Web", "1")
+		if userID != "" {
+			c.Request.Header.Set("x-user-id", userID)
+		}
+		c.Request.SetBody(body)
+		c.SetIndex(-1)
+		c.SetHandlers([]app.HandlerFunc{handler, p.ServeHTTP})
+		c.Next(context.Background())
+		return c
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		c := run("application/grpc-web+proto", "1", frame(0, req))
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.Equal(t, "application/grpc-web+proto", string(c.Response.Header.ContentType()))
+		assert.Empty(t, c.Response.Header.Trailer().Get("grpc-status"))
+
+		messages, trailer := readFrames(t, c.Response.Body())
+		require.Len(t, messages, 1)
+		reply := &pb.HelloReply{}
+		require.NoError(t, proto.Unmarshal(messages[0], reply))
+		assert.Equal(t, "Hello bifrost", reply.GetMessage())
+		assert.Contains(t, trailer, "grpc-status:0\r\n")
+	})
+
+	t.Run("text", func(t *testing.T) {
+		body := base64.StdEncoding.EncodeToString(frame(0, req))
+		c := run("application/grpc-web-text", "1", []byte(body))
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.Equal(t, "application/grpc-web-text+proto", string(c.Response.Header.ContentType()))
+
+		decoded, err := base64.StdEncoding.DecodeString(string(c.Response.Body()))
+		require.NoError(t, err)
+		messages, trailer := readFrames(t, decoded)
+		require.Len(t, messages, 1)
+		reply := &pb.HelloReply{}
+		require.NoError(t, proto.Unmarshal(messages[0], reply))
+		assert.Equal(t, "Hello bifrost", reply.GetMessage())
+		assert.Contains(t, trailer, "grpc-status:0\r\n")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		c := run("application/grpc-web+proto", "", frame(0, req))
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+		assert.Empty(t, c.Response.Header.Get("grpc-status"))
+
+		messages, trailer := readFrames(t, c.Response.Body())
+		assert.Empty(t, messages)
+		assert.Contains(t, trailer, "grpc-status:16\r\n")
+		assert.Contains(t, trailer, "grpc-message:x-user-id is required\r\n")
+	})
+
+	t.Run("invalid text body", func(t *testing.T) {
+		c := run("application/grpc-web-text", "1", []byte("!!!"))
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+	})
+}
+
+func TestPassThrough(t *testing.T) {
+	m := NewMiddleware(Options{})
+
+	for _, contentType := range []string{"application/grpc", "application/json", "application/grpc-webx"} {
+		c := app.NewContext(0)
+		c.Request.SetMethod(http.MethodPost)
+		c.Request.Header.SetContentTypeBytes([]byte(contentType))
+		c.Response.SetBody([]byte("ok"))
+
+		m.ServeHTTP(context.Background(), c)
+
+		assert.Equal(t, contentType, string(c.Request.Header.ContentType()))
+		assert.Equal(t, "ok", string(c.Response.Body()))
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	// chunks are encoded separately and keep their own padding
+	body := base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cde"))
+	decoded, err := decodeText([]byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", string(decoded))
+
+	_, err = decodeText(nil)
+	assert.Error(t, err)
+}
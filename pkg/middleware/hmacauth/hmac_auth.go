@@ -0,0 +1,324 @@
+package hmacauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // hmac-sha1 is still used by some webhook senders
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/pkg/connector/redis"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	defaultClockSkew       = 5 * time.Minute
+	defaultSignatureHeader = "X-Signature"
+	defaultDateHeader      = "Date"
+	nonceKeyPrefix         = "hmac_auth:nonce:"
+	headerPartPrefix       = "header:"
+)
+
+// Canonical string parts.
+const (
+	PartMethod     = "method"
+	PartPath       = "path"
+	PartQuery      = "query"
+	PartDate       = "date"
+	PartNonce      = "nonce"
+	PartBodyDigest = "body_digest"
+)
+
+// NonceStoreMode defines where nonces are stored.
+type NonceStoreMode string
+
+const (
+	// Local stores nonces in local memory.
+	Local NonceStoreMode = "local"
+	// Redis stores nonces in redis.
+	Redis NonceStoreMode = "redis"
+)
+
+var defaultSignedParts = []string{PartMethod, PartPath, PartDate, PartBodyDigest}
+
+// Init registers the hmac_auth middleware.
+func Init() error {
+	return middleware.Register([]string{"hmac_auth"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
+
+// Options defines the configuration for the hmac_auth middleware.
+type Options struct {
+	Secrets                  map[string]string `mapstructure:"secrets"`
+	Algorithm                string            `mapstructure:"algorithm"`
+	Secret                   string            `mapstructure:"secret"`
+	KeyIDHeader              string            `mapstructure:"key_id_header"`
+	SignatureHeader          string            `mapstructure:"signature_header"`
+	SignaturePrefix          string            `mapstructure:"signature_prefix"`
+	SignatureEncoding        string            `mapstructure:"signature_encoding"`
+	DateHeader               string            `mapstructure:"date_header"`
+	NonceHeader              string            `mapstructure:"nonce_header"`
+	NonceStore               NonceStoreMode    `mapstructure:"nonce_store"`
+	RedisID                  string            `mapstructure:"redis_id"`
+	RejectedHTTPContentType  string            `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string            `mapstructure:"rejected_http_response_body"`
+	SignedParts              []string          `mapstructure:"signed_parts"`
+	ClockSkew                time.Duration     `mapstructure:"clock_skew"`
+	RejectedHTTPStatusCode   int               `mapstructure:"rejected_http_status_code"`
+}
+
+// Middleware verifies HMAC request signatures.
+type Middleware struct {
+	options  *Options
+	newHash  func() hash.Hash
+	decode   func(string) ([]byte, error)
+	nonces   NonceStore
+	nonceTTL time.Duration
+}
+
+// NewMiddleware creates a new hmac_auth middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Secret == "" && len(options.Secrets) == 0 {
+		return nil, errors.New("secret and secrets cannot both be empty")
+	}
+	if (len(options.Secrets) > 0) != (options.KeyIDHeader != "") {
+		return nil, errors.New("secrets and key_id_header must be set together")
+	}
+	if options.SignatureHeader == "" {
+		options.SignatureHeader = defaultSignatureHeader
+	}
+	if options.DateHeader == "" {
+		options.DateHeader = defaultDateHeader
+	}
+	if options.ClockSkew == 0 {
+		options.ClockSkew = defaultClockSkew
+	}
+	if len(options.SignedParts) == 0 {
+		options.SignedParts = defaultSignedParts
+	}
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusUnauthorized
+	}
+
+	m := &Middleware{
+		options:  &options,
+		nonceTTL: 2 * options.ClockSkew,
+	}
+
+	switch strings.ToLower(options.Algorithm) {
+	case "", "hmac-sha256":
+		m.newHash = sha256.New
+	case "hmac-sha512":
+		m.newHash = sha512.New
+	case "hmac-sha1":
+		m.newHash = sha1.New
+	default:
+		return nil, fmt.Errorf("algorithm '%s' is not supported", options.Algorithm)
+	}
+
+	switch options.SignatureEncoding {
+	case "", "hex":
+		m.decode = hex.DecodeString
+	case "base64":
+		m.decode = base64.StdEncoding.DecodeString
+	default:
+		return nil, fmt.Errorf("signature_encoding '%s' is not supported", options.SignatureEncoding)
+	}
+
+	for _, part := range options.SignedParts {
+		switch part {
+		case PartMethod, PartPath, PartQuery, PartDate, PartBodyDigest:
+		case PartNonce:
+			if options.NonceHeader == "" {
+				return nil, errors.New("nonce_header must be set when nonce is signed")
+			}
+		default:
+			if !strings.HasPrefix(part, headerPartPrefix) || len(part) == len(headerPartPrefix) {
+				return nil, fmt.Errorf("signed part '%s' is invalid", part)
+			}
+		}
+	}
+
+	// unsigned dates and nonces can be replaced by an attacker who replays a captured request
+	if options.ClockSkew > 0 && !slices.Contains(options.SignedParts, PartDate) {
+		return nil, errors.New("date must be in signed_parts when clock_skew is enabled")
+	}
+
+	if options.NonceHeader != "" {
+		if options.ClockSkew < 0 {
+			return nil, errors.New("clock_skew must be enabled when nonce_header is set")
+		}
+		if !slices.Contains(options.SignedParts, PartNonce) {
+			return nil, errors.New("nonce must be in signed_parts when nonce_header is set")
+		}
+
+		switch options.NonceStore {
+		case "", Local:
+			m.nonces = NewLocalNonceStore()
+		case Redis:
+			client, found := redis.Get(options.RedisID)
+			if !found {
+				return nil, fmt.Errorf("redis id '%s' not found for hmac_auth middleware", options.RedisID)
+			}
+			m.nonces = NewRedisNonceStore(client)
+		default:
+			return nil, fmt.Errorf("nonce_store '%s' is invalid for hmac_auth middleware", options.NonceStore)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	logger := log.FromContext(ctx)
+
+	signature := string(c.Request.Header.Peek(m.options.SignatureHeader))
+	if m.options.SignaturePrefix != "" {
+		var found bool
+		signature, found = strings.CutPrefix(signature, m.options.SignaturePrefix)
+		if !found {
+			m.reject(c)
+			return
+		}
+	}
+	expected, err := m.decode(signature)
+	if err != nil || len(expected) == 0 {
+		m.reject(c)
+		return
+	}
+
+	keyID := ""
+	secret := m.options.Secret
+	if m.options.KeyIDHeader != "" {
+		keyID = string(c.Request.Header.Peek(m.options.KeyIDHeader))
+		var found bool
+		secret, found = m.options.Secrets[keyID]
+		if !found {
+			m.reject(c)
+			return
+		}
+	}
+
+	date := string(c.Request.Header.Peek(m.options.DateHeader))
+	if m.options.ClockSkew > 0 && !m.withinClockSkew(date) {
+		m.reject(c)
+		return
+	}
+
+	mac := hmac.New(m.newHash, []byte(secret))
+	_, _ = mac.Write([]byte(m.canonicalString(c, date)))
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		m.reject(c)
+		return
+	}
+
+	// nonces are checked last, so unauthenticated requests cannot burn them
+	if m.nonces != nil {
+		nonce := string(c.Request.Header.Peek(m.options.NonceHeader))
+		if nonce == "" {
+			m.reject(c)
+			return
+		}
+
+		fresh, err := m.nonces.Add(ctx, nonceKeyPrefix+keyID+":"+nonce, m.nonceTTL)
+		if err != nil {
+			logger.Warn("hmac_auth: failed to record nonce", "error", err)
+			c.SetStatusCode(http.StatusServiceUnavailable)
+			c.Abort()
+			return
+		}
+		if !fresh {
+			m.reject(c)
+			return
+		}
+	}
+
+	if keyID != "" {
+		c.Set(variable.AuthConsumer, keyID)
+	}
+
+	c.Next(ctx)
+}
+
+// canonicalString joins the signed parts with a newline.
+func (m *Middleware) canonicalString(c *app.RequestContext, date string) string {
+	var sb strings.Builder
+	for i, part := range m.options.SignedParts {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+
+		switch part {
+		case PartMethod:
+			sb.Write(c.Request.Method())
+		case PartPath:
+			sb.Write(c.Request.URI().PathOriginal())
+		case PartQuery:
+			sb.Write(c.Request.URI().QueryString())
+		case PartDate:
+			sb.WriteString(date)
+		case PartNonce:
+			sb.Write(c.Request.Header.Peek(m.options.NonceHeader))
+		case PartBodyDigest:
+			sum := sha256.Sum256(c.Request.Body())
+			sb.WriteString(hex.EncodeToString(sum[:]))
+		default:
+			sb.Write(c.Request.Header.Peek(strings.TrimPrefix(part, headerPartPrefix)))
+		}
+	}
+	return sb.String()
+}
+
+// withinClockSkew accepts HTTP dates and unix timestamps in seconds.
+func (m *Middleware) withinClockSkew(date string) bool {
+	if date == "" {
+		return false
+	}
+
+	var t time.Time
+	if unix, err := strconv.ParseInt(date, 10, 64); err == nil {
+		t = time.Unix(unix, 0)
+	} else {
+		t, err = http.ParseTime(date)
+		if err != nil {
+			return false
+		}
+	}
+
+	diff := timecache.Now().Sub(t)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= m.options.ClockSkew
+}
+
+func (m *Middleware) reject(c *app.RequestContext) {
+	c.SetStatusCode(m.options.RejectedHTTPStatusCode)
+	if len(m.options.RejectedHTTPContentType) > 0 {
+		c.SetContentType(m.options.RejectedHTTPContentType)
+	}
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	}
+	c.Abort()
+}
@@ -0,0 +1,168 @@
+package hmacauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+func sign(secret string, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func bodyDigest(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func serve(m app.HandlerFunc, c *app.RequestContext) bool {
+	called := false
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{m, func(_ context.Context, c *app.RequestContext) {
+		called = true
+		c.SetStatusCode(http.StatusOK)
+	}})
+	c.Next(context.Background())
+	return called
+}
+
+func TestHMACAuth(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("hmac_auth")
+
+	m, err := h(map[string]any{
+		"secrets":          map[string]any{"partner-a": "s3cret"},
+		"key_id_header":    "X-Key-ID",
+		"signature_prefix": "sha256=",
+		"date_header":      "X-Timestamp",
+		"nonce_header":     "X-Nonce",
+		"signed_parts":     []string{"method", "path", "query", "date", "nonce", "header:X-Key-ID", "body_digest"},
+		"clock_skew":       "1m",
+	})
+	require.NoError(t, err)
+
+	newRequest := func(nonce string, ts time.Time, secret string) *app.RequestContext {
+		body := `{"event":"paid"}`
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		canonical := "POST\n/webhooks\nid=1\n" + timestamp + "\n" + nonce + "\npartner-a\n" + bodyDigest(body)
+
+		c := app.NewContext(0)
+		c.Request.SetMethod(http.MethodPost)
+		c.Request.SetRequestURI("/webhooks?id=1")
+		c.Request.SetBodyString(body)
+		c.Request.Header.Set("X-Key-ID", "partner-a")
+		c.Request.Header.Set("X-Timestamp", timestamp)
+		c.Request.Header.Set("X-Nonce", nonce)
+		c.Request.Header.Set("X-Signature", "sha256="+sign(secret, canonical))
+		return c
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		c := newRequest("n1", time.Now(), "s3cret")
+		assert.True(t, serve(m, c))
+		assert.Equal(t, "partner-a", c.GetString(variable.AuthConsumer))
+	})
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		c := newRequest("n1", time.Now(), "s3cret")
+		assert.False(t, serve(m, c))
+		assert.Equal(t, http.StatusUnauthorized, c.Response.StatusCode())
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		c := newRequest("n2", time.Now(), "wrong")
+		assert.False(t, serve(m, c))
+
+		// the nonce was not burned by the invalid request
+		c = newRequest("n2", time.Now(), "s3cret")
+		assert.True(t, serve(m, c))
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		c := newRequest("n3", time.Now().Add(-2*time.Minute), "s3cret")
+		assert.False(t, serve(m, c))
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		c := newRequest("n4", time.Now(), "s3cret")
+		c.Request.SetBodyString(`{"event":"refunded"}`)
+		assert.False(t, serve(m, c))
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		c := newRequest("n5", time.Now(), "s3cret")
+		c.Request.Header.Set("X-Key-ID", "partner-b")
+		assert.False(t, serve(m, c))
+	})
+
+	t.Run("replayed with a new nonce", func(t *testing.T) {
+		c := newRequest("n7", time.Now(), "s3cret")
+		assert.True(t, serve(m, c))
+
+		c = newRequest("n7", time.Now(), "s3cret")
+		c.Request.Header.Set("X-Nonce", "n8")
+		assert.False(t, serve(m, c))
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		c := newRequest("n6", time.Now(), "s3cret")
+		c.Request.Header.Del("X-Signature")
+		assert.False(t, serve(m, c))
+	})
+}
+
+func TestHMACAuthHTTPDate(t *testing.T) {
+	m, err := NewMiddleware(Options{Secret: "s3cret"})
+	require.NoError(t, err)
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	c := app.NewContext(0)
+	c.Request.SetMethod(http.MethodGet)
+	c.Request.SetRequestURI("/orders")
+	c.Request.Header.Set("Date", date)
+	c.Request.Header.Set("X-Signature", sign("s3cret", "GET\n/orders\n"+date+"\n"+bodyDigest("")))
+	assert.True(t, serve(m.ServeHTTP, c))
+
+	c.Request.Header.Del("Date")
+	assert.False(t, serve(m.ServeHTTP, c))
+}
+
+func TestNewMiddlewareValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr string
+	}{
+		{name: "no secret", options: Options{}, wantErr: "cannot both be empty"},
+		{name: "secrets without header", options: Options{Secrets: map[string]string{"a": "b"}}, wantErr: "must be set together"},
+		{name: "algorithm", options: Options{Secret: "s", Algorithm: "md5"}, wantErr: "algorithm 'md5'"},
+		{name: "encoding", options: Options{Secret: "s", SignatureEncoding: "base32"}, wantErr: "signature_encoding"},
+		{name: "signed part", options: Options{Secret: "s", SignedParts: []string{"header:"}}, wantErr: "signed part"},
+		{name: "nonce part", options: Options{Secret: "s", SignedParts: []string{"nonce"}}, wantErr: "nonce_header"},
+		{name: "redis", options: Options{Secret: "s", NonceHeader: "X-Nonce", SignedParts: []string{"date", "nonce"}, NonceStore: Redis, RedisID: "missing"}, wantErr: "redis id"},
+		{name: "unsigned nonce", options: Options{Secret: "s", NonceHeader: "X-Nonce"}, wantErr: "nonce must be in signed_parts"},
+		{name: "unsigned date", options: Options{Secret: "s", SignedParts: []string{"method", "path"}}, wantErr: "date must be in signed_parts"},
+		{name: "clock skew", options: Options{Secret: "s", NonceHeader: "X-Nonce", ClockSkew: -1}, wantErr: "clock_skew"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMiddleware(tt.options)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package hmacauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nite-coder/blackbear/pkg/cache/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore remembers nonces to detect replayed requests.
+type NonceStore interface {
+	// Add records the nonce and reports whether it was not seen before.
+	Add(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// LocalNonceStore keeps nonces in local memory.
+type LocalNonceStore struct {
+	cache *cache.Cache[string, struct{}]
+	mu    sync.Mutex
+}
+
+// NewLocalNonceStore creates a new LocalNonceStore instance.
+func NewLocalNonceStore() *LocalNonceStore {
+	const defaultCacheCleanupInterval = time.Minute
+	return &LocalNonceStore{
+		cache: cache.NewCache[string, struct{}](defaultCacheCleanupInterval),
+	}
+}
+
+// Add records the nonce and reports whether it was not seen before.
+func (s *LocalNonceStore) Add(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.cache.Get(nonce); found {
+		return false, nil
+	}
+	s.cache.PutWithTTL(nonce, struct{}{}, ttl)
+	return true, nil
+}
+
+// RedisNonceStore keeps nonces in Redis, so replays are detected across gateway instances.
+type RedisNonceStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisNonceStore creates a new RedisNonceStore instance.
+func NewRedisNonceStore(client redis.UniversalClient) *RedisNonceStore {
+	return &RedisNonceStore{client: client}
+}
+
+// Add records the nonce and reports whether it was not seen before.
+func (s *RedisNonceStore) Add(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, nonce, 1, ttl).Result()
+}
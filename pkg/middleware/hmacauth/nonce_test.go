@@ -0,0 +1,28 @@
+package hmacauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalNonceStore(t *testing.T) {
+	store := NewLocalNonceStore()
+	ctx := context.Background()
+
+	fresh, err := store.Add(ctx, "n1", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.Add(ctx, "n1", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+
+	assert.Eventually(t, func() bool {
+		fresh, _ := store.Add(ctx, "n1", time.Minute)
+		return fresh
+	}, time.Second, 20*time.Millisecond)
+}
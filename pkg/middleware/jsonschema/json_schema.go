@@ -0,0 +1,251 @@
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/nite-coder/blackbear/pkg/cast"
+	jsonschemav6 "github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+// inlineSchemaURL is the URL of inline schemas, relative `$ref`s are resolved from the working directory
+const inlineSchemaURL = "inline.json"
+
+var (
+	printer        = message.NewPrinter(language.English)
+	pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// Options defines the configuration for the json_schema middleware.
+type Options struct {
+	// Schema is an inline JSON schema, either as a YAML object or a JSON string.
+	Schema any `mapstructure:"schema"`
+	// SchemaFile is the path of a JSON schema file in JSON or YAML.
+	SchemaFile string `mapstructure:"schema_file"`
+	// Methods are the request methods whose bodies are validated, requests with other methods
+	// are passed through.
+	Methods                  []string `mapstructure:"methods"`
+	RejectedHTTPContentType  string   `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string   `mapstructure:"rejected_http_response_body"`
+	RejectedHTTPStatusCode   int      `mapstructure:"rejected_http_status_code"`
+}
+
+// Violation describes a part of the body which does not match the schema.
+type Violation struct {
+	// Pointer is the JSON pointer of the invalid value, empty for the whole body.
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Middleware is a middleware that validates JSON request bodies against a JSON schema.
+type Middleware struct {
+	options *Options
+	schema  *jsonschemav6.Schema
+	methods map[string]struct{}
+}
+
+// NewMiddleware creates a new json_schema middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	schema, err := compile(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusBadRequest
+	}
+	if len(options.RejectedHTTPContentType) == 0 {
+		options.RejectedHTTPContentType = "application/json"
+	}
+	if len(options.Methods) == 0 {
+		options.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+	}
+
+	m := &Middleware{
+		options: &options,
+		schema:  schema,
+		methods: make(map[string]struct{}, len(options.Methods)),
+	}
+	for _, method := range options.Methods {
+		m.methods[strings.ToUpper(method)] = struct{}{}
+	}
+
+	return m, nil
+}
+
+func compile(options *Options) (*jsonschemav6.Schema, error) {
+	switch {
+	case options.Schema != nil && options.SchemaFile != "":
+		return nil, errors.New("schema and schema_file cannot be set at the same time")
+	case options.Schema != nil:
+		doc, err := inlineSchema(options.Schema)
+		if err != nil {
+			return nil, err
+		}
+		return compileDocument(inlineSchemaURL, doc)
+	case options.SchemaFile != "":
+		path, err := filepath.Abs(options.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file '%s': %w", options.SchemaFile, err)
+		}
+
+		var doc any
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &doc)
+			if err == nil {
+				doc, err = normalize(doc)
+			}
+		default:
+			doc, err = jsonschemav6.UnmarshalJSON(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema file '%s': %w", options.SchemaFile, err)
+		}
+		return compileDocument(path, doc)
+	default:
+		return nil, errors.New("schema or schema_file must be set for json_schema middleware")
+	}
+}
+
+func inlineSchema(schema any) (any, error) {
+	if s, ok := schema.(string); ok {
+		doc, err := jsonschemav6.UnmarshalJSON(strings.NewReader(s))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema: %w", err)
+		}
+		return doc, nil
+	}
+	return normalize(schema)
+}
+
+// normalize converts a YAML document to the JSON types the schema compiler expects.
+func normalize(doc any) (any, error) {
+	data, err := sonic.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return jsonschemav6.UnmarshalJSON(bytes.NewReader(data))
+}
+
+func compileDocument(url string, doc any) (*jsonschemav6.Schema, error) {
+	compiler := jsonschemav6.NewCompiler()
+	err := compiler.AddResource(url, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+	return schema, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	// requests without a body such as GET or CORS preflight requests are not validated
+	if _, found := m.methods[cast.B2S(c.Request.Method())]; !found {
+		c.Next(ctx)
+		return
+	}
+
+	body, err := jsonschemav6.UnmarshalJSON(bytes.NewReader(c.Request.Body()))
+	if err != nil {
+		m.reject(c, "request body is not a valid JSON document", nil)
+		return
+	}
+
+	err = m.schema.Validate(body)
+	if err != nil {
+		var validationErr *jsonschemav6.ValidationError
+		if !errors.As(err, &validationErr) {
+			m.reject(c, err.Error(), nil)
+			return
+		}
+		m.reject(c, "request body does not match the JSON schema", toViolations(validationErr))
+		return
+	}
+
+	c.Next(ctx)
+}
+
+func (m *Middleware) reject(c *app.RequestContext, message string, violations []Violation) {
+	c.SetStatusCode(m.options.RejectedHTTPStatusCode)
+	c.SetContentType(m.options.RejectedHTTPContentType)
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	} else {
+		body, _ := sonic.Marshal(errorResponse{
+			Message:    message,
+			Violations: violations,
+		})
+		c.Response.SetBody(body)
+	}
+	c.Abort()
+}
+
+// toViolations returns the leaf errors of the validation, the errors of keywords such as
+// `allOf` or `$ref` only group them.
+func toViolations(err *jsonschemav6.ValidationError) []Violation {
+	if len(err.Causes) == 0 {
+		return []Violation{{
+			Pointer: pointer(err.InstanceLocation),
+			Message: err.ErrorKind.LocalizedString(printer),
+		}}
+	}
+
+	var result []Violation
+	for _, cause := range err.Causes {
+		result = append(result, toViolations(cause)...)
+	}
+	return result
+}
+
+func pointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/")
+		sb.WriteString(pointerEscaper.Replace(token))
+	}
+	return sb.String()
+}
+
+// Init registers the json_schema middleware.
+func Init() error {
+	err := middleware.RegisterValidator([]string{"json_schema"}, func(opts Options) error {
+		_, err := compile(&opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return middleware.Register([]string{"json_schema"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
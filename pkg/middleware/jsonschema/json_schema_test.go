@@ -0,0 +1,151 @@
+package jsonschema
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func perform(handler app.HandlerFunc, body string) (*app.RequestContext, bool) {
+	return performMethod(handler, http.MethodPost, body)
+}
+
+func performMethod(handler app.HandlerFunc, method string, body string) (*app.RequestContext, bool) {
+	called := false
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.SetRequestURI("/orders")
+	c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	c.Request.SetBodyString(body)
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{handler, func(_ context.Context, _ *app.RequestContext) {
+		called = true
+	}})
+	c.Next(context.Background())
+	return c, called
+}
+
+func TestInlineSchema(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("json_schema")
+	handler, err := h(map[string]any{
+		"schema": map[string]any{
+			"type":     "object",
+			"required": []any{"name"},
+			"properties": map[string]any{
+				"name": map[string]any{"type": "string", "minLength": 1},
+				"age":  map[string]any{"type": "integer", "minimum": 0},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	c, called := perform(handler, `{"name":"bifrost","age":3}`)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+
+	c, called = perform(handler, `{"name":"","age":-1}`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+	assert.Equal(t, "application/json", string(c.Response.Header.ContentType()))
+
+	resp := errorResponse{}
+	require.NoError(t, sonic.Unmarshal(c.Response.Body(), &resp))
+	assert.Equal(t, "request body does not match the JSON schema", resp.Message)
+	pointers := []string{}
+	for _, v := range resp.Violations {
+		pointers = append(pointers, v.Pointer)
+		assert.NotEmpty(t, v.Message)
+	}
+	assert.ElementsMatch(t, []string{"/name", "/age"}, pointers)
+
+	c, called = perform(handler, `{"name":`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+	assert.JSONEq(t, `{"message":"request body is not a valid JSON document"}`, string(c.Response.Body()))
+}
+
+func TestJSONStringSchema(t *testing.T) {
+	m, err := NewMiddleware(Options{
+		Schema:                   `{"type":"array","items":{"type":"number"}}`,
+		RejectedHTTPStatusCode:   http.StatusUnprocessableEntity,
+		RejectedHTTPContentType:  "text/plain",
+		RejectedHTTPResponseBody: "invalid body",
+	})
+	require.NoError(t, err)
+
+	_, called := perform(m.ServeHTTP, `[1, 2.5]`)
+	assert.True(t, called)
+
+	c, called := perform(m.ServeHTTP, `[1, "2"]`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response.StatusCode())
+	assert.Equal(t, "text/plain", string(c.Response.Header.ContentType()))
+	assert.Equal(t, "invalid body", string(c.Response.Body()))
+}
+
+func TestSchemaFile(t *testing.T) {
+	m, err := NewMiddleware(Options{SchemaFile: "testdata/order.yaml"})
+	require.NoError(t, err)
+
+	_, called := perform(m.ServeHTTP, `{"id":"ord_1","items":[{"sku":"a","quantity":2}]}`)
+	assert.True(t, called)
+
+	// the items are validated by the referenced schema file
+	c, called := perform(m.ServeHTTP, `{"id":"ord_1","items":[{"sku":"a","quantity":0}]}`)
+	assert.False(t, called)
+	resp := errorResponse{}
+	require.NoError(t, sonic.Unmarshal(c.Response.Body(), &resp))
+	require.Len(t, resp.Violations, 1)
+	assert.Equal(t, "/items/0/quantity", resp.Violations[0].Pointer)
+}
+
+func TestMethods(t *testing.T) {
+	m, err := NewMiddleware(Options{Schema: `{"type":"object"}`})
+	require.NoError(t, err)
+
+	// reads and CORS preflight requests on the same route are not validated
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete} {
+		_, called := performMethod(m.ServeHTTP, method, "")
+		assert.True(t, called, method)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch} {
+		_, called := performMethod(m.ServeHTTP, method, "")
+		assert.False(t, called, method)
+	}
+
+	m, err = NewMiddleware(Options{Schema: `{"type":"object"}`, Methods: []string{"delete"}})
+	require.NoError(t, err)
+	_, called := performMethod(m.ServeHTTP, http.MethodDelete, "")
+	assert.False(t, called)
+	_, called = performMethod(m.ServeHTTP, http.MethodPost, "")
+	assert.True(t, called)
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{Schema: `{"type":"object"}`, SchemaFile: "testdata/order.yaml"})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{Schema: `{"type":`})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{Schema: map[string]any{"type": "unknown"}})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{SchemaFile: "testdata/not_found.json"})
+	assert.Error(t, err)
+
+	_ = Init()
+	err = middleware.Validate("json_schema", map[string]any{"schema_file": "testdata/not_found.json"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/nite-coder/blackbear/pkg/cache/v2"
+
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+const (
+	defaultIntrospectionCacheTTL = 30 * time.Second
+	defaultCacheCleanupInterval  = 10 * time.Minute
+)
+
+// IntrospectionOptions defines the configuration for the oauth2_introspection middleware.
+type IntrospectionOptions struct {
+	UpstreamHeaders          map[string]string `mapstructure:"upstream_headers"`
+	IntrospectionEndpoint    string            `mapstructure:"introspection_endpoint"`
+	ClientID                 string            `mapstructure:"client_id"`
+	ClientSecret             string            `mapstructure:"client_secret"`
+	TokenTypeHint            string            `mapstructure:"token_type_hint"`
+	RejectedHTTPContentType  string            `mapstructure:"rejected_http_content_type"`
+	RejectedHTTPResponseBody string            `mapstructure:"rejected_http_response_body"`
+	RequiredScopes           []string          `mapstructure:"required_scopes"`
+	CacheTTL                 time.Duration     `mapstructure:"cache_ttl"`
+	NegativeCacheTTL         time.Duration     `mapstructure:"negative_cache_ttl"`
+	Timeout                  time.Duration     `mapstructure:"timeout"`
+	RejectedHTTPStatusCode   int               `mapstructure:"rejected_http_status_code"`
+	StripAuthorization       bool              `mapstructure:"strip_authorization"`
+}
+
+// introspectionResult is the cached outcome of a token introspection.
+type introspectionResult struct {
+	identity *identity
+	scopes   []string
+	active   bool
+}
+
+// IntrospectionMiddleware validates opaque bearer tokens with an RFC 7662 introspection endpoint.
+type IntrospectionMiddleware struct {
+	options         *IntrospectionOptions
+	client          *client.Client
+	cache           *cache.Cache[string, *introspectionResult]
+	upstreamHeaders []headerTemplate
+	authorization   string
+}
+
+// NewIntrospectionMiddleware creates a new IntrospectionMiddleware instance.
+func NewIntrospectionMiddleware(options IntrospectionOptions) (*IntrospectionMiddleware, error) {
+	err := validateEndpoint("introspection_endpoint", options.IntrospectionEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if options.ClientID == "" {
+		return nil, errors.New("client_id cannot be empty")
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = defaultIntrospectionCacheTTL
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.RejectedHTTPStatusCode == 0 {
+		options.RejectedHTTPStatusCode = http.StatusUnauthorized
+	}
+	if len(options.RejectedHTTPContentType) == 0 {
+		options.RejectedHTTPContentType = "application/json"
+	}
+
+	httpClient, err := newHTTPClient(options.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	return &IntrospectionMiddleware{
+		options:         &options,
+		client:          httpClient,
+		cache:           cache.NewCache[string, *introspectionResult](defaultCacheCleanupInterval),
+		upstreamHeaders: newHeaderTemplates(options.UpstreamHeaders),
+		authorization:   clientCredentials(options.ClientID, options.ClientSecret),
+	}, nil
+}
+
+func (m *IntrospectionMiddleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	token := bearerToken(c)
+	if token == "" {
+		m.reject(c, m.options.RejectedHTTPStatusCode, "Bearer")
+		return
+	}
+
+	result, err := m.introspect(ctx, token)
+	if err != nil {
+		log.FromContext(ctx).Warn("oauth2_introspection: failed to introspect token", "error", err)
+		c.SetStatusCode(http.StatusServiceUnavailable)
+		c.Abort()
+		return
+	}
+
+	if !result.active {
+		m.reject(c, m.options.RejectedHTTPStatusCode, `Bearer error="invalid_token"`)
+		return
+	}
+
+	for _, scope := range m.options.RequiredScopes {
+		if !slices.Contains(result.scopes, scope) {
+			m.reject(c, http.StatusForbidden, `Bearer error="insufficient_scope"`)
+			return
+		}
+	}
+
+	result.identity.apply(c)
+	setUpstreamHeaders(c, m.upstreamHeaders)
+	if m.options.StripAuthorization {
+		c.Request.Header.Del("Authorization")
+	}
+
+	c.Next(ctx)
+}
+
+func (m *IntrospectionMiddleware) introspect(ctx context.Context, token string) (*introspectionResult, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	if result, found := m.cache.Get(key); found {
+		return result, nil
+	}
+
+	result, exp, err := m.requestIntrospection(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := m.options.NegativeCacheTTL
+	if result.active {
+		ttl = m.options.CacheTTL
+		if !exp.IsZero() {
+			ttl = min(ttl, exp.Sub(timecache.Now()))
+		}
+	}
+	if ttl > 0 {
+		m.cache.PutWithTTL(key, result, ttl)
+	}
+
+	return result, nil
+}
+
+func (m *IntrospectionMiddleware) requestIntrospection(
+	ctx context.Context,
+	token string,
+) (*introspectionResult, time.Time, error) {
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	form := url.Values{}
+	form.Set("token", token)
+	if m.options.TokenTypeHint != "" {
+		form.Set("token_type_hint", m.options.TokenTypeHint)
+	}
+
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(m.options.IntrospectionEndpoint)
+	req.Header.SetContentTypeBytes([]byte("application/x-www-form-urlencoded"))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", m.authorization)
+	req.SetBodyString(form.Encode())
+
+	err := m.client.DoTimeout(ctx, req, resp, m.options.Timeout)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	body := resp.Body()
+	if resp.IsBodyStream() {
+		body, err = io.ReadAll(resp.BodyStream())
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("unexpected status code %d from introspection endpoint", resp.StatusCode())
+	}
+
+	claims := map[string]any{}
+	err = sonic.Unmarshal(body, &claims)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid introspection response: %w", err)
+	}
+
+	active, _ := claims["active"].(bool)
+	result := &introspectionResult{active: active}
+	if !active {
+		return result, time.Time{}, nil
+	}
+
+	if scope, ok := claims["scope"].(string); ok {
+		result.scopes = strings.Fields(scope)
+	}
+
+	result.identity = &identity{
+		User:     claimString(claims, "username", "sub"),
+		Consumer: claimString(claims, "client_id"),
+		Groups:   claimStrings(claims, "groups"),
+		Claims:   claims,
+	}
+
+	var exp time.Time
+	if val, ok := claims["exp"].(float64); ok && val > 0 {
+		exp = time.Unix(int64(val), 0)
+	}
+
+	return result, exp, nil
+}
+
+func (m *IntrospectionMiddleware) reject(c *app.RequestContext, statusCode int, challenge string) {
+	c.Response.Header.Set("WWW-Authenticate", challenge)
+	c.SetStatusCode(statusCode)
+	if len(m.options.RejectedHTTPContentType) > 0 {
+		c.SetContentType(m.options.RejectedHTTPContentType)
+	}
+	if len(m.options.RejectedHTTPResponseBody) > 0 {
+		c.SetBodyString(m.options.RejectedHTTPResponseBody)
+	}
+	c.Abort()
+}
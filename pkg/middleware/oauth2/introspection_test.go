@@ -0,0 +1,169 @@
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+func newIntrospectionServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "gateway" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("token") {
+		case "good":
+			_, _ = w.Write([]byte(`{"active":true,"sub":"u1","username":"alice","client_id":"mobile","scope":"read write","tenant":"acme"}`))
+		case "readonly":
+			_, _ = w.Write([]byte(`{"active":true,"sub":"u2","client_id":"mobile","scope":"read"}`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"active":false}`))
+		}
+	}))
+}
+
+func TestIntrospectionMiddleware(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("oauth2_introspection")
+
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+	defer server.Close()
+
+	m, err := h(map[string]any{
+		"introspection_endpoint":      server.URL,
+		"client_id":                   "gateway",
+		"client_secret":               "secret",
+		"required_scopes":             []string{"read"},
+		"negative_cache_ttl":          "1m",
+		"strip_authorization":         true,
+		"rejected_http_response_body": `{"error":"unauthorized"}`,
+		"upstream_headers": map[string]any{
+			"X-User":   "$auth.user",
+			"X-Tenant": "$auth.claim.tenant",
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("active token", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.Header.Set("Authorization", "Bearer good")
+		hzCtx.Request.Header.Set("X-User", "spoofed")
+
+		m(ctx, hzCtx)
+		assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+		assert.False(t, hzCtx.IsAborted())
+		assert.Equal(t, "alice", hzCtx.GetString(variable.AuthUser))
+		assert.Equal(t, "mobile", hzCtx.GetString(variable.AuthConsumer))
+		assert.Equal(t, "alice", string(hzCtx.Request.Header.Peek("X-User")))
+		assert.Equal(t, "acme", string(hzCtx.Request.Header.Peek("X-Tenant")))
+		assert.Empty(t, hzCtx.Request.Header.Peek("Authorization"))
+
+		// cached
+		hzCtx = app.NewContext(0)
+		hzCtx.Request.Header.Set("Authorization", "Bearer good")
+		m(ctx, hzCtx)
+		assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		m(ctx, hzCtx)
+		assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+		assert.Equal(t, "Bearer", string(hzCtx.Response.Header.Peek("WWW-Authenticate")))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(hzCtx.Response.Body()))
+		assert.True(t, hzCtx.IsAborted())
+	})
+
+	t.Run("inactive token", func(t *testing.T) {
+		before := calls.Load()
+		for range 2 {
+			hzCtx := app.NewContext(0)
+			hzCtx.Request.Header.Set("Authorization", "bearer revoked")
+			m(ctx, hzCtx)
+			assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+			assert.Equal(t, `Bearer error="invalid_token"`, string(hzCtx.Response.Header.Peek("WWW-Authenticate")))
+		}
+		assert.Equal(t, before+1, calls.Load())
+	})
+
+	t.Run("insufficient scope", func(t *testing.T) {
+		m, err := h(map[string]any{
+			"introspection_endpoint": server.URL,
+			"client_id":              "gateway",
+			"client_secret":          "secret",
+			"required_scopes":        []string{"write"},
+		})
+		require.NoError(t, err)
+
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.Header.Set("Authorization", "Bearer readonly")
+		m(ctx, hzCtx)
+		assert.Equal(t, http.StatusForbidden, hzCtx.Response.StatusCode())
+		assert.Equal(t, `Bearer error="insufficient_scope"`, string(hzCtx.Response.Header.Peek("WWW-Authenticate")))
+	})
+
+	t.Run("introspection failure", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.Header.Set("Authorization", "Bearer broken")
+		m(ctx, hzCtx)
+		assert.Equal(t, http.StatusServiceUnavailable, hzCtx.Response.StatusCode())
+		assert.True(t, hzCtx.IsAborted())
+	})
+}
+
+func TestIntrospectionCacheExpiry(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls)
+	defer server.Close()
+
+	m, err := NewIntrospectionMiddleware(IntrospectionOptions{
+		IntrospectionEndpoint: server.URL,
+		ClientID:              "gateway",
+		ClientSecret:          "secret",
+		CacheTTL:              -1,
+		Timeout:               time.Second,
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err = m.introspect(context.Background(), "good")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNewIntrospectionMiddlewareValidation(t *testing.T) {
+	_, err := NewIntrospectionMiddleware(IntrospectionOptions{})
+	require.ErrorContains(t, err, "introspection_endpoint cannot be empty")
+
+	_, err = NewIntrospectionMiddleware(IntrospectionOptions{IntrospectionEndpoint: "ftp://example.com"})
+	require.ErrorContains(t, err, "must start with http:// or https://")
+
+	_, err = NewIntrospectionMiddleware(IntrospectionOptions{IntrospectionEndpoint: "https://example.com"})
+	require.ErrorContains(t, err, "client_id cannot be empty")
+}
@@ -0,0 +1,174 @@
+package oauth2
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/client"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+const (
+	defaultTimeout   = 5 * time.Second
+	allocationFactor = 2
+)
+
+// Init registers the oauth2_introspection and oidc middlewares.
+func Init() error {
+	err := middleware.Register([]string{"oauth2_introspection"}, func(opts IntrospectionOptions) (app.HandlerFunc, error) {
+		m, err := NewIntrospectionMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return middleware.Register([]string{"oidc"}, func(opts OIDCOptions) (app.HandlerFunc, error) {
+		m, err := NewOIDCMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
+
+// identity is the authenticated principal resolved by the middlewares in this package.
+type identity struct {
+	Claims   map[string]any `json:"claims,omitempty"`
+	User     string         `json:"user"`
+	Consumer string         `json:"consumer,omitempty"`
+	Groups   []string       `json:"groups,omitempty"`
+}
+
+// apply stores the identity in the request context so that it can be read
+// through the `$auth.*` directives.
+func (id *identity) apply(c *app.RequestContext) {
+	c.Set(variable.AuthUser, id.User)
+	c.Set(variable.AuthConsumer, id.Consumer)
+	if len(id.Groups) > 0 {
+		c.Set(variable.AuthGroups, id.Groups)
+	}
+	if id.Claims != nil {
+		c.Set(variable.AuthClaims, id.Claims)
+	}
+}
+
+// headerTemplate is an upstream header whose value may contain directives.
+type headerTemplate struct {
+	name       string
+	value      string
+	directives []string
+}
+
+func newHeaderTemplates(headers map[string]string) []headerTemplate {
+	templates := make([]headerTemplate, 0, len(headers))
+	for name, value := range headers {
+		if name == "" {
+			continue
+		}
+		templates = append(templates, headerTemplate{
+			name:       name,
+			value:      value,
+			directives: variable.ParseDirectives(value),
+		})
+	}
+	return templates
+}
+
+// setUpstreamHeaders always overwrites the configured headers, so clients
+// cannot spoof the identity headers that upstreams trust.
+func setUpstreamHeaders(c *app.RequestContext, templates []headerTemplate) {
+	for _, tmpl := range templates {
+		val := tmpl.value
+		if len(tmpl.directives) > 0 {
+			replacements := make([]string, 0, len(tmpl.directives)*allocationFactor)
+			for _, key := range tmpl.directives {
+				replacements = append(replacements, key, variable.GetString(key, c))
+			}
+			val = strings.NewReplacer(replacements...).Replace(val)
+		}
+
+		if val == "" {
+			c.Request.Header.Del(tmpl.name)
+			continue
+		}
+		c.Request.Header.Set(tmpl.name, val)
+	}
+}
+
+func newHTTPClient(timeout time.Duration) (*client.Client, error) {
+	return client.NewClient(
+		client.WithDialTimeout(timeout),
+		client.WithClientReadTimeout(timeout),
+		client.WithWriteTimeout(timeout),
+		client.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}),
+	)
+}
+
+// clientCredentials builds the HTTP Basic credentials defined in RFC 6749 section 2.3.1.
+func clientCredentials(clientID, clientSecret string) string {
+	raw := url.QueryEscape(clientID) + ":" + url.QueryEscape(clientSecret)
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+func bearerToken(c *app.RequestContext) string {
+	auth := string(c.Request.Header.Peek("Authorization"))
+	const prefix = "bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+func validateEndpoint(name, endpoint string) error {
+	if endpoint == "" {
+		return fmt.Errorf("%s cannot be empty", name)
+	}
+	addr, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %w", name, err)
+	}
+	if addr.Scheme != "http" && addr.Scheme != "https" {
+		return errors.New(name + " must start with http:// or https://")
+	}
+	return nil
+}
+
+// claimString returns the first non-empty string claim among keys.
+func claimString(claims map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := claims[key].(string); ok && val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// claimStrings returns a list claim, such as groups, as strings.
+func claimStrings(claims map[string]any, key string) []string {
+	switch val := claims[key].(type) {
+	case []any:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	case string:
+		return strings.Fields(val)
+	default:
+		return nil
+	}
+}
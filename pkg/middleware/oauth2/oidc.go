@@ -0,0 +1,598 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+const (
+	defaultSessionTTL     = 24 * time.Hour
+	defaultCookieName     = "bifrost_oidc"
+	loginStateTTL         = 10 * time.Minute
+	tokenRefreshSkew      = 30 * time.Second
+	randomStringSize      = 24
+	jwtPartsCount         = 3
+	discoveryPath         = "/.well-known/openid-configuration"
+	stateCookieNameSuffix = "_state"
+	maxCookieSize         = 4096 // browsers silently drop larger cookies
+)
+
+// claims that only matter for token validation and are not kept in the session cookie.
+var droppedClaims = []string{"aud", "azp", "at_hash", "c_hash", "exp", "iat", "iss", "nbf", "nonce", "auth_time", "sid"}
+
+// OIDCOptions defines the configuration for the oidc middleware.
+type OIDCOptions struct {
+	UpstreamHeaders       map[string]string `mapstructure:"upstream_headers"`
+	Issuer                string            `mapstructure:"issuer"`
+	AuthorizationEndpoint string            `mapstructure:"authorization_endpoint"`
+	TokenEndpoint         string            `mapstructure:"token_endpoint"`
+	ClientID              string            `mapstructure:"client_id"`
+	ClientSecret          string            `mapstructure:"client_secret"`
+	RedirectURL           string            `mapstructure:"redirect_url"`
+	LogoutPath            string            `mapstructure:"logout_path"`
+	PostLogoutRedirectURL string            `mapstructure:"post_logout_redirect_url"`
+	CookieName            string            `mapstructure:"cookie_name"`
+	CookieSecret          string            `mapstructure:"cookie_secret"`
+	CookieDomain          string            `mapstructure:"cookie_domain"`
+	UserClaim             string            `mapstructure:"user_claim"`
+	Scopes                []string          `mapstructure:"scopes"`
+	SessionTTL            time.Duration     `mapstructure:"session_ttl"`
+	Timeout               time.Duration     `mapstructure:"timeout"`
+	CookieInsecure        bool              `mapstructure:"cookie_insecure"`
+	PassAccessToken       bool              `mapstructure:"pass_access_token"`
+}
+
+// providerMetadata is the subset of the OpenID Provider metadata used by the middleware.
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// tokenResponse is the successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// OIDCMiddleware authenticates browser users with the OpenID Connect authorization code flow.
+type OIDCMiddleware struct {
+	options         *OIDCOptions
+	client          *client.Client
+	codec           *cookieCodec
+	metadata        atomic.Pointer[providerMetadata]
+	upstreamHeaders []headerTemplate
+	callbackPath    string
+	stateCookieName string
+	authorization   string
+	scope           string
+	mu              sync.Mutex
+}
+
+// NewOIDCMiddleware creates a new OIDCMiddleware instance.
+func NewOIDCMiddleware(options OIDCOptions) (*OIDCMiddleware, error) {
+	if options.ClientID == "" {
+		return nil, errors.New("client_id cannot be empty")
+	}
+
+	err := validateEndpoint("redirect_url", options.RedirectURL)
+	if err != nil {
+		return nil, err
+	}
+	redirectURL, _ := url.Parse(options.RedirectURL)
+
+	if options.Issuer == "" && (options.AuthorizationEndpoint == "" || options.TokenEndpoint == "") {
+		return nil, errors.New("issuer or both authorization_endpoint and token_endpoint must be set")
+	}
+
+	codec, err := newCookieCodec(options.CookieSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.CookieName == "" {
+		options.CookieName = defaultCookieName
+	}
+	if options.UserClaim == "" {
+		options.UserClaim = "sub"
+	}
+	if len(options.Scopes) == 0 {
+		options.Scopes = []string{"openid", "profile", "email"}
+	} else if !slices.Contains(options.Scopes, "openid") {
+		options.Scopes = append([]string{"openid"}, options.Scopes...)
+	}
+	if options.SessionTTL <= 0 {
+		options.SessionTTL = defaultSessionTTL
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	httpClient, err := newHTTPClient(options.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	m := &OIDCMiddleware{
+		options:         &options,
+		client:          httpClient,
+		codec:           codec,
+		upstreamHeaders: newHeaderTemplates(options.UpstreamHeaders),
+		callbackPath:    redirectURL.Path,
+		stateCookieName: options.CookieName + stateCookieNameSuffix,
+		authorization:   clientCredentials(options.ClientID, options.ClientSecret),
+		scope:           strings.Join(options.Scopes, " "),
+	}
+
+	if options.AuthorizationEndpoint != "" && options.TokenEndpoint != "" {
+		m.metadata.Store(&providerMetadata{
+			Issuer:                options.Issuer,
+			AuthorizationEndpoint: options.AuthorizationEndpoint,
+			TokenEndpoint:         options.TokenEndpoint,
+		})
+	}
+
+	return m, nil
+}
+
+func (m *OIDCMiddleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	path := string(c.Request.Path())
+
+	switch {
+	case path == m.callbackPath:
+		m.handleCallback(ctx, c)
+		return
+	case m.options.LogoutPath != "" && path == m.options.LogoutPath:
+		m.handleLogout(c)
+		return
+	default:
+	}
+
+	sess, found := m.loadSession(ctx, c)
+	if !found {
+		m.startLogin(ctx, c)
+		return
+	}
+
+	sess.Identity.apply(c)
+	if m.options.PassAccessToken && sess.AccessToken != "" {
+		c.Request.Header.Set("Authorization", "Bearer "+sess.AccessToken)
+	}
+	setUpstreamHeaders(c, m.upstreamHeaders)
+
+	c.Next(ctx)
+}
+
+func (m *OIDCMiddleware) loadSession(ctx context.Context, c *app.RequestContext) (*session, bool) {
+	value := c.Cookie(m.options.CookieName)
+	if len(value) == 0 {
+		return nil, false
+	}
+
+	sess := &session{}
+	err := m.codec.decode(string(value), sess)
+	if err != nil {
+		return nil, false
+	}
+
+	now := timecache.Now()
+	if now.After(time.Unix(sess.CreatedAt, 0).Add(m.options.SessionTTL)) {
+		return nil, false
+	}
+
+	if sess.ExpiresAt == 0 || now.Add(tokenRefreshSkew).Before(time.Unix(sess.ExpiresAt, 0)) {
+		return sess, true
+	}
+
+	if sess.RefreshToken == "" {
+		return nil, false
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", sess.RefreshToken)
+
+	token, err := m.requestToken(ctx, form)
+	if err != nil {
+		log.FromContext(ctx).Info("oidc: failed to refresh token", "error", err)
+		return nil, false
+	}
+
+	sess.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		sess.RefreshToken = token.RefreshToken
+	}
+	sess.ExpiresAt = 0
+	if token.ExpiresIn > 0 {
+		sess.ExpiresAt = now.Unix() + token.ExpiresIn
+	}
+
+	err = m.saveSession(c, sess)
+	if err != nil {
+		log.FromContext(ctx).Warn("oidc: failed to save session", "error", err)
+	}
+
+	return sess, true
+}
+
+func (m *OIDCMiddleware) saveSession(c *app.RequestContext, sess *session) error {
+	value, err := m.codec.encode(sess)
+	if err != nil {
+		return err
+	}
+
+	size := len(m.options.CookieName) + 1 + len(value)
+	if size > maxCookieSize {
+		return fmt.Errorf("session cookie is %d bytes and exceeds the browser limit of %d bytes", size, maxCookieSize)
+	}
+
+	remaining := time.Unix(sess.CreatedAt, 0).Add(m.options.SessionTTL).Sub(timecache.Now())
+	m.setCookie(c, m.options.CookieName, value, int(remaining.Seconds()))
+	return nil
+}
+
+func (m *OIDCMiddleware) startLogin(ctx context.Context, c *app.RequestContext) {
+	if !wantsRedirect(c) {
+		c.SetStatusCode(http.StatusUnauthorized)
+		c.Abort()
+		return
+	}
+
+	metadata, err := m.providerMetadata(ctx)
+	if err != nil {
+		log.FromContext(ctx).Warn("oidc: failed to load provider metadata", "error", err)
+		c.SetStatusCode(http.StatusServiceUnavailable)
+		c.Abort()
+		return
+	}
+
+	state, err := randomString(randomStringSize)
+	if err != nil {
+		c.SetStatusCode(http.StatusInternalServerError)
+		c.Abort()
+		return
+	}
+	nonce, err := randomString(randomStringSize)
+	if err != nil {
+		c.SetStatusCode(http.StatusInternalServerError)
+		c.Abort()
+		return
+	}
+
+	value, err := m.codec.encode(&loginState{
+		State:     state,
+		Nonce:     nonce,
+		Redirect:  string(c.Request.RequestURI()),
+		ExpiresAt: timecache.Now().Add(loginStateTTL).Unix(),
+	})
+	if err != nil {
+		c.SetStatusCode(http.StatusInternalServerError)
+		c.Abort()
+		return
+	}
+	m.setCookie(c, m.stateCookieName, value, int(loginStateTTL.Seconds()))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", m.options.ClientID)
+	query.Set("redirect_uri", m.options.RedirectURL)
+	query.Set("scope", m.scope)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	c.Redirect(http.StatusFound, []byte(metadata.AuthorizationEndpoint+separator+query.Encode()))
+	c.Abort()
+}
+
+func (m *OIDCMiddleware) handleCallback(ctx context.Context, c *app.RequestContext) {
+	logger := log.FromContext(ctx)
+
+	defer m.setCookie(c, m.stateCookieName, "", -1)
+
+	value := c.Cookie(m.stateCookieName)
+	state := &loginState{}
+	if len(value) == 0 || m.codec.decode(string(value), state) != nil {
+		c.SetStatusCode(http.StatusBadRequest)
+		c.Abort()
+		return
+	}
+
+	if state.State != c.Query("state") || timecache.Now().Unix() > state.ExpiresAt {
+		c.SetStatusCode(http.StatusBadRequest)
+		c.Abort()
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		logger.Info("oidc: authorization failed", "error", errCode, "description", c.Query("error_description"))
+		c.SetStatusCode(http.StatusUnauthorized)
+		c.Abort()
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.SetStatusCode(http.StatusBadRequest)
+		c.Abort()
+		return
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", m.options.RedirectURL)
+
+	token, err := m.requestToken(ctx, form)
+	if err != nil {
+		logger.Warn("oidc: failed to exchange authorization code", "error", err)
+		c.SetStatusCode(http.StatusBadGateway)
+		c.Abort()
+		return
+	}
+
+	claims, err := m.verifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		logger.Warn("oidc: invalid id_token", "error", err)
+		c.SetStatusCode(http.StatusUnauthorized)
+		c.Abort()
+		return
+	}
+
+	now := timecache.Now()
+	sess := &session{
+		Identity: identity{
+			User:     claimString(claims, m.options.UserClaim, "sub"),
+			Consumer: m.options.ClientID,
+			Groups:   claimStrings(claims, "groups"),
+			Claims:   claims,
+		},
+		CreatedAt: now.Unix(),
+	}
+	// the tokens are only kept when they are forwarded, otherwise the session lasts for session_ttl
+	if m.options.PassAccessToken {
+		sess.AccessToken = token.AccessToken
+		sess.RefreshToken = token.RefreshToken
+		if token.ExpiresIn > 0 {
+			sess.ExpiresAt = now.Unix() + token.ExpiresIn
+		}
+	}
+
+	err = m.saveSession(c, sess)
+	if err != nil {
+		logger.Warn("oidc: failed to save session", "error", err)
+		c.SetStatusCode(http.StatusInternalServerError)
+		c.Abort()
+		return
+	}
+
+	redirect := state.Redirect
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") {
+		redirect = "/"
+	}
+	c.Redirect(http.StatusFound, []byte(redirect))
+	c.Abort()
+}
+
+func (m *OIDCMiddleware) handleLogout(c *app.RequestContext) {
+	m.setCookie(c, m.options.CookieName, "", -1)
+
+	redirect := m.options.PostLogoutRedirectURL
+	if redirect == "" {
+		redirect = "/"
+	}
+	c.Redirect(http.StatusFound, []byte(redirect))
+	c.Abort()
+}
+
+// verifyIDToken validates the claims of an ID token received directly from the token endpoint.
+// As allowed by OpenID Connect Core 1.0 section 3.1.3.7, the TLS connection to the token endpoint
+// authenticates the issuer, so the JWS signature is not checked.
+func (m *OIDCMiddleware) verifyIDToken(ctx context.Context, idToken string, nonce string) (map[string]any, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != jwtPartsCount {
+		return nil, errors.New("malformed id_token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id_token payload: %w", err)
+	}
+
+	claims := map[string]any{}
+	err = sonic.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("malformed id_token payload: %w", err)
+	}
+
+	metadata, err := m.providerMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Issuer != "" && claims["iss"] != metadata.Issuer {
+		return nil, fmt.Errorf("unexpected issuer '%v'", claims["iss"])
+	}
+
+	if !audienceContains(claims["aud"], m.options.ClientID) {
+		return nil, errors.New("id_token audience does not contain client_id")
+	}
+
+	exp, _ := claims["exp"].(float64)
+	if int64(exp) <= timecache.Now().Unix() {
+		return nil, errors.New("id_token is expired")
+	}
+
+	if claims["nonce"] != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	for _, key := range droppedClaims {
+		delete(claims, key)
+	}
+
+	return claims, nil
+}
+
+func audienceContains(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, item := range v {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *OIDCMiddleware) providerMetadata(ctx context.Context) (*providerMetadata, error) {
+	if metadata := m.metadata.Load(); metadata != nil {
+		return metadata, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if metadata := m.metadata.Load(); metadata != nil {
+		return metadata, nil
+	}
+
+	body, statusCode, err := m.do(ctx, http.MethodGet, strings.TrimSuffix(m.options.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from discovery endpoint", statusCode)
+	}
+
+	metadata := &providerMetadata{}
+	err = sonic.Unmarshal(body, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery document: %w", err)
+	}
+
+	if m.options.AuthorizationEndpoint != "" {
+		metadata.AuthorizationEndpoint = m.options.AuthorizationEndpoint
+	}
+	if m.options.TokenEndpoint != "" {
+		metadata.TokenEndpoint = m.options.TokenEndpoint
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+		return nil, errors.New("discovery document is missing authorization_endpoint or token_endpoint")
+	}
+
+	m.metadata.Store(metadata)
+	return metadata, nil
+}
+
+func (m *OIDCMiddleware) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	metadata, err := m.providerMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, statusCode, err := m.do(ctx, http.MethodPost, metadata.TokenEndpoint, []byte(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from token endpoint", statusCode)
+	}
+
+	token := &tokenResponse{}
+	err = sonic.Unmarshal(body, token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token response is missing access_token")
+	}
+
+	return token, nil
+}
+
+func (m *OIDCMiddleware) do(ctx context.Context, method string, uri string, form []byte) ([]byte, int, error) {
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.SetContentTypeBytes([]byte("application/x-www-form-urlencoded"))
+		req.Header.Set("Authorization", m.authorization)
+		req.SetBody(form)
+	}
+
+	err := m.client.DoTimeout(ctx, req, resp, m.options.Timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	body := resp.Body()
+	if resp.IsBodyStream() {
+		body, err = io.ReadAll(resp.BodyStream())
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// the response is released when this function returns
+	result := make([]byte, len(body))
+	copy(result, body)
+
+	return result, resp.StatusCode(), nil
+}
+
+func (m *OIDCMiddleware) setCookie(c *app.RequestContext, name string, value string, maxAge int) {
+	c.SetCookie(
+		name,
+		value,
+		maxAge,
+		"/",
+		m.options.CookieDomain,
+		protocol.CookieSameSiteLaxMode,
+		!m.options.CookieInsecure,
+		true,
+	)
+}
+
+// wantsRedirect reports whether the client is a browser navigation that can follow a login redirect.
+func wantsRedirect(c *app.RequestContext) bool {
+	method := string(c.Request.Method())
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+
+	accept := string(c.Request.Header.Peek("Accept"))
+	return accept == "" || strings.Contains(accept, "text/html") || strings.Contains(accept, "*/*")
+}
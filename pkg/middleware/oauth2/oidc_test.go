@@ -0,0 +1,247 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+type fakeProvider struct {
+	server *httptest.Server
+	nonce  string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	p := &fakeProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"issuer":%q,"authorization_endpoint":%q,"token_endpoint":%q}`,
+			p.server.URL, p.server.URL+"/authorize", p.server.URL+"/token")
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			if r.PostForm.Get("code") != "abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			payload := fmt.Sprintf(`{"iss":%q,"aud":"web","exp":%d,"nonce":%q,"sub":"u1","email":"alice@example.com"}`,
+				p.server.URL, time.Now().Add(time.Hour).Unix(), p.nonce)
+			idToken := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".sig"
+			fmt.Fprintf(w, `{"access_token":"at1","refresh_token":"rt1","expires_in":3600,"id_token":%q}`, idToken)
+		case "refresh_token":
+			_, _ = w.Write([]byte(`{"access_token":"at2","expires_in":3600}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+func responseCookie(c *app.RequestContext, name string) string {
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey(name)
+	if !c.Response.Header.Cookie(cookie) {
+		return ""
+	}
+	return string(cookie.Value())
+}
+
+func TestOIDCMiddleware(t *testing.T) {
+	provider := newFakeProvider(t)
+	defer provider.server.Close()
+
+	m, err := NewOIDCMiddleware(OIDCOptions{
+		Issuer:          provider.server.URL,
+		ClientID:        "web",
+		ClientSecret:    "secret",
+		RedirectURL:     "https://gateway.example.com/oauth2/callback",
+		LogoutPath:      "/logout",
+		CookieSecret:    "0123456789abcdef",
+		UserClaim:       "email",
+		PassAccessToken: true,
+		UpstreamHeaders: map[string]string{"X-User": "$auth.user"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// unauthenticated browser request is redirected to the provider
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/orders?page=2")
+	hzCtx.Request.Header.Set("Accept", "text/html")
+	m.ServeHTTP(ctx, hzCtx)
+	require.Equal(t, http.StatusFound, hzCtx.Response.StatusCode())
+
+	location, err := url.Parse(string(hzCtx.Response.Header.Peek("Location")))
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", location.Path)
+	assert.Equal(t, "code", location.Query().Get("response_type"))
+	assert.Equal(t, "web", location.Query().Get("client_id"))
+	assert.Contains(t, location.Query().Get("scope"), "openid")
+
+	state := location.Query().Get("state")
+	provider.nonce = location.Query().Get("nonce")
+	stateCookie := responseCookie(hzCtx, "bifrost_oidc_state")
+	require.NotEmpty(t, stateCookie)
+
+	// callback with a forged state is rejected
+	hzCtx = app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/oauth2/callback?code=abc&state=forged")
+	hzCtx.Request.SetCookie("bifrost_oidc_state", stateCookie)
+	m.ServeHTTP(ctx, hzCtx)
+	assert.Equal(t, http.StatusBadRequest, hzCtx.Response.StatusCode())
+
+	// valid callback creates the session
+	hzCtx = app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/oauth2/callback?code=abc&state=" + state)
+	hzCtx.Request.SetCookie("bifrost_oidc_state", stateCookie)
+	m.ServeHTTP(ctx, hzCtx)
+	require.Equal(t, http.StatusFound, hzCtx.Response.StatusCode())
+	assert.Equal(t, "/orders?page=2", string(hzCtx.Response.Header.Peek("Location")))
+
+	sessionCookie := responseCookie(hzCtx, "bifrost_oidc")
+	require.NotEmpty(t, sessionCookie)
+
+	// authenticated request
+	hzCtx = app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/orders")
+	hzCtx.Request.SetCookie("bifrost_oidc", sessionCookie)
+	m.ServeHTTP(ctx, hzCtx)
+	assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+	assert.False(t, hzCtx.IsAborted())
+	assert.Equal(t, "alice@example.com", hzCtx.GetString(variable.AuthUser))
+	assert.Equal(t, "alice@example.com", string(hzCtx.Request.Header.Peek("X-User")))
+	assert.Equal(t, "Bearer at1", string(hzCtx.Request.Header.Peek("Authorization")))
+
+	claims, _ := hzCtx.Get(variable.AuthClaims)
+	assert.Equal(t, "u1", claims.(map[string]any)["sub"])
+	assert.NotContains(t, claims.(map[string]any), "nonce")
+
+	// logout clears the session
+	hzCtx = app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/logout")
+	hzCtx.Request.SetCookie("bifrost_oidc", sessionCookie)
+	m.ServeHTTP(ctx, hzCtx)
+	assert.Equal(t, http.StatusFound, hzCtx.Response.StatusCode())
+	assert.Empty(t, responseCookie(hzCtx, "bifrost_oidc"))
+}
+
+func TestOIDCRefreshToken(t *testing.T) {
+	provider := newFakeProvider(t)
+	defer provider.server.Close()
+
+	m, err := NewOIDCMiddleware(OIDCOptions{
+		Issuer:          provider.server.URL,
+		ClientID:        "web",
+		RedirectURL:     "https://gateway.example.com/oauth2/callback",
+		CookieSecret:    "0123456789abcdef",
+		PassAccessToken: true,
+	})
+	require.NoError(t, err)
+
+	value, err := m.codec.encode(&session{
+		Identity:     identity{User: "u1"},
+		AccessToken:  "expired",
+		RefreshToken: "rt1",
+		ExpiresAt:    time.Now().Add(-time.Minute).Unix(),
+		CreatedAt:    time.Now().Unix(),
+	})
+	require.NoError(t, err)
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/orders")
+	hzCtx.Request.SetCookie("bifrost_oidc", value)
+	m.ServeHTTP(context.Background(), hzCtx)
+	assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+	assert.Equal(t, "Bearer at2", string(hzCtx.Request.Header.Peek("Authorization")))
+	assert.NotEmpty(t, responseCookie(hzCtx, "bifrost_oidc"))
+}
+
+func TestOIDCNonBrowserRequest(t *testing.T) {
+	m, err := NewOIDCMiddleware(OIDCOptions{
+		AuthorizationEndpoint: "https://idp.example.com/authorize",
+		TokenEndpoint:         "https://idp.example.com/token",
+		ClientID:              "web",
+		RedirectURL:           "https://gateway.example.com/oauth2/callback",
+		CookieSecret:          "0123456789abcdef",
+	})
+	require.NoError(t, err)
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Request.SetMethod(http.MethodPost)
+	hzCtx.Request.SetRequestURI("/orders")
+	hzCtx.Request.Header.Set("Accept", "application/json")
+	m.ServeHTTP(context.Background(), hzCtx)
+	assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+	assert.True(t, hzCtx.IsAborted())
+
+	// tampered cookies are ignored
+	hzCtx = app.NewContext(0)
+	hzCtx.Request.SetRequestURI("/orders")
+	hzCtx.Request.Header.Set("Accept", "application/json")
+	hzCtx.Request.SetCookie("bifrost_oidc", "tampered")
+	m.ServeHTTP(context.Background(), hzCtx)
+	assert.Equal(t, http.StatusUnauthorized, hzCtx.Response.StatusCode())
+}
+
+func TestOIDCSessionSize(t *testing.T) {
+	m, err := NewOIDCMiddleware(OIDCOptions{
+		AuthorizationEndpoint: "https://idp.example.com/authorize",
+		TokenEndpoint:         "https://idp.example.com/token",
+		ClientID:              "web",
+		RedirectURL:           "https://gateway.example.com/oauth2/callback",
+		CookieSecret:          "0123456789abcdef",
+	})
+	require.NoError(t, err)
+
+	hzCtx := app.NewContext(0)
+	err = m.saveSession(hzCtx, &session{Identity: identity{User: "u1"}, CreatedAt: time.Now().Unix()})
+	require.NoError(t, err)
+	assert.NotEmpty(t, responseCookie(hzCtx, "bifrost_oidc"))
+
+	// sessions which browsers would drop are rejected
+	hzCtx = app.NewContext(0)
+	err = m.saveSession(hzCtx, &session{
+		Identity:  identity{User: "u1", Claims: map[string]any{"roles": strings.Repeat("admin,", 700)}},
+		CreatedAt: time.Now().Unix(),
+	})
+	require.ErrorContains(t, err, "exceeds the browser limit")
+	assert.Empty(t, responseCookie(hzCtx, "bifrost_oidc"))
+}
+
+func TestNewOIDCMiddlewareValidation(t *testing.T) {
+	_, err := NewOIDCMiddleware(OIDCOptions{})
+	require.ErrorContains(t, err, "client_id cannot be empty")
+
+	_, err = NewOIDCMiddleware(OIDCOptions{ClientID: "web", RedirectURL: "https://gw/cb"})
+	require.ErrorContains(t, err, "issuer or both")
+
+	_, err = NewOIDCMiddleware(OIDCOptions{
+		ClientID:     "web",
+		RedirectURL:  "https://gw/cb",
+		Issuer:       "https://idp",
+		CookieSecret: "short",
+	})
+	require.ErrorContains(t, err, "cookie_secret")
+}
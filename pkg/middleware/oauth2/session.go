@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/bytedance/sonic"
+)
+
+// session is the OIDC login state persisted in the encrypted session cookie.
+type session struct {
+	Identity     identity `json:"identity"`
+	AccessToken  string   `json:"access_token,omitempty"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
+	ExpiresAt    int64    `json:"expires_at,omitempty"`
+	CreatedAt    int64    `json:"created_at"`
+}
+
+// loginState is stored in a short-lived cookie between the authorization redirect and the callback.
+type loginState struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Redirect  string `json:"redirect"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// cookieCodec seals cookie payloads with AES-256-GCM so they cannot be read or tampered with by clients.
+type cookieCodec struct {
+	aead cipher.AEAD
+}
+
+func newCookieCodec(secret string) (*cookieCodec, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("cookie_secret must be at least 16 characters")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cookieCodec{aead: aead}, nil
+}
+
+func (c *cookieCodec) encode(v any) (string, error) {
+	plaintext, err := sonic.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *cookieCodec) decode(value string, v any) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return errors.New("cookie value is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return err
+	}
+
+	return sonic.Unmarshal(plaintext, v)
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
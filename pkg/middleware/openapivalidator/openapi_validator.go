@@ -0,0 +1,286 @@
+package openapivalidator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/nite-coder/bifrost/internal/pkg/hzadaptor"
+	"github.com/nite-coder/bifrost/pkg/log"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+const contentTypeJSON = "application/json"
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Options defines the configuration for the openapi_validator middleware.
+type Options struct {
+	// Spec is the path of an OpenAPI 3 document in YAML or JSON.
+	Spec string `mapstructure:"spec"`
+	// ValidateResponse validates the upstream responses and logs the violations, responses are never rejected.
+	ValidateResponse bool `mapstructure:"validate_response"`
+	// AllowUnknownOperations passes requests which match no operation of the spec instead of rejecting them.
+	AllowUnknownOperations bool `mapstructure:"allow_unknown_operations"`
+}
+
+// Violation describes a part of the request which does not match the spec.
+type Violation struct {
+	// In is the location of the violation: `path`, `query`, `header`, `cookie`, `body` or `request`.
+	In string `json:"in"`
+	// Name is the name of the parameter.
+	Name string `json:"name,omitempty"`
+	// Pointer is the JSON pointer of the invalid value in the body.
+	Pointer string `json:"pointer,omitempty"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Middleware is a middleware that validates requests against an OpenAPI 3 document.
+type Middleware struct {
+	options *Options
+	router  routers.Router
+}
+
+// NewMiddleware creates a new openapi_validator middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	router, err := loadSpec(options.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Middleware{
+		options: &options,
+		router:  router,
+	}, nil
+}
+
+func loadSpec(path string) (routers.Router, error) {
+	if path == "" {
+		return nil, errors.New("spec must be set for openapi_validator middleware")
+	}
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec '%s': %w", path, err)
+	}
+
+	err = doc.Validate(loader.Context)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI spec '%s' is invalid: %w", path, err)
+	}
+
+	// the requests are matched by the base paths of the servers only, as the hosts of the
+	// spec are usually the public hosts and not the ones the gateway sees
+	doc.Servers, err = basePaths(doc.Servers)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI spec '%s' is invalid: %w", path, err)
+	}
+	for _, item := range doc.Paths.Map() {
+		item.Servers, err = basePaths(item.Servers)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAPI spec '%s' is invalid: %w", path, err)
+		}
+	}
+
+	return gorillamux.NewRouter(doc)
+}
+
+func basePaths(servers openapi3.Servers) (openapi3.Servers, error) {
+	result := make(openapi3.Servers, 0, len(servers))
+	for _, server := range servers {
+		basePath, err := server.BasePath()
+		if err != nil {
+			return nil, fmt.Errorf("server url '%s' is invalid: %w", server.URL, err)
+		}
+		result = append(result, &openapi3.Server{URL: basePath})
+	}
+	return result, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	req, err := hzadaptor.ToHTTPRequest(ctx, &c.Request)
+	if err != nil {
+		m.reject(c, http.StatusBadRequest, "invalid request", nil)
+		return
+	}
+	// the body is buffered, so it is still forwarded after it was validated
+	body := c.Request.Body()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	route, pathParams, err := m.router.FindRoute(req)
+	if err != nil {
+		if m.options.AllowUnknownOperations {
+			return
+		}
+		if errors.Is(err, routers.ErrMethodNotAllowed) {
+			m.reject(c, http.StatusMethodNotAllowed, "method is not allowed by the OpenAPI spec", nil)
+			return
+		}
+		m.reject(c, http.StatusNotFound, "no operation of the OpenAPI spec matches the request", nil)
+		return
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError: true,
+			// authentication is left to the auth middlewares
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			// the request is validated only, it is forwarded as it is
+			SkipSettingDefaults: true,
+		},
+	}
+
+	err = openapi3filter.ValidateRequest(ctx, input)
+	if err != nil {
+		m.reject(c, http.StatusBadRequest, "request does not match the OpenAPI spec", toViolations(err))
+		return
+	}
+
+	if !m.options.ValidateResponse {
+		return
+	}
+
+	c.Next(ctx)
+
+	m.validateResponse(ctx, c, input)
+}
+
+func (m *Middleware) validateResponse(
+	ctx context.Context,
+	c *app.RequestContext,
+	input *openapi3filter.RequestValidationInput,
+) {
+	// streamed and encoded bodies cannot be validated without buffering or decoding them
+	if c.Response.IsBodyStream() || len(c.Response.Header.Peek("Content-Encoding")) > 0 {
+		return
+	}
+
+	header := http.Header{}
+	c.Response.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 c.Response.StatusCode(),
+		Header:                 header,
+		Options:                &openapi3filter.Options{MultiError: true},
+	}
+	responseInput.SetBodyBytes(c.Response.Body())
+
+	err := openapi3filter.ValidateResponse(ctx, responseInput)
+	if err != nil {
+		log.FromContext(ctx).Warn("openapi_validator: response does not match the OpenAPI spec",
+			"method", input.Request.Method,
+			"path", input.Route.Path,
+			"status", c.Response.StatusCode(),
+			"error", err,
+		)
+	}
+}
+
+func (m *Middleware) reject(c *app.RequestContext, statusCode int, message string, violations []Violation) {
+	body, _ := sonic.Marshal(errorResponse{
+		Message:    message,
+		Violations: violations,
+	})
+	c.Response.Header.SetContentType(contentTypeJSON)
+	c.Response.SetStatusCode(statusCode)
+	c.Response.SetBody(body)
+	c.Abort()
+}
+
+// toViolations flattens the errors of the request validation.
+func toViolations(err error) []Violation {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var result []Violation
+		for _, inner := range e {
+			result = append(result, toViolations(inner)...)
+		}
+		return result
+	case *openapi3filter.RequestError:
+		v := Violation{In: "request"}
+		switch {
+		case e.Parameter != nil:
+			v.In = e.Parameter.In
+			v.Name = e.Parameter.Name
+		case e.RequestBody != nil:
+			v.In = "body"
+		}
+
+		if result := schemaViolations(v, e.Err); len(result) > 0 {
+			return result
+		}
+
+		v.Message = e.Reason
+		if v.Message == "" && e.Err != nil {
+			v.Message = e.Err.Error()
+		}
+		return []Violation{v}
+	default:
+		return []Violation{{In: "request", Message: err.Error()}}
+	}
+}
+
+func schemaViolations(base Violation, err error) []Violation {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var result []Violation
+		for _, inner := range e {
+			result = append(result, schemaViolations(base, inner)...)
+		}
+		return result
+	case *openapi3.SchemaError:
+		v := base
+		for _, token := range e.JSONPointer() {
+			v.Pointer += "/" + pointerEscaper.Replace(token)
+		}
+		v.Message = e.Reason
+		return []Violation{v}
+	default:
+		return nil
+	}
+}
+
+// Init registers the openapi_validator middleware.
+func Init() error {
+	// the spec is loaded when the configuration is validated, so a broken spec fails the reload
+	err := middleware.RegisterValidator([]string{"openapi_validator"}, func(opts Options) error {
+		_, err := loadSpec(opts.Spec)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return middleware.Register([]string{"openapi_validator"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
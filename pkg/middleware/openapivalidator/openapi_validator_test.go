@@ -0,0 +1,160 @@
+package openapivalidator
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func perform(handler app.HandlerFunc, method, uri string, headers map[string]string, body string) (*app.RequestContext, bool) {
+	called := false
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.SetRequestURI(uri)
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	if body != "" {
+		c.Request.Header.SetContentTypeBytes([]byte("application/json"))
+		c.Request.SetBodyString(body)
+	}
+	c.SetIndex(-1)
+	c.SetHandlers([]app.HandlerFunc{handler, func(_ context.Context, c *app.RequestContext) {
+		called = true
+		c.Response.Header.SetContentType("application/json")
+		c.Response.SetBodyString(`[{"name":"kitty"}]`)
+	}})
+	c.Next(context.Background())
+	return c, called
+}
+
+func violations(t *testing.T, c *app.RequestContext) []Violation {
+	t.Helper()
+	resp := errorResponse{}
+	require.NoError(t, sonic.Unmarshal(c.Response.Body(), &resp))
+	return resp.Violations
+}
+
+func TestValidateRequest(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("openapi_validator")
+	handler, err := h(map[string]any{
+		"spec": "testdata/petstore.yaml",
+	})
+	require.NoError(t, err)
+
+	t.Run("valid requests", func(t *testing.T) {
+		c, called := perform(handler, http.MethodGet, "http://gateway/api/pets?limit=10", nil, "")
+		assert.True(t, called)
+		assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+
+		c, called = perform(handler, http.MethodPost, "/api/pets", map[string]string{"X-Request-Id": "1"},
+			`{"name":"kitty","age":2}`)
+		assert.True(t, called)
+		assert.Equal(t, `{"name":"kitty","age":2}`, string(c.Request.Body()))
+
+		_, called = perform(handler, http.MethodGet, "/api/pets/1", nil, "")
+		assert.True(t, called)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		c, called := perform(handler, http.MethodGet, "/api/pets?limit=1000", nil, "")
+		assert.False(t, called)
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+		v := violations(t, c)
+		require.Len(t, v, 1)
+		assert.Equal(t, "query", v[0].In)
+		assert.Equal(t, "limit", v[0].Name)
+		assert.NotEmpty(t, v[0].Message)
+	})
+
+	t.Run("invalid path parameter", func(t *testing.T) {
+		c, called := perform(handler, http.MethodGet, "/api/pets/abc", nil, "")
+		assert.False(t, called)
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+		v := violations(t, c)
+		require.Len(t, v, 1)
+		assert.Equal(t, "path", v[0].In)
+		assert.Equal(t, "id", v[0].Name)
+	})
+
+	t.Run("invalid header and body", func(t *testing.T) {
+		c, called := perform(handler, http.MethodPost, "/api/pets", nil, `{"name":"","age":-1,"tags":[1]}`)
+		assert.False(t, called)
+		assert.Equal(t, http.StatusBadRequest, c.Response.StatusCode())
+		assert.Equal(t, "application/json", string(c.Response.Header.ContentType()))
+
+		pointers := map[string]bool{}
+		header := false
+		for _, v := range violations(t, c) {
+			if v.In == "header" && v.Name == "X-Request-Id" {
+				header = true
+			}
+			if v.In == "body" {
+				pointers[v.Pointer] = true
+			}
+		}
+		assert.True(t, header)
+		assert.Equal(t, map[string]bool{"/name": true, "/age": true, "/tags/0": true}, pointers)
+	})
+
+	t.Run("unknown operations", func(t *testing.T) {
+		c, called := perform(handler, http.MethodGet, "/api/stores", nil, "")
+		assert.False(t, called)
+		assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+
+		c, called = perform(handler, http.MethodDelete, "/api/pets", nil, "")
+		assert.False(t, called)
+		assert.Equal(t, http.StatusMethodNotAllowed, c.Response.StatusCode())
+	})
+}
+
+func TestAllowUnknownOperations(t *testing.T) {
+	m, err := NewMiddleware(Options{Spec: "testdata/petstore.yaml", AllowUnknownOperations: true})
+	require.NoError(t, err)
+
+	_, called := perform(m.ServeHTTP, http.MethodGet, "/api/stores", nil, "")
+	assert.True(t, called)
+
+	_, called = perform(m.ServeHTTP, http.MethodGet, "/api/pets?limit=0", nil, "")
+	assert.False(t, called)
+}
+
+func TestValidateResponse(t *testing.T) {
+	m, err := NewMiddleware(Options{Spec: "testdata/petstore.yaml", ValidateResponse: true})
+	require.NoError(t, err)
+
+	// invalid responses are only logged
+	c, called := perform(m.ServeHTTP, http.MethodGet, "/api/pets/1", nil, "")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, `[{"name":"kitty"}]`, string(c.Response.Body()))
+}
+
+func TestLoadSpec(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(Options{Spec: "testdata/not_found.yaml"})
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("openapi: 3.0.3\npaths: {}\n"), 0o600))
+	_, err = NewMiddleware(Options{Spec: path})
+	assert.Error(t, err)
+
+	_ = Init()
+	err = middleware.Validate("openapi_validator", map[string]any{"spec": path})
+	assert.Error(t, err)
+	err = middleware.Validate("openapi_validator", map[string]any{"spec": "testdata/petstore.yaml"})
+	assert.NoError(t, err)
+}
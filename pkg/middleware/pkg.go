@@ -8,11 +8,17 @@ import (
 	"github.com/go-viper/mapstructure/v2"
 )
 
-var handlers = make(map[string]CreateMiddlewareHandler)
+var (
+	handlers   = make(map[string]CreateMiddlewareHandler)
+	validators = make(map[string]ValidateHandler)
+)
 
 // CreateMiddlewareHandler is a function that creates an app.HandlerFunc from parameters.
 type CreateMiddlewareHandler func(param any) (app.HandlerFunc, error)
 
+// ValidateHandler is a function that validates the parameters of a middleware.
+type ValidateHandler func(param any) error
+
 // Factory returns a middleware creator for the given kind.
 func Factory(kind string) CreateMiddlewareHandler {
 	return handlers[kind]
@@ -28,31 +34,10 @@ func Register[T any](names []string, handler func(T) (app.HandlerFunc, error)) e
 	}
 
 	wrappedHandler := func(params any) (app.HandlerFunc, error) {
-		if typedParams, ok := params.(T); ok {
-			return handler(typedParams)
-		}
-
-		var cfg T
-		if params == nil {
-			// If params is nil, we pass the zero value of T
-			// If T is a pointer type, it will be nil. If T is a struct, it will be empty struct.
-			return handler(cfg)
-		}
-
-		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			Result:           &cfg,
-			TagName:          "mapstructure",
-			WeaklyTypedInput: true,
-			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
-		})
+		cfg, err := decode[T](params)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create decoder: %w", err)
-		}
-
-		if err := decoder.Decode(params); err != nil {
-			return nil, fmt.Errorf("failed to decode middleware params: %w", err)
+			return nil, err
 		}
-
 		return handler(cfg)
 	}
 
@@ -66,3 +51,68 @@ func Register[T any](names []string, handler func(T) (app.HandlerFunc, error)) e
 
 	return nil
 }
+
+// RegisterValidator registers a validator for the parameters of a middleware, so invalid
+// parameters are reported by config validation before any middleware is created.
+func RegisterValidator[T any](names []string, validate func(T) error) error {
+	if len(names) == 0 {
+		return errors.New("middleware names cannot be empty")
+	}
+
+	wrappedValidator := func(params any) error {
+		cfg, err := decode[T](params)
+		if err != nil {
+			return err
+		}
+		return validate(cfg)
+	}
+
+	for _, name := range names {
+		if _, found := validators[name]; found {
+			return fmt.Errorf("middleware validator '%s' already exists", name)
+		}
+
+		validators[name] = wrappedValidator
+	}
+
+	return nil
+}
+
+// Validate validates the parameters of a middleware. Middlewares without a validator are always valid.
+func Validate(kind string, params any) error {
+	validate, found := validators[kind]
+	if !found {
+		return nil
+	}
+	return validate(params)
+}
+
+// decode decodes generic params (map[string]any) into struct T.
+func decode[T any](params any) (T, error) {
+	if typedParams, ok := params.(T); ok {
+		return typedParams, nil
+	}
+
+	var cfg T
+	if params == nil {
+		// If params is nil, we pass the zero value of T
+		// If T is a pointer type, it will be nil. If T is a struct, it will be empty struct.
+		return cfg, nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &cfg,
+		TagName:          "mapstructure",
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+	})
+	if err != nil {
+		return cfg, fmt.Errorf("failed to create decoder: %w", err)
+	}
+
+	if err := decoder.Decode(params); err != nil {
+		return cfg, fmt.Errorf("failed to decode middleware params: %w", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,198 @@
+package requestdecompression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/nite-coder/bifrost/internal/pkg/bodylimit"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+const (
+	encodingGzip               = "gzip"
+	encodingDeflate            = "deflate"
+	encodingBrotli             = "br"
+	encodingZstd               = "zstd"
+	encodingIdentity           = "identity"
+	headerAcceptEncoding       = "Accept-Encoding"
+	headerContentEncoding      = "Content-Encoding"
+	defaultMaxDecompressedSize = 10 * 1024 * 1024
+)
+
+var defaultEncodings = []string{encodingGzip, encodingDeflate, encodingBrotli, encodingZstd}
+
+// Options defines the configuration for the request_decompression middleware.
+type Options struct {
+	// MaxDecompressedSize limits the size of the decompressed body, which protects
+	// the upstream and the following middlewares from zip bombs.
+	MaxDecompressedSize int64    `mapstructure:"max_decompressed_size"`
+	Encodings           []string `mapstructure:"encodings"`
+}
+
+// Middleware is a middleware that decompresses the request body.
+type Middleware struct {
+	options *Options
+}
+
+// NewMiddleware creates a new request_decompression middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.MaxDecompressedSize <= 0 {
+		options.MaxDecompressedSize = defaultMaxDecompressedSize
+	}
+	if len(options.Encodings) == 0 {
+		options.Encodings = slices.Clone(defaultEncodings)
+	}
+	for i, encoding := range options.Encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if !slices.Contains(defaultEncodings, encoding) {
+			return nil, fmt.Errorf("encoding '%s' is invalid for request_decompression middleware", encoding)
+		}
+		options.Encodings[i] = encoding
+	}
+
+	return &Middleware{
+		options: &options,
+	}, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	encodings := parseContentEncoding(c.Request.Header.Get(headerContentEncoding))
+	if len(encodings) == 0 {
+		c.Next(ctx)
+		return
+	}
+
+	for _, encoding := range encodings {
+		if !slices.Contains(m.options.Encodings, encoding) {
+			c.Response.Header.Set(headerAcceptEncoding, strings.Join(m.options.Encodings, ", "))
+			c.AbortWithStatus(http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	// the body is decoded before the request is passed on, so an invalid body or a zip bomb
+	// never reaches the following middlewares or the upstream
+	decoder := &decodeReader{encodings: encodings}
+	err := bodylimit.ReadBody(&c.Request, m.options.MaxDecompressedSize, func(r io.Reader) io.Reader {
+		decoder.src = r
+		return decoder
+	})
+	_ = decoder.Close()
+
+	if errors.Is(err, bodylimit.ErrTooLarge) {
+		bodylimit.Reject(c)
+		return
+	}
+	if err != nil {
+		// the rest of the invalid body is not read, so the connection cannot be reused
+		c.SetConnectionClose()
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	c.Request.Header.Del(headerContentEncoding)
+
+	c.Next(ctx)
+}
+
+// parseContentEncoding returns the content codings in the order they were applied.
+func parseContentEncoding(value string) []string {
+	var encodings []string
+	for encoding := range strings.SplitSeq(value, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "" || encoding == encodingIdentity {
+			continue
+		}
+		encodings = append(encodings, encoding)
+	}
+	return encodings
+}
+
+// decodeReader creates the decoders on the first read, so a body which cannot be decoded is
+// reported as a read error.
+type decodeReader struct {
+	src       io.Reader
+	r         io.Reader
+	err       error
+	encodings []string
+	closers   []io.Closer
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.r == nil {
+		r := d.src
+		// codings are removed in the reverse order they were applied
+		for i := len(d.encodings) - 1; i >= 0; i-- {
+			dec, err := newDecoder(d.encodings[i], r)
+			if err != nil {
+				d.err = err
+				return 0, err
+			}
+			if closer, ok := dec.(io.Closer); ok {
+				d.closers = append(d.closers, closer)
+			}
+			r = dec
+		}
+		d.r = r
+	}
+
+	n, err := d.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		d.err = err
+	}
+	return n, err
+}
+
+// Close releases the decoders, it is safe to call it more than once.
+func (d *decodeReader) Close() error {
+	for _, closer := range d.closers {
+		_ = closer.Close()
+	}
+	d.closers = nil
+	return nil
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case encodingGzip:
+		return gzip.NewReader(r)
+	case encodingDeflate:
+		return flate.NewReader(r), nil
+	case encodingBrotli:
+		return brotli.NewReader(r), nil
+	case encodingZstd:
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+}
+
+// Init registers the request_decompression middleware.
+func Init() error {
+	return middleware.Register([]string{"request_decompression"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
@@ -0,0 +1,156 @@
+package requestdecompression
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	switch encoding {
+	case encodingGzip:
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case encodingDeflate:
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case encodingBrotli:
+		w := brotli.NewWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case encodingZstd:
+		w, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	}
+	return buf.Bytes()
+}
+
+func newRouter(t *testing.T, params map[string]any, received *string) *route.Engine {
+	t.Helper()
+
+	_ = Init()
+	h := middleware.Factory("request_decompression")
+	handler, err := h(params)
+	require.NoError(t, err)
+
+	router := route.NewEngine(config.NewOptions([]config.Option{}))
+	router.Use(handler)
+	router.POST("/", func(_ context.Context, c *app.RequestContext) {
+		*received = string(c.Request.Body())
+		c.String(http.StatusOK, c.Request.Header.Get(headerContentEncoding))
+	})
+	return router
+}
+
+func TestDecompression(t *testing.T) {
+	var received string
+	router := newRouter(t, map[string]any{}, &received)
+	payload := []byte(`{"hello":"world"}`)
+
+	for _, encoding := range defaultEncodings {
+		t.Run(encoding, func(t *testing.T) {
+			received = ""
+			body := compress(t, encoding, payload)
+
+			w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: bytes.NewReader(body), Len: len(body)},
+				ut.Header{Key: headerContentEncoding, Value: encoding})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, string(payload), received)
+			assert.Empty(t, w.Body.String())
+		})
+	}
+
+	t.Run("stacked encodings", func(t *testing.T) {
+		received = ""
+		body := compress(t, encodingBrotli, compress(t, encodingGzip, payload))
+
+		w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: bytes.NewReader(body), Len: -1},
+			ut.Header{Key: headerContentEncoding, Value: "gzip, br"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, string(payload), received)
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		received = ""
+
+		w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: bytes.NewReader(payload), Len: len(payload)},
+			ut.Header{Key: headerContentEncoding, Value: encodingIdentity})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, string(payload), received)
+	})
+}
+
+func TestUnsupportedEncoding(t *testing.T) {
+	var received string
+	router := newRouter(t, map[string]any{"encodings": []string{"gzip"}}, &received)
+	body := compress(t, encodingZstd, []byte("hello"))
+
+	w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: bytes.NewReader(body), Len: len(body)},
+		ut.Header{Key: headerContentEncoding, Value: encodingZstd})
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get(headerAcceptEncoding))
+	assert.Empty(t, received)
+}
+
+func TestZipBomb(t *testing.T) {
+	var received string
+	router := newRouter(t, map[string]any{"max_decompressed_size": 1024}, &received)
+	body := compress(t, encodingGzip, bytes.Repeat([]byte("a"), 1024*1024))
+
+	w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: bytes.NewReader(body), Len: len(body)},
+		ut.Header{Key: headerContentEncoding, Value: encodingGzip})
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.True(t, w.Result().Header.ConnectionClose())
+	assert.Empty(t, w.Body.String())
+	// the truncated body never reaches the handler
+	assert.Empty(t, received)
+}
+
+func TestInvalidBody(t *testing.T) {
+	var received string
+	router := newRouter(t, map[string]any{}, &received)
+
+	w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: strings.NewReader("not gzip"), Len: -1},
+		ut.Header{Key: headerContentEncoding, Value: encodingGzip})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, w.Result().Header.ConnectionClose())
+	assert.Empty(t, received)
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_, err := NewMiddleware(Options{Encodings: []string{"compress"}})
+	require.Error(t, err)
+
+	m, err := NewMiddleware(Options{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(defaultMaxDecompressedSize), m.options.MaxDecompressedSize)
+}
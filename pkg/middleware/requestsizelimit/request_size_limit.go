@@ -0,0 +1,83 @@
+package requestsizelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/internal/pkg/bodylimit"
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+// Options defines the configuration for the request_size_limit middleware.
+type Options struct {
+	// MaxRequestBodySize limits the maximum number of bytes for the request body.
+	MaxRequestBodySize int64 `json:"max_request_body_size" mapstructure:"max_request_body_size"`
+}
+
+// Middleware is a middleware that limits the request body size. Bodies with a Content-Length
+// are streamed to the upstream without being buffered in memory.
+type Middleware struct {
+	options *Options
+}
+
+// NewMiddleware creates a new request_size_limit middleware instance.
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.MaxRequestBodySize <= 0 {
+		return nil, errors.New("max_request_body_size must be greater than 0")
+	}
+	return &Middleware{
+		options: &options,
+	}, nil
+}
+
+func (m *Middleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
+	contentLength := c.Request.Header.ContentLength()
+	if contentLength > 0 && int64(contentLength) > m.options.MaxRequestBodySize {
+		bodylimit.Reject(c)
+		return
+	}
+
+	if !c.Request.IsBodyStream() {
+		if int64(len(c.Request.Body())) > m.options.MaxRequestBodySize {
+			bodylimit.Reject(c)
+			return
+		}
+		c.Next(ctx)
+		return
+	}
+
+	// the server never reads more than the Content-Length, so only chunked bodies are counted
+	if contentLength >= 0 {
+		c.Next(ctx)
+		return
+	}
+
+	// chunked bodies are read before the request is passed on, so a body over the limit never
+	// reaches the following middlewares or the upstream truncated
+	err := bodylimit.ReadBody(&c.Request, m.options.MaxRequestBodySize, nil)
+	if errors.Is(err, bodylimit.ErrTooLarge) {
+		bodylimit.Reject(c)
+		return
+	}
+	if err != nil {
+		c.SetConnectionClose()
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	c.Next(ctx)
+}
+
+// Init registers the request_size_limit middleware.
+func Init() error {
+	return middleware.Register([]string{"request_size_limit"}, func(opts Options) (app.HandlerFunc, error) {
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
+		return m.ServeHTTP, nil
+	})
+}
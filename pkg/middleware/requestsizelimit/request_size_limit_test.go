@@ -0,0 +1,96 @@
+package requestsizelimit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/middleware"
+)
+
+func TestRequestSizeLimit(t *testing.T) {
+	_ = Init()
+	h := middleware.Factory("request_size_limit")
+
+	handler, err := h(map[string]any{"max_request_body_size": 10})
+	require.NoError(t, err)
+
+	var called bool
+	var received string
+	router := route.NewEngine(config.NewOptions([]config.Option{}))
+	router.Use(handler)
+	router.POST("/", func(_ context.Context, c *app.RequestContext) {
+		called = true
+		received = string(c.Request.Body())
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		length       int
+		wantStatus   int
+		wantCalled   bool
+		wantReceived string
+	}{
+		{
+			name:         "chunked body within limit",
+			body:         "0123456789",
+			length:       -1,
+			wantStatus:   http.StatusOK,
+			wantCalled:   true,
+			wantReceived: "0123456789",
+		},
+		{
+			name:       "chunked body exceeds limit",
+			body:       "0123456789a",
+			length:     -1,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "content length exceeds limit",
+			body:       "0123456789a",
+			length:     11,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "content length within limit",
+			body:         "hello",
+			length:       5,
+			wantStatus:   http.StatusOK,
+			wantCalled:   true,
+			wantReceived: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			received = ""
+
+			w := ut.PerformRequest(router, http.MethodPost, "/", &ut.Body{Body: strings.NewReader(tt.body), Len: tt.length})
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCalled, called)
+			if tt.wantStatus == http.StatusRequestEntityTooLarge {
+				assert.True(t, w.Result().Header.ConnectionClose())
+				assert.Empty(t, w.Body.String())
+			} else {
+				assert.Equal(t, tt.wantReceived, received)
+			}
+		})
+	}
+}
+
+func TestNewMiddleware(t *testing.T) {
+	_, err := NewMiddleware(Options{})
+	require.Error(t, err)
+}
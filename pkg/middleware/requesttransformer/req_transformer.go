@@ -5,13 +5,16 @@ import (
 
 	"github.com/cloudwego/hertz/pkg/app"
 
+	"github.com/nite-coder/bifrost/internal/pkg/jsonbody"
+	"github.com/nite-coder/bifrost/pkg/log"
 	"github.com/nite-coder/bifrost/pkg/middleware"
 	"github.com/nite-coder/bifrost/pkg/variable"
 )
 
-// RequestTransFormaterMiddleware is a middleware that transforms the request by adding, setting, or removing headers and query parameters.
+// RequestTransFormaterMiddleware is a middleware that transforms the request by adding, setting, or removing headers, query parameters and JSON body fields.
 type RequestTransFormaterMiddleware struct {
 	options *Options
+	body    *jsonbody.Transformer
 }
 
 // RemoveOptions defines the headers and query parameters to be removed from the request.
@@ -32,21 +35,40 @@ type SetOptions struct {
 	Querystring map[string]string
 }
 
+// BodyOptions defines the JSON fields to be removed, renamed, added or set in the request body.
+// Fields are addressed by a dot path (e.g. `user.name`) or a JSONPath (e.g. `$.items[0].id`).
+type BodyOptions struct {
+	Remove []string
+	Rename map[string]string
+	Add    map[string]any
+	Set    map[string]any
+}
+
 // Options defines the total configuration for the request transformer middleware.
 type Options struct {
 	Add    AddOptions
 	Set    SetOptions
 	Remove RemoveOptions
+	Body   BodyOptions
 }
 
 // NewMiddleware creates a new RequestTransFormaterMiddleware instance.
-func NewMiddleware(opts Options) *RequestTransFormaterMiddleware {
-	return &RequestTransFormaterMiddleware{
+func NewMiddleware(opts Options) (*RequestTransFormaterMiddleware, error) {
+	body, err := jsonbody.New(jsonbody.Options(opts.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &RequestTransFormaterMiddleware{
 		options: &opts,
 	}
+	if !body.IsEmpty() {
+		m.body = body
+	}
+	return m, nil
 }
 
-func (m *RequestTransFormaterMiddleware) ServeHTTP(_ context.Context, c *app.RequestContext) {
+func (m *RequestTransFormaterMiddleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
 	if len(m.options.Remove.Headers) > 0 {
 		for _, header := range m.options.Remove.Headers {
 			if header == "" {
@@ -114,12 +136,41 @@ func (m *RequestTransFormaterMiddleware) ServeHTTP(_ context.Context, c *app.Req
 			c.Request.URI().QueryArgs().Set(k, v)
 		}
 	}
+	if m.body != nil {
+		m.transformBody(ctx, c)
+	}
+}
+
+func (m *RequestTransFormaterMiddleware) transformBody(ctx context.Context, c *app.RequestContext) {
+	// compressed bodies and other content types are forwarded as they are
+	if !jsonbody.IsJSON(c.Request.Header.ContentType()) || len(c.Request.Header.Peek("Content-Encoding")) > 0 {
+		return
+	}
+
+	body := c.Request.Body()
+	if len(body) == 0 {
+		return
+	}
+
+	result, changed, err := m.body.Transform(c, body)
+	if err != nil {
+		log.FromContext(ctx).Warn("request_transformer: failed to transform body", "error", err)
+		return
+	}
+	if changed {
+		c.Request.SetBody(result)
+		body = result
+	}
+	c.Request.Header.SetContentLength(len(body))
 }
 
 // Init registers the request_transformer middleware.
 func Init() error {
 	return middleware.Register([]string{"request_transformer"}, func(opts Options) (app.HandlerFunc, error) {
-		m := NewMiddleware(opts)
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
 		return m.ServeHTTP, nil
 	})
 }
@@ -157,3 +157,66 @@ func TestFactory_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestBody(t *testing.T) {
+	h := middleware.Factory("request_transformer")
+
+	params := map[string]any{
+		"body": map[string]any{
+			"remove": []string{"internal", "$.items[*].secret"},
+			"rename": map[string]string{"name": "user.name"},
+			"add": map[string]any{
+				"tenant_id": "$var.tenant",
+				"source":    "web",
+			},
+			"set": map[string]any{
+				"$.meta.version": 2,
+			},
+		},
+	}
+
+	m, err := h(params)
+	require.NoError(t, err)
+
+	t.Run("json body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Set("tenant", "acme")
+		hzCtx.Request.SetMethod("POST")
+		hzCtx.Request.Header.SetContentTypeBytes([]byte("application/json; charset=utf-8"))
+		hzCtx.Request.SetBodyString(`{"name":"john","source":"app","internal":true,"items":[{"id":12345678901234567890,"secret":"x"}]}`)
+		m(context.Background(), hzCtx)
+
+		body := hzCtx.Request.Body()
+		assert.JSONEq(t, `{"user":{"name":"john"},"source":"app","tenant_id":"acme","meta":{"version":2},"items":[{"id":12345678901234567890}]}`, string(body))
+		assert.Equal(t, len(body), hzCtx.Request.Header.ContentLength())
+	})
+
+	t.Run("non json body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetMethod("POST")
+		hzCtx.Request.Header.SetContentTypeBytes([]byte("text/plain"))
+		hzCtx.Request.SetBodyString(`{"name":"john"}`)
+		m(context.Background(), hzCtx)
+
+		assert.Equal(t, `{"name":"john"}`, string(hzCtx.Request.Body()))
+	})
+
+	t.Run("invalid json body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetMethod("POST")
+		hzCtx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+		hzCtx.Request.SetBodyString(`{"name":`)
+		m(context.Background(), hzCtx)
+
+		assert.Equal(t, `{"name":`, string(hzCtx.Request.Body()))
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		_, err := h(map[string]any{
+			"body": map[string]any{
+				"set": map[string]any{"items.*.id": 1},
+			},
+		})
+		require.Error(t, err)
+	})
+}
@@ -2,16 +2,20 @@ package responsetransformer
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
 
+	"github.com/nite-coder/bifrost/internal/pkg/jsonbody"
+	"github.com/nite-coder/bifrost/pkg/log"
 	"github.com/nite-coder/bifrost/pkg/middleware"
 	"github.com/nite-coder/bifrost/pkg/variable"
 )
 
-// ResponseTransFormaterMiddleware is a middleware that transforms the response by adding, setting, or removing headers.
+// ResponseTransFormaterMiddleware is a middleware that transforms the response by adding, setting, or removing headers and JSON body fields.
 type ResponseTransFormaterMiddleware struct {
 	options *Options
+	body    *jsonbody.Transformer
 }
 
 // RemoveOptions defines the headers to be removed from the response.
@@ -29,18 +33,37 @@ type SetOptions struct {
 	Headers map[string]string
 }
 
+// BodyOptions defines the JSON fields to be removed, renamed, added or set in the response body.
+// Fields are addressed by a dot path (e.g. `user.name`) or a JSONPath (e.g. `$.items[0].id`).
+type BodyOptions struct {
+	Remove []string
+	Rename map[string]string
+	Add    map[string]any
+	Set    map[string]any
+}
+
 // Options defines the total configuration for the response transformer middleware.
 type Options struct {
 	Add    AddOptions
 	Set    SetOptions
 	Remove RemoveOptions
+	Body   BodyOptions
 }
 
 // NewMiddleware creates a new ResponseTransFormaterMiddleware instance.
-func NewMiddleware(opts Options) *ResponseTransFormaterMiddleware {
-	return &ResponseTransFormaterMiddleware{
+func NewMiddleware(opts Options) (*ResponseTransFormaterMiddleware, error) {
+	body, err := jsonbody.New(jsonbody.Options(opts.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ResponseTransFormaterMiddleware{
 		options: &opts,
 	}
+	if !body.IsEmpty() {
+		m.body = body
+	}
+	return m, nil
 }
 
 func (m *ResponseTransFormaterMiddleware) ServeHTTP(ctx context.Context, c *app.RequestContext) {
@@ -77,12 +100,44 @@ func (m *ResponseTransFormaterMiddleware) ServeHTTP(ctx context.Context, c *app.
 			c.Response.Header.Set(k, v)
 		}
 	}
+	if m.body != nil {
+		m.transformBody(ctx, c)
+	}
+}
+
+func (m *ResponseTransFormaterMiddleware) transformBody(ctx context.Context, c *app.RequestContext) {
+	// compressed bodies and other content types are returned as they are
+	if !jsonbody.IsJSON(c.Response.Header.ContentType()) || len(c.Response.Header.ContentEncoding()) > 0 {
+		return
+	}
+	if c.Request.Header.IsHead() || c.Response.StatusCode() == http.StatusNoContent || c.Response.StatusCode() == http.StatusNotModified {
+		return
+	}
+
+	body := c.Response.Body()
+	if len(body) == 0 {
+		return
+	}
+
+	result, changed, err := m.body.Transform(c, body)
+	if err != nil {
+		log.FromContext(ctx).Warn("response_transformer: failed to transform body", "error", err)
+		return
+	}
+	if changed {
+		c.Response.SetBody(result)
+		body = result
+	}
+	c.Response.Header.SetContentLength(len(body))
 }
 
 // Init registers the response_transformer middleware.
 func Init() error {
 	return middleware.Register([]string{"response_transformer"}, func(opts Options) (app.HandlerFunc, error) {
-		m := NewMiddleware(opts)
+		m, err := NewMiddleware(opts)
+		if err != nil {
+			return nil, err
+		}
 		return m.ServeHTTP, nil
 	})
 }
@@ -89,3 +89,53 @@ func TestSet(t *testing.T) {
 
 	assert.Equal(t, "hello", hzCtx.Response.Header.Get("x-existing-value"))
 }
+
+func TestBody(t *testing.T) {
+	h := middleware.Factory("response_transformer")
+
+	params := map[string]any{
+		"body": map[string]any{
+			"remove": []string{"password", "data.*.internal"},
+			"set": map[string]any{
+				"request_id": "$var.request_id",
+			},
+		},
+	}
+
+	m, err := h(params)
+	require.NoError(t, err)
+
+	t.Run("json body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Set("request_id", "abc")
+		hzCtx.Request.SetMethod("GET")
+		hzCtx.Response.Header.SetContentType("application/problem+json")
+		hzCtx.Response.SetBodyString(`{"password":"secret","data":[{"id":1,"internal":"x"},{"id":2}]}`)
+		m(context.Background(), hzCtx)
+
+		body := hzCtx.Response.Body()
+		assert.JSONEq(t, `{"request_id":"abc","data":[{"id":1},{"id":2}]}`, string(body))
+		assert.Equal(t, len(body), hzCtx.Response.Header.ContentLength())
+	})
+
+	t.Run("compressed body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetMethod("GET")
+		hzCtx.Response.Header.SetContentType("application/json")
+		hzCtx.Response.Header.SetContentEncoding("gzip")
+		hzCtx.Response.SetBodyString(`{"password":"secret"}`)
+		m(context.Background(), hzCtx)
+
+		assert.Equal(t, `{"password":"secret"}`, string(hzCtx.Response.Body()))
+	})
+
+	t.Run("non json body", func(t *testing.T) {
+		hzCtx := app.NewContext(0)
+		hzCtx.Request.SetMethod("GET")
+		hzCtx.Response.Header.SetContentType("text/html")
+		hzCtx.Response.SetBodyString(`{"password":"secret"}`)
+		m(context.Background(), hzCtx)
+
+		assert.Equal(t, `{"password":"secret"}`, string(hzCtx.Response.Body()))
+	})
+}
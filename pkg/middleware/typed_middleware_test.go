@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -69,3 +70,27 @@ func TestRegisterTyped_DefaultValues(t *testing.T) {
 	assert.Empty(t, c.GetString("prefix"))
 	assert.Equal(t, 0, c.GetInt("retries"))
 }
+
+func TestRegisterValidator(t *testing.T) {
+	err := RegisterValidator([]string{"validator_test_middleware"}, func(cfg TestConfig) error {
+		if cfg.MaxRetries < 0 {
+			return errors.New("max_retries cannot be negative")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = RegisterValidator([]string{"validator_test_middleware"}, func(_ TestConfig) error { return nil })
+	require.Error(t, err)
+
+	err = RegisterValidator([]string{}, func(_ TestConfig) error { return nil })
+	require.Error(t, err)
+
+	require.NoError(t, Validate("validator_test_middleware", map[string]any{"max_retries": 3}))
+	require.NoError(t, Validate("validator_test_middleware", nil))
+	require.Error(t, Validate("validator_test_middleware", map[string]any{"max_retries": -1}))
+	require.Error(t, Validate("validator_test_middleware", "invalid"))
+
+	// middlewares without a validator are always valid
+	require.NoError(t, Validate("unknown_middleware", map[string]any{"max_retries": -1}))
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/timecache"
+)
+
+// The embeddings, images and audio families are served in the OpenAI wire format whatever the
+// client format is, since other providers don't define an equivalent API.
+
+func (p *Proxy) handleEmbeddings(
+	ctx context.Context,
+	hzCtx *app.RequestContext,
+	req *ai.EmbeddingRequest,
+	adapter ai.LLMAdapter,
+	virtualModel string,
+	modelID string,
+) {
+	embeddingAdapter, ok := adapter.(ai.EmbeddingAdapter)
+	if !ok {
+		_ = hzCtx.Error(newUnsupportedError(adapter, "Embeddings API"))
+		return
+	}
+
+	startTime := timecache.Now()
+
+	resp, err := embeddingAdapter.Embeddings(ctx, req)
+	if err != nil {
+		_ = hzCtx.Error(toAIError(err))
+		return
+	}
+
+	durationSecs := timecache.Now().Sub(startTime).Seconds()
+
+	resp.Usage.CalculateCost(p.pricing)
+	p.recordUsage(ctx, hzCtx, virtualModel, modelID, durationSecs, resp.Usage)
+
+	// Mask model name in response
+	resp.Model = virtualModel
+
+	hzCtx.JSON(http.StatusOK, resp)
+}
+
+func (p *Proxy) handleImages(
+	ctx context.Context,
+	hzCtx *app.RequestContext,
+	req *ai.ImageRequest,
+	adapter ai.LLMAdapter,
+	virtualModel string,
+	modelID string,
+) {
+	imageAdapter, ok := adapter.(ai.ImageAdapter)
+	if !ok {
+		_ = hzCtx.Error(newUnsupportedError(adapter, "Images API"))
+		return
+	}
+
+	startTime := timecache.Now()
+
+	resp, err := imageAdapter.Images(ctx, req)
+	if err != nil {
+		_ = hzCtx.Error(toAIError(err))
+		return
+	}
+
+	durationSecs := timecache.Now().Sub(startTime).Seconds()
+
+	resp.CalculateCost(p.pricing)
+	p.recordUsage(ctx, hzCtx, virtualModel, modelID, durationSecs, resp.Usage)
+
+	hzCtx.JSON(http.StatusOK, resp)
+}
+
+func (p *Proxy) handleTranscriptions(
+	ctx context.Context,
+	hzCtx *app.RequestContext,
+	req *ai.TranscriptionRequest,
+	adapter ai.LLMAdapter,
+	virtualModel string,
+	modelID string,
+) {
+	transcriptionAdapter, ok := adapter.(ai.TranscriptionAdapter)
+	if !ok {
+		_ = hzCtx.Error(newUnsupportedError(adapter, "Audio API"))
+		return
+	}
+
+	startTime := timecache.Now()
+
+	resp, err := transcriptionAdapter.Transcriptions(ctx, req)
+	if err != nil {
+		_ = hzCtx.Error(toAIError(err))
+		return
+	}
+
+	durationSecs := timecache.Now().Sub(startTime).Seconds()
+
+	resp.CalculateCost(p.pricing)
+	p.recordUsage(ctx, hzCtx, virtualModel, modelID, durationSecs, resp.Usage)
+
+	if req.IsText() {
+		hzCtx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(resp.Text))
+		return
+	}
+	hzCtx.JSON(http.StatusOK, resp)
+}
+
+func newUnsupportedError(adapter ai.LLMAdapter, api string) *ai.AIError {
+	return &ai.AIError{
+		Type:       "invalid_request_error",
+		Message:    api + " is not supported by " + adapter.Name() + " adapter",
+		StatusCode: http.StatusNotImplemented,
+		Provider:   adapter.Name(),
+	}
+}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/config"
+	"github.com/nite-coder/bifrost/pkg/target"
+	"github.com/nite-coder/bifrost/pkg/variable"
+)
+
+var (
+	mockMedia     *MockMediaAdapter
+	mockMediaOnce sync.Once
+)
+
+// MockMediaAdapter mocks an LLM adapter which supports the embeddings, images and audio APIs.
+type MockMediaAdapter struct {
+	MockLLMAdapter
+	embeddingsFunc     func(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error)
+	imagesFunc         func(ctx context.Context, req *ai.ImageRequest) (*ai.ImageResponse, error)
+	transcriptionsFunc func(ctx context.Context, req *ai.TranscriptionRequest) (*ai.TranscriptionResponse, error)
+}
+
+func (m *MockMediaAdapter) Embeddings(ctx context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+	return m.embeddingsFunc(ctx, req)
+}
+
+func (m *MockMediaAdapter) Images(ctx context.Context, req *ai.ImageRequest) (*ai.ImageResponse, error) {
+	return m.imagesFunc(ctx, req)
+}
+
+func (m *MockMediaAdapter) Transcriptions(
+	ctx context.Context,
+	req *ai.TranscriptionRequest,
+) (*ai.TranscriptionResponse, error) {
+	return m.transcriptionsFunc(ctx, req)
+}
+
+func newMediaProxy(t *testing.T, handler string, pricing *config.AIPricingOptions) *Proxy {
+	t.Helper()
+	setupMockAdapter(t)
+	mockMediaOnce.Do(func() {
+		ai.RegisterLLMAdapter("mock-media", func(_ ai.LLMAdapterOptions) (ai.LLMAdapter, error) {
+			return mockMedia, nil
+		})
+	})
+	mockMedia = &MockMediaAdapter{}
+
+	p, err := NewProxy(ProxyOptions{
+		ID:     "id1",
+		Target: "p1/model",
+		AIOptions: &config.AIOptions{
+			Providers: map[string]*config.AIProvider{
+				"p1": {Handler: handler, BaseURL: "http://localhost", APIKey: "key"},
+			},
+		},
+		Pricing: pricing,
+		Endpoint: &target.Endpoint{
+			Address: "p1/model",
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func newMediaContext(family string, key string, req any) *app.RequestContext {
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyClientAdapter, &MockClientAdapter{})
+	hzCtx.Set(ai.ContextKeyAIFamily, family)
+	hzCtx.Set(ai.ContextKeyVirtualModelName, "virtual")
+	hzCtx.Set(key, req)
+	return hzCtx
+}
+
+func TestAIProxy_ServeHTTP_Embeddings(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	p := newMediaProxy(t, "mock-media", &config.AIPricingOptions{InputPerMtok: 1_000_000})
+
+	mockMedia.embeddingsFunc = func(_ context.Context, req *ai.EmbeddingRequest) (*ai.EmbeddingResponse, error) {
+		assert.Equal(t, "model", req.Model)
+		return &ai.EmbeddingResponse{
+			Object: "list",
+			Data:   []ai.Embedding{{Object: "embedding", Embedding: []float64{0.1, 0.2}}},
+			Model:  "model",
+			Usage:  ai.Usage{PromptTokens: 3, TotalTokens: 3},
+		}, nil
+	}
+
+	hzCtx := newMediaContext(ai.FamilyEmbeddings, ai.ContextKeyEmbeddingRequest,
+		&ai.EmbeddingRequest{Model: "virtual", Input: "hello"})
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+	var resp ai.EmbeddingResponse
+	require.NoError(t, sonic.Unmarshal(hzCtx.Response.Body(), &resp))
+	assert.Equal(t, "virtual", resp.Model)
+	assert.Equal(t, []float64{0.1, 0.2}, resp.Data[0].Embedding)
+
+	assert.Equal(t, 3, hzCtx.GetInt(variable.InputTokens))
+	assert.InDelta(t, 3.0, hzCtx.GetFloat64(variable.TotalCost), 0.0001)
+}
+
+func TestAIProxy_ServeHTTP_Images(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	p := newMediaProxy(t, "mock-media", &config.AIPricingOptions{PerImage: 0.04})
+
+	mockMedia.imagesFunc = func(_ context.Context, req *ai.ImageRequest) (*ai.ImageResponse, error) {
+		assert.Equal(t, "model", req.Model)
+		return &ai.ImageResponse{
+			Created: 1,
+			Data:    []ai.ImageData{{URL: "https://example.com/1.png"}, {URL: "https://example.com/2.png"}},
+		}, nil
+	}
+
+	hzCtx := newMediaContext(ai.FamilyImages, ai.ContextKeyImageRequest,
+		&ai.ImageRequest{Model: "virtual", Prompt: "a cat"})
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+	var resp ai.ImageResponse
+	require.NoError(t, sonic.Unmarshal(hzCtx.Response.Body(), &resp))
+	assert.Len(t, resp.Data, 2)
+	assert.InDelta(t, 0.08, hzCtx.GetFloat64(variable.TotalCost), 0.0001)
+}
+
+func TestAIProxy_ServeHTTP_Transcriptions(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	p := newMediaProxy(t, "mock-media", &config.AIPricingOptions{PerMinute: 0.006})
+
+	mockMedia.transcriptionsFunc = func(
+		_ context.Context,
+		req *ai.TranscriptionRequest,
+	) (*ai.TranscriptionResponse, error) {
+		assert.Equal(t, "model", req.Model)
+		return &ai.TranscriptionResponse{Text: "hello world", Duration: 90}, nil
+	}
+
+	t.Run("json", func(t *testing.T) {
+		hzCtx := newMediaContext(ai.FamilyTranscriptions, ai.ContextKeyTranscriptionRequest,
+			&ai.TranscriptionRequest{Model: "virtual"})
+		p.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+		assert.JSONEq(t, `{"text":"hello world","duration":90}`, string(hzCtx.Response.Body()))
+		assert.InDelta(t, 0.009, hzCtx.GetFloat64(variable.TotalCost), 0.0001)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		hzCtx := newMediaContext(ai.FamilyTranscriptions, ai.ContextKeyTranscriptionRequest,
+			&ai.TranscriptionRequest{Model: "virtual", ResponseFormat: "text"})
+		p.ServeHTTP(context.Background(), hzCtx)
+
+		assert.Equal(t, http.StatusOK, hzCtx.Response.StatusCode())
+		assert.Equal(t, "hello world", string(hzCtx.Response.Body()))
+		assert.Contains(t, string(hzCtx.Response.Header.ContentType()), "text/plain")
+	})
+}
+
+func TestAIProxy_ServeHTTP_MediaNotSupported(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	p := newMediaProxy(t, "mock", nil)
+
+	hzCtx := newMediaContext(ai.FamilyEmbeddings, ai.ContextKeyEmbeddingRequest,
+		&ai.EmbeddingRequest{Model: "virtual", Input: "hello"})
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	require.Len(t, hzCtx.Errors, 1)
+	var aiErr *ai.AIError
+	require.ErrorAs(t, hzCtx.Errors[0].Err, &aiErr)
+	assert.Equal(t, http.StatusNotImplemented, aiErr.StatusCode)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -13,9 +14,9 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/bytedance/sonic"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/client"
+	hzerrors "github.com/cloudwego/hertz/pkg/common/errors"
 	hertzresp "github.com/cloudwego/hertz/pkg/protocol/http1/resp"
 
 	"github.com/nite-coder/bifrost/pkg/ai"
@@ -32,6 +33,9 @@ const (
 	TargetPartsCount = 2
 	// StreamReadBufferSize is the buffer size for reading SSE streams.
 	StreamReadBufferSize = 4096
+	// DefaultRateLimitCooldown is how long a rate limited target is skipped when the provider
+	// doesn't return when its rate limit resets.
+	DefaultRateLimitCooldown = 10 * time.Second
 )
 
 // Proxy implements proxy.Proxy for LLM upstream connections.
@@ -47,6 +51,9 @@ type Proxy struct {
 	initOnce       sync.Once
 	initErr        error
 	endpoint       atomic.Pointer[target.Endpoint]
+	stats          *ai.TargetStats
+	// skipRateLimited suspends the endpoint when the provider returns 429
+	skipRateLimited bool
 }
 
 var _ proxy.Proxy = (*Proxy)(nil)
@@ -59,6 +66,9 @@ type ProxyOptions struct {
 	MetricsEnabled bool
 	Pricing        *config.AIPricingOptions
 	Endpoint       *target.Endpoint
+	// SkipRateLimited suspends the endpoint until the rate limit of the provider resets, so that
+	// the balancer skips it.
+	SkipRateLimited bool
 }
 
 // NewProxy creates a new AIProxy instance.
@@ -69,11 +79,13 @@ func NewProxy(opts ProxyOptions) (*Proxy, error) {
 	endpoint := opts.Endpoint
 
 	p := &Proxy{
-		id:             opts.ID,
-		target:         opts.Target,
-		options:        opts.AIOptions,
-		metricsEnabled: opts.MetricsEnabled,
-		pricing:        opts.Pricing,
+		id:              opts.ID,
+		target:          opts.Target,
+		options:         opts.AIOptions,
+		metricsEnabled:  opts.MetricsEnabled,
+		pricing:         opts.Pricing,
+		stats:           ai.StatsOf(opts.Target),
+		skipRateLimited: opts.SkipRateLimited,
 	}
 	p.endpoint.Store(endpoint)
 
@@ -169,6 +181,18 @@ func (p *Proxy) ServeHTTP(ctx context.Context, hzCtx *app.RequestContext) {
 		return
 	}
 
+	p.stats.Begin()
+	startTime := timecache.Now()
+	errCount := len(hzCtx.Errors)
+	defer func() {
+		p.stats.Done()
+		if len(hzCtx.Errors) > errCount {
+			err := hzCtx.Errors.Last().Err
+			p.recordFailure(err, timecache.Now().Sub(startTime))
+			p.suspendIfRateLimited(ctx, err)
+		}
+	}()
+
 	switch aiFamily {
 	case ai.FamilyChat:
 		reqVal, ok := hzCtx.Get(ai.ContextKeyChatRequest)
@@ -206,12 +230,98 @@ func (p *Proxy) ServeHTTP(ctx context.Context, hzCtx *app.RequestContext) {
 		respReq.Model = targetModel
 
 		p.handleResponses(ctx, hzCtx, respReq, adapter, clientAdapter, virtualModel, p.target)
+	case ai.FamilyEmbeddings:
+		reqVal, ok := hzCtx.Get(ai.ContextKeyEmbeddingRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		embeddingReq, ok := reqVal.(*ai.EmbeddingRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		embeddingReq.Model = targetModel
+
+		p.handleEmbeddings(ctx, hzCtx, embeddingReq, adapter, virtualModel, p.target)
+	case ai.FamilyImages:
+		reqVal, ok := hzCtx.Get(ai.ContextKeyImageRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		imageReq, ok := reqVal.(*ai.ImageRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		imageReq.Model = targetModel
+
+		p.handleImages(ctx, hzCtx, imageReq, adapter, virtualModel, p.target)
+	case ai.FamilyTranscriptions:
+		reqVal, ok := hzCtx.Get(ai.ContextKeyTranscriptionRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		transcriptionReq, ok := reqVal.(*ai.TranscriptionRequest)
+		if !ok {
+			hzCtx.SetStatusCode(http.StatusBadRequest)
+			return
+		}
+		transcriptionReq.Model = targetModel
+
+		p.handleTranscriptions(ctx, hzCtx, transcriptionReq, adapter, virtualModel, p.target)
 	default:
 		hzCtx.SetStatusCode(http.StatusInternalServerError)
 		return
 	}
 }
 
+// recordFailure records a request which failed on the target: the latency balancer sees a penalty
+// sample instead of no sample at all, and server errors count as failures of the endpoint like the
+// 5xx responses of the HTTP proxy. Errors caused by the request or by a response filter are not
+// failures of the target.
+func (p *Proxy) recordFailure(err error, elapsed time.Duration) {
+	errorClass := ai.ClassifyError(err)
+	if errorClass == "" || errorClass == ai.ErrorClassContextLengthExceeded {
+		return
+	}
+	p.stats.ObserveFailure(elapsed)
+
+	if errorClass != ai.ErrorClassServerError && errorClass != ai.ErrorClassOverloaded {
+		return
+	}
+	ep := p.Endpoint()
+	if ep != nil && ep.State != nil {
+		ep.State.RecordFailure()
+	}
+}
+
+// suspendIfRateLimited suspends the endpoint of the proxy if the provider rate limited the request,
+// until the time the provider asked for or DefaultRateLimitCooldown.
+func (p *Proxy) suspendIfRateLimited(ctx context.Context, err error) {
+	if !p.skipRateLimited || ai.ClassifyError(err) != ai.ErrorClassRateLimited {
+		return
+	}
+	ep := p.Endpoint()
+	if ep == nil || ep.State == nil {
+		return
+	}
+
+	cooldown := DefaultRateLimitCooldown
+	var aiErr *ai.AIError
+	if errors.As(err, &aiErr) && aiErr.RetryAfter > 0 {
+		cooldown = aiErr.RetryAfter
+	}
+	ep.State.Suspend(timecache.Now().Add(cooldown))
+
+	slog.DebugContext(ctx, "ai target is rate limited and skipped",
+		"model_id", p.target,
+		"cooldown", cooldown,
+	)
+}
+
 // Close releases resources.
 func (p *Proxy) Close() error {
 	return nil
@@ -248,9 +358,15 @@ func (p *Proxy) LLMAdapter() (ai.LLMAdapter, error) {
 		}
 
 		opts := ai.LLMAdapterOptions{
-			HTTPClient: p.httpClient,
-			APIKey:     providerOptions.APIKey,
-			BaseURL:    providerOptions.BaseURL,
+			HTTPClient:  p.httpClient,
+			APIKey:      providerOptions.APIKey,
+			BaseURL:     providerOptions.BaseURL,
+			URLTemplate: providerOptions.URLTemplate,
+			AuthHeader:  providerOptions.AuthHeader,
+			AuthScheme:  providerOptions.AuthScheme,
+			Headers:     providerOptions.Headers,
+			QueryParams: providerOptions.QueryParams,
+			Deployments: providerOptions.Deployments,
 		}
 		adapter, err := ai.GetAdapter(providerOptions.Handler, opts)
 		if err != nil {
@@ -275,16 +391,7 @@ func (p *Proxy) handleChatUnary(
 
 	resp, err := adapter.Chat(ctx, chatReq)
 	if err != nil {
-		var aiErr *ai.AIError
-		if errors.As(err, &aiErr) {
-			_ = hzCtx.Error(aiErr)
-		} else {
-			_ = hzCtx.Error(&ai.AIError{
-				Type:       "api_error",
-				Message:    err.Error(),
-				StatusCode: http.StatusInternalServerError,
-			})
-		}
+		_ = hzCtx.Error(toAIError(err))
 		return
 	}
 
@@ -294,38 +401,18 @@ func (p *Proxy) handleChatUnary(
 	// Calculate cost
 	resp.Usage.CalculateCost(p.pricing)
 
-	// Record Prometheus Metrics
-	if p.metricsEnabled {
-		metrics.AIRequestDuration.WithLabelValues(virtualModel, modelID).Observe(durationSecs)
-		metrics.AIInputTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.PromptTokens))
-		if resp.Usage.PromptTokensDetails != nil && resp.Usage.PromptTokensDetails.CachedTokens > 0 {
-			metrics.AIInputCachedTokens.WithLabelValues(virtualModel, modelID).
-				Add(float64(resp.Usage.PromptTokensDetails.CachedTokens))
-		}
-		metrics.AIOutputTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.CompletionTokens))
-		if resp.Usage.CompletionTokensDetails != nil && resp.Usage.CompletionTokensDetails.ReasoningTokens > 0 {
-			metrics.AIOutputReasoningTokens.WithLabelValues(virtualModel, modelID).
-				Add(float64(resp.Usage.CompletionTokensDetails.ReasoningTokens))
-		}
-		metrics.AITotalTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.TotalTokens))
-		metrics.AIRequestCost.WithLabelValues(virtualModel, modelID).Add(resp.Usage.InputCost + resp.Usage.OutputCost)
-	}
+	p.recordUsage(ctx, hzCtx, virtualModel, modelID, durationSecs, resp.Usage)
 
-	// Set access log variables in context
-	hzCtx.Set(variable.InputTokens, resp.Usage.PromptTokens)
-	hzCtx.Set(variable.OutputTokens, resp.Usage.CompletionTokens)
-	if resp.Usage.PromptTokensDetails != nil {
-		hzCtx.Set(variable.InputCachedTokens, resp.Usage.PromptTokensDetails.CachedTokens)
-	} else {
-		hzCtx.Set(variable.InputCachedTokens, 0)
+	for _, filter := range ai.ResponseFilters(hzCtx) {
+		if err := filter.FilterChatResponse(ctx, resp); err != nil {
+			_ = hzCtx.Error(&ai.ResponseFilterError{Err: toAIError(err)})
+			return
+		}
 	}
-	hzCtx.Set(variable.TotalTokens, resp.Usage.TotalTokens)
-	hzCtx.Set(variable.InputCost, resp.Usage.InputCost)
-	hzCtx.Set(variable.OutputCost, resp.Usage.OutputCost)
-	hzCtx.Set(variable.TotalCost, resp.Usage.InputCost+resp.Usage.OutputCost)
 
 	// Mask model name in response
 	resp.Model = virtualModel
+	hzCtx.Set(ai.ContextKeyChatResponse, resp)
 
 	// Translate canonical response to client format
 	clientResp, err := clientAdapter.ToClientChatResponse(resp)
@@ -341,6 +428,110 @@ func (p *Proxy) handleChatUnary(
 	hzCtx.JSON(http.StatusOK, clientResp)
 }
 
+// recordUsage records the metrics and the access log variables of the usage, and notifies the usage
+// observers.
+func (p *Proxy) recordUsage(
+	ctx context.Context,
+	hzCtx *app.RequestContext,
+	virtualModel string,
+	modelID string,
+	durationSecs float64,
+	usage ai.Usage,
+) {
+	// The first byte of a unary response is the whole response
+	p.stats.ObserveTTFB(time.Duration(durationSecs * float64(time.Second)))
+
+	// Record Prometheus Metrics
+	if p.metricsEnabled {
+		metrics.AIRequestDuration.WithLabelValues(virtualModel, modelID).Observe(durationSecs)
+		metrics.AIInputTokens.WithLabelValues(virtualModel, modelID).Add(float64(usage.PromptTokens))
+		if usage.PromptTokensDetails != nil && usage.PromptTokensDetails.CachedTokens > 0 {
+			metrics.AIInputCachedTokens.WithLabelValues(virtualModel, modelID).
+				Add(float64(usage.PromptTokensDetails.CachedTokens))
+		}
+		metrics.AIOutputTokens.WithLabelValues(virtualModel, modelID).Add(float64(usage.CompletionTokens))
+		if usage.CompletionTokensDetails != nil && usage.CompletionTokensDetails.ReasoningTokens > 0 {
+			metrics.AIOutputReasoningTokens.WithLabelValues(virtualModel, modelID).
+				Add(float64(usage.CompletionTokensDetails.ReasoningTokens))
+		}
+		metrics.AITotalTokens.WithLabelValues(virtualModel, modelID).Add(float64(usage.TotalTokens))
+		metrics.AIRequestCost.WithLabelValues(virtualModel, modelID).Add(usage.InputCost + usage.OutputCost)
+	}
+
+	// Set access log variables in context
+	hzCtx.Set(variable.InputTokens, usage.PromptTokens)
+	hzCtx.Set(variable.OutputTokens, usage.CompletionTokens)
+	if usage.PromptTokensDetails != nil {
+		hzCtx.Set(variable.InputCachedTokens, usage.PromptTokensDetails.CachedTokens)
+	} else {
+		hzCtx.Set(variable.InputCachedTokens, 0)
+	}
+	hzCtx.Set(variable.TotalTokens, usage.TotalTokens)
+	hzCtx.Set(variable.InputCost, usage.InputCost)
+	hzCtx.Set(variable.OutputCost, usage.OutputCost)
+	hzCtx.Set(variable.TotalCost, usage.InputCost+usage.OutputCost)
+
+	metadata := usageMetadata(hzCtx, virtualModel, modelID)
+	metadata.Latency = time.Duration(durationSecs * float64(time.Second))
+	notifyUsage(ctx, hzCtx, metadata, usage)
+}
+
+// usageMetadata returns the metadata of the usage of the request.
+func usageMetadata(hzCtx *app.RequestContext, virtualModel string, modelID string) ai.UsageMetadata {
+	family := ai.FamilyChat
+	if val, found := hzCtx.Get(ai.ContextKeyAIFamily); found {
+		if s, ok := val.(string); ok && s != "" {
+			family = s
+		}
+	}
+
+	return ai.UsageMetadata{
+		Model:    virtualModel,
+		UserID:   variable.GetString(variable.AuthUser, hzCtx),
+		Consumer: variable.GetString(variable.AuthConsumer, hzCtx),
+		RouteID:  variable.GetString(variable.RouteID, hzCtx),
+		TraceID:  variable.GetString(variable.TraceID, hzCtx),
+		Provider: modelID,
+		Family:   family,
+	}
+}
+
+// toAIError converts an error of an upstream request into an AIError, timeouts become gateway
+// timeouts so that they can be told apart from the errors of the provider.
+func toAIError(err error) *ai.AIError {
+	var aiErr *ai.AIError
+	if errors.As(err, &aiErr) {
+		return aiErr
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, hzerrors.ErrTimeout) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return &ai.AIError{
+			Type:       "timeout_error",
+			Message:    err.Error(),
+			StatusCode: http.StatusGatewayTimeout,
+		}
+	}
+
+	return &ai.AIError{
+		Type:       "api_error",
+		Message:    err.Error(),
+		StatusCode: http.StatusInternalServerError,
+	}
+}
+
+// notifyUsage notifies the usage observers which middlewares registered for the request, e.g. ai_quota,
+// and the global usage observers, e.g. usage ledgers.
+func notifyUsage(ctx context.Context, hzCtx *app.RequestContext, metadata ai.UsageMetadata, usage ai.Usage) {
+	for _, observer := range ai.UsageObservers(hzCtx) {
+		observer.OnUsage(ctx, metadata, usage)
+	}
+	for _, observer := range ai.GlobalUsageObservers() {
+		observer.OnUsage(ctx, metadata, usage)
+	}
+}
+
 type streamUsageObserver struct {
 	onUsage func(metadata ai.UsageMetadata, usage ai.Usage)
 }
@@ -362,23 +553,12 @@ func (p *Proxy) handleChatStream(
 
 	stream, err := adapter.StreamChat(ctx, chatReq)
 	if err != nil {
-		var aiErr *ai.AIError
-		if errors.As(err, &aiErr) {
-			_ = hzCtx.Error(aiErr)
-		} else {
-			_ = hzCtx.Error(&ai.AIError{
-				Type:       "api_error",
-				Message:    err.Error(),
-				StatusCode: http.StatusInternalServerError,
-			})
-		}
+		_ = hzCtx.Error(toAIError(err))
 		return
 	}
 
-	metadata := ai.UsageMetadata{
-		Model:    virtualModel,
-		Provider: modelID,
-	}
+	metadata := usageMetadata(hzCtx, virtualModel, modelID)
+	metadata.Stream = true
 
 	var totalCompletionTokens int
 	var usageReceived bool
@@ -422,10 +602,20 @@ func (p *Proxy) handleChatStream(
 			hzCtx.Set(variable.InputCost, u.InputCost)
 			hzCtx.Set(variable.OutputCost, u.OutputCost)
 			hzCtx.Set(variable.TotalCost, u.InputCost+u.OutputCost)
+
+			metadata.Latency = timecache.Now().Sub(startTime)
+			notifyUsage(ctx, hzCtx, metadata, u)
 		},
 	}
 
 	observedStream := ai.NewObservedStream(stream, observer, metadata)
+	observedStream = ai.NewFilteredStream(ctx, observedStream, ai.ResponseFilters(hzCtx))
+
+	var recorder *ai.ChatStreamRecorder
+	if hzCtx.GetBool(ai.ContextKeyRecordChatStream) {
+		recorder = ai.NewChatStreamRecorder(observedStream)
+		observedStream = recorder
+	}
 	finalStream := clientAdapter.StreamConverter(observedStream)
 	defer finalStream.Close()
 
@@ -450,6 +640,7 @@ func (p *Proxy) handleChatStream(
 		if n > 0 {
 			firstByteOnce.Do(func() {
 				firstByteTime = timecache.Now()
+				p.stats.ObserveTTFB(firstByteTime.Sub(startTime))
 				ttfb := firstByteTime.Sub(startTime).Seconds()
 				if p.metricsEnabled {
 					metrics.AIRequestTTFB.WithLabelValues(virtualModel, modelID).Observe(ttfb)
@@ -468,15 +659,11 @@ func (p *Proxy) handleChatStream(
 				break
 			}
 
-			// Mid-stream error
-			var sseErrBytes []byte
+			p.recordFailure(err, timecache.Now().Sub(startTime))
+
+			// Mid-stream error, the client adapter encodes it as the error event of its protocol
 			var aiErr *ai.AIError
-			if errors.As(err, &aiErr) {
-				formattedErr, _ := clientAdapter.ToClientError(aiErr)
-				if errJSON, errMar := sonic.Marshal(formattedErr); errMar == nil {
-					sseErrBytes = fmt.Appendf(nil, "data: %s\n\n", errJSON)
-				}
-			} else {
+			if !errors.As(err, &aiErr) {
 				// SECURITY: Log full error details internally, return generic error to client
 				routeID := variable.GetString(variable.RouteID, hzCtx)
 				slog.ErrorContext(ctx, "stream mid-error intercepted",
@@ -486,26 +673,27 @@ func (p *Proxy) handleChatStream(
 					"error", err.Error(),
 				)
 
-				genericErr := &ai.AIError{
+				aiErr = &ai.AIError{
 					Type:       "internal_error",
 					Message:    "Internal server error",
 					StatusCode: http.StatusBadGateway,
 				}
-				formattedErr, _ := clientAdapter.ToClientError(genericErr)
-				if errJSON, errMar := sonic.Marshal(formattedErr); errMar == nil {
-					sseErrBytes = fmt.Appendf(nil, "data: %s\n\n", errJSON)
-				}
 			}
-			if len(sseErrBytes) == 0 {
-				sseErrBytes = []byte("data: {\"error\":{\"message\":\"internal error\"}}\n\n")
+			if sseErrBytes, encodeErr := clientAdapter.ToClientStreamError(aiErr); encodeErr == nil {
+				_, _ = hzCtx.Write(sseErrBytes)
 			}
-			_, _ = hzCtx.Write(sseErrBytes)
-			_, _ = hzCtx.Write([]byte("data: [DONE]\n\n"))
 			_ = hzCtx.Flush()
 			return
 		}
 	}
 
+	if recorder != nil {
+		if resp, ok := recorder.Response(); ok {
+			resp.Model = virtualModel
+			hzCtx.Set(ai.ContextKeyChatResponse, resp)
+		}
+	}
+
 	endTime := timecache.Now()
 	durationSecs := endTime.Sub(startTime).Seconds()
 	if p.metricsEnabled {
@@ -516,6 +704,7 @@ func (p *Proxy) handleChatStream(
 		generationDuration := endTime.Sub(firstByteTime).Seconds()
 		if generationDuration > 0 {
 			tps := float64(totalCompletionTokens) / generationDuration
+			p.stats.ObserveTPS(tps)
 			if p.metricsEnabled {
 				metrics.AIGenerationTPS.WithLabelValues(virtualModel, modelID).Observe(tps)
 			}
@@ -536,16 +725,7 @@ func (p *Proxy) handleResponses(
 
 	resp, err := adapter.Responses(ctx, req)
 	if err != nil {
-		var aiErr *ai.AIError
-		if errors.As(err, &aiErr) {
-			_ = hzCtx.Error(aiErr)
-		} else {
-			_ = hzCtx.Error(&ai.AIError{
-				Type:       "api_error",
-				Message:    err.Error(),
-				StatusCode: http.StatusInternalServerError,
-			})
-		}
+		_ = hzCtx.Error(toAIError(err))
 		return
 	}
 
@@ -555,35 +735,7 @@ func (p *Proxy) handleResponses(
 	// Calculate cost
 	resp.Usage.CalculateCost(p.pricing)
 
-	// Record Prometheus Metrics
-	if p.metricsEnabled {
-		metrics.AIRequestDuration.WithLabelValues(virtualModel, modelID).Observe(durationSecs)
-		metrics.AIInputTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.PromptTokens))
-		if resp.Usage.PromptTokensDetails != nil && resp.Usage.PromptTokensDetails.CachedTokens > 0 {
-			metrics.AIInputCachedTokens.WithLabelValues(virtualModel, modelID).
-				Add(float64(resp.Usage.PromptTokensDetails.CachedTokens))
-		}
-		metrics.AIOutputTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.CompletionTokens))
-		if resp.Usage.CompletionTokensDetails != nil && resp.Usage.CompletionTokensDetails.ReasoningTokens > 0 {
-			metrics.AIOutputReasoningTokens.WithLabelValues(virtualModel, modelID).
-				Add(float64(resp.Usage.CompletionTokensDetails.ReasoningTokens))
-		}
-		metrics.AITotalTokens.WithLabelValues(virtualModel, modelID).Add(float64(resp.Usage.TotalTokens))
-		metrics.AIRequestCost.WithLabelValues(virtualModel, modelID).Add(resp.Usage.InputCost + resp.Usage.OutputCost)
-	}
-
-	// Set access log variables in context
-	hzCtx.Set(variable.InputTokens, resp.Usage.PromptTokens)
-	hzCtx.Set(variable.OutputTokens, resp.Usage.CompletionTokens)
-	if resp.Usage.PromptTokensDetails != nil {
-		hzCtx.Set(variable.InputCachedTokens, resp.Usage.PromptTokensDetails.CachedTokens)
-	} else {
-		hzCtx.Set(variable.InputCachedTokens, 0)
-	}
-	hzCtx.Set(variable.TotalTokens, resp.Usage.TotalTokens)
-	hzCtx.Set(variable.InputCost, resp.Usage.InputCost)
-	hzCtx.Set(variable.OutputCost, resp.Usage.OutputCost)
-	hzCtx.Set(variable.TotalCost, resp.Usage.InputCost+resp.Usage.OutputCost)
+	p.recordUsage(ctx, hzCtx, virtualModel, modelID, durationSecs, resp.Usage)
 
 	// Mask model name in response
 	resp.Model = virtualModel
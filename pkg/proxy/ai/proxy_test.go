@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/cloudwego/hertz/pkg/app"
@@ -18,6 +20,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/nite-coder/bifrost/pkg/ai"
+	"github.com/nite-coder/bifrost/pkg/balancer/aibalancer"
 	"github.com/nite-coder/bifrost/pkg/config"
 	"github.com/nite-coder/bifrost/pkg/target"
 	"github.com/nite-coder/bifrost/pkg/telemetry/metrics"
@@ -105,6 +108,15 @@ func (m *MockClientAdapter) StreamConverter(stream io.ReadCloser) io.ReadCloser
 	return stream
 }
 
+func (m *MockClientAdapter) ToClientStreamError(err *ai.AIError) ([]byte, error) {
+	resp, _ := m.ToClientError(err)
+	data, marshalErr := sonic.Marshal(resp)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return fmt.Appendf(nil, "data: %s\n\ndata: [DONE]\n\n", data), nil
+}
+
 func (m *MockClientAdapter) ToClientError(err *ai.AIError) (any, error) {
 	if m.toClientErrorFunc != nil {
 		return m.toClientErrorFunc(err)
@@ -112,6 +124,15 @@ func (m *MockClientAdapter) ToClientError(err *ai.AIError) (any, error) {
 	return map[string]any{"error": err.Message}, nil
 }
 
+// usageRecorder records the usage metadata it is notified of.
+type usageRecorder struct {
+	metadata []ai.UsageMetadata
+}
+
+func (r *usageRecorder) OnUsage(_ context.Context, metadata ai.UsageMetadata, _ ai.Usage) {
+	r.metadata = append(r.metadata, metadata)
+}
+
 func setupMockAdapter(t *testing.T) {
 	t.Helper()
 	mockLLMOnce.Do(func() {
@@ -177,6 +198,11 @@ func TestAIProxy_ServeHTTP_UnarySuccess(t *testing.T) {
 	hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
 	hzCtx.Set(ai.ContextKeyVirtualModelName, "gpt-4o")
 	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o"})
+	hzCtx.Set(variable.AuthConsumer, "billing-app")
+
+	recorder := &usageRecorder{}
+	ai.RegisterUsageObserver(recorder)
+	defer ai.UnregisterUsageObserver(recorder)
 
 	// Reset metrics before recording
 	metrics.AIInputTokens.Reset()
@@ -197,6 +223,13 @@ func TestAIProxy_ServeHTTP_UnarySuccess(t *testing.T) {
 	assert.Equal(t, 20, hzCtx.GetInt(variable.OutputTokens))
 	assert.Equal(t, 0, hzCtx.GetInt(variable.InputCachedTokens))
 	assert.Equal(t, 30, hzCtx.GetInt(variable.TotalTokens))
+
+	require.Len(t, recorder.metadata, 1)
+	assert.Equal(t, "gpt-4o", recorder.metadata[0].Model)
+	assert.Equal(t, "p1/gpt-4", recorder.metadata[0].Provider)
+	assert.Equal(t, "billing-app", recorder.metadata[0].Consumer)
+	assert.Equal(t, ai.FamilyChat, recorder.metadata[0].Family)
+	assert.False(t, recorder.metadata[0].Stream)
 }
 
 func TestAIProxy_ServeHTTP_UnaryError(t *testing.T) {
@@ -252,6 +285,169 @@ func TestAIProxy_ServeHTTP_UnaryError(t *testing.T) {
 	assert.Equal(t, expectedErr, hzCtx.Errors[0].Err)
 }
 
+type rejectFilter struct {
+	err error
+}
+
+func (f *rejectFilter) FilterChatResponse(_ context.Context, _ *ai.ChatResponse) error {
+	return f.err
+}
+
+func (f *rejectFilter) FilterChatChunk(_ context.Context, _ *ai.StreamChunk) error {
+	return f.err
+}
+
+func TestAIProxy_ServeHTTP_SkipRateLimited(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	setupMockAdapter(t)
+
+	aiOpts := &config.AIOptions{
+		Providers: map[string]*config.AIProvider{
+			"p1": {Handler: "mock", BaseURL: "http://localhost", APIKey: "key"},
+		},
+	}
+
+	newProxy := func(t *testing.T, skip bool) (*Proxy, *target.State) {
+		t.Helper()
+		state := target.NewState(0, 0)
+		p, err := NewProxy(ProxyOptions{
+			ID:              "id1",
+			Target:          "p1/gpt-4-rate-limited",
+			AIOptions:       aiOpts,
+			SkipRateLimited: skip,
+			Endpoint:        &target.Endpoint{Address: "p1/gpt-4-rate-limited", Weight: 1, State: state},
+		})
+		require.NoError(t, err)
+		return p, state
+	}
+
+	serve := func(p *Proxy) *app.RequestContext {
+		hzCtx := app.NewContext(0)
+		hzCtx.Set(ai.ContextKeyClientAdapter, &MockClientAdapter{})
+		hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
+		hzCtx.Set(ai.ContextKeyVirtualModelName, "gpt-4o")
+		hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o"})
+		p.ServeHTTP(context.Background(), hzCtx)
+		return hzCtx
+	}
+
+	mockLL.chatFunc = func(_ context.Context, _ *ai.ChatRequest) (*ai.ChatResponse, error) {
+		return nil, &ai.AIError{
+			Type:       "rate_limit_error",
+			Message:    "rate limit reached",
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: time.Minute,
+		}
+	}
+
+	p, state := newProxy(t, false)
+	serve(p)
+	assert.True(t, state.IsAvailable())
+
+	p, state = newProxy(t, true)
+	hzCtx := serve(p)
+	assert.Len(t, hzCtx.Errors, 1)
+	assert.False(t, state.IsAvailable())
+	assert.Equal(t, int64(0), ai.StatsOf("p1/gpt-4-rate-limited").InFlight())
+}
+
+func TestAIProxy_ServeHTTP_TargetFailure(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	setupMockAdapter(t)
+
+	aiOpts := &config.AIOptions{
+		Providers: map[string]*config.AIProvider{
+			"p1": {Handler: "mock", BaseURL: "http://localhost", APIKey: "key"},
+		},
+	}
+
+	mockLL.chatFunc = func(_ context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+		if req.Model == "gpt-4-failing" {
+			return nil, &ai.AIError{Type: "api_error", Message: "internal error", StatusCode: http.StatusInternalServerError}
+		}
+		return &ai.ChatResponse{ID: "1"}, nil
+	}
+
+	states := map[string]*target.State{}
+	for _, address := range []string{"p1/gpt-4-failing", "p1/gpt-4-healthy"} {
+		states[address] = target.NewState(1, time.Minute)
+		p, err := NewProxy(ProxyOptions{
+			ID:        "id1",
+			Target:    address,
+			AIOptions: aiOpts,
+			Endpoint:  &target.Endpoint{Address: address, Weight: 1, State: states[address]},
+		})
+		require.NoError(t, err)
+
+		hzCtx := app.NewContext(0)
+		hzCtx.Set(ai.ContextKeyClientAdapter, &MockClientAdapter{})
+		hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
+		hzCtx.Set(ai.ContextKeyVirtualModelName, "gpt-4o")
+		hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o"})
+		p.ServeHTTP(context.Background(), hzCtx)
+	}
+
+	// the failure counts against the endpoint like a 5xx response of the HTTP proxy
+	assert.False(t, states["p1/gpt-4-failing"].IsAvailable())
+	assert.True(t, states["p1/gpt-4-healthy"].IsAvailable())
+
+	// the failed request is recorded as a slow response, so the latency balancer prefers the
+	// healthy target even when the failing endpoint is available again
+	assert.GreaterOrEqual(t, ai.StatsOf("p1/gpt-4-failing").TTFB(), ai.FailurePenalty)
+	b := aibalancer.NewLowestLatency([]*target.Endpoint{
+		{Address: "p1/gpt-4-failing", Weight: 1, State: target.NewState(0, 0)},
+		{Address: "p1/gpt-4-healthy", Weight: 1, State: target.NewState(0, 0)},
+	})
+	ep, err := b.Select(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "p1/gpt-4-healthy", ep.Address)
+}
+
+func TestAIProxy_ServeHTTP_ResponseFilter(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	setupMockAdapter(t)
+
+	p, err := NewProxy(ProxyOptions{
+		ID:     "id1",
+		Target: "p1/gpt-4",
+		AIOptions: &config.AIOptions{
+			Providers: map[string]*config.AIProvider{
+				"p1": {Handler: "mock", BaseURL: "http://localhost", APIKey: "key"},
+			},
+		},
+		Endpoint: &target.Endpoint{
+			Address: "p1/gpt-4",
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+
+	mockLL.chatFunc = func(_ context.Context, _ *ai.ChatRequest) (*ai.ChatResponse, error) {
+		return &ai.ChatResponse{Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "hi"}}}}, nil
+	}
+
+	rejected := &ai.AIError{Type: "invalid_request_error", Message: "rejected", StatusCode: http.StatusBadRequest}
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyClientAdapter, &MockClientAdapter{})
+	hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
+	hzCtx.Set(ai.ContextKeyVirtualModelName, "gpt-4o")
+	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o"})
+	ai.AddResponseFilter(hzCtx, &rejectFilter{err: rejected})
+
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	require.Len(t, hzCtx.Errors, 1)
+	assert.ErrorIs(t, hzCtx.Errors[0].Err, rejected)
+	assert.Empty(t, ai.ClassifyError(hzCtx.Errors[0].Err))
+	_, found := hzCtx.Get(ai.ContextKeyChatResponse)
+	assert.False(t, found)
+}
+
 func TestAIProxy_ServeHTTP_StreamSuccess(t *testing.T) {
 	mockLLMMu.Lock()
 	defer mockLLMMu.Unlock()
@@ -308,6 +504,60 @@ func TestAIProxy_ServeHTTP_StreamSuccess(t *testing.T) {
 	assert.Equal(t, 34, hzCtx.GetInt(variable.TotalTokens))
 }
 
+func TestAIProxy_ServeHTTP_StreamRecord(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	setupMockAdapter(t)
+
+	aiOpts := &config.AIOptions{
+		Providers: map[string]*config.AIProvider{
+			"p1": {
+				Handler: "mock",
+				BaseURL: "http://localhost",
+				APIKey:  "key",
+			},
+		},
+	}
+
+	p, err := NewProxy(ProxyOptions{
+		ID:        "id1",
+		Target:    "p1/gpt-4",
+		AIOptions: aiOpts,
+		Endpoint: &target.Endpoint{
+			Address: "p1/gpt-4",
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+
+	canonicalChunks := "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"hello\"},\"finish_reason\":\"stop\"}]}\n\ndata: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":22,\"total_tokens\":34}}\n\ndata: [DONE]\n\n"
+	mockLL.streamChatFunc = func(_ context.Context, _ *ai.ChatRequest) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(canonicalChunks)), nil
+	}
+
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyClientAdapter, &MockClientAdapter{})
+	hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
+	hzCtx.Set(ai.ContextKeyVirtualModelName, "gpt-4o")
+	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "gpt-4o", Stream: true})
+	hzCtx.Set(ai.ContextKeyRecordChatStream, true)
+
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	assert.Equal(t, canonicalChunks, string(hzCtx.Response.Body()))
+
+	val, found := hzCtx.Get(ai.ContextKeyChatResponse)
+	require.True(t, found)
+	resp, ok := val.(*ai.ChatResponse)
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "hello", resp.Choices[0].Message.Content)
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Equal(t, 34, resp.Usage.TotalTokens)
+}
+
 type errorReader struct {
 	data []byte
 	err  error
@@ -387,6 +637,59 @@ func TestAIProxy_ServeHTTP_StreamMidError(t *testing.T) {
 	assert.NotContains(t, bodyStr, "network failure mid-stream")
 }
 
+func TestAIProxy_ServeHTTP_MidStreamError_ClientProtocol(t *testing.T) {
+	mockLLMMu.Lock()
+	defer mockLLMMu.Unlock()
+	setupMockAdapter(t)
+
+	aiOpts := &config.AIOptions{
+		Providers: map[string]*config.AIProvider{
+			"p1": {
+				Handler: "mock",
+				BaseURL: "http://localhost",
+				APIKey:  "key",
+			},
+		},
+	}
+
+	p, err := NewProxy(ProxyOptions{
+		ID:        "id1",
+		Target:    "p1/gpt-4",
+		AIOptions: aiOpts,
+		Endpoint: &target.Endpoint{
+			Address: "p1/gpt-4",
+			Weight:  1,
+			State:   target.NewState(0, 0),
+		},
+	})
+	require.NoError(t, err)
+
+	mockLL.streamChatFunc = func(_ context.Context, _ *ai.ChatRequest) (io.ReadCloser, error) {
+		return &errorReader{
+			data: []byte(
+				"data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\n",
+			),
+			err: &ai.AIError{Type: "upstream_error", Message: "overloaded", StatusCode: http.StatusServiceUnavailable},
+		}, nil
+	}
+
+	// the error is written as the error event of the client protocol instead of an OpenAI event
+	hzCtx := app.NewContext(0)
+	hzCtx.Set(ai.ContextKeyClientAdapter, ai.NewAnthropicClientAdapter())
+	hzCtx.Set(ai.ContextKeyAIFamily, ai.FamilyChat)
+	hzCtx.Set(ai.ContextKeyVirtualModelName, "claude")
+	hzCtx.Set(ai.ContextKeyChatRequest, &ai.ChatRequest{Model: "claude", Stream: true})
+
+	p.ServeHTTP(context.Background(), hzCtx)
+
+	bodyStr := string(hzCtx.Response.Body())
+	assert.Contains(t, bodyStr, "event: content_block_delta\n")
+	assert.True(t, strings.HasSuffix(bodyStr,
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"overloaded\"}}\n\n",
+	))
+	assert.NotContains(t, bodyStr, "[DONE]")
+}
+
 func TestAIProxy_ServeHTTP_InvalidTarget(t *testing.T) {
 	mockLLMMu.Lock()
 	defer mockLLMMu.Unlock()
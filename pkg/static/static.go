@@ -0,0 +1,418 @@
+// Package static serves the files of a directory for the static service type.
+package static
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+const sniffLen = 512
+
+// encodings are the precompressed variants of a file in the order of preference.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{name: "br", ext: ".br"},
+	{name: "gzip", ext: ".gz"},
+}
+
+// Server serves the files of a directory.
+type Server struct {
+	options *config.StaticOptions
+	root    *os.Root
+	index   []string
+}
+
+// New creates a new Server for the root directory of the options.
+func New(options config.StaticOptions) (*Server, error) {
+	if len(options.Root) == 0 {
+		return nil, errors.New("root can't be empty for static service")
+	}
+
+	// paths can't escape the root, neither with '..' nor with symlinks
+	root, err := os.OpenRoot(options.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open root '%s' of static service: %w", options.Root, err)
+	}
+
+	index := options.Index
+	if len(index) == 0 {
+		index = []string{"index.html"}
+	}
+
+	return &Server{
+		options: &options,
+		root:    root,
+		index:   index,
+	}, nil
+}
+
+// Close closes the root directory.
+func (s *Server) Close() error {
+	return s.root.Close()
+}
+
+func (s *Server) ServeHTTP(_ context.Context, c *app.RequestContext) {
+	if !c.Request.Header.IsGet() && !c.Request.Header.IsHead() {
+		c.Response.Header.Set("Allow", "GET, HEAD")
+		c.SetStatusCode(http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestPath := string(c.Request.URI().Path())
+	urlPath := path.Clean("/" + requestPath)
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" {
+		name = "."
+	}
+
+	if isHidden(name) {
+		s.notFound(c, urlPath)
+		return
+	}
+
+	info, err := s.root.Stat(name)
+	if err != nil {
+		s.notFound(c, urlPath)
+		return
+	}
+
+	if !info.IsDir() {
+		s.serveFile(c, name, info)
+		return
+	}
+
+	// relative links of an index file are resolved from the directory
+	if !strings.HasSuffix(requestPath, "/") {
+		location := path.Base(urlPath) + "/"
+		if query := c.Request.URI().QueryString(); len(query) > 0 {
+			location += "?" + string(query)
+		}
+		c.Response.Header.Set("Location", location)
+		c.SetStatusCode(http.StatusMovedPermanently)
+		return
+	}
+
+	if s.serveIndex(c, name) {
+		return
+	}
+
+	if s.options.DirectoryListing {
+		s.listDirectory(c, name, urlPath)
+		return
+	}
+
+	s.notFound(c, urlPath)
+}
+
+func (s *Server) serveIndex(c *app.RequestContext, dir string) bool {
+	for _, index := range s.index {
+		name := path.Join(dir, index)
+		info, err := s.root.Stat(name)
+		if err == nil && !info.IsDir() {
+			s.serveFile(c, name, info)
+			return true
+		}
+	}
+	return false
+}
+
+// notFound serves the index file of the root for SPA routes. Paths with an extension are
+// assets, so a missing asset is still a 404.
+func (s *Server) notFound(c *app.RequestContext, urlPath string) {
+	if s.options.SPA && path.Ext(urlPath) == "" && s.serveIndex(c, ".") {
+		return
+	}
+	c.SetStatusCode(http.StatusNotFound)
+}
+
+func (s *Server) serveFile(c *app.RequestContext, name string, info fs.FileInfo) {
+	contentType := mime.TypeByExtension(path.Ext(name))
+
+	if s.options.Precompressed {
+		c.Response.Header.Add("Vary", "Accept-Encoding")
+		for _, encoding := range encodings {
+			if !acceptsEncoding(c, encoding.name) {
+				continue
+			}
+			encodedInfo, err := s.root.Stat(name + encoding.ext)
+			if err != nil || encodedInfo.IsDir() {
+				continue
+			}
+			c.Response.Header.Set("Content-Encoding", encoding.name)
+			name += encoding.ext
+			info = encodedInfo
+			break
+		}
+	}
+
+	modTime := info.ModTime().UTC().Truncate(time.Second)
+	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size())
+	c.Response.Header.Set("ETag", etag)
+	c.Response.Header.Set("Last-Modified", modTime.Format(http.TimeFormat))
+	c.Response.Header.Set("Accept-Ranges", "bytes")
+
+	if status := checkPreconditions(c, etag, modTime); status != 0 {
+		if status == http.StatusNotModified {
+			c.Response.Header.Del("Content-Encoding")
+		}
+		c.SetStatusCode(status)
+		return
+	}
+
+	file, err := s.root.Open(name)
+	if err != nil {
+		c.Response.Header.Del("Content-Encoding")
+		c.SetStatusCode(http.StatusNotFound)
+		return
+	}
+
+	if contentType == "" {
+		buf := make([]byte, sniffLen)
+		n, _ := io.ReadFull(file, buf)
+		contentType = http.DetectContentType(buf[:n])
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			_ = file.Close()
+			c.SetStatusCode(http.StatusInternalServerError)
+			return
+		}
+	}
+	c.Response.Header.SetContentType(contentType)
+
+	size := info.Size()
+	rangeHeader := string(c.Request.Header.Peek("Range"))
+	if rangeHeader == "" || !checkIfRange(c, etag, modTime) {
+		c.SetStatusCode(http.StatusOK)
+		c.Response.SetBodyStream(file, int(size))
+		return
+	}
+
+	start, end, valid, satisfiable := parseRange(rangeHeader, size)
+	switch {
+	case !valid:
+		// multiple or malformed ranges are answered with the whole file
+		c.SetStatusCode(http.StatusOK)
+		c.Response.SetBodyStream(file, int(size))
+	case !satisfiable:
+		_ = file.Close()
+		c.Response.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		c.SetStatusCode(http.StatusRequestedRangeNotSatisfiable)
+	default:
+		_, err = file.Seek(start, io.SeekStart)
+		if err != nil {
+			_ = file.Close()
+			c.SetStatusCode(http.StatusInternalServerError)
+			return
+		}
+		length := end - start + 1
+		c.Response.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		c.SetStatusCode(http.StatusPartialContent)
+		c.Response.SetBodyStream(&sectionReader{Reader: io.LimitReader(file, length), Closer: file}, int(length))
+	}
+}
+
+func (s *Server) listDirectory(c *app.RequestContext, name string, urlPath string) {
+	dir, err := s.root.Open(name)
+	if err != nil {
+		c.SetStatusCode(http.StatusNotFound)
+		return
+	}
+	defer dir.Close()
+
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		c.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	if urlPath != "/" {
+		urlPath += "/"
+	}
+	title := html.EscapeString(urlPath)
+
+	var sb strings.Builder
+	sb.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<title>Index of " + title + "</title>\n")
+	sb.WriteString("<h1>Index of " + title + "</h1>\n<ul>\n")
+	if urlPath != "/" {
+		sb.WriteString("<li><a href=\"../\">../</a></li>\n")
+	}
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if isHidden(entryName) {
+			continue
+		}
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		href := (&url.URL{Path: entryName}).EscapedPath()
+		sb.WriteString("<li><a href=\"" + html.EscapeString(href) + "\">" + html.EscapeString(entryName) + "</a></li>\n")
+	}
+	sb.WriteString("</ul>\n")
+
+	c.Response.Header.SetContentType("text/html; charset=utf-8")
+	c.SetStatusCode(http.StatusOK)
+	c.Response.SetBodyString(sb.String())
+}
+
+// checkPreconditions evaluates the conditional request headers in the order of RFC 9110,
+// it returns 0 when the file should be served.
+func checkPreconditions(c *app.RequestContext, etag string, modTime time.Time) int {
+	if ifMatch := string(c.Request.Header.Peek("If-Match")); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseTime(c, "If-Unmodified-Since"); ok && modTime.After(since) {
+		return http.StatusPreconditionFailed
+	}
+
+	if ifNoneMatch := string(c.Request.Header.Peek("If-None-Match")); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			return http.StatusNotModified
+		}
+	} else if since, ok := parseTime(c, "If-Modified-Since"); ok && !modTime.After(since) {
+		return http.StatusNotModified
+	}
+
+	return 0
+}
+
+// checkIfRange returns whether the range of the request applies to the current file.
+func checkIfRange(c *app.RequestContext, etag string, modTime time.Time) bool {
+	ifRange := string(c.Request.Header.Peek("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return matchETag(ifRange, etag, false)
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && t.Equal(modTime)
+}
+
+// matchETag matches an etag against a list of etags, weak comparison ignores the `W/` prefix.
+func matchETag(list string, etag string, weak bool) bool {
+	for candidate := range strings.SplitSeq(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTime(c *app.RequestContext, header string) (time.Time, bool) {
+	val := string(c.Request.Header.Peek(header))
+	if val == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(val)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// parseRange parses a single byte range, e.g. `bytes=0-99`, `bytes=100-` or `bytes=-100`.
+func parseRange(header string, size int64) (start int64, end int64, valid bool, satisfiable bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, false
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, false
+	}
+
+	if first == "" {
+		// the suffix range is the last bytes of the file
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false, false
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, true, false
+		}
+		n = min(n, size)
+		return size - n, size - 1, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, false
+	}
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, false
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, true, false
+	}
+	return start, end, true, true
+}
+
+// acceptsEncoding returns whether the Accept-Encoding header of the request accepts the encoding.
+func acceptsEncoding(c *app.RequestContext, encoding string) bool {
+	for part := range strings.SplitSeq(string(c.Request.Header.Peek("Accept-Encoding")), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if val, err := strconv.ParseFloat(q, 64); err == nil && val == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isHidden returns whether a path has a dot file or directory, which are never served
+// except for `.well-known`.
+func isHidden(name string) bool {
+	for segment := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." && segment != ".well-known" {
+			return true
+		}
+	}
+	return false
+}
+
+type sectionReader struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,252 @@
+package static
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nite-coder/bifrost/pkg/config"
+)
+
+func newTestServer(t *testing.T, options config.StaticOptions) *Server {
+	t.Helper()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":               "<h1>home</h1>",
+		"app.js":                   "console.log('bifrost')",
+		"app.js.br":                "br",
+		"app.js.gz":                "gz",
+		"docs/index.html":          "<h1>docs</h1>",
+		"assets/logo.txt":          "0123456789",
+		"assets/data":              "plain text",
+		".env":                     "SECRET=1",
+		".well-known/security.txt": "contact",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	options.Root = root
+	s, err := New(options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func serve(s *Server, method string, uri string, headers map[string]string) *app.RequestContext {
+	c := app.NewContext(0)
+	c.Request.SetMethod(method)
+	c.Request.SetRequestURI(uri)
+	for key, val := range headers {
+		c.Request.Header.Set(key, val)
+	}
+	s.ServeHTTP(context.Background(), c)
+	return c
+}
+
+func body(t *testing.T, c *app.RequestContext) string {
+	t.Helper()
+	if !c.Response.IsBodyStream() {
+		return string(c.Response.Body())
+	}
+	b, err := io.ReadAll(c.Response.BodyStream())
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestServeFiles(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{})
+
+	c := serve(s, http.MethodGet, "/", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "text/html; charset=utf-8", string(c.Response.Header.ContentType()))
+	assert.Equal(t, "<h1>home</h1>", body(t, c))
+
+	c = serve(s, http.MethodGet, "/docs/", nil)
+	assert.Equal(t, "<h1>docs</h1>", body(t, c))
+
+	c = serve(s, http.MethodGet, "/docs?lang=en", nil)
+	assert.Equal(t, http.StatusMovedPermanently, c.Response.StatusCode())
+	assert.Equal(t, "docs/?lang=en", string(c.Response.Header.Peek("Location")))
+
+	c = serve(s, http.MethodGet, "/assets/data", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "text/plain; charset=utf-8", string(c.Response.Header.ContentType()))
+	assert.Equal(t, "plain text", body(t, c))
+
+	c = serve(s, http.MethodHead, "/app.js", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "bytes", string(c.Response.Header.Peek("Accept-Ranges")))
+
+	c = serve(s, http.MethodPost, "/app.js", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, c.Response.StatusCode())
+	assert.Equal(t, "GET, HEAD", string(c.Response.Header.Peek("Allow")))
+
+	// directory listing is disabled by default
+	c = serve(s, http.MethodGet, "/assets/", nil)
+	assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/missing", nil)
+	assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+}
+
+func TestHiddenFiles(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{})
+
+	c := serve(s, http.MethodGet, "/.env", nil)
+	assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/docs/../../../etc/passwd", nil)
+	assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/.well-known/security.txt", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "contact", body(t, c))
+}
+
+func TestSPA(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{SPA: true})
+
+	c := serve(s, http.MethodGet, "/users/42", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "<h1>home</h1>", body(t, c))
+
+	// missing assets are not routes of the app
+	c = serve(s, http.MethodGet, "/assets/missing.js", nil)
+	assert.Equal(t, http.StatusNotFound, c.Response.StatusCode())
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{})
+
+	c := serve(s, http.MethodGet, "/app.js", nil)
+	etag := string(c.Response.Header.Peek("ETag"))
+	lastModified := string(c.Response.Header.Peek("Last-Modified"))
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"If-None-Match": `"other", ` + etag})
+	assert.Equal(t, http.StatusNotModified, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"If-None-Match": "W/" + etag})
+	assert.Equal(t, http.StatusNotModified, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusNotModified, c.Response.StatusCode())
+
+	// If-None-Match takes precedence over If-Modified-Since
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": lastModified,
+	})
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"If-Match": `"other"`})
+	assert.Equal(t, http.StatusPreconditionFailed, c.Response.StatusCode())
+
+	past := time.Now().Add(-24 * time.Hour).UTC().Format(http.TimeFormat)
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"If-Unmodified-Since": past})
+	assert.Equal(t, http.StatusPreconditionFailed, c.Response.StatusCode())
+}
+
+func TestRangeRequests(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{})
+
+	tests := []struct {
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{rangeHeader: "bytes=2-5", status: http.StatusPartialContent, contentRange: "bytes 2-5/10", body: "2345"},
+		{rangeHeader: "bytes=7-", status: http.StatusPartialContent, contentRange: "bytes 7-9/10", body: "789"},
+		{rangeHeader: "bytes=-3", status: http.StatusPartialContent, contentRange: "bytes 7-9/10", body: "789"},
+		{rangeHeader: "bytes=8-100", status: http.StatusPartialContent, contentRange: "bytes 8-9/10", body: "89"},
+		{rangeHeader: "bytes=10-", status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{rangeHeader: "bytes=0-1,4-5", status: http.StatusOK, body: "0123456789"},
+		{rangeHeader: "items=0-1", status: http.StatusOK, body: "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeHeader, func(t *testing.T) {
+			c := serve(s, http.MethodGet, "/assets/logo.txt", map[string]string{"Range": tt.rangeHeader})
+			assert.Equal(t, tt.status, c.Response.StatusCode())
+			assert.Equal(t, tt.contentRange, string(c.Response.Header.Peek("Content-Range")))
+			if tt.body != "" {
+				assert.Equal(t, tt.body, body(t, c))
+			}
+		})
+	}
+
+	c := serve(s, http.MethodGet, "/assets/logo.txt", nil)
+	etag := string(c.Response.Header.Peek("ETag"))
+
+	c = serve(s, http.MethodGet, "/assets/logo.txt", map[string]string{"Range": "bytes=0-1", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, c.Response.StatusCode())
+
+	// the file changed, so the whole file is sent
+	c = serve(s, http.MethodGet, "/assets/logo.txt", map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`})
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	assert.Equal(t, "0123456789", body(t, c))
+}
+
+func TestPrecompressed(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{Precompressed: true})
+
+	c := serve(s, http.MethodGet, "/app.js", map[string]string{"Accept-Encoding": "gzip, br"})
+	assert.Equal(t, "br", string(c.Response.Header.Peek("Content-Encoding")))
+	assert.Equal(t, "Accept-Encoding", string(c.Response.Header.Peek("Vary")))
+	assert.Contains(t, string(c.Response.Header.ContentType()), "javascript")
+	assert.Equal(t, "br", body(t, c))
+
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	assert.Equal(t, "gzip", string(c.Response.Header.Peek("Content-Encoding")))
+	assert.Equal(t, "gz", body(t, c))
+
+	c = serve(s, http.MethodGet, "/app.js", nil)
+	assert.Empty(t, c.Response.Header.Peek("Content-Encoding"))
+	assert.Equal(t, "console.log('bifrost')", body(t, c))
+
+	s = newTestServer(t, config.StaticOptions{})
+	c = serve(s, http.MethodGet, "/app.js", map[string]string{"Accept-Encoding": "br"})
+	assert.Empty(t, c.Response.Header.Peek("Content-Encoding"))
+}
+
+func TestDirectoryListing(t *testing.T) {
+	s := newTestServer(t, config.StaticOptions{DirectoryListing: true, Index: []string{"default.htm"}})
+
+	c := serve(s, http.MethodGet, "/", nil)
+	assert.Equal(t, http.StatusOK, c.Response.StatusCode())
+	listing := body(t, c)
+	assert.Contains(t, listing, `<a href="assets/">assets/</a>`)
+	assert.Contains(t, listing, `<a href="app.js">app.js</a>`)
+	assert.NotContains(t, listing, ".env")
+	assert.NotContains(t, listing, `href="../"`)
+
+	c = serve(s, http.MethodGet, "/assets/", nil)
+	listing = body(t, c)
+	assert.Contains(t, listing, "Index of /assets/")
+	assert.Contains(t, listing, `<a href="../">../</a>`)
+	assert.Contains(t, listing, `<a href="logo.txt">logo.txt</a>`)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(config.StaticOptions{})
+	assert.Error(t, err)
+
+	_, err = New(config.StaticOptions{Root: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ type State struct {
 	maxFails     uint
 	failTimeout  time.Duration
 	failExpireAt time.Time
+	suspendUntil time.Time
 }
 
 // NewState creates a new State with the given max failures and fail timeout.
@@ -28,10 +29,13 @@ func NewState(maxFails uint, failTimeout time.Duration) *State {
 func (s *State) IsAvailable() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	now := timecache.Now()
+	if now.Before(s.suspendUntil) {
+		return false
+	}
 	if s.maxFails == 0 {
 		return true
 	}
-	now := timecache.Now()
 	if now.After(s.failExpireAt) {
 		return true
 	}
@@ -50,3 +54,13 @@ func (s *State) RecordFailure() {
 		s.failedCount++
 	}
 }
+
+// Suspend makes the endpoint unavailable until the given time, e.g. until the rate limit of an AI
+// provider resets. An earlier time doesn't shorten the current suspension.
+func (s *State) Suspend(until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until.After(s.suspendUntil) {
+		s.suspendUntil = until
+	}
+}
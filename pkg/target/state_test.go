@@ -30,3 +30,16 @@ func TestState_FailThenRecover(t *testing.T) {
 		return s.IsAvailable()
 	}, 200*time.Millisecond, 10*time.Millisecond, "should recover after failTimeout")
 }
+
+func TestState_Suspend(t *testing.T) {
+	s := target.NewState(0, time.Second)
+	s.Suspend(time.Now().Add(50 * time.Millisecond))
+	assert.False(t, s.IsAvailable(), "suspended endpoint is unavailable")
+
+	s.Suspend(time.Now())
+	assert.False(t, s.IsAvailable(), "an earlier time doesn't shorten the suspension")
+
+	assert.Eventually(t, func() bool {
+		return s.IsAvailable()
+	}, 200*time.Millisecond, 10*time.Millisecond, "should recover after the suspension")
+}
@@ -21,6 +21,8 @@ var (
 	AITotalTokens *prom.CounterVec
 	// AIRequestCost represents the total AI request cost in USD.
 	AIRequestCost *prom.CounterVec
+	// AIFallbacks represents the total requests which fell back from a failed target to another target.
+	AIFallbacks *prom.CounterVec
 )
 
 // InitAI initializes AI-related Prometheus metrics with custom or default buckets.
@@ -115,4 +117,13 @@ func InitAI(latencyBuckets, tpsBuckets []float64) {
 		[]string{"model", "model_id"},
 	)
 	prom.MustRegister(AIRequestCost)
+
+	AIFallbacks = prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "bifrost_ai_fallbacks_total",
+			Help: "Total requests which fell back from a failed target to another target",
+		},
+		[]string{"model", "model_id", "fallback_model_id", "error_class"},
+	)
+	prom.MustRegister(AIFallbacks)
 }
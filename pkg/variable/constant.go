@@ -127,6 +127,18 @@ const (
 	OutputCost = "$output_cost"
 	// TotalCost is the total calculated cost of the AI request.
 	TotalCost = "$total_cost"
+	// AuthUser is the identity of the authenticated user set by auth middlewares.
+	AuthUser = "$auth.user"
+	// AuthConsumer is the client application (consumer) set by auth middlewares.
+	AuthConsumer = "$auth.consumer"
+	// AuthGroups is the groups of the authenticated identity set by auth middlewares.
+	AuthGroups = "$auth.groups"
+	// AuthClaims is the key storing the claims of the authenticated identity in the context.
+	AuthClaims = "$auth.claims"
+	// CacheStatus is the result of the cache lookup set by the cache and ai_cache middlewares (HIT, MISS, STALE, REVALIDATED or BYPASS).
+	CacheStatus = "$cache.status"
+	// GuardDetections is the number of sensitive contents detected by the ai_guard middleware.
+	GuardDetections = "$guard.detections"
 	// B represents a byte unit (1).
 	B = 1
 	// KB represents a kilobyte unit (1024 bytes).
@@ -79,6 +79,11 @@ var (
 		InputCost:                   {},
 		OutputCost:                  {},
 		TotalCost:                   {},
+		AuthUser:                    {},
+		AuthConsumer:                {},
+		AuthGroups:                  {},
+		CacheStatus:                 {},
+		GuardDetections:             {},
 	}
 )
 
@@ -200,7 +205,8 @@ func IsDirective(key string) bool {
 		strings.HasPrefix(key, "$env.") ||
 		strings.HasPrefix(key, "$http.request.header.") ||
 		strings.HasPrefix(key, "$http.response.header.") ||
-		strings.HasPrefix(key, "$http.request.query.") {
+		strings.HasPrefix(key, "$http.request.query.") ||
+		strings.HasPrefix(key, "$auth.claim.") {
 		return true
 	}
 
@@ -589,6 +595,19 @@ func directive(key string, c *app.RequestContext) (val any, found bool) {
 		return c.Get(OutputCost)
 	case TotalCost:
 		return c.Get(TotalCost)
+	case AuthUser:
+		user := c.GetString(AuthUser)
+		return user, true
+	case AuthConsumer:
+		consumer := c.GetString(AuthConsumer)
+		return consumer, true
+	case AuthGroups:
+		return c.Get(AuthGroups)
+	case CacheStatus:
+		status := c.GetString(CacheStatus)
+		return status, true
+	case GuardDetections:
+		return c.Get(GuardDetections)
 	default:
 
 		if strings.HasPrefix(key, "$http.request.header.") {
@@ -635,6 +654,26 @@ func directive(key string, c *app.RequestContext) (val any, found bool) {
 			return string(val), true
 		}
 
+		if strings.HasPrefix(key, "$auth.claim.") {
+			claimKey := key[len("$auth.claim."):]
+			if len(claimKey) == 0 {
+				return "", false
+			}
+
+			val, found := c.Get(AuthClaims)
+			if !found {
+				return "", false
+			}
+
+			claims, ok := val.(map[string]any)
+			if !ok {
+				return "", false
+			}
+
+			claim, found := claims[claimKey]
+			return claim, found
+		}
+
 		if strings.HasPrefix(key, "$http.request.body.json.") {
 			jsonPath := key[len("$http.request.body.json."):]
 			if len(jsonPath) == 0 {
@@ -282,3 +282,32 @@ func TestJSONPath(t *testing.T) {
 	val = GetString("$http.response.body.json.age", hzCtx)
 	assert.Equal(t, "47", val)
 }
+
+func TestAuthDirective(t *testing.T) {
+	hzCtx := app.NewContext(0)
+
+	assert.Empty(t, GetString(AuthUser, hzCtx))
+	_, found := Get("$auth.claim.email", hzCtx)
+	assert.False(t, found)
+
+	hzCtx.Set(AuthUser, "alice")
+	hzCtx.Set(AuthConsumer, "web-app")
+	hzCtx.Set(AuthGroups, []string{"admin", "ops"})
+	hzCtx.Set(AuthClaims, map[string]any{
+		"email":  "alice@example.com",
+		"tenant": 42,
+	})
+
+	assert.Equal(t, "alice", GetString(AuthUser, hzCtx))
+	assert.Equal(t, "web-app", GetString(AuthConsumer, hzCtx))
+	groups, _ := Get(AuthGroups, hzCtx)
+	assert.Equal(t, []string{"admin", "ops"}, groups)
+	assert.Equal(t, "alice@example.com", GetString("$auth.claim.email", hzCtx))
+	assert.Equal(t, "42", GetString("$auth.claim.tenant", hzCtx))
+
+	_, found = Get("$auth.claim.not_found", hzCtx)
+	assert.False(t, found)
+
+	assert.True(t, IsDirective(AuthUser))
+	assert.True(t, IsDirective("$auth.claim.email"))
+}